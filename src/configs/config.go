@@ -2,6 +2,7 @@ package configs
 
 import (
 	"os"
+	"strings"
 
 	"gopkg.in/yaml.v3"
 )
@@ -107,6 +108,60 @@ type Config struct {
 
 	CMDExit []string `yaml:"CMD_exit" json:"CMD_exit"`
 
+	// 单个连接同时合成的TTS任务数，合成结果仍按文本顺序播放
+	TTSConcurrency int `yaml:"tts_concurrency" json:"tts_concurrency"`
+
+	// TTS文本规范化配置
+	TextNormalize TextNormalizeConfig `yaml:"text_normalize" json:"text_normalize"`
+
+	// 对话上下文窗口配置
+	ContextWindow ContextWindowConfig `yaml:"context_window" json:"context_window"`
+
+	// 对话历史持久化配置
+	ConversationHistory ConversationHistoryConfig `yaml:"conversation_history" json:"conversation_history"`
+
+	// 用户长期记忆配置
+	Memory MemoryConfig `yaml:"memory" json:"memory"`
+
+	// 提示词模板配置，prompt 和角色提示词支持模板变量
+	PromptTemplate PromptTemplateConfig `yaml:"prompt_template" json:"prompt_template"`
+
+	// 工具调用配置
+	ToolCall ToolCallConfig `yaml:"tool_call" json:"tool_call"`
+
+	// 多角色配音配置
+	MultiVoice MultiVoiceConfig `yaml:"multi_voice" json:"multi_voice"`
+
+	// 用量统计和配额配置
+	Usage UsageConfig `yaml:"usage" json:"usage"`
+
+	// 知识库检索配置
+	Knowledge KnowledgeConfig `yaml:"knowledge" json:"knowledge"`
+
+	// 内容审核配置
+	Moderation ModerationConfig `yaml:"moderation" json:"moderation"`
+
+	// 回复情绪配置
+	Emotion EmotionConfig `yaml:"emotion" json:"emotion"`
+
+	// 本地意图识别配置
+	Intent IntentConfig `yaml:"intent" json:"intent"`
+
+	// LLM路由配置
+	LLMRouter LLMRouterConfig `yaml:"llm_router" json:"llm_router"`
+
+	// 同声传译拾音模式配置
+	Translate TranslateConfig `yaml:"translate" json:"translate"`
+
+	// 听写拾音模式配置
+	Dictation DictationConfig `yaml:"dictation" json:"dictation"`
+
+	// 会议记录拾音模式配置
+	Meeting MeetingConfig `yaml:"meeting" json:"meeting"`
+
+	// LLM故障转移配置，selected_module.LLM 以逗号分隔主LLM和备用LLM
+	LLMFailover LLMFailoverConfig `yaml:"llm_failover" json:"llm_failover"`
+
 	// 连通性检查配置
 	ConnectivityCheck ConnectivityCheckConfig `yaml:"connectivity_check" json:"connectivity_check"`
 }
@@ -124,6 +179,231 @@ type McpPoolConfig struct {
 	PoolCheckInterval int `yaml:"pool_check_interval"`
 }
 
+// TextNormalizeConfig TTS文本规范化配置
+type TextNormalizeConfig struct {
+	Enabled  bool   `yaml:"enabled"   json:"enabled"`
+	Language string `yaml:"language"  json:"language"`  // 默认语言，如 zh-CN、en
+	MaxRunes int    `yaml:"max_runes" json:"max_runes"` // 单次合成的最大字符数，超出时按标点拆分
+}
+
+// ContextWindowConfig 对话上下文窗口配置
+// 对话历史超出token预算时，使用LLM将最早的对话摘要为一条系统消息
+type ContextWindowConfig struct {
+	Enabled    bool   `yaml:"enabled"     json:"enabled"`
+	MaxTokens  int    `yaml:"max_tokens"  json:"max_tokens"`  // 对话历史的token预算，可在LLM配置中用 context_tokens 覆盖
+	KeepRecent int    `yaml:"keep_recent" json:"keep_recent"` // 至少保留的最近消息数
+	Prompt     string `yaml:"prompt"      json:"prompt"`      // 摘要提示词，为空时使用默认提示词
+}
+
+// ConversationHistoryConfig 对话历史持久化配置
+type ConversationHistoryConfig struct {
+	Enabled bool `yaml:"enabled" json:"enabled"` // 是否将每轮对话保存到数据库
+}
+
+// MemoryConfig 用户长期记忆配置
+type MemoryConfig struct {
+	Enabled      bool `yaml:"enabled"        json:"enabled"`
+	TopK         int  `yaml:"top_k"          json:"top_k"`          // 每轮加入提示词的最大记忆条数
+	MinUserTurns int  `yaml:"min_user_turns" json:"min_user_turns"` // 用户发言少于该轮数的会话不提取记忆
+}
+
+// PromptTemplateConfig 提示词模板配置
+type PromptTemplateConfig struct {
+	Timezone string `yaml:"timezone" json:"timezone"` // 模板中日期时间使用的时区，为空时使用服务器时区
+}
+
+// ToolCallConfig 工具调用配置
+// LLM一次可请求多个工具调用，工具结果返回LLM后可继续调用工具，直到LLM给出回复或达到最大深度
+type ToolCallConfig struct {
+	MaxDepth    int    `yaml:"max_depth"    json:"max_depth"`    // 一轮对话中执行工具调用的最大次数
+	Concurrency int    `yaml:"concurrency"  json:"concurrency"`  // 同时执行的工具调用数
+	FillerDelay int    `yaml:"filler_delay" json:"filler_delay"` // 工具执行超过该时间（毫秒）时播报等待提示，0表示不播报
+	FillerText  string `yaml:"filler_text"  json:"filler_text"`  // 等待提示文本
+	// 可与其他工具并发执行的只读工具，本地工具可省略 local_ 前缀，为空时使用默认列表
+	// 未列出的工具可能有副作用（如调节音量、播放、切换角色），按模型返回的顺序依次执行
+	ParallelTools []string `yaml:"parallel_tools" json:"parallel_tools"`
+}
+
+// UsageConfig 用量统计和配额配置
+// 按用户、设备、提供者和日期汇总LLM token数、TTS字符数和ASR音频时长
+type UsageConfig struct {
+	Enabled      bool                        `yaml:"enabled"       json:"enabled"`
+	DefaultLevel string                      `yaml:"default_level" json:"default_level"` // 未设置级别的用户使用的级别：basic/premium/business
+	RefuseText   string                      `yaml:"refuse_text"   json:"refuse_text"`   // 超出配额时的回复
+	Quotas       map[string]UsageQuotaConfig `yaml:"quotas"        json:"quotas"`        // 各用户级别的配额，未配置的级别使用内置默认值
+	Prices       map[string]UsagePriceConfig `yaml:"prices"        json:"prices"`        // 各提供者的单价，键为配置中的提供者名称
+}
+
+// UsageQuotaConfig 对话用量配额，0表示不限制
+type UsageQuotaConfig struct {
+	DailyTokens       int64   `yaml:"daily_tokens"        json:"daily_tokens"`
+	MonthlyTokens     int64   `yaml:"monthly_tokens"      json:"monthly_tokens"`
+	DailyTTSChars     int64   `yaml:"daily_tts_chars"     json:"daily_tts_chars"`
+	MonthlyTTSChars   int64   `yaml:"monthly_tts_chars"   json:"monthly_tts_chars"`
+	DailyASRSeconds   float64 `yaml:"daily_asr_seconds"   json:"daily_asr_seconds"`
+	MonthlyASRSeconds float64 `yaml:"monthly_asr_seconds" json:"monthly_asr_seconds"`
+}
+
+// UsagePriceConfig 提供者单价，用于用量报表中的费用估算
+type UsagePriceConfig struct {
+	PromptPer1K     float64 `yaml:"prompt_per_1k"     json:"prompt_per_1k"`     // 每千输入token
+	CompletionPer1K float64 `yaml:"completion_per_1k" json:"completion_per_1k"` // 每千输出token
+	CharsPer1K      float64 `yaml:"chars_per_1k"      json:"chars_per_1k"`      // 每千TTS字符
+	PerMinute       float64 `yaml:"per_minute"        json:"per_minute"`        // 每分钟ASR音频
+}
+
+// KnowledgeConfig 知识库检索配置
+// 上传的文档切分后生成向量保存在数据库中，对话时检索相关内容加入提示词，也可由LLM调用 search_knowledge 工具检索
+type KnowledgeConfig struct {
+	Enabled         bool                     `yaml:"enabled"           json:"enabled"`
+	Embedding       KnowledgeEmbeddingConfig `yaml:"embedding"         json:"embedding"`
+	ChunkSize       int                      `yaml:"chunk_size"        json:"chunk_size"`        // 每个文本块的最大字符数
+	ChunkOverlap    int                      `yaml:"chunk_overlap"     json:"chunk_overlap"`     // 相邻文本块重叠的字符数
+	TopK            int                      `yaml:"top_k"             json:"top_k"`             // 每次检索返回的最大文本块数
+	MinScore        float64                  `yaml:"min_score"         json:"min_score"`         // 最低相似度，低于该值的文本块不返回
+	AutoInject      bool                     `yaml:"auto_inject"       json:"auto_inject"`       // 每轮对话自动检索并加入提示词
+	MaxDocumentSize int                      `yaml:"max_document_size" json:"max_document_size"` // 单个文档的最大字符数
+}
+
+// KnowledgeEmbeddingConfig 向量模型配置
+type KnowledgeEmbeddingConfig struct {
+	Type       string `yaml:"type"       json:"type"` // openai（兼容OpenAI的接口）或 ollama
+	BaseURL    string `yaml:"base_url"   json:"base_url"`
+	APIKey     string `yaml:"api_key"    json:"api_key"`
+	Model      string `yaml:"model"      json:"model"`
+	Dimensions int    `yaml:"dimensions" json:"dimensions"` // 向量维度，0表示使用模型默认维度
+	BatchSize  int    `yaml:"batch_size" json:"batch_size"` // 每次请求的文本数
+	Timeout    int    `yaml:"timeout"    json:"timeout"`    // 请求超时时间（秒）
+}
+
+// ModerationConfig 内容审核配置
+// 用户文本加入对话历史前、助手回复分段播报前分别审核，命中后按配置的动作处理并记录审计日志
+type ModerationConfig struct {
+	Enabled    bool                       `yaml:"enabled"     json:"enabled"`
+	Input      ModerationStageConfig      `yaml:"input"       json:"input"`
+	Output     ModerationStageConfig      `yaml:"output"      json:"output"`
+	Keywords   []string                   `yaml:"keywords"    json:"keywords"` // 敏感词，不区分大小写
+	Patterns   []string                   `yaml:"patterns"    json:"patterns"` // 正则表达式
+	Endpoint   ModerationEndpointConfig   `yaml:"endpoint"    json:"endpoint"`
+	Classifier ModerationClassifierConfig `yaml:"classifier"  json:"classifier"`
+	FailClosed bool                       `yaml:"fail_closed" json:"fail_closed"` // 审核服务出错时按命中处理
+	Timeout    int                        `yaml:"timeout"     json:"timeout"`     // 单次审核超时时间（毫秒）
+}
+
+// ModerationStageConfig 输入或输出阶段的审核配置
+type ModerationStageConfig struct {
+	Enabled     bool     `yaml:"enabled"     json:"enabled"`
+	Action      string   `yaml:"action"      json:"action"`      // block 替换为话术，redact 遮盖命中内容，end 播报话术后结束会话
+	Replacement string   `yaml:"replacement" json:"replacement"` // 替换话术
+	Checkers    []string `yaml:"checkers"    json:"checkers"`    // 使用的检查器：keyword/endpoint/llm，为空时使用全部已配置的检查器
+}
+
+// ModerationEndpointConfig 兼容OpenAI /moderations 接口的审核服务配置
+type ModerationEndpointConfig struct {
+	BaseURL string `yaml:"base_url" json:"base_url"` // 为空时不启用
+	APIKey  string `yaml:"api_key"  json:"api_key"`
+	Model   string `yaml:"model"    json:"model"`
+}
+
+// ModerationClassifierConfig LLM分类器配置，使用连接当前的LLM判断内容是否适合儿童
+type ModerationClassifierConfig struct {
+	Enabled bool   `yaml:"enabled" json:"enabled"`
+	Prompt  string `yaml:"prompt"  json:"prompt"` // 分类提示词，为空时使用默认提示词
+}
+
+// EmotionConfig 回复情绪配置，LLM在每句话开头标注情绪，播放该句时发送给设备
+type EmotionConfig struct {
+	Enabled bool   `yaml:"enabled" json:"enabled"`
+	Prompt  string `yaml:"prompt"  json:"prompt"` // 追加到系统提示词的标注说明，为空时使用默认说明
+}
+
+// IntentConfig 本地意图识别配置，常用指令命中后直接调用工具，不经过LLM
+type IntentConfig struct {
+	Enabled   bool                `yaml:"enabled"   json:"enabled"`
+	Threshold float64             `yaml:"threshold" json:"threshold"` // 置信度阈值（0-1），句式覆盖整句的比例低于阈值时交给LLM
+	Fillers   []string            `yaml:"fillers"   json:"fillers"`   // 匹配前去除的客套词和语气词，为空时使用默认列表
+	Synonyms  map[string][]string `yaml:"synonyms"  json:"synonyms"`  // 同义词，匹配前统一替换为键中的标准说法
+	Intents   []IntentRuleConfig  `yaml:"intents"   json:"intents"`
+}
+
+// IntentRuleConfig 单个本地意图
+type IntentRuleConfig struct {
+	Name      string                 `yaml:"name"      json:"name"`
+	Tool      string                 `yaml:"tool"      json:"tool"`      // 调用的本地或设备MCP工具，本地工具可省略 local_ 前缀
+	Patterns  []string               `yaml:"patterns"  json:"patterns"`  // 句式，{slot} 提取文本，{slot:number} 提取数字
+	Arguments map[string]interface{} `yaml:"arguments" json:"arguments"` // 工具参数，字符串中的 {slot} 替换为提取的值
+	Reply     string                 `yaml:"reply"     json:"reply"`     // 工具执行后的播报，为空且工具结果需要LLM处理时交给LLM回复
+}
+
+// LLMRouterConfig LLM路由配置，每轮对话按规则或分类LLM选择LLM和角色
+type LLMRouterConfig struct {
+	Enabled    bool             `yaml:"enabled"    json:"enabled"`
+	Classifier string           `yaml:"classifier" json:"classifier"` // 规则未命中时用于分类的LLM名称，为空时只使用规则
+	Default    string           `yaml:"default"    json:"default"`    // 未命中任何路由时使用的路由，为空时使用连接当前的LLM和角色
+	Timeout    int              `yaml:"timeout"    json:"timeout"`    // 分类超时时间（毫秒）
+	Routes     []LLMRouteConfig `yaml:"routes"     json:"routes"`
+}
+
+// LLMRouteConfig 单条LLM路由
+type LLMRouteConfig struct {
+	Name        string   `yaml:"name"        json:"name"`
+	Description string   `yaml:"description" json:"description"` // 提供给分类LLM的说明
+	LLM         string   `yaml:"llm"         json:"llm"`         // 使用的LLM名称，为空时使用角色指定的LLM或当前LLM
+	Persona     string   `yaml:"persona"     json:"persona"`     // 使用的角色，为空时保持当前角色
+	Keywords    []string `yaml:"keywords"    json:"keywords"`    // 规则：包含任一关键词，不区分大小写
+	Patterns    []string `yaml:"patterns"    json:"patterns"`    // 规则：匹配任一正则表达式
+	MinLength   int      `yaml:"min_length"  json:"min_length"`  // 规则：字数不少于该值，0表示不使用
+}
+
+// TranslateConfig 同声传译配置，客户端以 translate 拾音模式使用
+// 每句识别结果由LLM翻译后用目标语言的音色播报，不经过角色提示词和工具
+type TranslateConfig struct {
+	Source     string            `yaml:"source"      json:"source"`      // 源语言代码，如 zh
+	Target     string            `yaml:"target"      json:"target"`      // 目标语言代码，如 en
+	AutoDetect bool              `yaml:"auto_detect" json:"auto_detect"` // 自动识别说话语言，说目标语言时反向翻译，供两人通过同一设备交谈
+	LLM        string            `yaml:"llm"         json:"llm"`         // 翻译使用的LLM名称，为空时使用当前LLM
+	Prompt     string            `yaml:"prompt"      json:"prompt"`      // 翻译提示词，{source} 和 {target} 替换为语言名称
+	Voices     map[string]string `yaml:"voices"      json:"voices"`      // 语言代码到当前TTS音色的映射，未配置的语言使用当前音色
+}
+
+// DictationConfig 听写配置，客户端以 dictation 拾音模式使用
+// 识别结果只累积为文档，不经过LLM对话和TTS，听写结束时可由LLM整理标点
+type DictationConfig struct {
+	Polish bool   `yaml:"polish" json:"polish"` // 听写结束时由LLM补充标点、修正识别错误
+	LLM    string `yaml:"llm"    json:"llm"`    // 整理使用的LLM名称，为空时使用当前LLM
+	Prompt string `yaml:"prompt" json:"prompt"` // 整理提示词，为空时使用默认提示词
+}
+
+// MeetingConfig 会议记录配置，客户端以 meeting 拾音模式使用
+// 长时间持续识别并记录每句话的时间，结束时分段总结生成纪要和待办事项，播报并与完整记录一起保存
+type MeetingConfig struct {
+	MaxDuration    int    `yaml:"max_duration"    json:"max_duration"`    // 最长会议时长（分钟），超过后自动结束
+	StreamRollover int    `yaml:"stream_rollover" json:"stream_rollover"` // 单个ASR识别会话的最长时间（秒），超过后重新建立会话
+	ChunkRunes     int    `yaml:"chunk_runes"     json:"chunk_runes"`     // 每次总结请求的会议记录最大字符数，超过时分段提炼
+	LLM            string `yaml:"llm"             json:"llm"`             // 总结使用的LLM名称，为空时使用当前LLM
+	ChunkPrompt    string `yaml:"chunk_prompt"    json:"chunk_prompt"`    // 分段提炼要点的提示词，为空时使用默认提示词
+	SummaryPrompt  string `yaml:"summary_prompt"  json:"summary_prompt"`  // 生成纪要的提示词，为空时使用默认提示词
+}
+
+// MultiVoiceConfig 多角色配音配置
+// LLM按 【说话人】 标注对白，每个说话人使用当前TTS支持的不同音色合成
+type MultiVoiceConfig struct {
+	Enabled  bool              `yaml:"enabled"  json:"enabled"`
+	Roles    []string          `yaml:"roles"    json:"roles"`    // 启用的角色，default 表示默认角色，为空时对所有角色生效
+	Narrator []string          `yaml:"narrator" json:"narrator"` // 使用默认音色的说话人，如 旁白
+	Speakers map[string]string `yaml:"speakers" json:"speakers"` // 说话人到音色的固定映射
+	Prompt   string            `yaml:"prompt"   json:"prompt"`   // 追加到系统提示词的标注说明
+}
+
+// LLMFailoverConfig LLM故障转移与熔断配置
+type LLMFailoverConfig struct {
+	FirstTokenTimeout int     `yaml:"first_token_timeout" json:"first_token_timeout"` // 首个token超时时间（秒），超时后切换备用LLM
+	ErrorRate         float64 `yaml:"error_rate"          json:"error_rate"`          // 触发熔断的错误率，0-1
+	MinRequests       int     `yaml:"min_requests"        json:"min_requests"`        // 统计窗口内触发熔断的最少请求数
+	Window            int     `yaml:"window"              json:"window"`              // 错误率统计窗口（秒）
+	OpenDuration      int     `yaml:"open_duration"       json:"open_duration"`       // 熔断持续时间（秒），之后放行一个探测请求
+}
+
 // ASRConfig ASR配置结构
 type ASRConfig map[string]interface{}
 
@@ -145,6 +425,8 @@ type TTSConfig struct {
 	Token           string      `yaml:"token"            json:"token"`            // API密钥
 	Cluster         string      `yaml:"cluster"          json:"cluster"`          // 集群信息
 	SupportedVoices []VoiceInfo `yaml:"supported_voices" json:"supported_voices"` // 支持的语音列表
+	PreviewText     string      `yaml:"preview_text"     json:"preview_text"`     // 音色试听文本，需包含一个 %s 替换为音色显示名称，字面的百分号写作 %%
+	Language        string      `yaml:"language"         json:"language"`         // 文本规范化语言，为空时使用全局配置
 }
 
 // LLMConfig LLM配置结构
@@ -200,6 +482,26 @@ var (
 	Cfg *Config
 )
 
+// SelectedLLMs 返回选定的LLM列表，第一个为主LLM，其余为按顺序使用的备用LLM
+// selected_module.LLM 支持逗号分隔，如 "QwenLLM,ChatGLMLLM"
+func (cfg *Config) SelectedLLMs() []string {
+	var names []string
+	for _, name := range strings.Split(cfg.SelectedModule["LLM"], ",") {
+		if name = strings.TrimSpace(name); name != "" {
+			names = append(names, name)
+		}
+	}
+	return names
+}
+
+// PrimaryLLM 返回选定的主LLM，未配置时返回空
+func (cfg *Config) PrimaryLLM() string {
+	if names := cfg.SelectedLLMs(); len(names) > 0 {
+		return names[0]
+	}
+	return ""
+}
+
 func (cfg *Config) ToString() string {
 	data, _ := yaml.Marshal(cfg)
 	return string(data)
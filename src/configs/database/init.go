@@ -181,6 +181,21 @@ func migrateTables(db *gorm.DB) error {
 		&models.DeviceBind{},
 		&models.UserAIConfig{},
 		&models.UserSessionConfig{},
+		&models.UserVoicePreference{},
+		&models.LexiconEntry{},
+		&models.UserMemory{},
+		&models.ConversationSession{},
+		&models.ConversationMessage{},
+		&models.UserPromptProfile{},
+		&models.Persona{},
+		&models.UsageRecord{},
+		&models.UserUsageLevel{},
+		&models.KnowledgeBase{},
+		&models.KnowledgeDocument{},
+		&models.KnowledgeChunk{},
+		&models.ModerationEvent{},
+		&models.DictationDocument{},
+		&models.MeetingRecord{},
 	)
 }
 
@@ -2,6 +2,8 @@ package chat
 
 import (
 	"encoding/json"
+	"strings"
+	"sync"
 
 	"angrymiao-ai-server/src/core/types"
 	"angrymiao-ai-server/src/core/utils"
@@ -9,11 +11,30 @@ import (
 
 type Message = types.Message
 
+// SummaryPrefix 对话摘要消息的内容前缀，用于识别摘要消息
+const SummaryPrefix = "【之前的对话摘要】\n"
+
+// InterruptedSuffix 回复被用户打断时追加在助手消息末尾的标记，让LLM知道用户没有听完
+const InterruptedSuffix = "……【回复被用户打断，用户只听到了以上内容】"
+
+// InterruptedReply 生成被打断的助手回复内容
+func InterruptedReply(spoken string) string {
+	if strings.TrimSpace(spoken) == "" {
+		return "【回复被用户打断，用户没有听到任何内容】"
+	}
+	return spoken + InterruptedSuffix
+}
+
 // DialogueManager 管理对话上下文和历史
+// 对话轮次、打断截断和后台摘要在不同协程中修改对话历史，所有方法都持有 mu
 type DialogueManager struct {
 	logger   *utils.Logger
+	mu       sync.Mutex
 	dialogue []Message
 	memory   MemoryInterface
+
+	// 本轮新增的消息，不受保留最近消息和摘要的影响，用于保存对话记录
+	roundMessages []Message
 }
 
 // NewDialogueManager 创建对话管理器实例
@@ -26,12 +47,14 @@ func NewDialogueManager(logger *utils.Logger, memory MemoryInterface) *DialogueM
 }
 
 func (dm *DialogueManager) SetSystemMessage(systemMessage string) {
+	dm.mu.Lock()
+	defer dm.mu.Unlock()
 	if systemMessage == "" {
 		return
 	}
 
 	// 如果对话中已经有系统消息，则不再添加
-	if len(dm.dialogue) > 0 && dm.dialogue[0].Role == "system" {
+	if len(dm.dialogue) > 0 && dm.dialogue[0].Role == "system" && !isSummary(dm.dialogue[0]) {
 		dm.dialogue[0].Content = systemMessage
 		return
 	}
@@ -44,56 +67,197 @@ func (dm *DialogueManager) SetSystemMessage(systemMessage string) {
 
 // 保留最近的几条对话消息
 func (dm *DialogueManager) KeepRecentMessages(maxMessages int) {
+	dm.mu.Lock()
+	defer dm.mu.Unlock()
 	if maxMessages <= 0 || len(dm.dialogue) <= maxMessages {
 		return
 	}
+	// 不从工具调用和工具结果之间截断
+	start := skipToolResults(dm.dialogue, len(dm.dialogue)-maxMessages)
 	// 保留system消息和最近的 maxMessages 条消息
 	if len(dm.dialogue) > 0 && dm.dialogue[0].Role == "system" {
 		// 保留system消息
-		dm.dialogue = append(dm.dialogue[:1], dm.dialogue[len(dm.dialogue)-maxMessages:]...)
+		dm.dialogue = append(dm.dialogue[:1], dm.dialogue[start:]...)
 		return
 	}
 	// 如果没有system消息，直接保留最近的 maxMessages 条消息
-	if len(dm.dialogue) > maxMessages {
-		dm.dialogue = dm.dialogue[len(dm.dialogue)-maxMessages:]
+	dm.dialogue = dm.dialogue[start:]
+}
+
+// SelectForSummary 对话超出token预算时，选出需要摘要的最早消息
+// 返回已有的摘要内容和需要摘要的消息，至少保留最近 keepRecent 条消息，不需要摘要时返回空
+// 摘要后剩余的对话控制在预算的一半以内，避免每轮都触发摘要
+func (dm *DialogueManager) SelectForSummary(estimator *TokenEstimator, budget int, keepRecent int) (string, []Message) {
+	dm.mu.Lock()
+	defer dm.mu.Unlock()
+	if budget <= 0 {
+		return "", nil
+	}
+	total := estimator.EstimateMessages(dm.dialogue)
+	if total <= budget {
+		return "", nil
+	}
+
+	start := dm.systemHead()
+	previous := ""
+	if start < len(dm.dialogue) && isSummary(dm.dialogue[start]) {
+		previous = strings.TrimPrefix(dm.dialogue[start].Content, SummaryPrefix)
+		start++
+	}
+
+	end := start
+	target := budget / 2
+	for end < len(dm.dialogue)-keepRecent && total > target {
+		total -= estimator.EstimateMessage(dm.dialogue[end])
+		end++
+	}
+	// 工具结果和对应的工具调用一起摘要
+	end = skipToolResults(dm.dialogue, end)
+	if end <= start {
+		return "", nil
 	}
+
+	messages := make([]Message, end-start)
+	copy(messages, dm.dialogue[start:end])
+	return previous, messages
+}
+
+// ApplySummary 用摘要替换 SelectForSummary 选出的消息，摘要作为系统消息放在系统提示词之后
+// 摘要在后台生成，期间对话历史可能已被截断或摘要，选出的消息不再位于原处时放弃本次摘要并返回false
+func (dm *DialogueManager) ApplySummary(summary string, summarized []Message) bool {
+	dm.mu.Lock()
+	defer dm.mu.Unlock()
+	head := dm.systemHead()
+	start := head
+	if start < len(dm.dialogue) && isSummary(dm.dialogue[start]) {
+		start++
+	}
+	end := start + len(summarized)
+	if len(summarized) == 0 || end > len(dm.dialogue) {
+		return false
+	}
+	for i, msg := range summarized {
+		if !sameMessage(dm.dialogue[start+i], msg) {
+			return false
+		}
+	}
+
+	dialogue := make([]Message, 0, len(dm.dialogue)-end+head+1)
+	dialogue = append(dialogue, dm.dialogue[:head]...)
+	dialogue = append(dialogue, Message{Role: "system", Content: SummaryPrefix + summary})
+	dialogue = append(dialogue, dm.dialogue[end:]...)
+	dm.dialogue = dialogue
+	return true
+}
+
+// sameMessage 判断两条消息是否为同一条对话消息
+func sameMessage(a, b Message) bool {
+	return a.Role == b.Role && a.Content == b.Content && a.ToolCallID == b.ToolCallID && len(a.ToolCalls) == len(b.ToolCalls)
+}
+
+// systemHead 返回系统提示词之后的位置
+func (dm *DialogueManager) systemHead() int {
+	if len(dm.dialogue) > 0 && dm.dialogue[0].Role == "system" && !isSummary(dm.dialogue[0]) {
+		return 1
+	}
+	return 0
+}
+
+// isSummary 是否为对话摘要消息
+func isSummary(msg Message) bool {
+	return msg.Role == "system" && strings.HasPrefix(msg.Content, SummaryPrefix)
+}
+
+// skipToolResults 从index开始跳过工具结果消息，保证工具调用和工具结果不被拆开
+func skipToolResults(dialogue []Message, index int) int {
+	for index < len(dialogue) && dialogue[index].Role == "tool" {
+		index++
+	}
+	return index
 }
 
 // GetRecentMessages 获取最近的对话消息
 // 如果 maxMessages <= 0，则返回全部对话消息
 func (dm *DialogueManager) GetRecentMessages(maxMessages int) []Message {
+	dm.mu.Lock()
+	defer dm.mu.Unlock()
 	if maxMessages <= 0 || len(dm.dialogue) <= maxMessages {
-		return dm.dialogue
+		return dm.copyDialogue()
 	}
 	// 保留system消息和最近的 maxMessages 条消息
 	if len(dm.dialogue) > 0 && dm.dialogue[0].Role == "system" {
 		// 保留system消息
 		return append([]Message{dm.dialogue[0]}, dm.dialogue[len(dm.dialogue)-maxMessages:]...)
 	}
-	return dm.dialogue
+	return dm.copyDialogue()
 }
 
 // Put 添加新消息到对话
 func (dm *DialogueManager) Put(message Message) {
+	dm.mu.Lock()
+	defer dm.mu.Unlock()
 	dm.dialogue = append(dm.dialogue, message)
+	dm.roundMessages = append(dm.roundMessages, message)
+}
+
+// BeginRound 开始新的对话轮次，清空上一轮新增的消息
+func (dm *DialogueManager) BeginRound() {
+	dm.mu.Lock()
+	defer dm.mu.Unlock()
+	dm.roundMessages = nil
+}
+
+// RoundMessages 返回本轮新增消息的副本
+func (dm *DialogueManager) RoundMessages() []Message {
+	dm.mu.Lock()
+	defer dm.mu.Unlock()
+	messages := make([]Message, len(dm.roundMessages))
+	copy(messages, dm.roundMessages)
+	return messages
+}
+
+// TruncateLastReply 将最后一条助手回复替换为用户实际听到的内容，并标记为被打断
+// 最后一条消息不是助手的文本回复时不做处理
+func (dm *DialogueManager) TruncateLastReply(spoken string) bool {
+	dm.mu.Lock()
+	defer dm.mu.Unlock()
+	if len(dm.dialogue) == 0 {
+		return false
+	}
+	last := &dm.dialogue[len(dm.dialogue)-1]
+	if last.Role != "assistant" || len(last.ToolCalls) > 0 {
+		return false
+	}
+	// 回复是本轮新增的消息时同步修改，保证对话记录与对话历史一致
+	if n := len(dm.roundMessages); n > 0 {
+		if added := &dm.roundMessages[n-1]; added.Role == "assistant" && len(added.ToolCalls) == 0 && added.Content == last.Content {
+			added.Content = InterruptedReply(spoken)
+		}
+	}
+	last.Content = InterruptedReply(spoken)
+	return true
 }
 
 func (dm *DialogueManager) GetLastTwoMessages() []Message {
+	dm.mu.Lock()
+	defer dm.mu.Unlock()
 	if len(dm.dialogue) < 2 {
 		return nil
 	}
-	return dm.dialogue[len(dm.dialogue)-2:]
+	return append([]Message(nil), dm.dialogue[len(dm.dialogue)-2:]...)
 }
 
-// GetLLMDialogue 获取完整对话历史
+// GetLLMDialogue 获取完整对话历史的副本
 func (dm *DialogueManager) GetLLMDialogue() []Message {
-	return dm.dialogue
+	return dm.Snapshot()
 }
 
 // GetLLMDialogueWithMemory 获取带记忆的对话
 func (dm *DialogueManager) GetLLMDialogueWithMemory(memoryStr string) []Message {
+	dm.mu.Lock()
+	defer dm.mu.Unlock()
 	if memoryStr == "" {
-		return dm.GetLLMDialogue()
+		return dm.copyDialogue()
 	}
 
 	memoryMsg := Message{
@@ -101,24 +265,84 @@ func (dm *DialogueManager) GetLLMDialogueWithMemory(memoryStr string) []Message
 		Content: memoryStr,
 	}
 
+	// 记忆放在系统提示词之后，保证角色设定仍是第一条消息
+	head := dm.systemHead()
 	dialogue := make([]Message, 0, len(dm.dialogue)+1)
+	dialogue = append(dialogue, dm.dialogue[:head]...)
 	dialogue = append(dialogue, memoryMsg)
-	dialogue = append(dialogue, dm.dialogue...)
+	dialogue = append(dialogue, dm.dialogue[head:]...)
+
+	return dialogue
+}
+
+// SetMemory 设置长期记忆
+func (dm *DialogueManager) SetMemory(memory MemoryInterface) {
+	dm.mu.Lock()
+	defer dm.mu.Unlock()
+	dm.memory = memory
+}
+
+// QueryMemory 查询与内容相关的长期记忆，未设置记忆或查询失败时返回空
+func (dm *DialogueManager) QueryMemory(query string) string {
+	memory := dm.getMemory()
+	if memory == nil {
+		return ""
+	}
+	memoryStr, err := memory.QueryMemory(query)
+	if err != nil {
+		dm.logger.Error("查询长期记忆失败: %v", err)
+		return ""
+	}
+	return memoryStr
+}
+
+// SaveMemory 从当前对话中提取并保存长期记忆
+func (dm *DialogueManager) SaveMemory() error {
+	memory := dm.getMemory()
+	if memory == nil {
+		return nil
+	}
+	return memory.SaveMemory(dm.Snapshot())
+}
+
+// getMemory 返回当前设置的长期记忆，查询和保存时不持有锁
+func (dm *DialogueManager) getMemory() MemoryInterface {
+	dm.mu.Lock()
+	defer dm.mu.Unlock()
+	return dm.memory
+}
+
+// Snapshot 返回对话历史的副本
+func (dm *DialogueManager) Snapshot() []Message {
+	dm.mu.Lock()
+	defer dm.mu.Unlock()
+	return dm.copyDialogue()
+}
 
+// copyDialogue 复制对话历史，调用方需持有 mu
+func (dm *DialogueManager) copyDialogue() []Message {
+	dialogue := make([]Message, len(dm.dialogue))
+	copy(dialogue, dm.dialogue)
 	return dialogue
 }
 
 // Clear 清空对话历史
 func (dm *DialogueManager) Clear() {
+	dm.mu.Lock()
+	defer dm.mu.Unlock()
 	dm.dialogue = make([]Message, 0)
 }
 
 func (dm *DialogueManager) Length() int {
+	dm.mu.Lock()
+	defer dm.mu.Unlock()
 	return len(dm.dialogue)
 }
 
 // ToJSON 将对话历史转换为JSON字符串
 func (dm *DialogueManager) ToJSON(keepSystemPrompt bool) (string, error) {
+	dm.mu.Lock()
+	defer dm.mu.Unlock()
 	dialogue := dm.dialogue
 	if !keepSystemPrompt && len(dialogue) > 0 && dialogue[0].Role == "system" {
 		// 如果不保留系统消息，则移除第一条消息
@@ -133,5 +357,7 @@ func (dm *DialogueManager) ToJSON(keepSystemPrompt bool) (string, error) {
 
 // LoadFromJSON 从JSON字符串加载对话历史
 func (dm *DialogueManager) LoadFromJSON(jsonStr string) error {
+	dm.mu.Lock()
+	defer dm.mu.Unlock()
 	return json.Unmarshal([]byte(jsonStr), &dm.dialogue)
 }
@@ -0,0 +1,172 @@
+package chat
+
+import (
+	"strings"
+	"sync"
+	"testing"
+
+	"angrymiao-ai-server/src/core/types"
+)
+
+func TestSelectForSummaryKeepsToolPairs(t *testing.T) {
+	dm := NewDialogueManager(nil, nil)
+	dm.SetSystemMessage("你是助手")
+	long := strings.Repeat("很长的内容", 40)
+	dm.Put(Message{Role: "user", Content: long})
+	dm.Put(Message{Role: "assistant", ToolCalls: []types.ToolCall{{ID: "1", Function: types.FunctionCall{Name: "get_weather", Arguments: "{}"}}}})
+	dm.Put(Message{Role: "tool", ToolCallID: "1", Content: long})
+	dm.Put(Message{Role: "assistant", Content: "晴天"})
+	dm.Put(Message{Role: "user", Content: "谢谢"})
+	dm.Put(Message{Role: "assistant", Content: "不客气"})
+
+	estimator := NewTokenEstimator("qwen-plus")
+	previous, messages := dm.SelectForSummary(estimator, 200, 2)
+	if previous != "" || len(messages) != 3 || messages[len(messages)-1].Role != "tool" {
+		t.Fatalf("SelectForSummary() = %q, %v", previous, messages)
+	}
+
+	if !dm.ApplySummary("用户询问了天气", messages) {
+		t.Fatal("ApplySummary() = false, 期望替换选出的消息")
+	}
+	dialogue := dm.GetLLMDialogue()
+	if len(dialogue) != 5 || dialogue[0].Content != "你是助手" || dialogue[1].Content != SummaryPrefix+"用户询问了天气" {
+		t.Fatalf("ApplySummary() 后对话 = %v", dialogue)
+	}
+
+	// 预算内不再摘要，更新系统提示词不影响摘要
+	if _, messages := dm.SelectForSummary(estimator, 200, 2); len(messages) != 0 {
+		t.Fatalf("预算内不应摘要: %v", messages)
+	}
+	dm.SetSystemMessage("新的提示词")
+	if dialogue := dm.GetLLMDialogue(); dialogue[0].Content != "新的提示词" || dialogue[1].Content != SummaryPrefix+"用户询问了天气" {
+		t.Fatalf("SetSystemMessage() 后对话 = %v", dialogue)
+	}
+}
+
+// 摘要生成期间对话历史已被修改时放弃摘要
+func TestApplySummarySkipsChangedDialogue(t *testing.T) {
+	dm := NewDialogueManager(nil, nil)
+	dm.SetSystemMessage("你是助手")
+	long := strings.Repeat("很长的内容", 40)
+	dm.Put(Message{Role: "user", Content: long})
+	dm.Put(Message{Role: "assistant", Content: long})
+	dm.Put(Message{Role: "user", Content: "谢谢"})
+	dm.Put(Message{Role: "assistant", Content: "不客气"})
+
+	estimator := NewTokenEstimator("qwen-plus")
+	_, messages := dm.SelectForSummary(estimator, 200, 2)
+	if len(messages) == 0 {
+		t.Fatal("SelectForSummary() 应选出需要摘要的消息")
+	}
+	dm.KeepRecentMessages(2)
+	before := dm.GetLLMDialogue()
+	if dm.ApplySummary("摘要", messages) {
+		t.Error("对话历史已修改时 ApplySummary() 应返回false")
+	}
+	if after := dm.GetLLMDialogue(); len(after) != len(before) || after[1].Content != "谢谢" {
+		t.Errorf("放弃摘要后对话 = %v", after)
+	}
+}
+
+func TestKeepRecentMessagesKeepsToolPairs(t *testing.T) {
+	dm := NewDialogueManager(nil, nil)
+	dm.SetSystemMessage("你是助手")
+	dm.Put(Message{Role: "user", Content: "天气"})
+	dm.Put(Message{Role: "assistant", ToolCalls: []types.ToolCall{{ID: "1"}}})
+	dm.Put(Message{Role: "tool", ToolCallID: "1", Content: "晴"})
+	dm.Put(Message{Role: "assistant", Content: "晴天"})
+
+	dm.KeepRecentMessages(2)
+	dialogue := dm.GetLLMDialogue()
+	if len(dialogue) != 2 || dialogue[1].Content != "晴天" {
+		t.Fatalf("KeepRecentMessages() 后对话 = %v", dialogue)
+	}
+}
+
+func TestTruncateLastReply(t *testing.T) {
+	dm := NewDialogueManager(nil, nil)
+	dm.Put(Message{Role: "user", Content: "讲个故事"})
+	if dm.TruncateLastReply("从前") {
+		t.Fatal("最后一条不是助手回复时不应截断")
+	}
+
+	dm.Put(Message{Role: "assistant", Content: "从前有座山，山里有座庙。"})
+	if !dm.TruncateLastReply("从前有座山，") {
+		t.Fatal("TruncateLastReply() 应截断助手回复")
+	}
+	if got := dm.GetLLMDialogue()[1].Content; got != "从前有座山，"+InterruptedSuffix {
+		t.Errorf("截断后内容 = %s", got)
+	}
+}
+
+func TestRoundMessages(t *testing.T) {
+	dm := NewDialogueManager(nil, nil)
+	dm.SetSystemMessage("你是助手")
+	dm.Put(Message{Role: "user", Content: "你好"})
+	dm.Put(Message{Role: "assistant", Content: "你好呀"})
+
+	dm.BeginRound()
+	dm.Put(Message{Role: "user", Content: "切换到英语老师"})
+	dm.Put(Message{Role: "assistant", ToolCalls: []types.ToolCall{{ID: "1"}}})
+	dm.Put(Message{Role: "tool", ToolCallID: "1", Content: "已切换"})
+	// 切换角色时只保留最近的消息，不影响本轮新增的消息
+	dm.KeepRecentMessages(1)
+	dm.Put(Message{Role: "assistant", Content: "Hello, let's start."})
+
+	messages := dm.RoundMessages()
+	if len(messages) != 4 || messages[0].Content != "切换到英语老师" || messages[3].Content != "Hello, let's start." {
+		t.Fatalf("RoundMessages() = %v", messages)
+	}
+
+	dm.TruncateLastReply("Hello,")
+	if got := dm.RoundMessages()[3].Content; got != "Hello,"+InterruptedSuffix {
+		t.Errorf("截断后本轮回复 = %s", got)
+	}
+
+	dm.BeginRound()
+	if messages := dm.RoundMessages(); len(messages) != 0 {
+		t.Errorf("BeginRound() 后 RoundMessages() = %v", messages)
+	}
+}
+
+// 打断截断、后台摘要和新一轮对话在不同协程中同时修改对话历史
+func TestConcurrentDialogueMutation(t *testing.T) {
+	dm := NewDialogueManager(nil, nil)
+	dm.SetSystemMessage("你是助手")
+	estimator := NewTokenEstimator("")
+	long := strings.Repeat("很长的内容", 20)
+
+	var wg sync.WaitGroup
+	for i := 0; i < 3; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			for j := 0; j < 50; j++ {
+				switch i {
+				case 0:
+					dm.Put(Message{Role: "user", Content: long})
+					dm.Put(Message{Role: "assistant", Content: long})
+				case 1:
+					dm.TruncateLastReply("很长")
+				case 2:
+					if _, messages := dm.SelectForSummary(estimator, 200, 2); len(messages) > 0 {
+						dm.ApplySummary("摘要", messages)
+					}
+				}
+			}
+		}(i)
+	}
+	wg.Wait()
+
+	if dialogue := dm.GetLLMDialogue(); len(dialogue) == 0 || dialogue[0].Content != "你是助手" {
+		t.Errorf("并发修改后系统提示词丢失: %v", dialogue)
+	}
+}
+
+func TestTokenEstimator(t *testing.T) {
+	zh := NewTokenEstimator("qwen-plus").Estimate("今天天气怎么样")
+	en := NewTokenEstimator("gpt-3.5-turbo").Estimate("今天天气怎么样")
+	if zh <= 0 || en <= zh {
+		t.Errorf("qwen估算 %d, gpt-3.5估算 %d", zh, en)
+	}
+}
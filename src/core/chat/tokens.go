@@ -0,0 +1,102 @@
+package chat
+
+import (
+	"strings"
+	"unicode"
+)
+
+// 每条消息的格式开销（角色、分隔符等）
+const messageOverheadTokens = 4
+
+// tokenProfile 模型分词器的估算参数
+type tokenProfile struct {
+	cjkPerToken   float64 // 每个token平均对应的中日韩字符数
+	charsPerToken float64 // 每个token平均对应的其他字符数
+}
+
+// 按模型名称前缀匹配的估算参数，越具体的前缀越靠前
+var tokenProfiles = []struct {
+	prefix  string
+	profile tokenProfile
+}{
+	{"qwen", tokenProfile{1.5, 4}},
+	{"glm", tokenProfile{1.6, 4}},
+	{"deepseek", tokenProfile{1.5, 4}},
+	{"doubao", tokenProfile{1.5, 4}},
+	{"gpt-4o", tokenProfile{1.2, 4}},
+	{"gpt-4.1", tokenProfile{1.2, 4}},
+	{"o1", tokenProfile{1.2, 4}},
+	{"o3", tokenProfile{1.2, 4}},
+	{"o4", tokenProfile{1.2, 4}},
+	{"gpt", tokenProfile{0.8, 4}},
+	{"claude", tokenProfile{0.8, 3.5}},
+}
+
+// 未知模型使用的保守估算参数
+var defaultTokenProfile = tokenProfile{1.0, 4}
+
+// TokenEstimator 按模型估算文本的token数
+// 不依赖具体分词器，按字符类别和模型分词器的平均压缩率估算，用于上下文预算控制
+type TokenEstimator struct {
+	profile tokenProfile
+}
+
+// NewTokenEstimator 创建指定模型的token估算器
+func NewTokenEstimator(model string) *TokenEstimator {
+	name := strings.ToLower(model)
+	// 兼容带厂商前缀的模型名称，如 openai/gpt-4o
+	if i := strings.LastIndex(name, "/"); i >= 0 {
+		name = name[i+1:]
+	}
+	for _, p := range tokenProfiles {
+		if strings.HasPrefix(name, p.prefix) {
+			return &TokenEstimator{profile: p.profile}
+		}
+	}
+	return &TokenEstimator{profile: defaultTokenProfile}
+}
+
+// Estimate 估算文本的token数
+func (e *TokenEstimator) Estimate(text string) int {
+	if text == "" {
+		return 0
+	}
+	cjk, other := 0, 0
+	for _, r := range text {
+		if isCJK(r) {
+			cjk++
+		} else {
+			other++
+		}
+	}
+	tokens := float64(cjk)/e.profile.cjkPerToken + float64(other)/e.profile.charsPerToken
+	return int(tokens) + 1
+}
+
+// EstimateMessage 估算单条消息的token数，包含工具调用
+func (e *TokenEstimator) EstimateMessage(msg Message) int {
+	tokens := messageOverheadTokens + e.Estimate(msg.Content)
+	for _, tc := range msg.ToolCalls {
+		tokens += e.Estimate(tc.Function.Name) + e.Estimate(tc.Function.Arguments)
+	}
+	return tokens
+}
+
+// EstimateMessages 估算消息列表的token数
+func (e *TokenEstimator) EstimateMessages(messages []Message) int {
+	tokens := 0
+	for _, msg := range messages {
+		tokens += e.EstimateMessage(msg)
+	}
+	return tokens
+}
+
+// isCJK 是否为中日韩字符或全角标点
+func isCJK(r rune) bool {
+	return unicode.Is(unicode.Han, r) ||
+		unicode.Is(unicode.Hiragana, r) ||
+		unicode.Is(unicode.Katakana, r) ||
+		unicode.Is(unicode.Hangul, r) ||
+		(r >= 0x3000 && r <= 0x303F) || // 中日韩标点
+		(r >= 0xFF00 && r <= 0xFFEF) // 全角字符
+}
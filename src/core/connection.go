@@ -15,15 +15,25 @@ import (
 	"angrymiao-ai-server/src/configs"
 	"angrymiao-ai-server/src/core/auth"
 	"angrymiao-ai-server/src/core/chat"
+	"angrymiao-ai-server/src/core/dictation"
 	"angrymiao-ai-server/src/core/function"
 	"angrymiao-ai-server/src/core/image"
+	"angrymiao-ai-server/src/core/intent"
+	"angrymiao-ai-server/src/core/lexicon"
 	"angrymiao-ai-server/src/core/mcp"
+	"angrymiao-ai-server/src/core/meeting"
+	"angrymiao-ai-server/src/core/memory"
+	"angrymiao-ai-server/src/core/moderation"
 	"angrymiao-ai-server/src/core/pool"
+	"angrymiao-ai-server/src/core/prompt"
 	"angrymiao-ai-server/src/core/providers"
 	"angrymiao-ai-server/src/core/providers/llm"
 	"angrymiao-ai-server/src/core/providers/tts"
 	"angrymiao-ai-server/src/core/providers/vlllm"
+	"angrymiao-ai-server/src/core/router"
+	"angrymiao-ai-server/src/core/textnorm"
 	"angrymiao-ai-server/src/core/types"
+	"angrymiao-ai-server/src/core/usage"
 	"angrymiao-ai-server/src/core/utils"
 	"angrymiao-ai-server/src/models"
 	"angrymiao-ai-server/src/services"
@@ -53,6 +63,32 @@ type Connection interface {
 	IsStale(timeout time.Duration) bool
 }
 
+// 单次TTS合成的默认最大字符数
+const defaultMaxTTSRunes = 120
+
+// ttsTask TTS合成任务
+type ttsTask struct {
+	text      string
+	round     int // 轮次
+	textIndex int
+	filepath  string // 如果有path，就直接使用
+	voice     string // 指定音色，为空时使用提供者当前音色
+	emotion   string // 句子的情绪，开始播放时发送给设备
+	partial   bool   // 长文本拆分后的非末尾片段，发送完不结束本轮播放
+
+	ctx context.Context // 所属轮次的上下文，轮次取消后不再合成
+}
+
+// audioTask 音频发送任务
+type audioTask struct {
+	filepath  string
+	text      string
+	round     int // 轮次
+	textIndex int
+	emotion   string
+	partial   bool
+}
+
 type configGetter interface {
 	Config() *tts.Config
 }
@@ -81,6 +117,7 @@ type ConnectionHandler struct {
 	sessionID     string            // 设备与服务端会话ID
 	deviceID      string            // 设备ID
 	clientId      string            // 客户端ID
+	board         string            // 设备型号，来自请求头或hello消息
 	headers       map[string]string // HTTP头部信息
 	transportType string            // 传输类型
 
@@ -96,6 +133,7 @@ type ConnectionHandler struct {
 	serverAudioFrameDuration int
 
 	clientListenMode string
+	clientReasoning  bool // 客户端在hello的features中声明接收思考过程
 	isDeviceVerified bool
 	closeAfterChat   bool
 
@@ -110,6 +148,11 @@ type ConnectionHandler struct {
 	tts_last_text_index int
 	client_asr_text     string // 客户端ASR文本
 	quickReplyCache     *utils.QuickReplyCache
+	currentRole         string                          // 当前角色，空为默认角色
+	lexicon             atomic.Pointer[lexicon.Lexicon] // 当前角色下生效的发音词典
+	speakerVoices       map[string]string               // 多角色配音时说话人到音色的分配
+	speakerVoicesMu     sync.Mutex
+	compacting          atomic.Bool // 正在后台摘要对话历史
 
 	// 并发控制
 	stopChan         chan struct{}
@@ -117,30 +160,93 @@ type ConnectionHandler struct {
 	clientTextQueue  chan string
 
 	// TTS任务队列
-	ttsQueue chan struct {
-		text      string
-		round     int // 轮次
-		textIndex int
-		filepath  string // 如果有path，就直接使用
-	}
-
-	audioMessagesQueue chan struct {
-		filepath  string
-		text      string
-		round     int // 轮次
-		textIndex int
-	}
-
-	talkRound      int       // 轮次计数
-	roundStartTime time.Time // 轮次开始时间
+	ttsQueue           chan ttsTask
+	audioMessagesQueue chan audioTask
+	ttsMu              sync.Mutex // TTS提供者不支持并发合成时保证依次合成
+
+	talkRound        int       // 轮次计数
+	roundStartTime   time.Time // 轮次开始时间
+	roundFirstTextAt time.Time // 本轮第一句回复的时间
+
+	// 当前轮次的上下文，新轮次开始或打断时取消
+	roundMu     sync.Mutex
+	roundCtx    context.Context
+	roundCancel context.CancelFunc
+
+	// 回复的播放进度，打断时对话历史只保留用户实际听到的内容
+	playback   playbackTracker
+	playbackMu sync.Mutex
+	lastReply  replyRange
 	// functions
 	functionRegister *function.FunctionRegistry
 	mcpManager       *mcp.Manager
 
 	// 用户AI配置服务
-	userConfigService services.UserAIConfigService
-	userID            string        // 从JWT中提取的用户ID
-	request           *http.Request // HTTP请求对象，用于获取用户配置等信息
+	userConfigService   services.UserAIConfigService
+	voiceService        services.VoiceService
+	lexiconService      services.LexiconService
+	memoryService       services.MemoryService
+	memoryStore         *memory.Store // 长期记忆，未启用时为空
+	conversationService services.ConversationService
+	conversationID      string // 对话历史中的会话ID，未启用时为空
+	historyMu           sync.Mutex
+	historyDone         chan struct{} // 最近一次对话记录写入完成时关闭，保证写入按顺序执行
+	recordedRound       int           // 最近一次保存的对话轮次
+	userID              string        // 从JWT中提取的用户ID
+	request             *http.Request // HTTP请求对象，用于获取用户配置等信息
+
+	// 系统提示词模板，每轮对话开始时用运行时变量渲染
+	promptService  services.PromptService
+	promptTemplate *prompt.Template
+	promptProfile  *models.UserPromptProfile // 用户的昵称和自定义字段
+
+	// 当前角色及其覆盖的LLM
+	personaService    services.PersonaService
+	persona           *models.Persona       // 当前角色，为空时使用默认提示词且不限制工具
+	baseLLM           providers.LLMProvider // 连接原有的LLM，随提供者集合归还资源池
+	personaLLM        providers.LLMProvider // 角色指定的LLM，从资源池借用
+	personaLLMRelease func()                // 归还角色LLM
+	roundLLM          providers.LLMProvider // 本轮路由指定的LLM，本轮结束时归还
+
+	// 用量统计，每轮对话结束后写入数据库
+	usageService services.UsageService
+	usageMeter   *usage.Meter
+
+	// 知识库检索
+	knowledgeService services.KnowledgeService
+
+	// 内容审核，未启用时为空
+	moderator         *moderation.Moderator
+	moderationService services.ModerationService
+	outputMu          sync.Mutex
+	output            outputModeration // 本轮的输出审核状态
+
+	// 本地意图识别，未启用时为空
+	intentEngine *intent.Engine
+
+	// LLM路由，未启用时为空；roundRoute 为本轮的路由结果，写入对话历史
+	llmRouter  *router.Router
+	llmSource  LLMSource
+	roundRoute router.Decision
+
+	// 同声传译的语言，进入 translate 拾音模式时设置
+	translateSource     string
+	translateTarget     string
+	translateAutoDetect bool
+
+	// 听写文档，进入 dictation 拾音模式时创建，听写结束时保存
+	dictationService   services.DictationService
+	dictationMu        sync.Mutex
+	dictationDoc       *dictation.Document
+	dictationStartedAt time.Time
+
+	// 会议记录，进入 meeting 拾音模式时创建，会议结束时保存
+	meetingService    services.MeetingService
+	meetingMu         sync.Mutex
+	meetingTranscript *meeting.Transcript
+	meetingStreamAt   time.Time   // 当前ASR识别会话开始的时间，超过配置的时长后轮换
+	meetingExpired    bool        // 会议超过最长时长自动结束，设备重新开始拾音前丢弃音频
+	meetingSummaries  chan string // 待播报的会议纪要，由文本消息协程在新轮次中播报
 
 	mcpResultHandlers map[string]func(interface{}) // MCP处理器映射
 	ctx               context.Context
@@ -155,24 +261,15 @@ func NewConnectionHandler(
 	ctx context.Context,
 ) *ConnectionHandler {
 	handler := &ConnectionHandler{
-		config:           config,
-		logger:           logger,
-		clientListenMode: "auto",
-		stopChan:         make(chan struct{}),
-		clientAudioQueue: make(chan []byte, 100),
-		clientTextQueue:  make(chan string, 100),
-		ttsQueue: make(chan struct {
-			text      string
-			round     int // 轮次
-			textIndex int
-			filepath  string
-		}, 100),
-		audioMessagesQueue: make(chan struct {
-			filepath  string
-			text      string
-			round     int // 轮次
-			textIndex int
-		}, 100),
+		config:             config,
+		logger:             logger,
+		clientListenMode:   "auto",
+		stopChan:           make(chan struct{}),
+		clientAudioQueue:   make(chan []byte, 100),
+		clientTextQueue:    make(chan string, 100),
+		meetingSummaries:   make(chan string, 1),
+		ttsQueue:           make(chan ttsTask, 100),
+		audioMessagesQueue: make(chan audioTask, 100),
 
 		tts_last_text_index: -1,
 
@@ -199,6 +296,9 @@ func NewConnectionHandler(
 		if key == "Client-Id" {
 			handler.clientId = values[0]
 		}
+		if key == "Board" {
+			handler.board = values[0]
+		}
 		if key == "Session-Id" {
 			handler.sessionID = values[0]
 		}
@@ -223,6 +323,7 @@ func NewConnectionHandler(
 	if providerSet != nil {
 		handler.providers.asr = providerSet.ASR
 		handler.providers.llm = providerSet.LLM
+		handler.baseLLM = providerSet.LLM
 		handler.providers.tts = providerSet.TTS
 		handler.providers.vlllm = providerSet.VLLLM
 		handler.mcpManager = providerSet.MCP
@@ -240,13 +341,46 @@ func NewConnectionHandler(
 
 	// 初始化对话管理器
 	handler.dialogueManager = chat.NewDialogueManager(handler.logger, nil)
-	handler.dialogueManager.SetSystemMessage(config.DefaultPrompt)
 	handler.functionRegister = function.NewFunctionRegistry()
+	handler.usageMeter = usage.NewMeter()
+	handler.setPromptTemplate(config.DefaultPrompt)
 	handler.initMCPResultHandlers()
 
 	return handler
 }
 
+// ConnectionServices 连接处理器依赖的业务服务
+type ConnectionServices struct {
+	UserConfig   services.UserAIConfigService
+	Voice        services.VoiceService
+	Lexicon      services.LexiconService
+	Memory       services.MemoryService
+	Conversation services.ConversationService
+	Prompt       services.PromptService
+	Persona      services.PersonaService
+	Usage        services.UsageService
+	Knowledge    services.KnowledgeService
+	Moderation   services.ModerationService
+	Dictation    services.DictationService
+	Meeting      services.MeetingService
+}
+
+// SetServices 设置连接处理器依赖的业务服务
+func (h *ConnectionHandler) SetServices(s ConnectionServices) {
+	h.userConfigService = s.UserConfig
+	h.voiceService = s.Voice
+	h.lexiconService = s.Lexicon
+	h.memoryService = s.Memory
+	h.conversationService = s.Conversation
+	h.promptService = s.Prompt
+	h.personaService = s.Persona
+	h.usageService = s.Usage
+	h.knowledgeService = s.Knowledge
+	h.moderationService = s.Moderation
+	h.dictationService = s.Dictation
+	h.meetingService = s.Meeting
+}
+
 func (h *ConnectionHandler) SetTaskCallback(callback func(func(*ConnectionHandler)) func()) {
 	h.safeCallbackFunc = callback
 }
@@ -303,7 +437,17 @@ func (h *ConnectionHandler) Handle(conn Connection) {
 	// 此时用户已通过JWT认证，可以安全地加载用户配置
 	if h.request != nil {
 		h.loadUserAIConfigurations(h.request)
-	}
+		h.applyUserVoice()
+	}
+	h.initMemory()
+	h.initModeration()
+	h.initIntent()
+	h.initLLMRouter()
+	h.loadPromptProfile()
+	h.loadDefaultPersona()
+	h.registerKnowledgeTool()
+	h.startConversation()
+	h.loadLexicon()
 
 	// 启动消息处理协程
 	go h.processClientAudioMessagesCoroutine() // 添加客户端音频消息处理协程
@@ -333,6 +477,7 @@ func (h *ConnectionHandler) Handle(conn Connection) {
 		}
 		// 不需要重新初始化服务器，只需要确保连接相关的服务正常
 		h.LogInfo("MCP管理器连接绑定完成，跳过重复初始化")
+		h.updateRoleTool()
 	}
 
 	// 主消息循环
@@ -361,9 +506,11 @@ func (h *ConnectionHandler) processClientTextMessagesCoroutine() {
 		case <-h.stopChan:
 			return
 		case text := <-h.clientTextQueue:
-			if err := h.processClientTextMessage(context.Background(), text); err != nil {
+			if err := h.processClientTextMessage(h.connContext(), text); err != nil {
 				h.LogError(fmt.Sprintf("处理文本数据失败: %v", err))
 			}
+		case summary := <-h.meetingSummaries:
+			h.speakMeetingSummary(summary)
 		}
 	}
 }
@@ -378,9 +525,14 @@ func (h *ConnectionHandler) processClientAudioMessagesCoroutine() {
 			if h.closeAfterChat {
 				continue
 			}
+			if !h.checkMeetingLimits() {
+				continue
+			}
 			if err := h.providers.asr.AddAudio(audioData); err != nil {
 				h.LogError(fmt.Sprintf("处理音频数据失败: %v", err))
+				continue
 			}
+			h.recordASRAudio(len(audioData))
 		}
 	}
 }
@@ -391,7 +543,7 @@ func (h *ConnectionHandler) sendAudioMessageCoroutine() {
 		case <-h.stopChan:
 			return
 		case task := <-h.audioMessagesQueue:
-			h.sendAudioMessage(task.filepath, task.text, task.textIndex, task.round)
+			h.sendAudioMessage(task.filepath, task.text, task.emotion, task.textIndex, task.round, task.partial)
 		}
 	}
 }
@@ -399,7 +551,39 @@ func (h *ConnectionHandler) sendAudioMessageCoroutine() {
 // OnAsrResult 实现 AsrEventListener 接口
 // 返回true则停止语音识别，返回false会继续语音识别
 func (h *ConnectionHandler) OnAsrResult(result string) bool {
+	if result != "" {
+		h.recordASRResult()
+	}
 	//h.LogInfo(fmt.Sprintf("[%s] ASR识别结果: %s", h.clientListenMode, result))
+	// 同声传译、听写和会议记录只处理用户说的话，静音时ASR返回的提示语不处理，也不因静音结束对话
+	switch h.clientListenMode {
+	case listenModeTranslate:
+		if result == "" || h.providers.asr.GetSilenceCount() > 0 {
+			return false
+		}
+		h.LogInfo(fmt.Sprintf("[%s] ASR识别结果: %s", h.clientListenMode, result))
+		h.handleTranslateMessage(h.connContext(), result)
+		return true
+	case listenModeDictation:
+		if result == "" || h.providers.asr.GetSilenceCount() > 0 {
+			return false
+		}
+		h.providers.asr.Reset() // 重置ASR状态，继续识别下一句
+		h.LogInfo(fmt.Sprintf("[%s] ASR识别结果: %s", h.clientListenMode, result))
+		h.handleDictationResult(result)
+		return true
+	case listenModeMeeting:
+		if result == "" {
+			return false
+		}
+		// 每句结果后重新建立识别会话；长时间静音时ASR返回的提示语只用于轮换会话，不计入记录
+		h.rolloverMeetingASR()
+		if h.providers.asr.GetSilenceCount() == 0 {
+			h.LogInfo(fmt.Sprintf("[%s] ASR识别结果: %s", h.clientListenMode, result))
+			h.handleMeetingResult(result)
+		}
+		return true
+	}
 	if h.providers.asr.GetSilenceCount() >= 2 {
 		h.LogInfo("检测到连续两次静音，结束对话")
 		h.closeAfterChat = true // 如果连续两次静音，则结束对话
@@ -410,7 +594,7 @@ func (h *ConnectionHandler) OnAsrResult(result string) bool {
 			return false
 		}
 		h.LogInfo(fmt.Sprintf("[%s] ASR识别结果: %s", h.clientListenMode, result))
-		h.handleChatMessage(context.Background(), result)
+		h.handleChatMessage(h.connContext(), result)
 		return true
 	} else if h.clientListenMode == "manual" {
 		h.client_asr_text += result
@@ -421,7 +605,7 @@ func (h *ConnectionHandler) OnAsrResult(result string) bool {
 			// 防止重复处理，只处理一次完整的ASR文本
 			asrText := h.client_asr_text
 			h.client_asr_text = "" // 清空文本，防止重复处理
-			h.handleChatMessage(context.Background(), asrText)
+			h.handleChatMessage(h.connContext(), asrText)
 			return true
 		}
 		return false
@@ -432,7 +616,7 @@ func (h *ConnectionHandler) OnAsrResult(result string) bool {
 		h.stopServerSpeak()
 		h.providers.asr.Reset() // 重置ASR状态，准备下一次识别
 		h.LogInfo(fmt.Sprintf("[%s] ASR识别结果: %s", h.clientListenMode, result))
-		h.handleChatMessage(context.Background(), result)
+		h.handleChatMessage(h.connContext(), result)
 		return true
 	}
 	return false
@@ -496,10 +680,17 @@ func (h *ConnectionHandler) handleChatMessage(ctx context.Context, text string)
 		return fmt.Errorf("用户请求退出对话")
 	}
 
+	// 上一轮回复尚未播放完时，对话历史只保留已播放的内容
+	h.truncateInterruptedReply()
+
 	// 增加对话轮次
 	h.talkRound++
 	h.roundStartTime = time.Now()
+	h.roundFirstTextAt = time.Time{}
+	h.roundRoute = router.Decision{}
 	currentRound := h.talkRound
+	ctx = h.beginRound(ctx)
+	h.refreshSystemPrompt()
 	h.LogInfo(fmt.Sprintf("开始新的对话轮次: %d", currentRound))
 
 	// 普通文本消息处理流程
@@ -517,10 +708,7 @@ func (h *ConnectionHandler) handleChatMessage(ctx context.Context, text string)
 	}
 
 	// 发送思考状态的情绪
-	// if err := h.sendEmotionMessage("thinking"); err != nil {
-	// 	h.LogError(fmt.Sprintf("发送思考状态情绪消息失败: %v", err))
-	// 	return fmt.Errorf("发送情绪消息失败: %v", err)
-	// }
+	h.sendThinkingEmotion()
 
 	h.LogInfo("收到聊天消息: " + text)
 
@@ -528,13 +716,37 @@ func (h *ConnectionHandler) handleChatMessage(ctx context.Context, text string)
 		return nil
 	}
 
+	// 超出对话用量配额时礼貌拒绝
+	if h.usageQuotaExceeded() {
+		h.refuseForQuota(currentRound)
+		return nil
+	}
+
+	// 审核用户文本，命中时按配置遮盖、替换为话术或结束会话
+	text, ok := h.moderateInput(currentRound, text)
+	if !ok {
+		return nil
+	}
+
 	// 添加用户消息到对话历史
+	h.dialogueManager.BeginRound()
 	h.dialogueManager.Put(chat.Message{
 		Role:    "user",
 		Content: text,
 	})
 
-	return h.genResponseByLLM(ctx, h.dialogueManager.GetLLMDialogue(), currentRound)
+	// 常用指令命中本地意图时直接调用工具，否则检索与本轮内容相关的长期记忆和知识库资料，路由到对应的LLM和角色回复
+	if !h.handleLocalIntent(ctx, text, currentRound) {
+		restore := h.routeRound(ctx, text)
+		messages := h.dialogueManager.GetLLMDialogueWithMemory(h.retrievalContext(text))
+		err = h.genResponseByLLM(ctx, messages, currentRound)
+		restore()
+	}
+	h.recordRound(currentRound)
+	h.flushUsage(false)
+	// 超出预算时在后台摘要最早的对话，不阻塞后续消息处理
+	h.compactDialogueInBackground()
+	return err
 }
 
 func (h *ConnectionHandler) genResponseByLLM(ctx context.Context, messages []providers.Message, round int) error {
@@ -547,46 +759,151 @@ func (h *ConnectionHandler) genResponseByLLM(ctx context.Context, messages []pro
 		}
 	}()
 
-	llmStartTime := time.Now()
-	//h.logger.Info("开始生成LLM回复, round:%d ", round)
-	for _, msg := range messages {
-		_ = msg
-		//msg.Print()
+	atomic.StoreInt32(&h.serverVoiceStop, 0)
+	reply := &llmReply{
+		round:         round,
+		speakerParser: h.newSpeakerParser(), // 多角色配音时解析说话人标签
+	}
+
+	// LLM调用工具后，将工具结果返回LLM继续生成，直到LLM给出回复或达到最大深度
+	maxDepth := h.toolCallMaxDepth()
+	for depth := 1; ; depth++ {
+		content, toolCalls, err := h.streamLLMResponse(ctx, messages, reply)
+		if ctx.Err() != nil {
+			h.putCancelledReply(reply)
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		// 回复命中审核被替换后不再执行工具调用
+		output := h.roundOutputModeration(round)
+		if len(toolCalls) == 0 || output.replacement != "" {
+			// 添加助手回复到对话历史，回复被打断时再截断为已播放的内容
+			h.dialogueManager.Put(chat.Message{
+				Role:    "assistant",
+				Content: output.moderatedContent(content),
+			})
+			h.setLastReply(round, reply.firstIndex, reply.textIndex)
+			return nil
+		}
+		if depth > maxDepth {
+			h.logger.Warn("工具调用超过最大深度 %d，停止调用, round: %d", maxDepth, round)
+			h.SystemSpeak("抱歉，这个问题有点复杂，我暂时没能完成")
+			return nil
+		}
+		if !h.executeToolCalls(ctx, output.moderatedContent(content), toolCalls, reply) {
+			if ctx.Err() != nil {
+				h.putCancelledReply(reply)
+			}
+			return nil
+		}
+		messages = h.dialogueManager.GetLLMDialogue()
+	}
+}
+
+// putCancelledReply 轮次被取消时，将已播放的内容作为被打断的回复写入对话历史
+// 新轮次已开始时不再写入，避免插入到新的用户消息之后
+func (h *ConnectionHandler) putCancelledReply(reply *llmReply) {
+	h.LogInfo(fmt.Sprintf("对话轮次已取消，停止生成回复, round: %d", reply.round))
+	if reply.round != h.talkRound {
+		return
 	}
+	spoken, _ := h.spokenReply(reply.round, reply.firstIndex, reply.textIndex)
+	h.dialogueManager.Put(chat.Message{
+		Role:    "assistant",
+		Content: chat.InterruptedReply(spoken),
+	})
+}
+
+// llmReply 一轮对话中LLM回复的播报状态，多次调用LLM时文本索引连续递增
+type llmReply struct {
+	round         int
+	textIndex     int
+	firstIndex    int       // 本次LLM调用播报的第一句文本索引
+	llmStartTime  time.Time // 本次LLM调用的开始时间
+	speakerParser *utils.SpeakerParser
+	fillerSpoken  bool // 本轮是否已播报等待提示
+}
+
+// speak 播报一段回复文本
+func (h *ConnectionHandler) speak(reply *llmReply, text string, remaining bool) {
+	for _, part := range h.splitSpeakers(reply.speakerParser, text) {
+		emotion, sentence := h.extractEmotion(part.Text)
+		// 工具结果可能已直接播报，索引从最后播报的文本之后继续
+		if h.tts_last_text_index > reply.textIndex {
+			reply.textIndex = h.tts_last_text_index
+		}
+		reply.textIndex++
+		if reply.firstIndex == 0 {
+			reply.firstIndex = reply.textIndex
+		}
+		switch {
+		case remaining:
+			h.LogInfo(fmt.Sprintf("LLM回复分段[剩余文本]: %s, index: %d, round:%d", part.Text, reply.textIndex, reply.round))
+		case reply.textIndex == 1:
+			h.LogInfo(fmt.Sprintf("LLM回复耗时 %s 生成第一句话【%s】, round: %d", time.Since(reply.llmStartTime), part.Text, reply.round))
+		default:
+			h.LogInfo(fmt.Sprintf("LLM回复分段: %s, index: %d, round:%d", part.Text, reply.textIndex, reply.round))
+		}
+		if h.roundFirstTextAt.IsZero() {
+			h.roundFirstTextAt = time.Now()
+		}
+		h.tts_last_text_index = reply.textIndex
+		if err := h.SpeakAndPlayWithVoice(sentence, h.voiceForSpeaker(part.Speaker), emotion, reply.textIndex, reply.round); err != nil {
+			h.LogError(fmt.Sprintf("播放LLM回复分段失败: %v", err))
+		}
+	}
+}
+
+// streamLLMResponse 调用LLM并按标点分段播报回复，返回播报的文本和LLM请求的工具调用
+func (h *ConnectionHandler) streamLLMResponse(ctx context.Context, messages []providers.Message, reply *llmReply) (string, []types.ToolCall, error) {
+	reply.llmStartTime = time.Now()
+	reply.firstIndex = 0
+	round := reply.round
+
 	// 使用LLM生成回复
-	tools := h.functionRegister.GetAllFunctions()
-	fmt.Println("GetAllFunctions", tools)
-	fmt.Println("messagesmessages", messages)
+	tools := h.availableTools()
+	llmName := h.usageProviderName("LLM")
 	responses, err := h.providers.llm.ResponseWithFunctions(ctx, h.sessionID, messages, tools)
 	if err != nil {
-		return fmt.Errorf("LLM生成回复失败: %v", err)
+		return "", nil, fmt.Errorf("LLM生成回复失败: %v", err)
 	}
 
 	// 处理回复
 	var responseMessage []string
 	processedChars := 0
-	textIndex := 0
-
-	atomic.StoreInt32(&h.serverVoiceStop, 0)
 
 	// 处理流式响应
 	toolCallFlag := false
-	functionName := ""
-	functionID := ""
-	functionArguments := ""
 	contentArguments := ""
+	var toolCalls toolCallAccumulator
+	var reportedUsage *types.Usage
 
 	for response := range responses {
+		if response.Usage != nil {
+			reportedUsage = response.Usage
+		}
+		// 故障转移时按实际生成回复的LLM统计用量
+		if response.Provider != "" {
+			llmName = response.Provider
+		}
+		// 轮次已取消时继续读取直到LLM流结束，不再处理内容
+		if ctx.Err() != nil {
+			continue
+		}
 		content := response.Content
-		toolCall := response.ToolCalls
-		fmt.Println("response", response)
 
 		if response.Error != "" {
 			h.LogError(fmt.Sprintf("LLM响应错误: %s", response.Error))
 			errorMsg := "抱歉，服务暂时不可用，请稍后再试"
 			h.tts_last_text_index = 1 // 重置文本索引
 			h.SpeakAndPlay(errorMsg, 1, round)
-			return fmt.Errorf("LLM响应错误: %s", response.Error)
+			return "", nil, fmt.Errorf("LLM响应错误: %s", response.Error)
+		}
+		// 思考内容只推送给声明支持的客户端，不参与TTS和对话历史
+		if response.Reasoning != "" {
+			h.sendReasoningMessage(response.Reasoning)
 		}
 
 		if content != "" {
@@ -598,17 +915,9 @@ func (h *ConnectionHandler) genResponseByLLM(ctx context.Context, messages []pro
 			toolCallFlag = true
 		}
 
-		if len(toolCall) > 0 {
+		if len(response.ToolCalls) > 0 {
 			toolCallFlag = true
-			if toolCall[0].ID != "" {
-				functionID = toolCall[0].ID
-			}
-			if toolCall[0].Function.Name != "" {
-				functionName = toolCall[0].Function.Name
-			}
-			if toolCall[0].Function.Arguments != "" {
-				functionArguments += toolCall[0].Function.Arguments
-			}
+			toolCalls.add(response.ToolCalls)
 		}
 
 		if content != "" {
@@ -617,7 +926,7 @@ func (h *ConnectionHandler) genResponseByLLM(ctx context.Context, messages []pro
 				errorMsg := "抱歉，LLM服务暂时不可用，请稍后再试"
 				h.tts_last_text_index = 1 // 重置文本索引
 				h.SpeakAndPlay(errorMsg, 1, round)
-				return fmt.Errorf("LLM服务异常")
+				return "", nil, fmt.Errorf("LLM服务异常")
 			}
 
 			if toolCallFlag {
@@ -635,208 +944,42 @@ func (h *ConnectionHandler) genResponseByLLM(ctx context.Context, messages []pro
 
 			// 按标点符号分割
 			if segment, charsCnt := utils.SplitAtLastPunctuation(currentText); charsCnt > 0 {
-				textIndex++
-				segment = strings.TrimSpace(segment)
-				if textIndex == 1 {
-					now := time.Now()
-					llmSpentTime := now.Sub(llmStartTime)
-					h.LogInfo(fmt.Sprintf("LLM回复耗时 %s 生成第一句话【%s】, round: %d", llmSpentTime, segment, round))
-				} else {
-					h.LogInfo(fmt.Sprintf("LLM回复分段: %s, index: %d, round:%d", segment, textIndex, round))
-				}
-				h.tts_last_text_index = textIndex
-				err := h.SpeakAndPlay(segment, textIndex, round)
-				if err != nil {
-					h.LogError(fmt.Sprintf("播放LLM回复分段失败: %v", err))
-				}
+				h.speak(reply, strings.TrimSpace(segment), false)
 				processedChars += charsCnt
 			}
 		}
 	}
 
-	if toolCallFlag {
-		bHasError := false
-		if functionID == "" {
-			a := utils.Extract_json_from_string(contentArguments)
-			if a != nil {
-				functionName = a["name"].(string)
-				argumentsJson, err := json.Marshal(a["arguments"])
-				if err != nil {
-					h.LogError(fmt.Sprintf("函数调用参数解析失败: %v", err))
-				}
-				functionArguments = string(argumentsJson)
-				functionID = uuid.New().String()
-			} else {
-				bHasError = true
-			}
-			if bHasError {
-				h.LogError(fmt.Sprintf("函数调用参数解析失败: %v", err))
-			}
-		}
-		if !bHasError {
-			// 清空responseMessage
-			responseMessage = []string{}
-			arguments := make(map[string]interface{})
-			if err := json.Unmarshal([]byte(functionArguments), &arguments); err != nil {
-				h.LogError(fmt.Sprintf("函数调用参数解析失败: %v", err))
-			}
-			functionCallData := map[string]interface{}{
-				"id":        functionID,
-				"name":      functionName,
-				"arguments": functionArguments,
-			}
-			h.LogInfo(fmt.Sprintf("函数调用: %v", arguments))
-			if h.mcpManager.IsMCPTool(functionName) {
-				fmt.Println("11111111111111111")
-				// 处理MCP函数调用
-				result, err := h.mcpManager.ExecuteTool(ctx, functionName, arguments)
-				if err != nil {
-					h.LogError(fmt.Sprintf("MCP函数调用失败: %v", err))
-					if result == nil {
-						result = "MCP工具调用失败"
-					}
-				}
-				// 判断result 是否是types.ActionResponse类型
-				if actionResult, ok := result.(types.ActionResponse); ok {
-					h.handleFunctionResult(actionResult, functionCallData, textIndex)
-				} else {
-					h.LogInfo(fmt.Sprintf("MCP函数调用结果: %v", result))
-					actionResult := types.ActionResponse{
-						Action: types.ActionTypeReqLLM, // 动作类型
-						Result: result,                 // 动作产生的结果
-					}
-					h.handleFunctionResult(actionResult, functionCallData, textIndex)
-				}
+	// 已取消的请求同样计入用量
+	h.recordLLMUsage(llmName, messages, contentArguments+toolCallText(toolCalls.calls), reportedUsage)
 
-			} else {
-				fmt.Println("22222222222")
-				// 处理普通函数调用
-				userFunCallConfig := models.UserAIConfig{}
-				if userFunConfig := h.request.Context().Value("user_configs"); userFunConfig != nil {
-					if configs, ok := userFunConfig.([]*models.UserAIConfig); ok {
-						h.logger.Info("获得上下文user_configs配置: %v", configs)
-
-						for _, v := range configs {
-							if v.FunctionName == functionName {
-								userFunCallConfig = *v
-								break
-							}
-						}
-					}
-				}
-				fmt.Println("functionCallData", functionCallData, functionName)
-				fmt.Println("userFunCallConfig", userFunCallConfig)
-				if userFunCallConfig.FunctionName != "" {
-					funResult, err := h.executeUserFunctionCall(&userFunCallConfig, functionCallData)
-					if err != nil {
-						h.LogError(fmt.Sprintf("MCP函数调用失败: %v", err))
-						if funResult.Result == "" {
-							funResult.Result = "BOT 模型调用失败"
-						}
-					}
-
-					actionResult := types.ActionResponse{
-						Action: types.ActionTypeReqLLM,
-						Result: funResult.Result,
-					}
-					h.handleFunctionResult(actionResult, functionCallData, textIndex)
-				}
-			}
-		}
+	if err := ctx.Err(); err != nil {
+		return "", nil, err
 	}
 
 	// 处理剩余文本
 	fullResponse := utils.JoinStrings(responseMessage)
 	if len(fullResponse) > processedChars {
-		remainingText := fullResponse[processedChars:]
-		if remainingText != "" {
-			textIndex++
-			h.LogInfo(fmt.Sprintf("LLM回复分段[剩余文本]: %s, index: %d, round:%d", remainingText, textIndex, round))
-			h.tts_last_text_index = textIndex
-			h.SpeakAndPlay(remainingText, textIndex, round)
-		}
+		h.speak(reply, fullResponse[processedChars:], true)
 	} else {
 		h.logger.Debug("无剩余文本需要处理: fullResponse长度=%d, processedChars=%d", len(fullResponse), processedChars)
 	}
 
-	// 分析回复并发送相应的情绪
-	content := utils.JoinStrings(responseMessage)
-
-	// 添加助手回复到对话历史
 	if !toolCallFlag {
-		h.dialogueManager.Put(chat.Message{
-			Role:    "assistant",
-			Content: content,
-		})
+		return fullResponse, nil, nil
 	}
 
-	return nil
-}
-
-func (h *ConnectionHandler) addToolCallMessage(toolResultText string, functionCallData map[string]interface{}) {
-
-	functionID := functionCallData["id"].(string)
-	functionName := functionCallData["name"].(string)
-	functionArguments := functionCallData["arguments"].(string)
-	h.LogInfo(fmt.Sprintf("函数调用结果: %s", toolResultText))
-	h.LogInfo(fmt.Sprintf("函数调用参数: %s", functionArguments))
-	h.LogInfo(fmt.Sprintf("函数调用名称: %s", functionName))
-	h.LogInfo(fmt.Sprintf("函数调用ID: %s", functionID))
-
-	// 添加 assistant 消息，包含 tool_calls
-	h.dialogueManager.Put(chat.Message{
-		Role: "assistant",
-		ToolCalls: []types.ToolCall{{
-			ID: functionID,
-			Function: types.FunctionCall{
-				Arguments: functionArguments,
-				Name:      functionName,
-			},
-			Type:  "function",
-			Index: 0,
-		}},
-	})
-
-	// 添加 tool 消息
-	toolCallID := functionID
-	if toolCallID == "" {
-		toolCallID = uuid.New().String()
-	}
-	h.dialogueManager.Put(chat.Message{
-		Role:       "tool",
-		ToolCallID: toolCallID,
-		Content:    toolResultText,
-	})
-}
-
-func (h *ConnectionHandler) handleFunctionResult(result types.ActionResponse, functionCallData map[string]interface{}, textIndex int) {
-	switch result.Action {
-	case types.ActionTypeError:
-		h.LogError(fmt.Sprintf("函数调用错误: %v", result.Result))
-	case types.ActionTypeNotFound:
-		h.LogError(fmt.Sprintf("函数未找到: %v", result.Result))
-	case types.ActionTypeNone:
-		h.LogInfo(fmt.Sprintf("函数调用无操作: %v", result.Result))
-	case types.ActionTypeResponse:
-		h.LogInfo(fmt.Sprintf("函数调用直接回复: %v", result.Response))
-		h.SystemSpeak(result.Response.(string))
-	case types.ActionTypeCallHandler:
-		resultStr := h.handleMCPResultCall(result)
-		h.addToolCallMessage(resultStr, functionCallData)
-	case types.ActionTypeReqLLM:
-		h.LogInfo(fmt.Sprintf("函数调用后请求LLM: %v", result.Result))
-		text, ok := result.Result.(string)
-		if ok && len(text) > 0 {
-			h.addToolCallMessage(text, functionCallData)
-			h.genResponseByLLM(context.Background(), h.dialogueManager.GetLLMDialogue(), h.talkRound)
-
-		} else {
-			h.LogError(fmt.Sprintf("函数调用结果解析失败: %v", result.Result))
-			// 发送错误消息
-			errorMessage := fmt.Sprintf("函数调用结果解析失败 %v", result.Result)
-			h.SystemSpeak(errorMessage)
+	calls := toolCalls.result()
+	if len(calls) == 0 {
+		// 部分模型以 <tool_call>{...}</tool_call> 文本形式输出工具调用
+		call, ok := parseTextToolCall(contentArguments)
+		if !ok {
+			h.LogError(fmt.Sprintf("函数调用参数解析失败: %s", contentArguments))
+			return fullResponse, nil, nil
 		}
+		calls = []types.ToolCall{call}
 	}
+	return fullResponse, calls, nil
 }
 
 func (h *ConnectionHandler) SystemSpeak(text string) error {
@@ -854,14 +997,16 @@ func (h *ConnectionHandler) SystemSpeak(text string) error {
 	return nil
 }
 
-// processTTSQueueCoroutine 处理TTS队列
+// processTTSQueueCoroutine 处理TTS队列，并发合成后按入队顺序送入音频发送队列
 func (h *ConnectionHandler) processTTSQueueCoroutine() {
+	pipeline := newTTSPipeline(h, h.ttsConcurrency())
+	go pipeline.flushCoroutine()
 	for {
 		select {
 		case <-h.stopChan:
 			return
 		case task := <-h.ttsQueue:
-			h.processTTSTask(task.text, task.textIndex, task.round, task.filepath)
+			pipeline.submit(task)
 		}
 	}
 }
@@ -870,6 +1015,8 @@ func (h *ConnectionHandler) processTTSQueueCoroutine() {
 func (h *ConnectionHandler) stopServerSpeak() {
 	h.LogInfo("服务端停止说话")
 	atomic.StoreInt32(&h.serverVoiceStop, 1)
+	h.truncateInterruptedReply()
+	h.cancelRound()
 	h.cleanTTSAndAudioQueue(false)
 }
 
@@ -899,20 +1046,24 @@ func (h *ConnectionHandler) deleteAudioFileIfNeeded(filepath string, reason stri
 }
 
 // processTTSTask 处理单个TTS任务
-func (h *ConnectionHandler) processTTSTask(text string, textIndex int, round int, filepath string) {
+func (h *ConnectionHandler) processTTSTask(task ttsTask) (result audioTask) {
+	text, textIndex, filepath := task.text, task.textIndex, task.filepath
 	defer func() {
-		h.audioMessagesQueue <- struct {
-			filepath  string
-			text      string
-			round     int
-			textIndex int
-		}{filepath, text, round, textIndex}
+		result = audioTask{filepath, text, task.round, textIndex, task.emotion, task.partial}
 	}()
 	if filepath != "" {
 		return
 	}
 
-	if utils.IsQuickReplyHit(text, h.config.QuickReplyWords) {
+	// 轮次已过期、已取消或服务端语音已停止时不再合成
+	if task.round != h.talkRound || atomic.LoadInt32(&h.serverVoiceStop) == 1 || (task.ctx != nil && task.ctx.Err() != nil) {
+		h.LogInfo(fmt.Sprintf("processTTSTask 跳过合成: 任务轮次=%d, 当前轮次=%d, 文本=%s", task.round, h.talkRound, text))
+		return
+	}
+
+	// 快速回复缓存只对应默认音色
+	quickReply := task.voice == "" && utils.IsQuickReplyHit(text, h.config.QuickReplyWords)
+	if quickReply {
 		// 尝试从缓存查找音频文件
 		if cachedFile := h.quickReplyCache.FindCachedAudio(text); cachedFile != "" {
 			h.LogInfo(fmt.Sprintf("使用缓存的快速回复音频: %s", cachedFile))
@@ -929,15 +1080,20 @@ func (h *ConnectionHandler) processTTSTask(text string, textIndex int, round int
 		return
 	}
 
-	// 生成语音文件
-	filepath, err := h.providers.tts.ToTTS(text)
+	// 生成语音文件，字幕仍使用原文，合成使用规范化后的口语文本
+	filepath, err := h.synthesizeInRound(task.ctx, h.buildTTSInput(text), task.voice)
 	if err != nil {
+		if task.ctx != nil && task.ctx.Err() != nil {
+			h.LogInfo(fmt.Sprintf("processTTSTask 轮次已取消，放弃合成: %s", text))
+			return
+		}
 		h.LogError(fmt.Sprintf("TTS转换失败:text(%s) %v", text, err))
 		return
 	} else {
 		h.logger.Debug(fmt.Sprintf("TTS转换成功: text(%s), index(%d) %s", text, textIndex, filepath))
+		h.recordTTSUsage(text)
 		// 如果是快速回复词，保存到缓存
-		if utils.IsQuickReplyHit(text, h.config.QuickReplyWords) {
+		if quickReply {
 			if err := h.quickReplyCache.SaveCachedAudio(text, filepath); err != nil {
 				h.LogError(fmt.Sprintf("保存快速回复音频失败: %v", err))
 			} else {
@@ -945,10 +1101,12 @@ func (h *ConnectionHandler) processTTSTask(text string, textIndex int, round int
 			}
 		}
 	}
-	if atomic.LoadInt32(&h.serverVoiceStop) == 1 { // 服务端语音停止
+	// 合成期间轮次已取消或服务端语音已停止时不再发送
+	if atomic.LoadInt32(&h.serverVoiceStop) == 1 || (task.ctx != nil && task.ctx.Err() != nil) {
 		h.LogInfo(fmt.Sprintf("processTTSTask 服务端语音停止, 不再发送音频数据：%s", text))
 		// 服务端语音停止时，根据配置删除已生成的音频文件
 		h.deleteAudioFileIfNeeded(filepath, "服务端语音停止时")
+		filepath = ""
 		return
 	}
 
@@ -957,18 +1115,44 @@ func (h *ConnectionHandler) processTTSTask(text string, textIndex int, round int
 		ttsSpentTime := now.Sub(ttsStartTime)
 		h.logger.Debug(fmt.Sprintf("TTS转换耗时: %s, 文本: %s, 索引: %d", ttsSpentTime, text, textIndex))
 	}
+	return
 }
 
 // speakAndPlay 合成并播放语音
 func (h *ConnectionHandler) SpeakAndPlay(text string, textIndex int, round int) error {
+	return h.SpeakAndPlayWithVoice(text, "", "", textIndex, round)
+}
+
+// SpeakAndPlayWithVoice 使用指定音色合成并播放语音，音色为空时使用当前音色，情绪不为空时在开始播放时发送
+func (h *ConnectionHandler) SpeakAndPlayWithVoice(text string, voice string, emotion string, textIndex int, round int) error {
+	// 所有播报文本都经过输出审核，本轮回复已被替换时不再播报
+	text, ok := h.moderateOutput(round, text)
+	if !ok {
+		h.LogInfo(fmt.Sprintf("本轮回复已被内容审核替换，不再播报: index: %d, round: %d", textIndex, round))
+		// 文本索引已推进，加入空任务使最后一句仍能发送stop并结束播放状态
+		h.ttsQueue <- ttsTask{round: round, textIndex: textIndex, ctx: h.roundContext()}
+		return nil
+	}
+
 	defer func() {
-		// 将任务加入队列，不阻塞当前流程
-		h.ttsQueue <- struct {
-			text      string
-			round     int
-			textIndex int
-			filepath  string
-		}{text, round, textIndex, ""}
+		// 将任务加入队列，不阻塞当前流程；超长文本按字符数拆分为多个片段
+		chunks := textnorm.SplitByRunes(text, h.maxTTSRunes())
+		if len(chunks) == 0 {
+			chunks = []string{text}
+		}
+		for i, chunk := range chunks {
+			h.playback.queue(round, textIndex, chunk)
+			h.ttsQueue <- ttsTask{
+				text:      chunk,
+				round:     round,
+				textIndex: textIndex,
+				voice:     voice,
+				emotion:   emotion,
+				partial:   i < len(chunks)-1,
+				ctx:       h.roundContext(),
+			}
+			emotion = "" // 情绪只在句子的第一个片段开始播放时发送
+		}
 	}()
 
 	originText := text // 保存原始文本用于日志
@@ -985,12 +1169,60 @@ func (h *ConnectionHandler) SpeakAndPlay(text string, textIndex int, round int)
 		return errors.New("服务端语音已停止，无法合成语音")
 	}
 
-	if len(text) > 255 {
-		h.logger.Warn(fmt.Sprintf("文本过长，超过255字符限制，截断合成语音: %s", text))
-		text = text[:255] // 截断文本
+	return nil
+}
+
+// maxTTSRunes 单次合成的最大字符数
+func (h *ConnectionHandler) maxTTSRunes() int {
+	if h.config.TextNormalize.MaxRunes > 0 {
+		return h.config.TextNormalize.MaxRunes
+	}
+	return defaultMaxTTSRunes
+}
+
+// buildTTSInput 生成TTS输入文本：先应用发音词典，再对其余文本做规范化
+// 提供者支持SSML时词条使用音标标签，否则使用别名替换
+func (h *ConnectionHandler) buildTTSInput(text string) string {
+	lex := h.lexicon.Load()
+	if lex.Empty() {
+		return h.normalizeTTSText(text)
 	}
+	ssml := false
+	if p, ok := h.providers.tts.(tts.SSMLProvider); ok {
+		ssml = p.SupportsSSML()
+	}
+	return lex.Apply(text, ssml, h.normalizeTTSText)
+}
 
-	return nil
+// loadLexicon 加载当前角色下生效的发音词典
+func (h *ConnectionHandler) loadLexicon() {
+	if h.lexiconService == nil {
+		return
+	}
+	entries, err := h.lexiconService.GetEffectiveEntries(context.Background(), h.currentRole)
+	if err != nil {
+		h.logger.Error("加载发音词典失败: %v", err)
+		return
+	}
+	h.lexicon.Store(lexicon.New(entries))
+	h.logger.Debug("加载发音词典，角色: %s, 词条数: %d", h.currentRole, len(entries))
+}
+
+// normalizeTTSText 将文本规范化为口语读法，语言优先使用当前TTS配置
+func (h *ConnectionHandler) normalizeTTSText(text string) string {
+	if !h.config.TextNormalize.Enabled {
+		return text
+	}
+	lang := h.config.TextNormalize.Language
+	if getter, ok := h.providers.tts.(configGetter); ok {
+		if ttsCfg, ok := h.config.TTS[getter.Config().Name]; ok && ttsCfg.Language != "" {
+			lang = ttsCfg.Language
+		}
+	}
+	if lang == "" {
+		lang = textnorm.DefaultLanguage
+	}
+	return textnorm.Normalize(lang, text)
 }
 
 func (h *ConnectionHandler) clearSpeakStatus() {
@@ -1046,6 +1278,14 @@ func (h *ConnectionHandler) Close() {
 	h.closeOnce.Do(func() {
 		close(h.stopChan)
 
+		// 会话结束时在后台提取长期记忆
+		h.saveMemoryInBackground()
+		h.endConversation()
+		h.finishDictation(false)
+		h.finishMeeting(false)
+		h.releasePersonaLLM()
+		h.flushUsage(true)
+
 		h.closeOpusDecoder()
 		if h.providers.tts != nil {
 			h.providers.tts.SetVoice(h.initailVoice) // 恢复初始语音
@@ -1090,40 +1330,56 @@ func (h *ConnectionHandler) genResponseByVLLM(ctx context.Context, messages []pr
 	atomic.StoreInt32(&h.serverVoiceStop, 0)
 
 	for response := range responses {
-		if response == "" {
+		// 轮次已取消时继续读取直到流结束，不再播报
+		if ctx.Err() != nil {
+			continue
+		}
+		if response.Reasoning != "" {
+			h.sendReasoningMessage(response.Reasoning)
+		}
+		if response.Content == "" {
 			continue
 		}
 
-		responseMessage = append(responseMessage, response)
+		responseMessage = append(responseMessage, response.Content)
 		// 处理分段
 		fullText := utils.JoinStrings(responseMessage)
 		currentText := fullText[processedChars:]
 
 		// 按标点符号分割
 		if segment, chars := utils.SplitAtLastPunctuation(currentText); chars > 0 {
+			processedChars += chars
 			textIndex++
 			h.tts_last_text_index = textIndex
-			h.SpeakAndPlay(segment, textIndex, round)
-			processedChars += chars
+			emotion, segment := h.extractEmotion(segment)
+			h.SpeakAndPlayWithVoice(segment, "", emotion, textIndex, round)
 		}
 	}
 
+	if ctx.Err() != nil {
+		h.LogInfo(fmt.Sprintf("对话轮次已取消，停止生成图片回复, round: %d", round))
+		return nil
+	}
+
 	// 处理剩余文本
 	remainingText := utils.JoinStrings(responseMessage)[processedChars:]
 	if remainingText != "" {
 		textIndex++
 		h.tts_last_text_index = textIndex
-		h.SpeakAndPlay(remainingText, textIndex, round)
+		emotion, remainingText := h.extractEmotion(remainingText)
+		h.SpeakAndPlayWithVoice(remainingText, "", emotion, textIndex, round)
 	}
 
 	// 获取完整回复内容
 	content := utils.JoinStrings(responseMessage)
+	h.recordLLMUsage(h.config.SelectedModule["VLLLM"], append(messages, providers.Message{Role: "user", Content: text}), content, nil)
 
 	// 添加VLLLM回复到对话历史
 	h.dialogueManager.Put(chat.Message{
 		Role:    "assistant",
-		Content: content,
+		Content: h.roundOutputModeration(round).moderatedContent(content),
 	})
+	h.setLastReply(round, 1, textIndex)
 
 	h.LogInfo(fmt.Sprintf("VLLLM回复处理完成 …%v", map[string]interface{}{
 		"content_length": len(content),
@@ -1196,6 +1452,69 @@ func (h *ConnectionHandler) loadUserAIConfigurations(req *http.Request) {
 	h.registerUserConfigs(configs)
 }
 
+// initMemory 为已登录用户启用长期记忆
+func (h *ConnectionHandler) initMemory() {
+	if !h.config.Memory.Enabled || h.userID == "" || h.memoryService == nil || h.providers.llm == nil {
+		return
+	}
+	h.memoryStore = memory.NewStore(h.userID, h.memoryService, h.providers.llm, h.config.Memory, h.logger)
+	h.dialogueManager.SetMemory(h.memoryStore)
+	h.logger.Info("用户 %s 已启用长期记忆", h.userID)
+}
+
+// saveMemoryInBackground 在后台提取长期记忆，不阻塞连接关闭
+// 连接的LLM随提供者集合归还资源池后可能被其他连接使用，提取时单独借用LLM，用完归还
+func (h *ConnectionHandler) saveMemoryInBackground() {
+	if h.memoryStore == nil {
+		return
+	}
+	if h.llmSource == nil {
+		h.LogError("保存长期记忆失败: 未设置LLM来源")
+		return
+	}
+	dialogue := h.dialogueManager.Snapshot()
+	name := h.config.PrimaryLLM()
+
+	go func() {
+		provider, release, err := h.llmSource.AcquireLLM(name)
+		if err != nil {
+			h.LogError(fmt.Sprintf("保存长期记忆失败: %v", err))
+			return
+		}
+		defer release()
+		if err := h.memoryStore.WithLLM(provider).SaveMemory(dialogue); err != nil {
+			h.LogError(fmt.Sprintf("保存长期记忆失败: %v", err))
+		}
+	}()
+}
+
+// applyUserVoice 应用用户在当前TTS提供者下设置的默认音色
+func (h *ConnectionHandler) applyUserVoice() {
+	if h.userID == "" || h.voiceService == nil {
+		return
+	}
+	getter, ok := h.providers.tts.(configGetter)
+	if !ok {
+		return
+	}
+
+	pref, err := h.voiceService.GetUserVoice(context.Background(), h.userID, getter.Config().Name)
+	if err != nil {
+		h.logger.Error("加载用户默认音色失败: %v", err)
+		return
+	}
+	if pref == nil || pref.Voice == getter.Config().Voice {
+		return
+	}
+
+	if err := h.providers.tts.SetVoice(pref.Voice); err != nil {
+		h.logger.Error("设置用户默认音色失败: %v", err)
+		return
+	}
+	h.quickReplyCache.VoiceName = pref.Voice
+	h.logger.Info("用户 %s 使用默认音色: %s", h.userID, pref.Voice)
+}
+
 // registerUserConfigs 注册用户配置到functionRegister
 func (h *ConnectionHandler) registerUserConfigs(configs []*models.UserAIConfig) {
 	// 将用户配置转换为OpenAI工具格式并注册到functionRegister
@@ -1236,7 +1555,7 @@ func (h *ConnectionHandler) convertConfigToOpenAITool(config *models.UserAIConfi
 }
 
 // executeUserFunctionCall 执行用户自定义Function Call
-func (h *ConnectionHandler) executeUserFunctionCall(config *models.UserAIConfig, args map[string]interface{}) (types.FunctionCallResult, error) {
+func (h *ConnectionHandler) executeUserFunctionCall(ctx context.Context, config *models.UserAIConfig, args map[string]interface{}) (types.FunctionCallResult, error) {
 	h.logger.Info("执行用户自定义Function Call: %s", config.FunctionName)
 
 	// 检查是否有LLM配置参数
@@ -1311,7 +1630,6 @@ func (h *ConnectionHandler) executeUserFunctionCall(config *models.UserAIConfig,
 	h.logger.Info("调用用户自定义LLM: %s, 模型: %s, 查询: %s", config.LLMType, config.ModelName, userMessage)
 
 	// 调用LLM生成回复
-	ctx := context.Background()
 	responses, err := provider.Response(ctx, h.sessionID, messages)
 	if err != nil {
 		h.logger.Error("LLM生成回复失败: %v", err)
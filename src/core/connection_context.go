@@ -0,0 +1,177 @@
+package core
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"angrymiao-ai-server/src/core/chat"
+	"angrymiao-ai-server/src/core/providers"
+	"angrymiao-ai-server/src/core/providers/llm"
+)
+
+const (
+	defaultContextTokens = 6000             // 默认对话历史token预算
+	defaultKeepRecent    = 6                // 默认至少保留的最近消息数
+	summaryTimeout       = 30 * time.Second // 生成摘要的超时时间
+	summaryMaxRunes      = 500              // 单条消息参与摘要的最大字符数
+)
+
+// 默认对话摘要提示词
+const defaultSummaryPrompt = `你是对话摘要助手。请将下面的对话历史压缩为简洁的摘要，供后续对话参考。
+要求：保留用户的身份信息、偏好、提出的需求和尚未完成的事项，以及已经得出的结论和工具调用的关键结果；
+省略寒暄和重复内容；使用第三人称陈述，不超过300字，直接输出摘要内容。`
+
+// llmConfigGetter 可获取LLM配置的提供者
+type llmConfigGetter interface {
+	Config() *llm.Config
+}
+
+// llmConfig 获取当前LLM配置，提供者不支持时返回nil
+func (h *ConnectionHandler) llmConfig() *llm.Config {
+	if getter, ok := h.providers.llm.(llmConfigGetter); ok {
+		return getter.Config()
+	}
+	return nil
+}
+
+// contextBudget 对话历史的token预算，LLM配置的 context_tokens 优先
+func (h *ConnectionHandler) contextBudget() int {
+	if cfg := h.llmConfig(); cfg != nil {
+		switch v := cfg.Extra["context_tokens"].(type) {
+		case int:
+			if v > 0 {
+				return v
+			}
+		case float64:
+			if v > 0 {
+				return int(v)
+			}
+		}
+	}
+	if h.config.ContextWindow.MaxTokens > 0 {
+		return h.config.ContextWindow.MaxTokens
+	}
+	return defaultContextTokens
+}
+
+// compactDialogueInBackground 对话历史超出token预算时，在后台用LLM将最早的对话摘要为一条系统消息
+// 摘要耗时较长，不阻塞消息处理和下一轮对话，同一时间只进行一次摘要
+// 连接的LLM随提供者集合归还资源池后可能被其他连接使用，有LLM来源时单独借用LLM，用完归还
+func (h *ConnectionHandler) compactDialogueInBackground() {
+	cfg := h.config.ContextWindow
+	if !cfg.Enabled || h.providers.llm == nil {
+		return
+	}
+	if !h.compacting.CompareAndSwap(false, true) {
+		return
+	}
+
+	name, model := "", ""
+	if llmCfg := h.llmConfig(); llmCfg != nil {
+		name, model = llmCfg.Name, llmCfg.ModelName
+	}
+	keepRecent := cfg.KeepRecent
+	if keepRecent <= 0 {
+		keepRecent = defaultKeepRecent
+	}
+
+	estimator := chat.NewTokenEstimator(model)
+	previous, messages := h.dialogueManager.SelectForSummary(estimator, h.contextBudget(), keepRecent)
+	if len(messages) == 0 {
+		h.compacting.Store(false)
+		return
+	}
+
+	provider, release := h.providers.llm, func() {}
+	go func() {
+		defer h.compacting.Store(false)
+		if h.llmSource != nil {
+			var err error
+			if provider, release, err = h.llmSource.AcquireLLM(name); err != nil {
+				h.LogError(fmt.Sprintf("生成对话摘要失败: %v", err))
+				return
+			}
+		}
+		defer release()
+
+		startTime := time.Now()
+		summary, err := h.summarizeDialogue(h.connContext(), provider, previous, messages)
+		if err != nil {
+			h.LogError(fmt.Sprintf("生成对话摘要失败: %v", err))
+			return
+		}
+		if !h.dialogueManager.ApplySummary(summary, messages) {
+			h.LogInfo("摘要期间对话历史已变化，放弃本次摘要")
+			return
+		}
+		h.LogInfo(fmt.Sprintf("对话历史已摘要: %d条消息, 耗时 %s, 剩余约%d tokens",
+			len(messages), time.Since(startTime), estimator.EstimateMessages(h.dialogueManager.GetLLMDialogue())))
+	}()
+}
+
+// summarizeDialogue 调用LLM生成对话摘要，已有摘要会合并到新摘要中
+func (h *ConnectionHandler) summarizeDialogue(ctx context.Context, provider providers.LLMProvider, previous string, messages []chat.Message) (string, error) {
+	var transcript strings.Builder
+	if previous != "" {
+		transcript.WriteString("之前的摘要：\n")
+		transcript.WriteString(previous)
+		transcript.WriteString("\n\n后续对话：\n")
+	}
+	for _, msg := range messages {
+		switch msg.Role {
+		case "user":
+			transcript.WriteString("用户：")
+		case "assistant":
+			transcript.WriteString("助手：")
+			for _, tc := range msg.ToolCalls {
+				transcript.WriteString(fmt.Sprintf("[调用工具 %s %s]", tc.Function.Name, truncateRunes(tc.Function.Arguments, summaryMaxRunes)))
+			}
+		case "tool":
+			transcript.WriteString("工具结果：")
+		default:
+			transcript.WriteString("系统：")
+		}
+		transcript.WriteString(truncateRunes(msg.Content, summaryMaxRunes))
+		transcript.WriteString("\n")
+	}
+
+	prompt := h.config.ContextWindow.Prompt
+	if prompt == "" {
+		prompt = defaultSummaryPrompt
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, summaryTimeout)
+	defer cancel()
+
+	responses, err := provider.Response(ctx, h.sessionID, []chat.Message{
+		{Role: "system", Content: prompt},
+		{Role: "user", Content: transcript.String()},
+	})
+	if err != nil {
+		return "", err
+	}
+
+	var summary strings.Builder
+	for content := range responses {
+		summary.WriteString(content)
+	}
+	result := strings.TrimSpace(summary.String())
+	if result == "" {
+		return "", fmt.Errorf("摘要内容为空")
+	}
+	if strings.Contains(result, "服务响应异常") {
+		return "", fmt.Errorf("%s", result)
+	}
+	return result, nil
+}
+
+// truncateRunes 按字符截断文本
+func truncateRunes(text string, maxRunes int) string {
+	runes := []rune(text)
+	if len(runes) <= maxRunes {
+		return text
+	}
+	return string(runes[:maxRunes]) + "..."
+}
@@ -0,0 +1,72 @@
+package core
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"angrymiao-ai-server/src/core/chat"
+	"angrymiao-ai-server/src/core/types"
+)
+
+// summaryLLM 收到 release 信号后才返回摘要的LLM
+type summaryLLM struct {
+	fakeLLM
+	release chan struct{}
+}
+
+func (l *summaryLLM) Response(ctx context.Context, sessionID string, messages []types.Message) (<-chan string, error) {
+	responses := make(chan string, 1)
+	go func() {
+		defer close(responses)
+		select {
+		case <-l.release:
+			responses <- "用户聊过很长的内容"
+		case <-ctx.Done():
+		}
+	}()
+	return responses, nil
+}
+
+func TestCompactDialogueInBackground(t *testing.T) {
+	h := newTestHandler(t)
+	h.config.ContextWindow.Enabled = true
+	h.config.ContextWindow.MaxTokens = 200
+	h.config.ContextWindow.KeepRecent = 2
+	provider := &summaryLLM{fakeLLM: fakeLLM{name: "base"}, release: make(chan struct{})}
+	h.providers.llm = provider
+	h.dialogueManager = chat.NewDialogueManager(h.logger, nil)
+	h.dialogueManager.SetSystemMessage("你是助手")
+	long := strings.Repeat("很长的内容", 40)
+	h.dialogueManager.Put(chat.Message{Role: "user", Content: long})
+	h.dialogueManager.Put(chat.Message{Role: "assistant", Content: long})
+	h.dialogueManager.Put(chat.Message{Role: "user", Content: "谢谢"})
+	h.dialogueManager.Put(chat.Message{Role: "assistant", Content: "不客气"})
+
+	// 摘要生成期间不阻塞调用方，也不重复发起摘要
+	done := make(chan struct{})
+	go func() {
+		h.compactDialogueInBackground()
+		h.compactDialogueInBackground()
+		close(done)
+	}()
+	select {
+	case <-done:
+	case <-time.After(time.Second):
+		t.Fatal("compactDialogueInBackground() 阻塞等待摘要完成")
+	}
+	if n := h.dialogueManager.Length(); n != 5 {
+		t.Errorf("摘要完成前对话历史 %d 条, 期望不变", n)
+	}
+
+	close(provider.release)
+	deadline := time.Now().Add(time.Second)
+	for h.compacting.Load() && time.Now().Before(deadline) {
+		time.Sleep(10 * time.Millisecond)
+	}
+	dialogue := h.dialogueManager.GetLLMDialogue()
+	if len(dialogue) != 4 || dialogue[1].Content != chat.SummaryPrefix+"用户聊过很长的内容" || dialogue[2].Content != "谢谢" {
+		t.Errorf("后台摘要后对话 = %v", dialogue)
+	}
+}
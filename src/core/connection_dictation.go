@@ -0,0 +1,162 @@
+package core
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"angrymiao-ai-server/src/core/dictation"
+	"angrymiao-ai-server/src/core/providers"
+	"angrymiao-ai-server/src/models"
+
+	"github.com/google/uuid"
+)
+
+// 听写拾音模式
+const listenModeDictation = "dictation"
+
+// 听写整理的超时时间
+const dictationPolishTimeout = 60 * time.Second
+
+// 默认的听写整理提示词
+const defaultDictationPrompt = `下面是一段语音听写的文本，请补充和修正标点，修正明显的同音字识别错误，删除无意义的语气词和重复。
+保持原意和段落划分，不要增加或删减内容，不要回答其中的问题，只输出整理后的文本。`
+
+// startDictation 开始一篇新的听写文档，已有未结束的文档时继续使用
+func (h *ConnectionHandler) startDictation() {
+	h.dictationMu.Lock()
+	defer h.dictationMu.Unlock()
+	if h.dictationDoc != nil {
+		return
+	}
+	h.dictationDoc = &dictation.Document{}
+	h.dictationStartedAt = time.Now()
+	h.LogInfo("开始听写")
+}
+
+// handleDictationResult 处理一句听写识别结果，正文以 stt 消息推送，并推送文档的最新全文
+func (h *ConnectionHandler) handleDictationResult(text string) {
+	h.startDictation()
+
+	h.dictationMu.Lock()
+	command := h.dictationDoc.Apply(text)
+	content := h.dictationDoc.Text()
+	h.dictationMu.Unlock()
+
+	if command == dictation.CommandNone {
+		if err := h.sendSTTMessage(text); err != nil {
+			h.LogError(fmt.Sprintf("发送STT消息失败: %v", err))
+		}
+	} else {
+		h.LogInfo(fmt.Sprintf("听写指令: %s", command))
+	}
+	if err := h.sendStateMessage(listenModeDictation, "update", map[string]interface{}{
+		"command": string(command),
+		"text":    content,
+	}); err != nil {
+		h.LogError(fmt.Sprintf("发送听写消息失败: %v", err))
+	}
+
+	if command == dictation.CommandFinish {
+		// 在ASR回调中调用，整理和保存不阻塞识别
+		go h.finishDictation(true)
+	}
+}
+
+// finishDictation 结束听写并保存文档，polish 为 true 且配置开启时先由LLM整理文本
+// 整理最长需要 dictationPolishTimeout，调用方应在单独的协程中调用；连接关闭时不再整理，直接保存原文
+func (h *ConnectionHandler) finishDictation(polish bool) {
+	h.dictationMu.Lock()
+	doc, startedAt := h.dictationDoc, h.dictationStartedAt
+	h.dictationDoc = nil
+	h.dictationMu.Unlock()
+	if doc == nil || doc.Empty() {
+		return
+	}
+
+	raw := doc.Text()
+	record := &models.DictationDocument{
+		DocumentID: uuid.New().String(),
+		UserID:     h.userID,
+		DeviceID:   h.deviceID,
+		SessionID:  h.sessionID,
+		Content:    raw,
+		RawContent: raw,
+		StartedAt:  startedAt,
+	}
+	if polish && h.config.Dictation.Polish {
+		if text, err := h.polishDictation(raw); err != nil {
+			h.LogError(fmt.Sprintf("整理听写文本失败，保存原文: %v", err))
+		} else {
+			record.Content = text
+			record.Polished = true
+		}
+	}
+
+	if h.dictationService != nil {
+		ctx, cancel := context.WithTimeout(context.Background(), historySaveTimeout)
+		defer cancel()
+		if err := h.dictationService.SaveDocument(ctx, record); err != nil {
+			h.LogError(fmt.Sprintf("保存听写文档失败: %v", err))
+			record.DocumentID = ""
+		}
+	} else {
+		record.DocumentID = ""
+	}
+	h.LogInfo(fmt.Sprintf("听写结束: %s, 共%d字", record.DocumentID, len([]rune(record.Content))))
+
+	if err := h.sendStateMessage(listenModeDictation, "finish", map[string]interface{}{
+		"document_id": record.DocumentID,
+		"text":        record.Content,
+		"polished":    record.Polished,
+	}); err != nil {
+		h.LogError(fmt.Sprintf("发送听写消息失败: %v", err))
+	}
+}
+
+// polishDictation 调用LLM整理听写文本
+func (h *ConnectionHandler) polishDictation(text string) (string, error) {
+	cfg := h.config.Dictation
+	llm, release, err := h.acquireLLM(cfg.LLM)
+	if err != nil {
+		return "", err
+	}
+	defer release()
+
+	prompt := cfg.Prompt
+	if prompt == "" {
+		prompt = defaultDictationPrompt
+	}
+	messages := []providers.Message{
+		{Role: "system", Content: prompt},
+		{Role: "user", Content: text},
+	}
+
+	ctx, cancel := context.WithTimeout(h.connContext(), dictationPolishTimeout)
+	defer cancel()
+	responses, err := llm.Response(ctx, h.sessionID, messages)
+	if err != nil {
+		return "", err
+	}
+	var result strings.Builder
+	for content := range responses {
+		result.WriteString(content)
+	}
+
+	llmName := cfg.LLM
+	if llmName == "" {
+		llmName = h.usageProviderName("LLM")
+	}
+	h.recordLLMUsage(llmName, messages, result.String(), nil)
+	h.flushUsage(false)
+
+	if err := ctx.Err(); err != nil {
+		return "", err
+	}
+	polished := strings.TrimSpace(result.String())
+	if polished == "" {
+		return "", fmt.Errorf("整理结果为空")
+	}
+	return polished, nil
+}
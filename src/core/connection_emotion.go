@@ -0,0 +1,41 @@
+package core
+
+import (
+	"fmt"
+
+	"angrymiao-ai-server/src/core/utils"
+)
+
+// 默认情绪标注说明
+const defaultEmotionPrompt = `
+请在每句话开头用一个表情符号表达这句话的情绪，只能从以下表情中选择：%s。
+表情不会被朗读出来，多角色对白时表情放在说话人标注之后。`
+
+// emotionPrompt 启用回复情绪时返回追加到系统提示词的标注说明
+func (h *ConnectionHandler) emotionPrompt() string {
+	if !h.config.Emotion.Enabled {
+		return ""
+	}
+	if h.config.Emotion.Prompt != "" {
+		return h.config.Emotion.Prompt
+	}
+	return fmt.Sprintf(defaultEmotionPrompt, utils.EmotionPromptList())
+}
+
+// extractEmotion 提取一句回复的情绪并去掉句首情绪标签，未启用回复情绪时原样返回
+func (h *ConnectionHandler) extractEmotion(text string) (string, string) {
+	if !h.config.Emotion.Enabled {
+		return "", text
+	}
+	return utils.ExtractEmotion(text)
+}
+
+// sendThinkingEmotion 启用回复情绪时在新一轮对话开始时发送思考表情
+func (h *ConnectionHandler) sendThinkingEmotion() {
+	if !h.config.Emotion.Enabled {
+		return
+	}
+	if err := h.sendEmotionMessage("thinking"); err != nil {
+		h.LogError(fmt.Sprintf("发送思考状态情绪消息失败: %v", err))
+	}
+}
@@ -4,7 +4,6 @@ import (
 	"angrymiao-ai-server/src/core/types"
 	"angrymiao-ai-server/src/core/utils"
 	"angrymiao-ai-server/src/vision"
-	"context"
 	"encoding/json"
 )
 
@@ -57,12 +56,13 @@ func (h *ConnectionHandler) mcp_handler_play_music(args interface{}) {
 			//h.SystemSpeak("这就为您播放音乐: " + songName)
 			h.tts_last_text_index = h.tts_last_text_index + 1
 
-			h.ttsQueue <- struct {
-				text      string
-				round     int
-				textIndex int
-				filepath  string
-			}{name, h.talkRound, h.tts_last_text_index, path}
+			h.ttsQueue <- ttsTask{
+				text:      name,
+				round:     h.talkRound,
+				textIndex: h.tts_last_text_index,
+				filepath:  path,
+				ctx:       h.roundContext(),
+			}
 		}
 	} else {
 		h.logger.Error("mcp_handler_play_music: args is not a string")
@@ -86,24 +86,8 @@ func (h *ConnectionHandler) mcp_handler_change_voice(args interface{}) {
 func (h *ConnectionHandler) mcp_handler_change_role(args interface{}) {
 	if params, ok := args.(map[string]string); ok {
 		role := params["role"]
-		prompt := params["prompt"]
-
 		h.logger.Info("mcp_handler_change_role: %s", role)
-		h.dialogueManager.SetSystemMessage(prompt)
-		h.dialogueManager.KeepRecentMessages(5) // 保留最近5条消息
-		if getter, ok := h.providers.tts.(configGetter); ok {
-			ttsProvider := getter.Config().Type
-			if ttsProvider == "edge" {
-				if role == "陕西女友" {
-					h.providers.tts.SetVoice("zh-CN-shaanxi-XiaoniNeural") // 陕西女友音色
-				} else if role == "英语老师" {
-					h.providers.tts.SetVoice("zh-CN-XiaoyiNeural") // 英语老师音色
-				} else if role == "好奇小男孩" {
-					h.providers.tts.SetVoice("zh-CN-YunxiNeural") // 好奇小男孩音色
-				}
-			}
-		}
-		h.SystemSpeak("已切换到新角色 " + role)
+		h.switchPersona(role, params["prompt"])
 	} else {
 		h.logger.Error("mcp_handler_change_role: args is not a string")
 	}
@@ -130,7 +114,7 @@ func (h *ConnectionHandler) mcp_handler_take_photo(args interface{}) {
 
 	if !visionResponse.Success {
 		h.logger.Error("拍照失败: %s", visionResponse.Message)
-		h.genResponseByLLM(context.Background(), h.dialogueManager.GetLLMDialogue(), h.talkRound)
+		h.genResponseByLLM(h.roundContext(), h.dialogueManager.GetLLMDialogue(), h.talkRound)
 
 	}
 
@@ -4,17 +4,23 @@ import (
 	"angrymiao-ai-server/src/core/chat"
 	"angrymiao-ai-server/src/core/image"
 	"angrymiao-ai-server/src/core/providers"
+	"angrymiao-ai-server/src/core/router"
 	"angrymiao-ai-server/src/core/utils"
 	"context"
 	"encoding/json"
 	"fmt"
 	"strings"
+	"time"
 )
 
 // handleMessage 处理接收到的消息
 func (h *ConnectionHandler) handleMessage(messageType int, message []byte) error {
 	switch messageType {
 	case 1: // 文本消息
+		// 中止消息直接处理，不排在正在进行的对话轮次之后
+		if isAbortMessage(message) {
+			return h.clientAbortChat()
+		}
 		h.clientTextQueue <- string(message)
 		return nil
 	case 2: // 二进制消息（音频数据）
@@ -49,6 +55,14 @@ func (h *ConnectionHandler) handleMessage(messageType int, message []byte) error
 	}
 }
 
+// isAbortMessage 是否为客户端中止消息
+func isAbortMessage(message []byte) bool {
+	var msg struct {
+		Type string `json:"type"`
+	}
+	return json.Unmarshal(message, &msg) == nil && msg.Type == "abort"
+}
+
 // processClientTextMessage 处理文本数据
 func (h *ConnectionHandler) processClientTextMessage(ctx context.Context, text string) error {
 	// 解析JSON消息
@@ -78,8 +92,6 @@ func (h *ConnectionHandler) processClientTextMessage(ctx context.Context, text s
 	switch msgType {
 	case "hello":
 		return h.handleHelloMessage(msgMap)
-	case "abort":
-		return h.clientAbortChat()
 	case "listen":
 		return h.handleListenMessage(msgMap)
 	case "chat":
@@ -113,6 +125,12 @@ func (h *ConnectionHandler) handleVisionMessage(msgMap map[string]interface{}) e
 // 客户端会上传语音格式和采样率等信息
 func (h *ConnectionHandler) handleHelloMessage(msgMap map[string]interface{}) error {
 	h.LogInfo("收到客户端欢迎消息: " + fmt.Sprintf("%v", msgMap))
+	if board, ok := msgMap["board"].(string); ok && board != "" {
+		h.board = board
+	}
+	if features, ok := msgMap["features"].(map[string]interface{}); ok {
+		h.clientReasoning, _ = features["reasoning"].(bool)
+	}
 	// 获取客户端编码格式
 	if audioParams, ok := msgMap["audio_params"].(map[string]interface{}); ok {
 		if format, ok := audioParams["format"].(string); ok {
@@ -160,11 +178,35 @@ func (h *ConnectionHandler) handleListenMessage(msgMap map[string]interface{}) e
 		return fmt.Errorf("listen消息缺少state参数")
 	}
 
+	// 设备重新开始拾音后恢复处理会议音频
+	if state == "start" {
+		h.clearMeetingExpired()
+	}
+
 	// 处理mode参数
 	if mode, ok := msgMap["mode"].(string); ok {
+		// 离开听写或会议记录模式时结束听写或会议
+		if h.clientListenMode == listenModeDictation && mode != listenModeDictation {
+			go h.finishDictation(true)
+		}
+		if h.clientListenMode == listenModeMeeting && mode != listenModeMeeting {
+			go h.finishMeeting(true)
+		}
 		h.clientListenMode = mode
 		h.LogInfo(fmt.Sprintf("客户端拾音模式：%s， %s", h.clientListenMode, state))
 		h.providers.asr.SetListener(h)
+		switch mode {
+		case listenModeTranslate:
+			h.setTranslateOptions(msgMap)
+		case listenModeDictation:
+			if state == "start" {
+				h.startDictation()
+			}
+		case listenModeMeeting:
+			if state == "start" {
+				h.startMeeting()
+			}
+		}
 	}
 
 	switch state {
@@ -183,6 +225,14 @@ func (h *ConnectionHandler) handleListenMessage(msgMap map[string]interface{}) e
 			}
 		}
 		h.LogInfo("客户端停止语音识别")
+		switch h.clientListenMode {
+		case listenModeDictation:
+			// 整理和保存耗时较长，不阻塞消息处理
+			go h.finishDictation(true)
+		case listenModeMeeting:
+			// 总结和播报耗时较长，不阻塞消息处理
+			go h.finishMeeting(true)
+		}
 	case "detect":
 		text, hasText := msgMap["text"].(string)
 
@@ -191,7 +241,7 @@ func (h *ConnectionHandler) handleListenMessage(msgMap map[string]interface{}) e
 			h.LogInfo(fmt.Sprintf("检测到纯文本消息，使用LLM处理 %v", map[string]interface{}{
 				"text": text,
 			}))
-			return h.handleChatMessage(context.Background(), text)
+			return h.handleChatMessage(h.connContext(), text)
 		} else {
 			// 既没有图片也没有文本
 			h.logger.Warn("detect消息既没有text也没有image参数")
@@ -203,9 +253,17 @@ func (h *ConnectionHandler) handleListenMessage(msgMap map[string]interface{}) e
 
 // handleImageMessage 处理图片消息
 func (h *ConnectionHandler) handleImageMessage(ctx context.Context, msgMap map[string]interface{}) error {
+	// 上一轮回复尚未播放完时，对话历史只保留已播放的内容
+	h.truncateInterruptedReply()
+
 	// 增加对话轮次
 	h.talkRound++
+	h.roundStartTime = time.Now()
+	h.roundFirstTextAt = time.Time{}
+	h.roundRoute = router.Decision{}
 	currentRound := h.talkRound
+	ctx = h.beginRound(ctx)
+	h.refreshSystemPrompt()
 	h.LogInfo(fmt.Sprintf("开始新的图片对话轮次: %d", currentRound))
 
 	// 检查是否有VLLLM Provider
@@ -269,8 +327,21 @@ func (h *ConnectionHandler) handleImageMessage(ctx context.Context, msgMap map[s
 		return fmt.Errorf("发送情绪消息失败: %v", err)
 	}
 
+	// 超出对话用量配额时礼貌拒绝
+	if h.usageQuotaExceeded() {
+		h.refuseForQuota(currentRound)
+		return nil
+	}
+
+	// 审核用户文本，命中时按配置遮盖、替换为话术或结束会话
+	text, ok = h.moderateInput(currentRound, text)
+	if !ok {
+		return nil
+	}
+
 	// 添加用户消息到对话历史（包含图片信息的描述）
 	userMessage := fmt.Sprintf("%s [用户发送了一张%s格式的图片]", text, imageData.Format)
+	h.dialogueManager.BeginRound()
 	h.dialogueManager.Put(chat.Message{
 		Role:    "user",
 		Content: userMessage,
@@ -289,5 +360,8 @@ func (h *ConnectionHandler) handleImageMessage(ctx context.Context, msgMap map[s
 		})
 	}
 
-	return h.genResponseByVLLM(ctx, messages, imageData, text, currentRound)
+	err = h.genResponseByVLLM(ctx, messages, imageData, text, currentRound)
+	h.recordRound(currentRound)
+	h.flushUsage(false)
+	return err
 }
@@ -0,0 +1,162 @@
+package core
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"angrymiao-ai-server/src/core/chat"
+	"angrymiao-ai-server/src/core/router"
+	"angrymiao-ai-server/src/models"
+
+	"github.com/google/uuid"
+)
+
+// 保存对话记录的超时时间
+const historySaveTimeout = 10 * time.Second
+
+// startConversation 创建本次连接的对话会话记录
+func (h *ConnectionHandler) startConversation() {
+	if !h.config.ConversationHistory.Enabled || h.conversationService == nil {
+		return
+	}
+
+	session := &models.ConversationSession{
+		ConversationID: uuid.New().String(),
+		SessionID:      h.sessionID,
+		UserID:         h.userID,
+		DeviceID:       h.deviceID,
+		ClientID:       h.clientId,
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), historySaveTimeout)
+	defer cancel()
+	if err := h.conversationService.StartSession(ctx, session); err != nil {
+		h.LogError(fmt.Sprintf("创建对话会话记录失败: %v", err))
+		return
+	}
+	h.conversationID = session.ConversationID
+}
+
+// recordRound 保存本轮新增的对话消息
+// 消息在后台写入数据库，不阻塞对话流程
+func (h *ConnectionHandler) recordRound(round int) {
+	if h.conversationID == "" {
+		return
+	}
+
+	h.historyMu.Lock()
+	defer h.historyMu.Unlock()
+	messages := buildRoundRecords(round, h.dialogueManager.RoundMessages(), roundMeta{
+		route:       h.roundRoute,
+		startedAt:   h.roundStartTime,
+		firstTextAt: h.roundFirstTextAt,
+		finishedAt:  time.Now(),
+	})
+	if len(messages) == 0 {
+		return
+	}
+	h.recordedRound = round
+
+	conversationID := h.conversationID
+	h.writeHistory("保存对话记录失败", func(ctx context.Context) error {
+		return h.conversationService.SaveRound(ctx, conversationID, messages)
+	})
+}
+
+// updateRecordedReply 回复被打断时更新已保存的助手回复，该轮尚未保存时不做处理
+// 调用方需持有 historyMu
+func (h *ConnectionHandler) updateRecordedReply(round int, content string) {
+	if h.conversationID == "" || round != h.recordedRound {
+		return
+	}
+	conversationID := h.conversationID
+	h.writeHistory("更新被打断的回复失败", func(ctx context.Context) error {
+		return h.conversationService.UpdateReply(ctx, conversationID, round, content)
+	})
+}
+
+// writeHistory 在后台写入对话记录，按调用顺序依次执行，避免更新早于保存
+// 调用方需持有 historyMu
+func (h *ConnectionHandler) writeHistory(errMsg string, write func(ctx context.Context) error) {
+	previous := h.historyDone
+	done := make(chan struct{})
+	h.historyDone = done
+
+	go func() {
+		defer close(done)
+		if previous != nil {
+			<-previous
+		}
+		ctx, cancel := context.WithTimeout(context.Background(), historySaveTimeout)
+		defer cancel()
+		if err := write(ctx); err != nil {
+			h.LogError(fmt.Sprintf("%s: %v", errMsg, err))
+		}
+	}()
+}
+
+// roundMeta 本轮的路由和耗时信息
+type roundMeta struct {
+	route       router.Decision
+	startedAt   time.Time
+	firstTextAt time.Time
+	finishedAt  time.Time
+}
+
+// buildRoundRecords 将本轮新增的对话消息转换为对话记录
+// 路由记录在用户消息上，首句耗时和总耗时记录在最后一条助手文本回复上，工具结果补充对应的工具名称
+func buildRoundRecords(round int, dialogue []chat.Message, meta roundMeta) []*models.ConversationMessage {
+	toolNames := make(map[string]string)
+	var messages []*models.ConversationMessage
+	var reply *models.ConversationMessage
+	for _, msg := range dialogue {
+		record := &models.ConversationMessage{
+			Round:      round,
+			Role:       msg.Role,
+			Content:    msg.Content,
+			ToolCallID: msg.ToolCallID,
+		}
+		switch msg.Role {
+		case "user":
+			record.CreatedAt = meta.startedAt
+			record.Route = meta.route.Route
+			record.LLM = meta.route.LLM
+		case "assistant":
+			if len(msg.ToolCalls) > 0 {
+				data, _ := json.Marshal(msg.ToolCalls)
+				record.ToolCalls = string(data)
+				for _, tc := range msg.ToolCalls {
+					toolNames[tc.ID] = tc.Function.Name
+				}
+			}
+			if msg.Content != "" {
+				reply = record
+			}
+		case "tool":
+			record.ToolName = toolNames[msg.ToolCallID]
+		default:
+			continue
+		}
+		messages = append(messages, record)
+	}
+	if reply != nil {
+		if !meta.firstTextAt.IsZero() {
+			reply.LatencyMs = meta.firstTextAt.Sub(meta.startedAt).Milliseconds()
+		}
+		reply.DurationMs = meta.finishedAt.Sub(meta.startedAt).Milliseconds()
+	}
+	return messages
+}
+
+// endConversation 标记对话会话结束
+func (h *ConnectionHandler) endConversation() {
+	if h.conversationID == "" {
+		return
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), historySaveTimeout)
+	defer cancel()
+	if err := h.conversationService.EndSession(ctx, h.conversationID); err != nil {
+		h.LogError(fmt.Sprintf("更新对话会话结束时间失败: %v", err))
+	}
+}
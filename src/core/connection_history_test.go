@@ -0,0 +1,119 @@
+package core
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+
+	"angrymiao-ai-server/src/core/chat"
+	"angrymiao-ai-server/src/core/router"
+	"angrymiao-ai-server/src/core/types"
+	"angrymiao-ai-server/src/models"
+	"angrymiao-ai-server/src/services"
+)
+
+func TestBuildRoundRecords(t *testing.T) {
+	start := time.Date(2026, 1, 1, 9, 0, 0, 0, time.Local)
+	dialogue := []chat.Message{
+		{Role: "user", Content: "北京天气"},
+		{Role: "assistant", ToolCalls: []types.ToolCall{{ID: "call_1", Function: types.FunctionCall{Name: "get_weather", Arguments: `{"city":"北京"}`}}}},
+		{Role: "tool", ToolCallID: "call_1", Content: "晴"},
+		{Role: "system", Content: "不应记录"},
+		{Role: "assistant", Content: "北京今天晴。"},
+	}
+	records := buildRoundRecords(3, dialogue, roundMeta{
+		route:       router.Decision{Route: "weather", LLM: "QwenLLM"},
+		startedAt:   start,
+		firstTextAt: start.Add(800 * time.Millisecond),
+		finishedAt:  start.Add(2 * time.Second),
+	})
+
+	if len(records) != 4 {
+		t.Fatalf("buildRoundRecords() 返回 %d 条, 期望 4", len(records))
+	}
+	for _, r := range records {
+		if r.Round != 3 {
+			t.Errorf("记录轮次 = %d, 期望 3", r.Round)
+		}
+	}
+	if user := records[0]; user.Route != "weather" || user.LLM != "QwenLLM" || !user.CreatedAt.Equal(start) {
+		t.Errorf("用户消息 = %+v", user)
+	}
+	if call := records[1]; call.ToolCalls == "" || call.LatencyMs != 0 {
+		t.Errorf("工具调用消息 = %+v", call)
+	}
+	if tool := records[2]; tool.ToolName != "get_weather" || tool.ToolCallID != "call_1" {
+		t.Errorf("工具结果消息 = %+v", tool)
+	}
+	if reply := records[3]; reply.LatencyMs != 800 || reply.DurationMs != 2000 {
+		t.Errorf("助手回复耗时 = %d, %d", reply.LatencyMs, reply.DurationMs)
+	}
+
+	if records := buildRoundRecords(1, nil, roundMeta{}); len(records) != 0 {
+		t.Errorf("空消息 buildRoundRecords() = %v", records)
+	}
+}
+
+// fakeConversationService 按调用顺序记录写入操作
+type fakeConversationService struct {
+	services.ConversationService
+	mu    sync.Mutex
+	calls []string
+	saved []*models.ConversationMessage
+	reply string
+}
+
+func (s *fakeConversationService) SaveRound(ctx context.Context, conversationID string, messages []*models.ConversationMessage) error {
+	time.Sleep(20 * time.Millisecond)
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.calls = append(s.calls, "save")
+	s.saved = messages
+	return nil
+}
+
+func (s *fakeConversationService) UpdateReply(ctx context.Context, conversationID string, round int, content string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.calls = append(s.calls, "update")
+	s.reply = content
+	return nil
+}
+
+func TestTruncateRecordedReply(t *testing.T) {
+	h := newTestHandler(t)
+	service := &fakeConversationService{}
+	h.conversationService = service
+	h.conversationID = "conv"
+	h.dialogueManager = chat.NewDialogueManager(nil, nil)
+	h.dialogueManager.Put(chat.Message{Role: "user", Content: "上一轮"})
+
+	h.talkRound = 1
+	h.roundStartTime = time.Now()
+	h.dialogueManager.BeginRound()
+	h.dialogueManager.Put(chat.Message{Role: "user", Content: "讲个故事"})
+	h.dialogueManager.Put(chat.Message{Role: "assistant", Content: "从前有座山，山里有座庙。"})
+	h.playback.queue(1, 1, "从前有座山，")
+	h.playback.queue(1, 2, "山里有座庙。")
+	h.playback.advance(1, 1, "从前有座山，", 1, 1)
+	h.setLastReply(1, 1, 2)
+
+	// 保存本轮后被打断，更新必须在保存之后执行
+	h.recordRound(1)
+	h.truncateInterruptedReply()
+	h.historyMu.Lock()
+	done := h.historyDone
+	h.historyMu.Unlock()
+	<-done
+
+	service.mu.Lock()
+	defer service.mu.Unlock()
+	if len(service.saved) != 2 || service.saved[0].Content != "讲个故事" {
+		t.Errorf("保存的消息 = %+v", service.saved)
+	}
+	want := chat.InterruptedReply("从前有座山，")
+	if len(service.calls) != 2 || service.calls[0] != "save" || service.calls[1] != "update" || service.reply != want {
+		t.Errorf("写入顺序 = %v, 更新内容 = %q", service.calls, service.reply)
+	}
+}
@@ -0,0 +1,97 @@
+package core
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"sync/atomic"
+
+	"angrymiao-ai-server/src/core/chat"
+	"angrymiao-ai-server/src/core/intent"
+	"angrymiao-ai-server/src/core/types"
+
+	"github.com/google/uuid"
+)
+
+// initIntent 创建本地意图识别引擎
+func (h *ConnectionHandler) initIntent() {
+	if !h.config.Intent.Enabled {
+		return
+	}
+	engine, err := intent.NewEngine(h.config.Intent)
+	if err != nil {
+		h.logger.Error("创建本地意图识别引擎失败: %v", err)
+		return
+	}
+	h.intentEngine = engine
+}
+
+// resolveIntentTool 查找意图对应的可用工具，本地工具可省略 local_ 前缀
+func (h *ConnectionHandler) resolveIntentTool(name string) (string, bool) {
+	for _, tool := range h.availableTools() {
+		if tool.Function == nil {
+			continue
+		}
+		if tool.Function.Name == name || strings.TrimPrefix(tool.Function.Name, "local_") == name {
+			return tool.Function.Name, true
+		}
+	}
+	return "", false
+}
+
+// handleLocalIntent 本地意图命中时直接调用工具，返回是否已处理
+// 未命中、工具不可用时交给LLM；工具结果需要LLM处理且未配置回复时，带着工具结果请求LLM
+func (h *ConnectionHandler) handleLocalIntent(ctx context.Context, text string, round int) bool {
+	if h.intentEngine == nil {
+		return false
+	}
+	match, ok := h.intentEngine.Match(text)
+	if !ok {
+		return false
+	}
+	toolName, ok := h.resolveIntentTool(match.Tool)
+	if !ok {
+		h.LogInfo(fmt.Sprintf("本地意图 %s 的工具 %s 不可用，交给LLM处理", match.Intent, match.Tool))
+		return false
+	}
+	arguments, err := json.Marshal(match.Arguments)
+	if err != nil {
+		h.LogError(fmt.Sprintf("序列化本地意图参数失败: %v", err))
+		return false
+	}
+	h.LogInfo(fmt.Sprintf("命中本地意图: %s, 置信度: %.2f, 工具: %s(%s)", match.Intent, match.Confidence, toolName, arguments))
+
+	atomic.StoreInt32(&h.serverVoiceStop, 0)
+	lastIndex := h.tts_last_text_index
+	reply := &llmReply{round: round}
+	call := types.ToolCall{
+		ID:   uuid.New().String(),
+		Type: "function",
+		Function: types.FunctionCall{
+			Name:      toolName,
+			Arguments: string(arguments),
+		},
+	}
+	needLLM := h.executeToolCalls(ctx, "", []types.ToolCall{call}, reply)
+	if ctx.Err() != nil {
+		return true
+	}
+
+	switch {
+	case match.Reply != "":
+		h.dialogueManager.Put(chat.Message{Role: "assistant", Content: match.Reply})
+		h.SystemSpeak(match.Reply)
+	case needLLM:
+		if err := h.genResponseByLLM(ctx, h.dialogueManager.GetLLMDialogue(), round); err != nil {
+			h.LogError(fmt.Sprintf("本地意图工具结果交给LLM回复失败: %v", err))
+		}
+	}
+
+	// 工具和回复都没有播报时结束本轮播放状态
+	if h.tts_last_text_index == lastIndex {
+		h.sendTTSMessage("stop", "", 0)
+		h.clearSpeakStatus()
+	}
+	return true
+}
@@ -0,0 +1,122 @@
+package core
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"angrymiao-ai-server/src/core/types"
+	"angrymiao-ai-server/src/models"
+	"angrymiao-ai-server/src/services"
+
+	"github.com/sashabaranov/go-openai"
+)
+
+const (
+	// 知识库检索工具在工具注册表中的名称
+	searchKnowledgeToolName = "search_knowledge"
+	// 自动检索知识库的超时时间，超时后本轮不加入知识库资料
+	knowledgeInjectTimeout = 3 * time.Second
+)
+
+// knowledgeEnabled 是否启用知识库
+func (h *ConnectionHandler) knowledgeEnabled() bool {
+	return h.config.Knowledge.Enabled && h.knowledgeService != nil
+}
+
+// knowledgeScope 当前连接的检索范围：共享知识库、当前用户和当前角色的知识库
+func (h *ConnectionHandler) knowledgeScope() services.KnowledgeScope {
+	return services.KnowledgeScope{UserID: h.userID, Persona: h.currentRole}
+}
+
+// registerKnowledgeTool 注册知识库检索工具，由连接处理器直接执行
+func (h *ConnectionHandler) registerKnowledgeTool() {
+	if !h.knowledgeEnabled() || h.functionRegister.FunctionExists(searchKnowledgeToolName) {
+		return
+	}
+	tool := openai.Tool{
+		Type: openai.ToolTypeFunction,
+		Function: &openai.FunctionDefinition{
+			Name:        searchKnowledgeToolName,
+			Description: "检索知识库中的产品资料。回答产品功能、参数、价格、使用方法、售后政策等问题时，如果提供的资料不足，先调用此工具",
+			Parameters: map[string]interface{}{
+				"type": "object",
+				"properties": map[string]interface{}{
+					"query": map[string]interface{}{
+						"type":        "string",
+						"description": "检索内容，使用完整的问题或关键词",
+					},
+				},
+				"required": []string{"query"},
+			},
+		},
+	}
+	if err := h.functionRegister.RegisterFunction(searchKnowledgeToolName, tool); err != nil {
+		h.logger.Error("注册知识库检索工具失败: %v", err)
+	}
+}
+
+// retrievalContext 检索与本轮内容相关的长期记忆和知识库资料，合并为一条系统消息的内容
+func (h *ConnectionHandler) retrievalContext(text string) string {
+	parts := make([]string, 0, 2)
+	for _, part := range []string{h.dialogueManager.QueryMemory(text), h.queryKnowledge(text)} {
+		if part != "" {
+			parts = append(parts, part)
+		}
+	}
+	return strings.Join(parts, "\n\n")
+}
+
+// queryKnowledge 自动检索与本轮内容相关的知识库资料，未启用或检索失败时返回空
+func (h *ConnectionHandler) queryKnowledge(text string) string {
+	if !h.knowledgeEnabled() || !h.config.Knowledge.AutoInject {
+		return ""
+	}
+	ctx, cancel := context.WithTimeout(h.roundContext(), knowledgeInjectTimeout)
+	defer cancel()
+
+	results, err := h.knowledgeService.Search(ctx, h.knowledgeScope(), text, 0)
+	if err != nil {
+		h.logger.Error("检索知识库失败: %v", err)
+		return ""
+	}
+	if len(results) == 0 {
+		return ""
+	}
+	h.LogInfo(fmt.Sprintf("知识库检索到 %d 条相关资料", len(results)))
+	return "以下是知识库中与用户问题相关的资料，回答时优先依据这些资料，资料中没有的内容不要编造：\n" + formatKnowledge(results)
+}
+
+// searchKnowledge 执行知识库检索工具
+func (h *ConnectionHandler) searchKnowledge(ctx context.Context, arguments map[string]interface{}) types.ActionResponse {
+	query, _ := arguments["query"].(string)
+	if strings.TrimSpace(query) == "" {
+		return types.ActionResponse{Action: types.ActionTypeReqLLM, Result: "检索内容不能为空"}
+	}
+	if !h.knowledgeEnabled() {
+		return types.ActionResponse{Action: types.ActionTypeReqLLM, Result: "知识库未启用"}
+	}
+
+	results, err := h.knowledgeService.Search(ctx, h.knowledgeScope(), query, 0)
+	if err != nil {
+		h.LogError(fmt.Sprintf("知识库检索失败: %v", err))
+		return types.ActionResponse{Action: types.ActionTypeReqLLM, Result: "知识库检索失败，请根据已有信息回答"}
+	}
+	if len(results) == 0 {
+		return types.ActionResponse{Action: types.ActionTypeReqLLM, Result: "知识库中没有找到与 " + query + " 相关的资料"}
+	}
+	return types.ActionResponse{Action: types.ActionTypeReqLLM, Result: formatKnowledge(results)}
+}
+
+// formatKnowledge 将检索结果格式化为提示词文本
+func formatKnowledge(results []*models.KnowledgeSearchResult) string {
+	var b strings.Builder
+	for i, r := range results {
+		if i > 0 {
+			b.WriteString("\n")
+		}
+		fmt.Fprintf(&b, "[%d]《%s》%s", i+1, r.Document, r.Content)
+	}
+	return b.String()
+}
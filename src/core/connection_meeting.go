@@ -0,0 +1,268 @@
+package core
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"sync/atomic"
+	"time"
+
+	"angrymiao-ai-server/src/core/meeting"
+	"angrymiao-ai-server/src/core/providers"
+	"angrymiao-ai-server/src/models"
+
+	"github.com/google/uuid"
+)
+
+// 会议记录拾音模式
+const listenModeMeeting = "meeting"
+
+// 未配置时的默认值
+const (
+	defaultMeetingMaxDuration    = 180 * time.Minute
+	defaultMeetingStreamRollover = 240 * time.Second
+	meetingSummaryTimeout        = 5 * time.Minute
+)
+
+// startMeeting 开始会议记录，已有未结束的会议时继续记录，会议超时结束后等待设备重新开始拾音
+func (h *ConnectionHandler) startMeeting() {
+	h.meetingMu.Lock()
+	if h.meetingTranscript != nil || h.meetingExpired {
+		h.meetingMu.Unlock()
+		return
+	}
+	now := time.Now()
+	h.meetingTranscript = meeting.NewTranscript(now)
+	h.meetingStreamAt = now
+	h.meetingMu.Unlock()
+
+	h.LogInfo("开始会议记录")
+	if err := h.sendStateMessage(listenModeMeeting, "start", nil); err != nil {
+		h.LogError(fmt.Sprintf("发送会议消息失败: %v", err))
+	}
+}
+
+// handleMeetingResult 记录一句带时间戳的会议发言，并推送给设备
+func (h *ConnectionHandler) handleMeetingResult(text string) {
+	h.startMeeting()
+
+	h.meetingMu.Lock()
+	if h.meetingTranscript == nil {
+		h.meetingMu.Unlock()
+		return
+	}
+	utterance := h.meetingTranscript.Add(time.Now(), text)
+	h.meetingMu.Unlock()
+
+	if err := h.sendSTTMessage(utterance.Text); err != nil {
+		h.LogError(fmt.Sprintf("发送STT消息失败: %v", err))
+	}
+	if err := h.sendStateMessage(listenModeMeeting, "utterance", map[string]interface{}{
+		"text":      utterance.Text,
+		"offset":    meeting.FormatOffset(utterance.Offset),
+		"offset_ms": utterance.Offset.Milliseconds(),
+		"timestamp": utterance.At.UnixMilli(),
+	}); err != nil {
+		h.LogError(fmt.Sprintf("发送会议消息失败: %v", err))
+	}
+}
+
+// rolloverMeetingASR 结束当前ASR识别会话，下一段音频到达时重新建立，避免触发识别服务的空闲超时和单会话时长限制
+func (h *ConnectionHandler) rolloverMeetingASR() {
+	h.meetingMu.Lock()
+	h.meetingStreamAt = time.Now()
+	h.meetingMu.Unlock()
+	if err := h.providers.asr.Reset(); err != nil {
+		h.LogError(fmt.Sprintf("重置ASR状态失败: %v", err))
+	}
+}
+
+// checkMeetingLimits 每段音频送入ASR前检查会议时长和识别会话时长，返回false时丢弃该段音频
+// 识别会话超时轮换时会丢弃尚未返回结果的半句话，因此轮换时间应远大于单句时长
+func (h *ConnectionHandler) checkMeetingLimits() bool {
+	h.meetingMu.Lock()
+	if h.meetingExpired {
+		h.meetingMu.Unlock()
+		return false
+	}
+	if h.meetingTranscript == nil {
+		h.meetingMu.Unlock()
+		return true
+	}
+	maxDuration, rollover := h.meetingLimits()
+	expired := time.Since(h.meetingTranscript.StartedAt) > maxDuration
+	streamExpired := time.Since(h.meetingStreamAt) > rollover
+	h.meetingExpired = expired
+	h.meetingMu.Unlock()
+
+	switch {
+	case expired:
+		h.LogInfo(fmt.Sprintf("会议记录超过最长时长 %s，自动结束", maxDuration))
+		h.stopExpiredMeeting(maxDuration)
+		return false
+	case streamExpired:
+		h.LogInfo("会议ASR识别会话达到最长时间，重新建立会话")
+		h.rolloverMeetingASR()
+	}
+	return true
+}
+
+// stopExpiredMeeting 会议超过最长时长时停止识别并通知设备，设备重新开始拾音前不再记录，避免之后的发言开始新的会议
+func (h *ConnectionHandler) stopExpiredMeeting(maxDuration time.Duration) {
+	if err := h.providers.asr.Reset(); err != nil {
+		h.LogError(fmt.Sprintf("重置ASR状态失败: %v", err))
+	}
+	if err := h.sendStateMessage(listenModeMeeting, "expired", map[string]interface{}{
+		"max_duration": int(maxDuration / time.Minute),
+	}); err != nil {
+		h.LogError(fmt.Sprintf("发送会议消息失败: %v", err))
+	}
+	go h.finishMeeting(true)
+}
+
+// clearMeetingExpired 设备重新开始拾音时恢复处理音频
+func (h *ConnectionHandler) clearMeetingExpired() {
+	h.meetingMu.Lock()
+	h.meetingExpired = false
+	h.meetingMu.Unlock()
+}
+
+// meetingLimits 返回最长会议时长和单个识别会话的最长时间
+func (h *ConnectionHandler) meetingLimits() (time.Duration, time.Duration) {
+	maxDuration, rollover := defaultMeetingMaxDuration, defaultMeetingStreamRollover
+	if h.config.Meeting.MaxDuration > 0 {
+		maxDuration = time.Duration(h.config.Meeting.MaxDuration) * time.Minute
+	}
+	if h.config.Meeting.StreamRollover > 0 {
+		rollover = time.Duration(h.config.Meeting.StreamRollover) * time.Second
+	}
+	return maxDuration, rollover
+}
+
+// finishMeeting 结束会议记录，summarize 为 true 时生成纪要并播报，然后与完整记录一起保存
+// 连接关闭时不再总结，只保存完整记录
+func (h *ConnectionHandler) finishMeeting(summarize bool) {
+	h.meetingMu.Lock()
+	transcript := h.meetingTranscript
+	h.meetingTranscript = nil
+	h.meetingMu.Unlock()
+	if transcript == nil {
+		return
+	}
+	// 静音期间累积的静音计数不能带到其他拾音模式，否则会被当作长时间无人说话而结束对话
+	if resetter, ok := h.providers.asr.(interface{ ResetSilenceCount() }); ok {
+		resetter.ResetSilenceCount()
+	}
+
+	endedAt := time.Now()
+	record := &models.MeetingRecord{
+		MeetingID:      uuid.New().String(),
+		UserID:         h.userID,
+		DeviceID:       h.deviceID,
+		SessionID:      h.sessionID,
+		Transcript:     transcript.Text(),
+		UtteranceCount: len(transcript.Utterances),
+		DurationSec:    int(endedAt.Sub(transcript.StartedAt) / time.Second),
+		StartedAt:      transcript.StartedAt,
+		EndedAt:        endedAt,
+	}
+	h.LogInfo(fmt.Sprintf("会议记录结束: %d句, 时长 %s", record.UtteranceCount, meeting.FormatOffset(endedAt.Sub(transcript.StartedAt))))
+
+	if summarize && record.UtteranceCount > 0 {
+		if err := h.sendStateMessage(listenModeMeeting, "summarizing", nil); err != nil {
+			h.LogError(fmt.Sprintf("发送会议消息失败: %v", err))
+		}
+		summary, err := h.summarizeMeeting(transcript.Lines())
+		if err != nil {
+			h.LogError(fmt.Sprintf("生成会议纪要失败: %v", err))
+		}
+		record.Summary = summary
+		h.queueMeetingSummary(summary)
+	}
+
+	if h.meetingService != nil && record.UtteranceCount > 0 {
+		ctx, cancel := context.WithTimeout(context.Background(), historySaveTimeout)
+		defer cancel()
+		if err := h.meetingService.SaveMeeting(ctx, record); err != nil {
+			h.LogError(fmt.Sprintf("保存会议记录失败: %v", err))
+			record.MeetingID = ""
+		}
+	} else {
+		record.MeetingID = ""
+	}
+
+	if err := h.sendStateMessage(listenModeMeeting, "finish", map[string]interface{}{
+		"meeting_id": record.MeetingID,
+		"summary":    record.Summary,
+		"utterances": record.UtteranceCount,
+		"duration":   record.DurationSec,
+	}); err != nil {
+		h.LogError(fmt.Sprintf("发送会议消息失败: %v", err))
+	}
+}
+
+// summarizeMeeting 调用LLM分段总结会议记录
+func (h *ConnectionHandler) summarizeMeeting(lines []string) (string, error) {
+	cfg := h.config.Meeting
+	llm, release, err := h.acquireLLM(cfg.LLM)
+	if err != nil {
+		return "", err
+	}
+	defer release()
+
+	llmName := cfg.LLM
+	if llmName == "" {
+		llmName = h.usageProviderName("LLM")
+	}
+	complete := func(ctx context.Context, system, user string) (string, error) {
+		messages := []providers.Message{
+			{Role: "system", Content: system},
+			{Role: "user", Content: user},
+		}
+		responses, err := llm.Response(ctx, h.sessionID, messages)
+		if err != nil {
+			return "", err
+		}
+		var result strings.Builder
+		for content := range responses {
+			result.WriteString(content)
+		}
+		h.recordLLMUsage(llmName, messages, result.String(), nil)
+		return result.String(), ctx.Err()
+	}
+
+	ctx, cancel := context.WithTimeout(h.connContext(), meetingSummaryTimeout)
+	defer cancel()
+	startTime := time.Now()
+	summary, err := meeting.Summarize(ctx, lines, cfg.ChunkRunes, cfg.ChunkPrompt, cfg.SummaryPrompt, complete)
+	h.flushUsage(false)
+	if err != nil {
+		return "", err
+	}
+	h.LogInfo(fmt.Sprintf("会议纪要生成完成，耗时 %s", time.Since(startTime)))
+	return summary, nil
+}
+
+// queueMeetingSummary 将会议纪要交给文本消息协程播报，轮次状态只在连接的消息处理流程中修改
+func (h *ConnectionHandler) queueMeetingSummary(summary string) {
+	select {
+	case h.meetingSummaries <- summary:
+	case <-h.stopChan:
+	}
+}
+
+// speakMeetingSummary 在新的轮次中播报会议纪要，纪要生成失败时提示已保存完整记录
+func (h *ConnectionHandler) speakMeetingSummary(summary string) {
+	if summary == "" {
+		summary = "会议纪要生成失败，完整记录已保存"
+	}
+	h.talkRound++
+	h.roundStartTime = time.Now()
+	h.beginRound(h.connContext())
+	atomic.StoreInt32(&h.serverVoiceStop, 0)
+	if err := h.sendTTSMessage("start", "", 0); err != nil {
+		h.LogError(fmt.Sprintf("发送TTS开始状态失败: %v", err))
+	}
+	h.tts_last_text_index = 0 // 重置文本索引
+	h.SystemSpeak(summary)
+}
@@ -0,0 +1,98 @@
+package core
+
+import (
+	"encoding/json"
+	"sync"
+	"testing"
+	"time"
+
+	"angrymiao-ai-server/src/core/meeting"
+	"angrymiao-ai-server/src/core/providers"
+)
+
+// fakeConn 记录发送给设备的消息
+type fakeConn struct {
+	Connection
+	mu       sync.Mutex
+	messages []map[string]interface{}
+}
+
+func (c *fakeConn) WriteMessage(messageType int, data []byte) error {
+	var msg map[string]interface{}
+	if err := json.Unmarshal(data, &msg); err != nil {
+		return err
+	}
+	c.mu.Lock()
+	c.messages = append(c.messages, msg)
+	c.mu.Unlock()
+	return nil
+}
+
+// states 返回指定类型消息的state列表
+func (c *fakeConn) states(msgType string) []string {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	var states []string
+	for _, msg := range c.messages {
+		if msg["type"] == msgType {
+			state, _ := msg["state"].(string)
+			states = append(states, state)
+		}
+	}
+	return states
+}
+
+// fakeASR 只记录复位次数的ASR
+type fakeASR struct {
+	providers.ASRProvider
+	resets int
+}
+
+func (a *fakeASR) Reset() error {
+	a.resets++
+	return nil
+}
+
+func (a *fakeASR) ResetStartListenTime() {}
+
+func TestMeetingExpiredStopsRecording(t *testing.T) {
+	h := newTestHandler(t)
+	conn := &fakeConn{}
+	asr := &fakeASR{}
+	h.conn = conn
+	h.providers.asr = asr
+	h.config.Meeting.MaxDuration = 60
+	h.meetingTranscript = meeting.NewTranscript(time.Now().Add(-2 * time.Hour))
+	h.meetingStreamAt = time.Now()
+
+	if h.checkMeetingLimits() {
+		t.Error("checkMeetingLimits() 会议超时后应丢弃音频")
+	}
+	if asr.resets != 1 {
+		t.Errorf("会议超时后应复位ASR, 复位 %d 次", asr.resets)
+	}
+
+	// 等待后台结束会议
+	deadline := time.Now().Add(time.Second)
+	for len(conn.states(listenModeMeeting)) < 2 && time.Now().Before(deadline) {
+		time.Sleep(10 * time.Millisecond)
+	}
+	if got := conn.states(listenModeMeeting); len(got) != 2 || got[0] != "expired" || got[1] != "finish" {
+		t.Fatalf("发送的会议消息 = %v, 期望 [expired finish]", got)
+	}
+
+	// 设备重新开始拾音前不处理音频，迟到的识别结果也不开始新的会议
+	h.handleMeetingResult("迟到的发言")
+	if h.checkMeetingLimits() || h.meetingTranscript != nil {
+		t.Error("会议超时结束后不应开始新的会议")
+	}
+
+	h.clearMeetingExpired()
+	if !h.checkMeetingLimits() {
+		t.Error("设备重新开始拾音后应处理音频")
+	}
+	h.handleMeetingResult("新的会议")
+	if h.meetingTranscript == nil {
+		t.Error("设备重新开始拾音后应开始新的会议")
+	}
+}
@@ -0,0 +1,133 @@
+package core
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"angrymiao-ai-server/src/core/moderation"
+	"angrymiao-ai-server/src/models"
+)
+
+// outputModeration 一轮对话的输出审核状态
+type outputModeration struct {
+	round       int
+	replacement string   // 命中替换或结束时播报的话术，本轮之后的文本不再播报
+	redactions  []string // 已遮盖的片段，写入对话历史前同样遮盖
+}
+
+// initModeration 创建内容审核器，LLM分类器使用连接原有的LLM
+func (h *ConnectionHandler) initModeration() {
+	if !h.config.Moderation.Enabled {
+		return
+	}
+	moderator, err := moderation.NewModerator(h.config.Moderation, h.providers.llm, h.logger)
+	if err != nil {
+		h.logger.Error("创建内容审核器失败: %v", err)
+		return
+	}
+	h.moderator = moderator
+}
+
+// moderateInput 审核用户文本，返回处理后的文本，命中替换或结束时播报话术并返回false
+func (h *ConnectionHandler) moderateInput(round int, text string) (string, bool) {
+	if !h.moderator.Enabled(moderation.Input) {
+		return text, true
+	}
+	decision := h.moderator.Moderate(h.roundContext(), moderation.Input, text)
+	if !decision.Flagged {
+		return text, true
+	}
+	h.recordModeration(moderation.Input, text, decision)
+
+	switch decision.Action {
+	case moderation.ActionRedact:
+		return decision.Text, true
+	case moderation.ActionEnd:
+		h.closeAfterChat = true
+	}
+	h.tts_last_text_index = 1 // 重置文本索引
+	h.SpeakAndPlay(decision.Text, 1, round)
+	return "", false
+}
+
+// moderateOutput 审核进入TTS的文本，返回要播报的文本，本轮已命中替换后不再播报后续文本
+// 在播报入口统一调用，覆盖LLM回复、系统播报、工具直接回复和同声传译
+func (h *ConnectionHandler) moderateOutput(round int, text string) (string, bool) {
+	h.outputMu.Lock()
+	if h.output.round != round {
+		h.output = outputModeration{round: round}
+	}
+	replaced := h.output.replacement != ""
+	h.outputMu.Unlock()
+	if replaced {
+		return "", false
+	}
+	if !h.moderator.Enabled(moderation.Output) {
+		return text, true
+	}
+	decision := h.moderator.Moderate(h.roundContext(), moderation.Output, text)
+	if !decision.Flagged {
+		return text, true
+	}
+	h.recordModeration(moderation.Output, text, decision)
+
+	h.outputMu.Lock()
+	defer h.outputMu.Unlock()
+	if h.output.round != round {
+		h.output = outputModeration{round: round}
+	}
+	switch decision.Action {
+	case moderation.ActionRedact:
+		h.output.redactions = append(h.output.redactions, decision.Result.Matches...)
+		return decision.Text, true
+	case moderation.ActionEnd:
+		h.closeAfterChat = true
+	}
+	h.output.replacement = decision.Text
+	return decision.Text, true
+}
+
+// roundOutputModeration 返回本轮的输出审核状态
+func (h *ConnectionHandler) roundOutputModeration(round int) outputModeration {
+	h.outputMu.Lock()
+	defer h.outputMu.Unlock()
+	if h.output.round != round {
+		return outputModeration{round: round}
+	}
+	return h.output
+}
+
+// moderatedContent 返回写入对话历史的回复，命中审核的内容不进入对话历史
+func (state outputModeration) moderatedContent(content string) string {
+	if state.replacement != "" {
+		return state.replacement
+	}
+	return moderation.Redact(content, state.redactions)
+}
+
+// recordModeration 异步记录审核事件
+func (h *ConnectionHandler) recordModeration(dir moderation.Direction, text string, decision moderation.Decision) {
+	h.LogInfo(fmt.Sprintf("内容审核命中: 阶段=%s, 检查器=%s, 类别=%s, 动作=%s, 文本=%s",
+		dir, decision.Result.Checker, decision.Result.Category, decision.Action, text))
+	if h.moderationService == nil {
+		return
+	}
+
+	event := &models.ModerationEvent{
+		UserID:    h.userID,
+		DeviceID:  h.deviceID,
+		SessionID: h.sessionID,
+		Direction: string(dir),
+		Checker:   decision.Result.Checker,
+		Category:  decision.Result.Category,
+		Action:    string(decision.Action),
+		Content:   text,
+		Result:    decision.Text,
+	}
+	go func() {
+		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		h.moderationService.RecordEvent(ctx, event)
+	}()
+}
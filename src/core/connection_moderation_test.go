@@ -0,0 +1,105 @@
+package core
+
+import (
+	"testing"
+
+	"angrymiao-ai-server/src/configs"
+	"angrymiao-ai-server/src/core/moderation"
+)
+
+func newModeratedHandler(t *testing.T, action string) *ConnectionHandler {
+	t.Helper()
+	h := newTestHandler(t)
+	moderator, err := moderation.NewModerator(configs.ModerationConfig{
+		Enabled:  true,
+		Output:   configs.ModerationStageConfig{Enabled: true, Action: action, Replacement: "换个话题吧"},
+		Keywords: []string{"坏话"},
+	}, nil, h.logger)
+	if err != nil {
+		t.Fatalf("NewModerator() 错误: %v", err)
+	}
+	h.moderator = moderator
+	return h
+}
+
+// queuedTexts 取出TTS队列中的全部文本
+func queuedTexts(h *ConnectionHandler) []string {
+	var texts []string
+	for {
+		select {
+		case task := <-h.ttsQueue:
+			texts = append(texts, task.text)
+		default:
+			return texts
+		}
+	}
+}
+
+func TestSpeakModeratesAllOutput(t *testing.T) {
+	h := newModeratedHandler(t, "block")
+	h.talkRound = 1
+
+	// 系统播报、工具直接回复和同声传译都经过播报入口审核
+	h.SystemSpeak("你好。这是一句坏话。后面的内容。")
+	got := queuedTexts(h)
+	if len(got) != 3 || got[0] != "你好" || got[1] != "换个话题吧" || got[2] != "" {
+		t.Errorf("SystemSpeak() 播报 %q, 期望 [你好 换个话题吧 空任务]", got)
+	}
+	if content := h.roundOutputModeration(1).moderatedContent("这是一句坏话"); content != "换个话题吧" {
+		t.Errorf("moderatedContent() = %q, 期望替换话术", content)
+	}
+
+	// 新轮次重新审核
+	h.SpeakAndPlayWithVoice("新的回复", "", "", 1, 2)
+	if got := queuedTexts(h); len(got) != 1 || got[0] != "新的回复" {
+		t.Errorf("新轮次播报 %q, 期望 [新的回复]", got)
+	}
+}
+
+func TestSpeakRedactsOutput(t *testing.T) {
+	h := newModeratedHandler(t, "redact")
+
+	h.SpeakAndPlayWithVoice("不要说坏话", "", "", 1, 1)
+	h.SpeakAndPlayWithVoice("继续回复", "", "", 2, 1)
+	got := queuedTexts(h)
+	if len(got) != 2 || got[0] == "不要说坏话" || got[1] != "继续回复" {
+		t.Errorf("遮盖后播报 %q", got)
+	}
+	if content := h.roundOutputModeration(1).moderatedContent("不要说坏话，继续回复"); content == "不要说坏话，继续回复" {
+		t.Errorf("moderatedContent() 未遮盖命中内容: %q", content)
+	}
+}
+
+// 回复被替换后丢弃的句子仍加入空任务，最后一句播放完时发送stop，结束对话时关闭连接
+func TestReplacedReplyStopsSpeaking(t *testing.T) {
+	h := newModeratedHandler(t, "end")
+	conn := &fakeConn{}
+	h.conn = conn
+	h.providers.asr = &fakeASR{}
+	// 测试清理时关闭原有的stopChan，Close关闭单独的stopChan
+	h.stopChan = make(chan struct{})
+	h.talkRound = 1
+
+	for i, sentence := range []string{"这是一句坏话", "后面的内容"} {
+		h.tts_last_text_index = i + 1
+		h.SpeakAndPlayWithVoice(sentence, "", "", i+1, 1)
+	}
+	for {
+		select {
+		case task := <-h.ttsQueue:
+			h.sendAudioMessage("", task.text, task.emotion, task.textIndex, task.round, task.partial)
+			continue
+		default:
+		}
+		break
+	}
+
+	if got := conn.states("tts"); len(got) != 1 || got[0] != "stop" {
+		t.Errorf("回复被替换后发送的TTS消息 = %v, 期望 [stop]", got)
+	}
+	select {
+	case <-h.stopChan:
+	default:
+		t.Error("命中结束对话的回复播放完后应关闭连接")
+	}
+}
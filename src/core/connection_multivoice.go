@@ -0,0 +1,189 @@
+package core
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"angrymiao-ai-server/src/core/providers/tts"
+	"angrymiao-ai-server/src/core/utils"
+)
+
+// 默认多角色配音标注说明
+const defaultMultiVoicePrompt = `
+当回复中包含多个角色的对白（如讲故事、角色扮演）时，请在每段话开头用【说话人】标注说话人，叙述部分使用【旁白】，不同角色使用不同的说话人。
+例如：【旁白】从前有一只小猫。【说话人】你好呀！
+普通对话不需要标注。`
+
+// 可用说话人说明，只有列出的说话人标签会按说话人配音
+const multiVoiceSpeakersPrompt = "说话人只能从以下名称中选择：%s。"
+
+// 默认使用初始音色的说话人
+var defaultNarrators = []string{"旁白", "narrator"}
+
+// multiVoiceActive 当前角色是否启用多角色配音
+func (h *ConnectionHandler) multiVoiceActive() bool {
+	cfg := h.config.MultiVoice
+	if !cfg.Enabled {
+		return false
+	}
+	if len(cfg.Roles) == 0 {
+		return true
+	}
+	role := h.currentRole
+	if role == "" {
+		role = "default"
+	}
+	return utils.IsInArray(role, cfg.Roles)
+}
+
+// buildSystemPrompt 根据当前角色生成系统提示词，启用多角色配音和回复情绪时追加标注说明
+func (h *ConnectionHandler) buildSystemPrompt(prompt string) string {
+	if h.multiVoiceActive() {
+		if h.config.MultiVoice.Prompt != "" {
+			prompt += "\n" + h.config.MultiVoice.Prompt
+		} else {
+			prompt += "\n" + defaultMultiVoicePrompt
+		}
+		prompt += "\n" + fmt.Sprintf(multiVoiceSpeakersPrompt, strings.Join(h.speakerNames(), "、"))
+	}
+	if emotionPrompt := h.emotionPrompt(); emotionPrompt != "" {
+		prompt += "\n" + emotionPrompt
+	}
+	return prompt
+}
+
+// newSpeakerParser 启用多角色配音时返回说话人解析器，否则返回nil
+func (h *ConnectionHandler) newSpeakerParser() *utils.SpeakerParser {
+	if !h.multiVoiceActive() {
+		return nil
+	}
+	return utils.NewSpeakerParser(h.speakerNames())
+}
+
+// speakerNames 可识别的说话人名称：旁白、配置映射的说话人和当前TTS的音色名称
+func (h *ConnectionHandler) speakerNames() []string {
+	cfg := h.config.MultiVoice
+	names := append([]string{}, cfg.Narrator...)
+	if len(names) == 0 {
+		names = append(names, defaultNarrators...)
+	}
+	seen := make(map[string]bool)
+	for _, name := range names {
+		seen[name] = true
+	}
+	var others []string
+	add := func(name string) {
+		if name != "" && !seen[name] {
+			seen[name] = true
+			others = append(others, name)
+		}
+	}
+	for speaker := range cfg.Speakers {
+		add(speaker)
+	}
+	if getter, ok := h.providers.tts.(configGetter); ok {
+		for _, v := range getter.Config().SupportedVoices {
+			add(v.DisplayName)
+		}
+	}
+	sort.Strings(others)
+	return append(names, others...)
+}
+
+// splitSpeakers 按说话人拆分文本，未启用多角色配音时原样返回
+func (h *ConnectionHandler) splitSpeakers(parser *utils.SpeakerParser, text string) []utils.SpeakerSegment {
+	if parser == nil {
+		if strings.TrimSpace(text) == "" {
+			return nil
+		}
+		return []utils.SpeakerSegment{{Text: text}}
+	}
+	return parser.Parse(text)
+}
+
+// voiceForSpeaker 获取说话人对应的音色，返回空表示使用默认音色
+// 优先使用配置映射和同名音色，其余说话人按出现顺序分配尚未使用的音色
+func (h *ConnectionHandler) voiceForSpeaker(speaker string) string {
+	if speaker == "" {
+		return ""
+	}
+	narrators := h.config.MultiVoice.Narrator
+	if len(narrators) == 0 {
+		narrators = defaultNarrators
+	}
+	for _, n := range narrators {
+		if strings.EqualFold(n, speaker) {
+			return ""
+		}
+	}
+
+	getter, ok := h.providers.tts.(configGetter)
+	if !ok {
+		return ""
+	}
+	ttsCfg := getter.Config()
+
+	h.speakerVoicesMu.Lock()
+	defer h.speakerVoicesMu.Unlock()
+
+	if h.speakerVoices == nil {
+		h.speakerVoices = make(map[string]string)
+	}
+	if voice, ok := h.speakerVoices[speaker]; ok {
+		return voice
+	}
+
+	voice := h.config.MultiVoice.Speakers[speaker]
+	if voice == "" {
+		for _, v := range ttsCfg.SupportedVoices {
+			if v.Name == speaker || v.DisplayName == speaker {
+				voice = v.Name
+				break
+			}
+		}
+	}
+	if voice == "" {
+		used := map[string]bool{ttsCfg.Voice: true}
+		for _, v := range h.speakerVoices {
+			used[v] = true
+		}
+		for _, v := range ttsCfg.SupportedVoices {
+			if !used[v.Name] {
+				voice = v.Name
+				break
+			}
+		}
+	}
+	if voice == "" && len(ttsCfg.SupportedVoices) > 0 {
+		// 音色不够分配时循环复用
+		voice = ttsCfg.SupportedVoices[len(h.speakerVoices)%len(ttsCfg.SupportedVoices)].Name
+	}
+
+	h.speakerVoices[speaker] = voice
+	h.logger.Info("说话人 %s 使用音色: %s", speaker, voice)
+	return voice
+}
+
+// resetSpeakerVoices 清空说话人音色分配，切换角色时调用
+func (h *ConnectionHandler) resetSpeakerVoices() {
+	h.speakerVoicesMu.Lock()
+	h.speakerVoices = nil
+	h.speakerVoicesMu.Unlock()
+}
+
+// synthesize 合成语音，指定音色时不修改提供者共享的音色配置
+// TTS提供者不支持并发合成时依次合成，包括轮次取消后仍在后台完成的合成
+func (h *ConnectionHandler) synthesize(text string, voice string) (string, error) {
+	if !h.ttsConcurrent() {
+		h.ttsMu.Lock()
+		defer h.ttsMu.Unlock()
+	}
+	if voice != "" {
+		if vs, ok := h.providers.tts.(tts.VoiceSynthesizer); ok {
+			return vs.ToTTSWithVoice(text, voice)
+		}
+		h.logger.Debug("TTS提供者不支持指定音色合成，使用默认音色: %s", voice)
+	}
+	return h.providers.tts.ToTTS(text)
+}
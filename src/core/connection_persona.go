@@ -0,0 +1,221 @@
+package core
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"angrymiao-ai-server/src/core/mcp"
+	"angrymiao-ai-server/src/core/prompt"
+	"angrymiao-ai-server/src/models"
+
+	"github.com/sashabaranov/go-openai"
+)
+
+// 角色切换工具在工具注册表中的名称
+const changeRoleToolName = "local_change_role"
+
+// loadDefaultPersona 应用数据库中设置的默认角色，新连接建立时调用
+func (h *ConnectionHandler) loadDefaultPersona() {
+	if h.personaService == nil {
+		return
+	}
+	persona, err := h.personaService.GetDefaultPersona(h.connContext())
+	if err != nil {
+		h.logger.Error("加载默认角色失败: %v", err)
+		return
+	}
+	if persona == nil {
+		return
+	}
+	h.applyPersona(persona)
+	h.logger.Info("使用默认角色: %s", persona.Name)
+}
+
+// findPersona 按名称查找角色，数据库中没有时回退到配置的角色列表
+func (h *ConnectionHandler) findPersona(name, configPrompt string) *models.Persona {
+	if h.personaService != nil {
+		persona, err := h.personaService.GetActivePersona(h.connContext(), name)
+		if err != nil {
+			h.logger.Error("查询角色 %s 失败: %v", name, err)
+		} else if persona != nil {
+			return persona
+		}
+	}
+	if configPrompt == "" {
+		if text, ok := prompt.RolePrompt(h.config, name); ok {
+			configPrompt = text
+		}
+	}
+	if configPrompt == "" {
+		return nil
+	}
+	return &models.Persona{Name: name, Prompt: configPrompt, IsActive: true}
+}
+
+// applyPersona 切换到角色，应用提示词、当前TTS下的音色、工具范围和LLM
+func (h *ConnectionHandler) applyPersona(persona *models.Persona) {
+	h.persona = persona
+	h.currentRole = persona.Name
+	h.setPromptTemplate(persona.Prompt)
+
+	if getter, ok := h.providers.tts.(configGetter); ok {
+		if voice := persona.VoiceMap()[getter.Config().Name]; voice != "" {
+			if err := h.providers.tts.SetVoice(voice); err != nil {
+				h.logger.Error("设置角色音色失败: %v", err)
+			} else {
+				h.quickReplyCache.VoiceName = voice
+			}
+		}
+	}
+
+	h.applyPersonaLLM(persona.LLM)
+}
+
+// applyPersonaLLM 切换角色指定的LLM，为空或与连接原有LLM相同时恢复连接原有的LLM
+// 角色LLM从资源池借用，切换角色或连接关闭时归还
+func (h *ConnectionHandler) applyPersonaLLM(name string) {
+	h.releasePersonaLLM()
+	if name == "" {
+		return
+	}
+	if getter, ok := h.baseLLM.(llmConfigGetter); ok && getter.Config().Name == name {
+		return
+	}
+	if h.llmSource == nil {
+		h.logger.Error("角色LLM %s 不可用: 未设置LLM来源", name)
+		return
+	}
+
+	provider, release, err := h.llmSource.AcquireLLM(name)
+	if err != nil {
+		h.logger.Error("获取角色LLM %s 失败: %v", name, err)
+		return
+	}
+	provider.SetIdentityFlag("session", h.sessionID)
+
+	h.personaLLM, h.personaLLMRelease = provider, release
+	h.syncLLM()
+	h.logger.Info("角色 %s 使用LLM: %s", h.currentRole, name)
+}
+
+// releasePersonaLLM 恢复连接原有的LLM并归还角色LLM
+func (h *ConnectionHandler) releasePersonaLLM() {
+	if h.personaLLM == nil {
+		return
+	}
+	release := h.personaLLMRelease
+	h.personaLLM, h.personaLLMRelease = nil, nil
+	h.syncLLM()
+	release()
+}
+
+// syncLLM 更新当前使用的LLM，按本轮路由的LLM、角色LLM、连接原有LLM的顺序选择
+func (h *ConnectionHandler) syncLLM() {
+	if h.roundLLM != nil {
+		h.providers.llm = h.roundLLM
+		return
+	}
+	if h.personaLLM != nil {
+		h.providers.llm = h.personaLLM
+		return
+	}
+	h.providers.llm = h.baseLLM
+}
+
+// availableTools 返回当前角色可用的工具，角色未限制时返回全部工具
+// 角色切换工具始终可用，避免切换到受限角色后无法切换回来
+func (h *ConnectionHandler) availableTools() []openai.Tool {
+	tools := h.functionRegister.GetAllFunctions()
+	if h.persona == nil {
+		return tools
+	}
+	allowed := h.persona.ToolList()
+	if len(allowed) == 0 {
+		return tools
+	}
+
+	filtered := make([]openai.Tool, 0, len(tools))
+	for _, tool := range tools {
+		if tool.Function == nil {
+			continue
+		}
+		name := tool.Function.Name
+		if name == changeRoleToolName || toolAllowed(name, allowed) {
+			filtered = append(filtered, tool)
+		}
+	}
+	return filtered
+}
+
+// toolAllowed 判断工具是否在允许列表中，本地工具可省略 local_ 前缀
+func toolAllowed(name string, allowed []string) bool {
+	for _, item := range allowed {
+		if item == name || item == strings.TrimPrefix(name, "local_") {
+			return true
+		}
+	}
+	return false
+}
+
+// updateRoleTool 用数据库中启用的角色更新角色切换工具的描述
+func (h *ConnectionHandler) updateRoleTool() {
+	if h.personaService == nil {
+		return
+	}
+	tool, err := h.functionRegister.GetFunction(changeRoleToolName)
+	if err != nil || tool.Function == nil {
+		return
+	}
+
+	personas, err := h.personaService.ListActivePersonas(h.connContext())
+	if err != nil {
+		h.logger.Error("加载角色列表失败: %v", err)
+		return
+	}
+	names := make([]string, 0, len(personas)+len(h.config.Roles))
+	seen := map[string]bool{}
+	for _, persona := range personas {
+		names = append(names, persona.Name)
+		seen[persona.Name] = true
+	}
+	for _, role := range h.config.Roles {
+		if name, _, ok := strings.Cut(role, "@"); ok && !seen[name] {
+			names = append(names, name)
+		}
+	}
+	if len(names) == 0 {
+		h.functionRegister.UnregisterFunction(changeRoleToolName)
+		return
+	}
+	sort.Strings(names)
+
+	def := *tool.Function
+	def.Description = mcp.ChangeRoleDescription(names)
+	tool.Function = &def
+	h.functionRegister.UnregisterFunction(changeRoleToolName)
+	if err := h.functionRegister.RegisterFunction(changeRoleToolName, tool); err != nil {
+		h.logger.Error("更新角色切换工具失败: %v", err)
+	}
+}
+
+// switchPersona 处理角色切换请求
+func (h *ConnectionHandler) switchPersona(role, configPrompt string) {
+	persona := h.findPersona(role, configPrompt)
+	if persona == nil {
+		h.logger.Warn("角色不存在: %s", role)
+		h.SystemSpeak(fmt.Sprintf("没有找到名为%s的角色", role))
+		return
+	}
+
+	h.applyPersona(persona)
+	h.loadLexicon() // 加载角色专属发音词条
+	h.resetSpeakerVoices()
+	h.dialogueManager.KeepRecentMessages(5) // 保留最近5条消息
+
+	greeting := persona.Greeting
+	if greeting == "" {
+		greeting = "已切换到新角色 " + persona.Name
+	}
+	h.SystemSpeak(greeting)
+}
@@ -0,0 +1,231 @@
+package core
+
+import (
+	"context"
+	"fmt"
+	"reflect"
+	"sort"
+	"strings"
+	"testing"
+
+	"angrymiao-ai-server/src/core/function"
+	"angrymiao-ai-server/src/core/providers"
+	"angrymiao-ai-server/src/core/providers/llm"
+	"angrymiao-ai-server/src/core/types"
+	"angrymiao-ai-server/src/models"
+	"angrymiao-ai-server/src/services"
+
+	"github.com/sashabaranov/go-openai"
+)
+
+// fakeLLM 只提供配置名称的LLM
+type fakeLLM struct {
+	types.LLMProvider
+	name string
+}
+
+func (f *fakeLLM) Config() *llm.Config                        { return &llm.Config{Name: f.name} }
+func (f *fakeLLM) SetIdentityFlag(idType string, flag string) {}
+
+// fakeLLMSource 按名称创建LLM并记录借用中的实例
+type fakeLLMSource struct {
+	borrowed map[string]int
+}
+
+func (s *fakeLLMSource) AcquireLLM(name string) (providers.LLMProvider, func(), error) {
+	if name == "missing" {
+		return nil, nil, fmt.Errorf("LLM配置不存在: %s", name)
+	}
+	if s.borrowed == nil {
+		s.borrowed = make(map[string]int)
+	}
+	s.borrowed[name]++
+	released := false
+	return &fakeLLM{name: name}, func() {
+		if !released {
+			released = true
+			s.borrowed[name]--
+		}
+	}, nil
+}
+
+func (s *fakeLLMSource) outstanding() int {
+	n := 0
+	for _, count := range s.borrowed {
+		n += count
+	}
+	return n
+}
+
+// fakePersonaService 从内存列表查找启用的角色
+type fakePersonaService struct {
+	services.PersonaService
+	personas []*models.Persona
+}
+
+func (s *fakePersonaService) ListActivePersonas(ctx context.Context) ([]*models.Persona, error) {
+	return s.personas, nil
+}
+
+func (s *fakePersonaService) GetActivePersona(ctx context.Context, name string) (*models.Persona, error) {
+	for _, persona := range s.personas {
+		if persona.Name == name {
+			return persona, nil
+		}
+	}
+	return nil, nil
+}
+
+func currentLLMName(h *ConnectionHandler) string {
+	if cfg := h.llmConfig(); cfg != nil {
+		return cfg.Name
+	}
+	return ""
+}
+
+func TestToolAllowed(t *testing.T) {
+	tests := []struct {
+		name    string
+		allowed []string
+		want    bool
+	}{
+		{"get_time", []string{"get_time"}, true},
+		{"local_play_music", []string{"play_music"}, true},
+		{"local_play_music", []string{"local_play_music"}, true},
+		{"play_music", []string{"local_play_music"}, false},
+		{"search_web", []string{"get_time", "play_music"}, false},
+		{"get_time", nil, false},
+	}
+	for _, tt := range tests {
+		if got := toolAllowed(tt.name, tt.allowed); got != tt.want {
+			t.Errorf("toolAllowed(%q, %v) = %v, 期望 %v", tt.name, tt.allowed, got, tt.want)
+		}
+	}
+}
+
+func TestAvailableTools(t *testing.T) {
+	h := newTestHandler(t)
+	h.functionRegister = function.NewFunctionRegistry()
+	for _, name := range []string{"get_time", "local_play_music", "search_web", changeRoleToolName} {
+		h.functionRegister.RegisterFunction(name, openai.Tool{
+			Type:     openai.ToolTypeFunction,
+			Function: &openai.FunctionDefinition{Name: name},
+		})
+	}
+
+	tests := []struct {
+		desc    string
+		persona *models.Persona
+		want    []string
+	}{
+		{"未设置角色", nil, []string{"get_time", "local_change_role", "local_play_music", "search_web"}},
+		{"角色未限制工具", &models.Persona{Name: "小明"}, []string{"get_time", "local_change_role", "local_play_music", "search_web"}},
+		{"省略local_前缀", &models.Persona{Name: "小明", AllowedTools: `["play_music"]`}, []string{"local_change_role", "local_play_music"}},
+		{"角色切换工具始终可用", &models.Persona{Name: "小明", AllowedTools: `["get_time","search_web"]`}, []string{"get_time", "local_change_role", "search_web"}},
+	}
+	for _, tt := range tests {
+		h.persona = tt.persona
+		var got []string
+		for _, tool := range h.availableTools() {
+			got = append(got, tool.Function.Name)
+		}
+		sort.Strings(got)
+		if !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("%s: availableTools() = %v, 期望 %v", tt.desc, got, tt.want)
+		}
+	}
+}
+
+func TestFindPersona(t *testing.T) {
+	h := newTestHandler(t)
+	h.config.Roles = []string{"老师@你是一位耐心的老师", "小明@配置中的小明"}
+	h.personaService = &fakePersonaService{personas: []*models.Persona{{Name: "小明", Prompt: "数据库中的小明"}}}
+
+	tests := []struct {
+		name, configPrompt string
+		want               string // 期望的提示词，为空表示找不到角色
+	}{
+		{"小明", "", "数据库中的小明"},
+		{"老师", "", "你是一位耐心的老师"},
+		{"医生", "你是一位医生", "你是一位医生"},
+		{"医生", "", ""},
+	}
+	for _, tt := range tests {
+		persona := h.findPersona(tt.name, tt.configPrompt)
+		got := ""
+		if persona != nil {
+			got = persona.Prompt
+		}
+		if got != tt.want {
+			t.Errorf("findPersona(%q, %q) 提示词 = %q, 期望 %q", tt.name, tt.configPrompt, got, tt.want)
+		}
+	}
+}
+
+func TestUpdateRoleTool(t *testing.T) {
+	h := newTestHandler(t)
+	h.functionRegister = function.NewFunctionRegistry()
+	h.functionRegister.RegisterFunction(changeRoleToolName, openai.Tool{
+		Type:     openai.ToolTypeFunction,
+		Function: &openai.FunctionDefinition{Name: changeRoleToolName, Description: "旧描述"},
+	})
+	h.config.Roles = []string{"老师@你是一位耐心的老师", "小明@配置中的小明"}
+	h.personaService = &fakePersonaService{personas: []*models.Persona{{Name: "小明"}, {Name: "医生"}}}
+
+	h.updateRoleTool()
+	tool, err := h.functionRegister.GetFunction(changeRoleToolName)
+	if err != nil {
+		t.Fatalf("updateRoleTool() 后角色切换工具不存在: %v", err)
+	}
+	for _, name := range []string{"小明", "医生", "老师"} {
+		if !strings.Contains(tool.Function.Description, name) {
+			t.Errorf("角色切换工具描述缺少角色 %s: %s", name, tool.Function.Description)
+		}
+	}
+	if strings.Count(tool.Function.Description, "小明") != 1 {
+		t.Errorf("角色切换工具描述中的角色重复: %s", tool.Function.Description)
+	}
+
+	h.config.Roles = nil
+	h.personaService = &fakePersonaService{}
+	h.updateRoleTool()
+	if h.functionRegister.FunctionExists(changeRoleToolName) {
+		t.Error("没有可选角色时应移除角色切换工具")
+	}
+}
+
+func TestApplyPersonaLLM(t *testing.T) {
+	h := newTestHandler(t)
+	source := &fakeLLMSource{}
+	h.llmSource = source
+	h.baseLLM = &fakeLLM{name: "base"}
+	h.providers.llm = h.baseLLM
+
+	h.applyPersonaLLM("fast")
+	if got := currentLLMName(h); got != "fast" || source.borrowed["fast"] != 1 {
+		t.Fatalf("applyPersonaLLM(fast) 当前LLM = %s, 借用 %v", got, source.borrowed)
+	}
+
+	// 切换角色时归还上一个角色的LLM
+	h.applyPersonaLLM("smart")
+	if got := currentLLMName(h); got != "smart" || source.borrowed["fast"] != 0 || source.borrowed["smart"] != 1 {
+		t.Fatalf("applyPersonaLLM(smart) 当前LLM = %s, 借用 %v", got, source.borrowed)
+	}
+
+	// 与连接原有LLM同名时直接使用原有LLM
+	h.applyPersonaLLM("base")
+	if h.providers.llm != h.baseLLM || source.outstanding() != 0 {
+		t.Fatalf("applyPersonaLLM(base) 应恢复原有LLM, 借用 %v", source.borrowed)
+	}
+
+	h.applyPersonaLLM("missing")
+	if h.providers.llm != h.baseLLM {
+		t.Error("角色LLM获取失败时应使用原有LLM")
+	}
+
+	h.applyPersonaLLM("fast")
+	h.releasePersonaLLM()
+	if h.providers.llm != h.baseLLM || source.outstanding() != 0 {
+		t.Errorf("releasePersonaLLM() 应恢复原有LLM并归还, 借用 %v", source.borrowed)
+	}
+}
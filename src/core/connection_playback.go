@@ -0,0 +1,125 @@
+package core
+
+import (
+	"fmt"
+	"strings"
+	"sync"
+
+	"angrymiao-ai-server/src/core/chat"
+)
+
+// playbackTracker 记录当前轮次每句回复的播放进度，打断时据此确定用户实际听到的内容
+type playbackTracker struct {
+	mu        sync.Mutex
+	round     int
+	sentences map[int]*playedSentence // textIndex -> 播放进度
+}
+
+// playedSentence 一句回复的播放进度，超长句子会拆分为多个片段依次播放
+type playedSentence struct {
+	text       []rune
+	played     int // 已播放的字符数
+	chunkStart int // 正在播放的片段在句子中的起始位置
+}
+
+// queue 记录加入TTS队列的文本片段，新轮次开始时清空之前的记录
+func (t *playbackTracker) queue(round, textIndex int, text string) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if t.round != round || t.sentences == nil {
+		t.round = round
+		t.sentences = make(map[int]*playedSentence)
+	}
+	s, ok := t.sentences[textIndex]
+	if !ok {
+		s = &playedSentence{}
+		t.sentences[textIndex] = s
+	}
+	s.text = append(s.text, []rune(text)...)
+}
+
+// advance 更新片段的播放进度，按已发送帧数占比估算已播放的字符数
+func (t *playbackTracker) advance(round, textIndex int, text string, sent, total int) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if t.round != round || total <= 0 {
+		return
+	}
+	s, ok := t.sentences[textIndex]
+	if !ok {
+		return
+	}
+	chunkRunes := len([]rune(text))
+	s.played = s.chunkStart + chunkRunes*sent/total
+	if sent >= total {
+		s.chunkStart += chunkRunes
+	}
+}
+
+// spoken 返回 [from, to] 范围内用户实际听到的文本，以及是否已全部播放完
+func (t *playbackTracker) spoken(round, from, to int) (string, bool) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if t.round != round {
+		return "", false
+	}
+	var b strings.Builder
+	for i := from; i <= to; i++ {
+		s, ok := t.sentences[i]
+		if !ok {
+			continue
+		}
+		if s.played < len(s.text) {
+			// 之后的句子都没有播放
+			b.WriteString(string(s.text[:s.played]))
+			return b.String(), false
+		}
+		b.WriteString(string(s.text))
+	}
+	return b.String(), true
+}
+
+// replyRange 本轮写入对话历史的助手回复对应的文本索引范围
+type replyRange struct {
+	round    int
+	from, to int
+}
+
+// setLastReply 记录本轮助手回复的文本索引范围，打断时据此截断对话历史
+func (h *ConnectionHandler) setLastReply(round, from, to int) {
+	h.playbackMu.Lock()
+	defer h.playbackMu.Unlock()
+	h.lastReply = replyRange{round: round, from: from, to: to}
+}
+
+// spokenReply 获取本轮回复中用户实际听到的内容，未播放完时返回false
+func (h *ConnectionHandler) spokenReply(round, from, to int) (string, bool) {
+	if from <= 0 || to < from {
+		return "", true
+	}
+	return h.playback.spoken(round, from, to)
+}
+
+// truncateInterruptedReply 回复被打断时，将对话历史中的助手回复截断为用户实际听到的内容
+// 在新轮次开始前调用，此时最后一条消息仍是被打断轮次的回复
+// 可能与对话轮次、后台摘要并发执行，对话历史由对话管理器加锁修改，historyMu 保证与对话记录的保存顺序一致
+func (h *ConnectionHandler) truncateInterruptedReply() {
+	h.playbackMu.Lock()
+	r := h.lastReply
+	h.lastReply = replyRange{}
+	h.playbackMu.Unlock()
+
+	if r.round == 0 || r.round != h.talkRound {
+		return
+	}
+	spoken, complete := h.spokenReply(r.round, r.from, r.to)
+	if complete {
+		return
+	}
+	h.historyMu.Lock()
+	defer h.historyMu.Unlock()
+	if h.dialogueManager.TruncateLastReply(spoken) {
+		h.LogInfo(fmt.Sprintf("回复被打断，对话历史只保留已播放的内容: %s, round: %d", spoken, r.round))
+		h.updateRecordedReply(r.round, chat.InterruptedReply(spoken))
+	}
+}
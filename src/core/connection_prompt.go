@@ -0,0 +1,70 @@
+package core
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"time"
+
+	"angrymiao-ai-server/src/core/prompt"
+)
+
+// setPromptTemplate 设置系统提示词模板（默认提示词或角色提示词）并立即渲染
+func (h *ConnectionHandler) setPromptTemplate(text string) {
+	tmpl, err := prompt.Parse(text)
+	if err != nil {
+		h.LogError(fmt.Sprintf("提示词模板无效，按原文使用: %v", err))
+		tmpl = prompt.Literal(text)
+	}
+	h.promptTemplate = tmpl
+	h.refreshSystemPrompt()
+}
+
+// loadPromptProfile 加载用户的昵称和自定义字段
+func (h *ConnectionHandler) loadPromptProfile() {
+	if h.userID == "" || h.promptService == nil {
+		return
+	}
+	profile, err := h.promptService.GetProfile(context.Background(), h.userID)
+	if err != nil {
+		h.logger.Error("加载用户提示词变量失败: %v", err)
+		return
+	}
+	h.promptProfile = profile
+}
+
+// promptVariables 收集渲染提示词模板的运行时变量
+func (h *ConnectionHandler) promptVariables() prompt.Variables {
+	vars := prompt.Variables{
+		DeviceID: h.deviceID,
+		Board:    h.board,
+		Role:     h.currentRole,
+	}
+	vars.SetTime(time.Now(), h.config.PromptTemplate.Timezone)
+	vars.SetProfile(h.promptProfile)
+	if getter, ok := h.providers.tts.(configGetter); ok {
+		vars.Voice = getter.Config().Voice
+	}
+	if h.functionRegister != nil {
+		for _, tool := range h.availableTools() {
+			if tool.Function != nil {
+				vars.Tools = append(vars.Tools, tool.Function.Name)
+			}
+		}
+		sort.Strings(vars.Tools)
+	}
+	return vars
+}
+
+// refreshSystemPrompt 用当前的运行时变量渲染系统提示词，每轮对话开始时调用
+func (h *ConnectionHandler) refreshSystemPrompt() {
+	if h.promptTemplate == nil {
+		return
+	}
+	text, err := h.promptTemplate.Render(h.promptVariables())
+	if err != nil {
+		h.LogError(fmt.Sprintf("渲染系统提示词失败: %v", err))
+		text = h.promptTemplate.Text()
+	}
+	h.dialogueManager.SetSystemMessage(h.buildSystemPrompt(text))
+}
@@ -0,0 +1,86 @@
+package core
+
+import (
+	"context"
+	"fmt"
+)
+
+// beginRound 为新的对话轮次创建可取消的上下文，并取消上一轮仍在进行的LLM、工具调用和TTS合成
+func (h *ConnectionHandler) beginRound(parent context.Context) context.Context {
+	if parent == nil {
+		parent = h.connContext()
+	}
+	ctx, cancel := context.WithCancel(parent)
+
+	h.roundMu.Lock()
+	if h.roundCancel != nil {
+		h.roundCancel()
+	}
+	h.roundCtx, h.roundCancel = ctx, cancel
+	h.roundMu.Unlock()
+	return ctx
+}
+
+// cancelRound 取消当前轮次，在客户端中止或服务端打断时调用
+func (h *ConnectionHandler) cancelRound() {
+	h.roundMu.Lock()
+	defer h.roundMu.Unlock()
+	if h.roundCancel != nil {
+		h.LogInfo("取消当前对话轮次")
+		h.roundCancel()
+	}
+	// 轮次之外的播报（如系统提示）使用连接上下文
+	h.roundCtx, h.roundCancel = nil, nil
+}
+
+// roundContext 获取当前轮次的上下文，不在对话轮次中时返回连接上下文
+func (h *ConnectionHandler) roundContext() context.Context {
+	h.roundMu.Lock()
+	defer h.roundMu.Unlock()
+	if h.roundCtx != nil {
+		return h.roundCtx
+	}
+	return h.connContext()
+}
+
+// connContext 获取连接的上下文，连接关闭时取消
+func (h *ConnectionHandler) connContext() context.Context {
+	if h.ctx != nil {
+		return h.ctx
+	}
+	return context.Background()
+}
+
+// synthesizeInRound 在轮次上下文中合成语音，轮次取消时立即返回，不等待正在进行的合成
+// TTS提供者不支持取消，被放弃的合成完成后在后台删除音频文件
+func (h *ConnectionHandler) synthesizeInRound(ctx context.Context, text string, voice string) (string, error) {
+	if ctx == nil {
+		return h.synthesize(text, voice)
+	}
+	if err := ctx.Err(); err != nil {
+		return "", err
+	}
+
+	type synthesisResult struct {
+		path string
+		err  error
+	}
+	done := make(chan synthesisResult, 1)
+	go func() {
+		path, err := h.synthesize(text, voice)
+		done <- synthesisResult{path, err}
+	}()
+
+	select {
+	case r := <-done:
+		return r.path, r.err
+	case <-ctx.Done():
+		go func() {
+			if r := <-done; r.err == nil {
+				h.recordTTSUsage(text)
+				h.deleteAudioFileIfNeeded(r.path, "轮次取消后")
+			}
+		}()
+		return "", fmt.Errorf("轮次已取消: %w", ctx.Err())
+	}
+}
@@ -0,0 +1,159 @@
+package core
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// fakeTTS 合成时等待放行，并统计合成次数
+type fakeTTS struct {
+	dir     string
+	calls   int32
+	release chan struct{}
+}
+
+func (f *fakeTTS) Initialize() error           { return nil }
+func (f *fakeTTS) Cleanup() error              { return nil }
+func (f *fakeTTS) SetVoice(voice string) error { return nil }
+
+func (f *fakeTTS) ToTTS(text string) (string, error) {
+	atomic.AddInt32(&f.calls, 1)
+	if f.release != nil {
+		<-f.release
+	}
+	path := filepath.Join(f.dir, "tts.mp3")
+	return path, os.WriteFile(path, []byte("audio"), 0o644)
+}
+
+func TestBeginRoundCancelsPrevious(t *testing.T) {
+	h := newTestHandler(t)
+	first := h.beginRound(nil)
+	second := h.beginRound(nil)
+	if first.Err() == nil {
+		t.Error("beginRound() 应取消上一轮的上下文")
+	}
+	if second.Err() != nil || h.roundContext() != second {
+		t.Error("roundContext() 应返回当前轮次的上下文")
+	}
+
+	h.cancelRound()
+	if second.Err() == nil {
+		t.Error("cancelRound() 应取消当前轮次")
+	}
+	if h.roundContext() != h.connContext() {
+		t.Error("cancelRound() 后 roundContext() 应返回连接上下文")
+	}
+}
+
+func TestProcessTTSTaskSkipsStaleRound(t *testing.T) {
+	h := newTestHandler(t)
+	fake := &fakeTTS{dir: t.TempDir()}
+	h.providers.tts = fake
+	h.talkRound = 2
+
+	if result := h.processTTSTask(ttsTask{text: "旧轮次", round: 1, textIndex: 1}); result.filepath != "" {
+		t.Errorf("过期轮次的任务不应合成: %+v", result)
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if result := h.processTTSTask(ttsTask{text: "已取消", round: 2, textIndex: 1, ctx: ctx}); result.filepath != "" {
+		t.Errorf("已取消轮次的任务不应合成: %+v", result)
+	}
+	if fake.calls != 0 {
+		t.Errorf("合成 %d 次, 期望 0", fake.calls)
+	}
+
+	if result := h.processTTSTask(ttsTask{text: "当前轮次", round: 2, textIndex: 1, ctx: context.Background()}); result.filepath == "" {
+		t.Error("当前轮次的任务应合成")
+	}
+}
+
+func TestProcessTTSTaskCancelDuringSynthesis(t *testing.T) {
+	h := newTestHandler(t)
+	h.config.DeleteAudio = true
+	fake := &fakeTTS{dir: t.TempDir(), release: make(chan struct{})}
+	h.providers.tts = fake
+	h.talkRound = 1
+	ctx := h.beginRound(nil)
+
+	done := make(chan audioTask, 1)
+	go func() {
+		done <- h.processTTSTask(ttsTask{text: "很长的一句话", round: 1, textIndex: 1, ctx: ctx})
+	}()
+	for atomic.LoadInt32(&fake.calls) == 0 {
+		time.Sleep(time.Millisecond)
+	}
+
+	// 打断后不等待正在进行的合成
+	h.cancelRound()
+	select {
+	case result := <-done:
+		if result.filepath != "" {
+			t.Errorf("取消后不应发送音频: %+v", result)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("取消轮次后 processTTSTask 仍在等待合成")
+	}
+
+	// 被放弃的合成完成后删除音频文件
+	close(fake.release)
+	path := filepath.Join(fake.dir, "tts.mp3")
+	deadline := time.Now().Add(time.Second)
+	for {
+		if _, err := os.Stat(path); os.IsNotExist(err) {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatal("被放弃的合成音频未删除")
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+}
+
+// 对话轮次进行中收到中止消息时立即取消本轮，不等待本轮结束
+func TestAbortDuringChatRound(t *testing.T) {
+	h := newTestHandler(t)
+	conn := &fakeConn{}
+	h.conn = conn
+	h.providers.asr = &fakeASR{}
+	h.clientTextQueue = make(chan string)
+
+	// 模拟文本消息协程正在处理流式回复的对话轮次，轮次取消前不再读取文本消息
+	streaming := make(chan context.Context)
+	go func() {
+		ctx := h.beginRound(nil)
+		streaming <- ctx
+		<-ctx.Done()
+		for {
+			select {
+			case <-h.clientTextQueue:
+			case <-h.stopChan:
+				return
+			}
+		}
+	}()
+	round := <-streaming
+
+	done := make(chan error, 1)
+	go func() {
+		done <- h.handleMessage(1, []byte(`{"type":"abort"}`))
+	}()
+	select {
+	case err := <-done:
+		if err != nil {
+			t.Fatalf("handleMessage(abort) 错误: %v", err)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("中止消息等待当前对话轮次结束")
+	}
+	if round.Err() == nil {
+		t.Error("中止消息应取消当前对话轮次")
+	}
+	if got := conn.states("tts"); len(got) != 1 || got[0] != "stop" {
+		t.Errorf("中止后发送的TTS消息 = %v, 期望 [stop]", got)
+	}
+}
@@ -0,0 +1,158 @@
+package core
+
+import (
+	"context"
+	"fmt"
+
+	"angrymiao-ai-server/src/core/prompt"
+	"angrymiao-ai-server/src/core/providers"
+	"angrymiao-ai-server/src/core/router"
+	"angrymiao-ai-server/src/models"
+)
+
+// LLMSource 按配置名称借用LLM，用完后调用返回的函数归还
+type LLMSource interface {
+	AcquireLLM(name string) (providers.LLMProvider, func(), error)
+}
+
+// SetLLMSource 设置LLM路由使用的LLM来源
+func (h *ConnectionHandler) SetLLMSource(source LLMSource) {
+	h.llmSource = source
+}
+
+// initLLMRouter 创建LLM路由器
+func (h *ConnectionHandler) initLLMRouter() {
+	if !h.config.LLMRouter.Enabled {
+		return
+	}
+	r, err := router.NewRouter(h.config.LLMRouter)
+	if err != nil {
+		h.logger.Error("创建LLM路由器失败: %v", err)
+		return
+	}
+	h.llmRouter = r
+}
+
+// routeRound 为本轮对话选择路由，临时切换到路由指定的LLM和角色
+// 对话历史保持共享，返回的函数在本轮回复生成后恢复原有的LLM和角色
+func (h *ConnectionHandler) routeRound(ctx context.Context, text string) func() {
+	if h.llmRouter == nil {
+		return func() {}
+	}
+
+	classifier, releaseClassifier := h.routeClassifier()
+	decision := h.llmRouter.Route(ctx, text, classifier)
+	releaseClassifier()
+	if decision.Route == "" {
+		return func() {}
+	}
+
+	var restores []func()
+	var persona *models.Persona
+	if decision.Persona != "" && (h.persona == nil || decision.Persona != h.persona.Name) {
+		persona = h.findPersona(decision.Persona, "")
+		if persona == nil {
+			h.logger.Error("路由 %s 指定的角色不存在: %s", decision.Route, decision.Persona)
+		} else {
+			restores = append(restores, h.usePersonaForRound(persona))
+		}
+	}
+
+	// 路由未指定LLM时使用路由角色指定的LLM
+	llmName := decision.LLM
+	if llmName == "" && persona != nil {
+		llmName = persona.LLM
+	}
+	if cfg := h.llmConfig(); llmName != "" && (cfg == nil || cfg.Name != llmName) {
+		if restore, err := h.useLLMForRound(llmName); err != nil {
+			h.logger.Error("路由 %s 切换LLM失败，使用当前LLM: %v", decision.Route, err)
+		} else {
+			restores = append(restores, restore)
+		}
+	}
+
+	if cfg := h.llmConfig(); cfg != nil {
+		decision.LLM = cfg.Name
+	}
+	if h.persona != nil {
+		decision.Persona = h.persona.Name
+	}
+	h.roundRoute = decision
+	h.LogInfo(fmt.Sprintf("本轮路由: %s, 来源: %s, LLM: %s, 角色: %s",
+		decision.Route, decision.Source, decision.LLM, decision.Persona))
+
+	return func() {
+		for i := len(restores) - 1; i >= 0; i-- {
+			restores[i]()
+		}
+	}
+}
+
+// routeClassifier 获取分类LLM，未配置时返回nil
+func (h *ConnectionHandler) routeClassifier() (providers.LLMProvider, func()) {
+	name := h.config.LLMRouter.Classifier
+	if name == "" {
+		return nil, func() {}
+	}
+	provider, release, err := h.acquireLLM(name)
+	if err != nil {
+		h.logger.Error("获取路由分类LLM失败: %v", err)
+		return nil, func() {}
+	}
+	return provider, release
+}
+
+// acquireLLM 借用指定名称的LLM，名称为空或与当前LLM同名时直接使用当前LLM
+func (h *ConnectionHandler) acquireLLM(name string) (providers.LLMProvider, func(), error) {
+	if cfg := h.llmConfig(); name == "" || (cfg != nil && cfg.Name == name) {
+		return h.providers.llm, func() {}, nil
+	}
+	if h.llmSource == nil {
+		return nil, nil, fmt.Errorf("未设置LLM来源")
+	}
+	return h.llmSource.AcquireLLM(name)
+}
+
+// useLLMForRound 本轮使用指定的LLM，返回恢复并归还的函数
+// 本轮LLM单独保存，本轮中切换角色LLM不受影响，恢复后使用当时的角色LLM或连接原有的LLM
+func (h *ConnectionHandler) useLLMForRound(name string) (func(), error) {
+	if h.llmSource == nil {
+		return nil, fmt.Errorf("未设置LLM来源")
+	}
+	provider, release, err := h.llmSource.AcquireLLM(name)
+	if err != nil {
+		return nil, err
+	}
+	provider.SetIdentityFlag("session", h.sessionID)
+
+	h.roundLLM = provider
+	h.syncLLM()
+	return func() {
+		if h.roundLLM == provider {
+			h.roundLLM = nil
+			h.syncLLM()
+		}
+		release()
+	}, nil
+}
+
+// usePersonaForRound 本轮使用指定角色的提示词和工具范围，音色保持不变，返回恢复的函数
+// 本轮中通过角色切换工具切换了角色时不再恢复，保留新角色
+func (h *ConnectionHandler) usePersonaForRound(persona *models.Persona) func() {
+	previous, previousTemplate := h.persona, h.promptTemplate
+	h.persona = persona
+	h.setPromptTemplate(persona.Prompt)
+	return func() {
+		if h.persona != persona {
+			return
+		}
+		h.persona = previous
+		h.restorePromptTemplate(previousTemplate)
+	}
+}
+
+// restorePromptTemplate 恢复提示词模板并重新渲染系统提示词
+func (h *ConnectionHandler) restorePromptTemplate(tmpl *prompt.Template) {
+	h.promptTemplate = tmpl
+	h.refreshSystemPrompt()
+}
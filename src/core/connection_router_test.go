@@ -0,0 +1,70 @@
+package core
+
+import (
+	"context"
+	"testing"
+
+	"angrymiao-ai-server/src/configs"
+	"angrymiao-ai-server/src/core/chat"
+	"angrymiao-ai-server/src/models"
+)
+
+func newRoutedHandler(t *testing.T) (*ConnectionHandler, *fakeLLMSource) {
+	t.Helper()
+	h := newTestHandler(t)
+	h.dialogueManager = chat.NewDialogueManager(h.logger, nil)
+	source := &fakeLLMSource{}
+	h.llmSource = source
+	h.baseLLM = &fakeLLM{name: "base"}
+	h.providers.llm = h.baseLLM
+	h.personaService = &fakePersonaService{personas: []*models.Persona{
+		{Name: "老师", Prompt: "你是一位老师"},
+		{Name: "医生", Prompt: "你是一位医生", LLM: "fast"},
+	}}
+	h.config.LLMRouter = configs.LLMRouterConfig{
+		Enabled: true,
+		Routes:  []configs.LLMRouteConfig{{Name: "homework", LLM: "smart", Persona: "老师", Keywords: []string{"作业"}}},
+	}
+	h.initLLMRouter()
+	return h, source
+}
+
+func TestRouteRoundRestores(t *testing.T) {
+	h, source := newRoutedHandler(t)
+
+	restore := h.routeRound(context.Background(), "帮我检查作业")
+	if got := currentLLMName(h); got != "smart" || h.persona == nil || h.persona.Name != "老师" {
+		t.Fatalf("routeRound() LLM = %s, 角色 = %v", got, h.persona)
+	}
+	restore()
+	if h.providers.llm != h.baseLLM || h.persona != nil || source.outstanding() != 0 {
+		t.Errorf("恢复后 LLM = %s, 角色 = %v, 借用 %v", currentLLMName(h), h.persona, source.borrowed)
+	}
+}
+
+func TestRouteRoundKeepsSwitchedPersona(t *testing.T) {
+	h, source := newRoutedHandler(t)
+
+	restore := h.routeRound(context.Background(), "帮我检查作业")
+	// 本轮中通过角色切换工具切换到指定了LLM的角色
+	h.switchPersona("医生", "")
+	if got := currentLLMName(h); got != "smart" {
+		t.Errorf("本轮结束前应继续使用路由的LLM, 当前 %s", got)
+	}
+	restore()
+
+	if h.persona == nil || h.persona.Name != "医生" || h.currentRole != "医生" {
+		t.Errorf("恢复后角色 = %v, 期望保留切换后的角色", h.persona)
+	}
+	if got := currentLLMName(h); got != "fast" {
+		t.Errorf("恢复后 LLM = %s, 期望角色LLM fast", got)
+	}
+	if source.borrowed["smart"] != 0 || source.borrowed["fast"] != 1 {
+		t.Errorf("恢复后借用 %v, 期望只借用角色LLM", source.borrowed)
+	}
+
+	h.releasePersonaLLM()
+	if h.providers.llm != h.baseLLM || source.outstanding() != 0 {
+		t.Errorf("releasePersonaLLM() 后 LLM = %s, 借用 %v", currentLLMName(h), source.borrowed)
+	}
+}
@@ -91,7 +91,42 @@ func (h *ConnectionHandler) sendEmotionMessage(emotion string) error {
 	return h.conn.WriteMessage(1, jsonData)
 }
 
-func (h *ConnectionHandler) sendAudioMessage(filepath string, text string, textIndex int, round int) {
+// sendReasoningMessage 发送思考过程增量，仅发给在hello中声明支持的客户端
+func (h *ConnectionHandler) sendReasoningMessage(text string) error {
+	if !h.clientReasoning {
+		return nil
+	}
+	data := map[string]interface{}{
+		"type":       "llm",
+		"state":      "reasoning",
+		"text":       text,
+		"session_id": h.sessionID,
+	}
+	jsonData, err := json.Marshal(data)
+	if err != nil {
+		return fmt.Errorf("序列化思考消息失败: %v", err)
+	}
+	return h.conn.WriteMessage(1, jsonData)
+}
+
+// sendStateMessage 发送听写、会议记录等模式的状态消息，data 中的字段合并到消息中
+func (h *ConnectionHandler) sendStateMessage(msgType, state string, data map[string]interface{}) error {
+	msg := map[string]interface{}{
+		"type":       msgType,
+		"state":      state,
+		"session_id": h.sessionID,
+	}
+	for key, value := range data {
+		msg[key] = value
+	}
+	jsonData, err := json.Marshal(msg)
+	if err != nil {
+		return fmt.Errorf("序列化%s消息失败: %v", msgType, err)
+	}
+	return h.conn.WriteMessage(1, jsonData)
+}
+
+func (h *ConnectionHandler) sendAudioMessage(filepath string, text string, emotion string, textIndex int, round int, partial bool) {
 	bFinishSuccess := false
 	defer func() {
 		// 音频发送完成后，根据配置决定是否删除文件
@@ -99,7 +134,7 @@ func (h *ConnectionHandler) sendAudioMessage(filepath string, text string, textI
 
 		h.LogInfo(fmt.Sprintf("TTS音频发送任务结束(%t): %s, 索引: %d/%d", bFinishSuccess, text, textIndex, h.tts_last_text_index))
 		h.providers.asr.ResetStartListenTime()
-		if textIndex == h.tts_last_text_index {
+		if textIndex == h.tts_last_text_index && !partial {
 			h.sendTTSMessage("stop", "", textIndex)
 			if h.closeAfterChat {
 				h.Close()
@@ -149,6 +184,13 @@ func (h *ConnectionHandler) sendAudioMessage(filepath string, text string, textI
 		}
 	}
 
+	// 句子的情绪与开始播放通知一起发送
+	if emotion != "" {
+		if err := h.sendEmotionMessage(emotion); err != nil {
+			h.LogError(fmt.Sprintf("发送情绪消息失败: %v", err))
+		}
+	}
+
 	// 发送TTS状态开始通知
 	if err := h.sendTTSMessage("sentence_start", text, textIndex); err != nil {
 		h.LogError(fmt.Sprintf("发送TTS开始状态失败: %v", err))
@@ -165,7 +207,7 @@ func (h *ConnectionHandler) sendAudioMessage(filepath string, text string, textI
 	h.logger.Debug("TTS发送(%s): \"%s\" (索引:%d/%d，时长:%f，帧数:%d)", h.serverAudioFormat, text, textIndex, h.tts_last_text_index, duration, len(audioData))
 
 	// 分时发送音频数据
-	if err := h.sendAudioFrames(audioData, text, round); err != nil {
+	if err := h.sendAudioFrames(audioData, text, textIndex, round); err != nil {
 		h.LogError(fmt.Sprintf("分时发送音频数据失败: %v", err))
 		return
 	}
@@ -180,7 +222,8 @@ func (h *ConnectionHandler) sendAudioMessage(filepath string, text string, textI
 }
 
 // sendAudioFrames 分时发送音频帧，避免撑爆客户端缓冲区
-func (h *ConnectionHandler) sendAudioFrames(audioData [][]byte, text string, round int) error {
+// 每发送一帧更新播放进度，用于打断时确定已播放的文本
+func (h *ConnectionHandler) sendAudioFrames(audioData [][]byte, text string, textIndex int, round int) error {
 	if len(audioData) == 0 {
 		return nil
 	}
@@ -206,6 +249,7 @@ func (h *ConnectionHandler) sendAudioFrames(audioData [][]byte, text string, rou
 		if err := h.conn.WriteMessage(2, audioData[i]); err != nil {
 			return fmt.Errorf("发送预缓冲音频帧失败: %v", err)
 		}
+		h.playback.advance(round, textIndex, text, i+1, len(audioData))
 		playPosition += h.serverAudioFrameDuration
 	}
 
@@ -255,6 +299,7 @@ func (h *ConnectionHandler) sendAudioFrames(audioData [][]byte, text string, rou
 		if err := h.conn.WriteMessage(2, chunk); err != nil {
 			return fmt.Errorf("发送音频帧失败: %v", err)
 		}
+		h.playback.advance(round, textIndex, text, preBufferFrames+i+1, len(audioData))
 
 		playPosition += h.serverAudioFrameDuration
 	}
@@ -0,0 +1,71 @@
+package core
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+
+	"angrymiao-ai-server/src/configs"
+	"angrymiao-ai-server/src/core/chat"
+	"angrymiao-ai-server/src/core/memory"
+	"angrymiao-ai-server/src/core/providers"
+	"angrymiao-ai-server/src/core/utils"
+)
+
+// newTestHandler 创建只包含队列、配置和日志的连接处理器，供不依赖提供者的单元测试使用
+func newTestHandler(t *testing.T) *ConnectionHandler {
+	t.Helper()
+	logger, err := utils.NewLogger(&utils.LogCfg{LogLevel: "ERROR", LogDir: t.TempDir(), LogFile: "test.log"})
+	if err != nil {
+		t.Fatalf("NewLogger() 错误: %v", err)
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	stopChan := make(chan struct{})
+	t.Cleanup(func() {
+		cancel()
+		close(stopChan)
+		logger.Close()
+	})
+	return &ConnectionHandler{
+		config:             &configs.Config{},
+		logger:             logger,
+		ctx:                ctx,
+		stopChan:           stopChan,
+		ttsQueue:           make(chan ttsTask, 100),
+		audioMessagesQueue: make(chan audioTask, 100),
+	}
+}
+
+// namedLLMSource 只能借用已配置的LLM，记录借用的名称
+type namedLLMSource struct {
+	names    map[string]bool
+	acquired chan string
+}
+
+func (s *namedLLMSource) AcquireLLM(name string) (providers.LLMProvider, func(), error) {
+	s.acquired <- name
+	if !s.names[name] {
+		return nil, nil, fmt.Errorf("找不到LLM配置 %s", name)
+	}
+	return &fakeLLM{name: name}, func() {}, nil
+}
+
+func TestSaveMemoryUsesPrimaryLLM(t *testing.T) {
+	h := newTestHandler(t)
+	h.config.SelectedModule = map[string]string{"LLM": "QwenLLM, ChatGLMLLM"}
+	source := &namedLLMSource{names: map[string]bool{"QwenLLM": true, "ChatGLMLLM": true}, acquired: make(chan string, 1)}
+	h.llmSource = source
+	h.dialogueManager = chat.NewDialogueManager(h.logger, nil)
+	h.memoryStore = memory.NewStore("user", nil, nil, configs.MemoryConfig{}, h.logger)
+
+	h.saveMemoryInBackground()
+	select {
+	case name := <-source.acquired:
+		if name != "QwenLLM" {
+			t.Errorf("保存长期记忆借用LLM %q, 期望主LLM QwenLLM", name)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("saveMemoryInBackground() 未借用LLM")
+	}
+}
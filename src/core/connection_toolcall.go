@@ -0,0 +1,348 @@
+package core
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"sync"
+	"time"
+
+	"angrymiao-ai-server/src/core/chat"
+	"angrymiao-ai-server/src/core/types"
+	"angrymiao-ai-server/src/core/utils"
+	"angrymiao-ai-server/src/models"
+
+	"github.com/google/uuid"
+)
+
+const (
+	defaultToolCallMaxDepth    = 5
+	defaultToolCallConcurrency = 4
+	defaultToolCallFillerText  = "请稍等，我查一下"
+)
+
+// toolCallMaxDepth 一轮对话中执行工具调用的最大次数
+func (h *ConnectionHandler) toolCallMaxDepth() int {
+	if h.config.ToolCall.MaxDepth > 0 {
+		return h.config.ToolCall.MaxDepth
+	}
+	return defaultToolCallMaxDepth
+}
+
+// toolCallConcurrency 同时执行的工具调用数
+func (h *ConnectionHandler) toolCallConcurrency() int {
+	if h.config.ToolCall.Concurrency > 0 {
+		return h.config.ToolCall.Concurrency
+	}
+	return defaultToolCallConcurrency
+}
+
+// 未配置时可并发执行的只读工具
+var defaultParallelTools = []string{"get_time", searchKnowledgeToolName}
+
+// toolCallParallelSafe 工具是否可与其他工具并发执行
+func (h *ConnectionHandler) toolCallParallelSafe(name string) bool {
+	tools := h.config.ToolCall.ParallelTools
+	if len(tools) == 0 {
+		tools = defaultParallelTools
+	}
+	return toolAllowed(name, tools)
+}
+
+// toolCallAccumulator 合并流式响应中的工具调用增量
+// 按增量的Index归并，不提供Index的服务以新的ID区分不同的调用
+type toolCallAccumulator struct {
+	calls   []types.ToolCall
+	byIndex map[int]int // 增量Index -> calls中的位置
+}
+
+// add 合并一个响应块中的工具调用增量
+func (a *toolCallAccumulator) add(deltas []types.ToolCall) {
+	if a.byIndex == nil {
+		a.byIndex = make(map[int]int)
+	}
+	for _, delta := range deltas {
+		pos, ok := a.byIndex[delta.Index]
+		if !ok || (delta.ID != "" && a.calls[pos].ID != "" && a.calls[pos].ID != delta.ID) {
+			a.calls = append(a.calls, types.ToolCall{Type: "function"})
+			pos = len(a.calls) - 1
+			a.byIndex[delta.Index] = pos
+		}
+
+		call := &a.calls[pos]
+		if delta.ID != "" {
+			call.ID = delta.ID
+		}
+		if delta.Type != "" {
+			call.Type = delta.Type
+		}
+		if delta.Function.Name != "" {
+			call.Function.Name = delta.Function.Name
+		}
+		call.Function.Arguments += delta.Function.Arguments
+	}
+}
+
+// result 返回合并后的工具调用，补全缺失的ID和参数
+func (a *toolCallAccumulator) result() []types.ToolCall {
+	calls := make([]types.ToolCall, 0, len(a.calls))
+	for _, call := range a.calls {
+		if call.Function.Name == "" {
+			continue
+		}
+		if call.ID == "" {
+			call.ID = uuid.New().String()
+		}
+		if call.Function.Arguments == "" {
+			call.Function.Arguments = "{}"
+		}
+		call.Index = len(calls)
+		calls = append(calls, call)
+	}
+	return calls
+}
+
+// parseTextToolCall 解析以文本形式输出的工具调用，如 <tool_call>{"name":..., "arguments":...}</tool_call>
+func parseTextToolCall(content string) (types.ToolCall, bool) {
+	data := utils.Extract_json_from_string(content)
+	if data == nil {
+		return types.ToolCall{}, false
+	}
+	name, _ := data["name"].(string)
+	if name == "" {
+		return types.ToolCall{}, false
+	}
+	arguments, err := json.Marshal(data["arguments"])
+	if err != nil || string(arguments) == "null" {
+		arguments = []byte("{}")
+	}
+	return types.ToolCall{
+		ID:   uuid.New().String(),
+		Type: "function",
+		Function: types.FunctionCall{
+			Name:      name,
+			Arguments: string(arguments),
+		},
+	}, true
+}
+
+// executeToolCalls 执行LLM请求的全部工具调用，并将调用和结果写入对话历史
+// 只读工具并发执行，其余工具按调用顺序依次执行，结果处理（播报、调用处理器）按调用顺序在当前协程进行
+// 返回是否需要将工具结果交给LLM继续生成
+func (h *ConnectionHandler) executeToolCalls(ctx context.Context, content string, calls []types.ToolCall, reply *llmReply) bool {
+	h.LogInfo(fmt.Sprintf("执行工具调用 %d 个, round: %d", len(calls), reply.round))
+
+	results, wg := startToolCalls(calls, h.toolCallConcurrency(), h.toolCallParallelSafe, func(call types.ToolCall) (result types.ActionResponse) {
+		defer func() {
+			if r := recover(); r != nil {
+				h.LogError(fmt.Sprintf("工具调用 %s 发生panic: %v", call.Function.Name, r))
+				result = types.ActionResponse{Action: types.ActionTypeError, Result: fmt.Sprintf("%v", r)}
+			}
+		}()
+		// 依次执行的工具在轮次取消后不再执行
+		if err := ctx.Err(); err != nil {
+			return types.ActionResponse{Action: types.ActionTypeError, Result: err.Error()}
+		}
+		return h.executeToolCall(ctx, call)
+	})
+	h.waitToolCalls(wg, reply)
+	if ctx.Err() != nil {
+		// 轮次已取消，工具结果不再写入对话历史
+		return false
+	}
+
+	// 添加 assistant 消息，包含全部 tool_calls
+	h.dialogueManager.Put(chat.Message{
+		Role:      "assistant",
+		Content:   content,
+		ToolCalls: calls,
+	})
+
+	needLLM := false
+	for i, call := range calls {
+		resultText, reqLLM := h.handleFunctionResult(results[i], call)
+		h.LogInfo(fmt.Sprintf("函数调用结果: %s(%s) -> %s", call.Function.Name, call.Function.Arguments, resultText))
+		// 每个调用都需要对应的 tool 消息
+		h.dialogueManager.Put(chat.Message{
+			Role:       "tool",
+			ToolCallID: call.ID,
+			Content:    resultText,
+		})
+		needLLM = needLLM || reqLLM
+	}
+	return needLLM
+}
+
+// startToolCalls 开始执行工具调用，返回按调用顺序排列的结果和等待全部完成的WaitGroup
+// 可并发的工具在最多 concurrency 个协程中同时执行，其余工具在一个协程中按调用顺序依次执行
+func startToolCalls(calls []types.ToolCall, concurrency int, parallelSafe func(name string) bool, execute func(types.ToolCall) types.ActionResponse) ([]types.ActionResponse, *sync.WaitGroup) {
+	results := make([]types.ActionResponse, len(calls))
+	var wg sync.WaitGroup
+	if concurrency <= 0 {
+		concurrency = 1
+	}
+	sem := make(chan struct{}, concurrency)
+
+	var serial []int
+	for i, call := range calls {
+		if !parallelSafe(call.Function.Name) {
+			serial = append(serial, i)
+			continue
+		}
+		wg.Add(1)
+		go func(i int, call types.ToolCall) {
+			defer wg.Done()
+			sem <- struct{}{}
+			defer func() { <-sem }()
+			results[i] = execute(call)
+		}(i, call)
+	}
+
+	if len(serial) > 0 {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for _, i := range serial {
+				results[i] = execute(calls[i])
+			}
+		}()
+	}
+	return results, &wg
+}
+
+// waitToolCalls 等待工具执行完成，超过配置的时间时播报一次等待提示
+func (h *ConnectionHandler) waitToolCalls(wg *sync.WaitGroup, reply *llmReply) {
+	done := make(chan struct{})
+	go func() {
+		wg.Wait()
+		close(done)
+	}()
+
+	delay := h.config.ToolCall.FillerDelay
+	if delay <= 0 || reply.fillerSpoken {
+		<-done
+		return
+	}
+
+	timer := time.NewTimer(time.Duration(delay) * time.Millisecond)
+	defer timer.Stop()
+	select {
+	case <-done:
+		return
+	case <-timer.C:
+	}
+
+	reply.fillerSpoken = true
+	filler := h.config.ToolCall.FillerText
+	if filler == "" {
+		filler = defaultToolCallFillerText
+	}
+	h.LogInfo(fmt.Sprintf("工具执行超过 %dms，播报等待提示: %s", delay, filler))
+	h.SystemSpeak(filler)
+	<-done
+}
+
+// executeToolCall 执行单个工具调用，可在多个协程中并发调用
+func (h *ConnectionHandler) executeToolCall(ctx context.Context, call types.ToolCall) types.ActionResponse {
+	functionName := call.Function.Name
+	arguments := make(map[string]interface{})
+	if err := json.Unmarshal([]byte(call.Function.Arguments), &arguments); err != nil {
+		h.LogError(fmt.Sprintf("函数调用参数解析失败: %v", err))
+	}
+	h.LogInfo(fmt.Sprintf("函数调用: %s %v", functionName, arguments))
+
+	if functionName == searchKnowledgeToolName {
+		return h.searchKnowledge(ctx, arguments)
+	}
+
+	if h.mcpManager.IsMCPTool(functionName) {
+		// 处理MCP函数调用
+		result, err := h.mcpManager.ExecuteTool(ctx, functionName, arguments)
+		if err != nil {
+			h.LogError(fmt.Sprintf("MCP函数调用失败: %v", err))
+			if result == nil {
+				result = "MCP工具调用失败"
+			}
+		}
+		// 判断result 是否是types.ActionResponse类型
+		if actionResult, ok := result.(types.ActionResponse); ok {
+			return actionResult
+		}
+		h.LogInfo(fmt.Sprintf("MCP函数调用结果: %v", result))
+		return types.ActionResponse{
+			Action: types.ActionTypeReqLLM, // 动作类型
+			Result: result,                 // 动作产生的结果
+		}
+	}
+
+	// 处理用户自定义函数调用
+	config := h.findUserFunction(functionName)
+	if config == nil {
+		return types.ActionResponse{
+			Action: types.ActionTypeNotFound,
+			Result: "没有找到函数: " + functionName,
+		}
+	}
+	funResult, err := h.executeUserFunctionCall(ctx, config, arguments)
+	if err != nil {
+		h.LogError(fmt.Sprintf("用户自定义函数调用失败: %v", err))
+		if funResult.Result == "" {
+			funResult.Result = "BOT 模型调用失败"
+		}
+	}
+	return types.ActionResponse{
+		Action: types.ActionTypeReqLLM,
+		Result: funResult.Result,
+	}
+}
+
+// findUserFunction 从请求上下文的用户配置中查找自定义函数
+func (h *ConnectionHandler) findUserFunction(functionName string) *models.UserAIConfig {
+	if h.request == nil {
+		return nil
+	}
+	userFunConfig := h.request.Context().Value("user_configs")
+	configs, ok := userFunConfig.([]*models.UserAIConfig)
+	if !ok {
+		return nil
+	}
+	for _, v := range configs {
+		if v.FunctionName == functionName {
+			return v
+		}
+	}
+	return nil
+}
+
+// handleFunctionResult 处理工具调用结果，返回写入对话历史的结果文本和是否需要请求LLM
+func (h *ConnectionHandler) handleFunctionResult(result types.ActionResponse, call types.ToolCall) (string, bool) {
+	switch result.Action {
+	case types.ActionTypeError:
+		h.LogError(fmt.Sprintf("函数调用错误: %v", result.Result))
+		return fmt.Sprintf("函数调用错误: %v", result.Result), false
+	case types.ActionTypeNotFound:
+		h.LogError(fmt.Sprintf("函数未找到: %v", result.Result))
+		return fmt.Sprintf("%v", result.Result), false
+	case types.ActionTypeNone:
+		h.LogInfo(fmt.Sprintf("函数调用无操作: %v", result.Result))
+		return "调用工具成功: " + call.Function.Name, false
+	case types.ActionTypeResponse:
+		h.LogInfo(fmt.Sprintf("函数调用直接回复: %v", result.Response))
+		text, _ := result.Response.(string)
+		h.SystemSpeak(text)
+		return text, false
+	case types.ActionTypeCallHandler:
+		return h.handleMCPResultCall(result), false
+	case types.ActionTypeReqLLM:
+		h.LogInfo(fmt.Sprintf("函数调用后请求LLM: %v", result.Result))
+		if text, ok := result.Result.(string); ok && len(text) > 0 {
+			return text, true
+		}
+		h.LogError(fmt.Sprintf("函数调用结果解析失败: %v", result.Result))
+		// 发送错误消息
+		errorMessage := fmt.Sprintf("函数调用结果解析失败 %v", result.Result)
+		h.SystemSpeak(errorMessage)
+		return errorMessage, false
+	}
+	return fmt.Sprintf("%v", result.Result), false
+}
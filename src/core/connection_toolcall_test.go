@@ -0,0 +1,132 @@
+package core
+
+import (
+	"sync"
+	"testing"
+	"time"
+
+	"angrymiao-ai-server/src/core/types"
+)
+
+func TestToolCallAccumulator(t *testing.T) {
+	var acc toolCallAccumulator
+	// OpenAI 风格：按 Index 分片下发名称和参数
+	acc.add([]types.ToolCall{{Index: 0, ID: "call_a", Function: types.FunctionCall{Name: "get_weather"}}})
+	acc.add([]types.ToolCall{{Index: 0, Function: types.FunctionCall{Arguments: `{"city":`}}})
+	acc.add([]types.ToolCall{
+		{Index: 0, Function: types.FunctionCall{Arguments: `"北京"}`}},
+		{Index: 1, ID: "call_b", Function: types.FunctionCall{Name: "get_time"}},
+	})
+	// 不提供 Index 的服务以新的ID区分调用
+	acc.add([]types.ToolCall{{Index: 1, ID: "call_c", Function: types.FunctionCall{Name: "play_music", Arguments: `{"song":"晴天"}`}}})
+	// 没有名称的调用丢弃
+	acc.add([]types.ToolCall{{Index: 5, Function: types.FunctionCall{Arguments: "{}"}}})
+
+	calls := acc.result()
+	if len(calls) != 3 {
+		t.Fatalf("result() 返回 %d 个调用: %+v", len(calls), calls)
+	}
+	want := []struct{ id, name, args string }{
+		{"call_a", "get_weather", `{"city":"北京"}`},
+		{"call_b", "get_time", "{}"},
+		{"call_c", "play_music", `{"song":"晴天"}`},
+	}
+	for i, w := range want {
+		c := calls[i]
+		if c.ID != w.id || c.Function.Name != w.name || c.Function.Arguments != w.args || c.Index != i || c.Type != "function" {
+			t.Errorf("result()[%d] = %+v, 期望 %+v", i, c, w)
+		}
+	}
+
+	var noID toolCallAccumulator
+	noID.add([]types.ToolCall{{Function: types.FunctionCall{Name: "exit"}}})
+	if calls := noID.result(); len(calls) != 1 || calls[0].ID == "" {
+		t.Errorf("result() 应补全缺失的ID: %+v", calls)
+	}
+}
+
+func TestParseTextToolCall(t *testing.T) {
+	tests := []struct {
+		content string
+		name    string
+		args    string
+		ok      bool
+	}{
+		{`<tool_call>{"name": "get_weather", "arguments": {"city": "北京"}}</tool_call>`, "get_weather", `{"city":"北京"}`, true},
+		{`好的 {"name":"exit"}`, "exit", "{}", true},
+		{`{"arguments": {"city": "北京"}}`, "", "", false},
+		{"今天天气不错", "", "", false},
+	}
+	for _, tt := range tests {
+		call, ok := parseTextToolCall(tt.content)
+		if ok != tt.ok {
+			t.Errorf("parseTextToolCall(%q) ok = %v, 期望 %v", tt.content, ok, tt.ok)
+			continue
+		}
+		if !ok {
+			continue
+		}
+		if call.Function.Name != tt.name || call.Function.Arguments != tt.args || call.ID == "" {
+			t.Errorf("parseTextToolCall(%q) = %+v", tt.content, call)
+		}
+	}
+}
+
+func TestStartToolCallsSerializesUnsafeTools(t *testing.T) {
+	calls := []types.ToolCall{
+		{Function: types.FunctionCall{Name: "local_change_role"}},
+		{Function: types.FunctionCall{Name: "get_time"}},
+		{Function: types.FunctionCall{Name: "self.audio_speaker.set_volume"}},
+		{Function: types.FunctionCall{Name: "search_knowledge"}},
+		{Function: types.FunctionCall{Name: "local_exit"}},
+	}
+	parallelSafe := func(name string) bool { return toolAllowed(name, defaultParallelTools) }
+
+	var mu sync.Mutex
+	var order []string
+	running, maxRunning := 0, 0
+	release := make(chan struct{})
+	execute := func(call types.ToolCall) types.ActionResponse {
+		mu.Lock()
+		running++
+		if running > maxRunning {
+			maxRunning = running
+		}
+		mu.Unlock()
+		if parallelSafe(call.Function.Name) {
+			// 只读工具等待放行，期间有副作用的工具仍按顺序执行
+			<-release
+		} else {
+			time.Sleep(5 * time.Millisecond)
+		}
+		mu.Lock()
+		running--
+		order = append(order, call.Function.Name)
+		mu.Unlock()
+		return types.ActionResponse{Result: call.Function.Name}
+	}
+
+	results, wg := startToolCalls(calls, 4, parallelSafe, execute)
+	time.Sleep(50 * time.Millisecond)
+	close(release)
+	wg.Wait()
+
+	var serial []string
+	for _, name := range order {
+		if !parallelSafe(name) {
+			serial = append(serial, name)
+		}
+	}
+	if len(serial) != 3 || serial[0] != "local_change_role" || serial[1] != "self.audio_speaker.set_volume" || serial[2] != "local_exit" {
+		t.Errorf("有副作用的工具执行顺序 = %v", serial)
+	}
+	// 两个只读工具和一个有副作用的工具同时执行
+	if maxRunning != 3 {
+		t.Errorf("最大并发数 = %d, 期望 3", maxRunning)
+	}
+	for i, call := range calls {
+		if results[i].Result != call.Function.Name {
+			t.Errorf("results[%d] = %v, 期望 %s", i, results[i].Result, call.Function.Name)
+		}
+	}
+}
@@ -0,0 +1,146 @@
+package core
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"sync/atomic"
+	"time"
+
+	"angrymiao-ai-server/src/core/providers"
+	"angrymiao-ai-server/src/core/router"
+	"angrymiao-ai-server/src/core/translate"
+	"angrymiao-ai-server/src/core/utils"
+)
+
+// 同声传译拾音模式
+const listenModeTranslate = "translate"
+
+// 未配置时的默认语言
+const (
+	defaultTranslateSource = "zh"
+	defaultTranslateTarget = "en"
+)
+
+// setTranslateOptions 进入同声传译模式时设置语言，listen消息未指定的使用配置的值
+func (h *ConnectionHandler) setTranslateOptions(msgMap map[string]interface{}) {
+	cfg := h.config.Translate
+	h.translateSource, h.translateTarget, h.translateAutoDetect = cfg.Source, cfg.Target, cfg.AutoDetect
+	if source, ok := msgMap["source"].(string); ok && source != "" {
+		h.translateSource = source
+	}
+	if target, ok := msgMap["target"].(string); ok && target != "" {
+		h.translateTarget = target
+	}
+	if autoDetect, ok := msgMap["auto_detect"].(bool); ok {
+		h.translateAutoDetect = autoDetect
+	}
+	if h.translateSource == "" {
+		h.translateSource = defaultTranslateSource
+	}
+	if h.translateTarget == "" {
+		h.translateTarget = defaultTranslateTarget
+	}
+	h.LogInfo(fmt.Sprintf("同声传译: %s -> %s, 自动识别: %t", h.translateSource, h.translateTarget, h.translateAutoDetect))
+}
+
+// handleTranslateMessage 翻译一句识别结果并用目标语言的音色播报
+// 翻译不写入对话历史，也不使用角色提示词和工具
+func (h *ConnectionHandler) handleTranslateMessage(ctx context.Context, text string) error {
+	h.talkRound++
+	h.roundStartTime = time.Now()
+	h.roundFirstTextAt = time.Time{}
+	h.roundRoute = router.Decision{}
+	round := h.talkRound
+	ctx = h.beginRound(ctx)
+
+	if err := h.sendSTTMessage(text); err != nil {
+		return fmt.Errorf("发送STT消息失败: %v", err)
+	}
+	if err := h.sendTTSMessage("start", "", 0); err != nil {
+		return fmt.Errorf("发送TTS开始状态失败: %v", err)
+	}
+	if h.usageQuotaExceeded() {
+		h.refuseForQuota(round)
+		return nil
+	}
+
+	from, to := translate.Direction(text, h.translateSource, h.translateTarget, h.translateAutoDetect)
+	h.LogInfo(fmt.Sprintf("同声传译 %s -> %s: %s", from, to, text))
+
+	// 配置了翻译专用的LLM时本轮临时切换
+	if name := h.config.Translate.LLM; name != "" {
+		if cfg := h.llmConfig(); cfg == nil || cfg.Name != name {
+			restore, err := h.useLLMForRound(name)
+			if err != nil {
+				h.LogError(fmt.Sprintf("切换翻译LLM失败，使用当前LLM: %v", err))
+			} else {
+				defer restore()
+			}
+		}
+	}
+
+	spoken, err := h.streamTranslation(ctx, text, from, to, round)
+	if err != nil {
+		h.LogError(fmt.Sprintf("翻译失败: %v", err))
+	}
+	// 没有播报任何译文时结束本轮播放状态
+	if !spoken && ctx.Err() == nil {
+		h.sendTTSMessage("stop", "", 0)
+		h.clearSpeakStatus()
+	}
+	h.flushUsage(false)
+	return err
+}
+
+// streamTranslation 流式请求LLM翻译，按标点分段播报译文，返回是否播报了译文
+func (h *ConnectionHandler) streamTranslation(ctx context.Context, text, from, to string, round int) (bool, error) {
+	messages := []providers.Message{
+		{Role: "system", Content: translate.Prompt(h.config.Translate.Prompt, from, to)},
+		{Role: "user", Content: text},
+	}
+	llmName := h.usageProviderName("LLM")
+	responses, err := h.providers.llm.Response(ctx, h.sessionID, messages)
+	if err != nil {
+		return false, err
+	}
+
+	atomic.StoreInt32(&h.serverVoiceStop, 0)
+	voice := h.config.Translate.Voices[to]
+	textIndex := 0
+	speak := func(segment string) {
+		segment = strings.TrimSpace(segment)
+		if segment == "" {
+			return
+		}
+		textIndex++
+		if h.roundFirstTextAt.IsZero() {
+			h.roundFirstTextAt = time.Now()
+		}
+		h.tts_last_text_index = textIndex
+		if err := h.SpeakAndPlayWithVoice(segment, voice, "", textIndex, round); err != nil {
+			h.LogError(fmt.Sprintf("播放译文失败: %v", err))
+		}
+	}
+
+	var full strings.Builder
+	processedChars := 0
+	for content := range responses {
+		// 轮次已取消时继续读取直到LLM流结束，不再播报
+		if ctx.Err() != nil {
+			continue
+		}
+		full.WriteString(content)
+		if segment, charsCnt := utils.SplitAtLastPunctuation(full.String()[processedChars:]); charsCnt > 0 {
+			speak(segment)
+			processedChars += charsCnt
+		}
+	}
+	h.recordLLMUsage(llmName, messages, full.String(), nil)
+	if ctx.Err() != nil {
+		return textIndex > 0, nil
+	}
+	speak(full.String()[processedChars:])
+	h.LogInfo(fmt.Sprintf("同声传译结果: %s", full.String()))
+	return textIndex > 0, nil
+}
@@ -0,0 +1,214 @@
+package core
+
+import (
+	"fmt"
+	"sync"
+
+	"angrymiao-ai-server/src/core/providers/tts"
+)
+
+// 默认单个连接同时合成的TTS任务数
+const defaultTTSConcurrency = 3
+
+// ttsPipeline TTS并发合成流水线
+// 任务按入队顺序编号后并发合成，结果先放入重排缓冲区，再按编号顺序送入音频发送队列，
+// 保证播放顺序与文本顺序一致
+type ttsPipeline struct {
+	h       *ConnectionHandler
+	sem     chan struct{}
+	nextSeq uint64 // 下一个任务编号，只在分发协程中使用
+	buffer  *reorderBuffer
+	notify  chan struct{}
+
+	// process 执行单个合成任务，测试时可替换
+	process func(ttsTask) audioTask
+}
+
+// newTTSPipeline 创建TTS合成流水线
+func newTTSPipeline(h *ConnectionHandler, concurrency int) *ttsPipeline {
+	if concurrency <= 0 {
+		concurrency = 1
+	}
+	return &ttsPipeline{
+		h:       h,
+		sem:     make(chan struct{}, concurrency),
+		buffer:  newReorderBuffer(),
+		notify:  make(chan struct{}, 1),
+		process: h.processTTSTask,
+	}
+}
+
+// ttsConcurrency 单个连接同时合成的TTS任务数，TTS提供者不支持并发合成时为1
+func (h *ConnectionHandler) ttsConcurrency() int {
+	if !h.ttsConcurrent() {
+		return 1
+	}
+	if h.config.TTSConcurrency > 0 {
+		return h.config.TTSConcurrency
+	}
+	return defaultTTSConcurrency
+}
+
+// ttsConcurrent TTS提供者是否支持在多个协程中同时合成
+func (h *ConnectionHandler) ttsConcurrent() bool {
+	p, ok := h.providers.tts.(tts.ConcurrentProvider)
+	return ok && p.SupportsConcurrency()
+}
+
+// submit 提交合成任务，并发数已满时阻塞等待
+func (p *ttsPipeline) submit(task ttsTask) {
+	seq := p.nextSeq
+	p.nextSeq++
+
+	select {
+	case p.sem <- struct{}{}:
+	case <-p.h.stopChan:
+		// 未执行的任务也要跳过编号，避免阻塞后续结果的发送
+		p.buffer.skip(seq)
+		p.wake()
+		return
+	}
+
+	go func() {
+		defer func() { <-p.sem }()
+		p.complete(seq, p.synthesize(task))
+	}()
+}
+
+// synthesize 执行合成，出现panic时返回空结果，避免阻塞后续任务的发送
+func (p *ttsPipeline) synthesize(task ttsTask) (result audioTask) {
+	defer func() {
+		if r := recover(); r != nil {
+			p.h.LogError(fmt.Sprintf("TTS合成任务异常: %v, 文本: %s", r, task.text))
+			result = audioTask{text: task.text, round: task.round, textIndex: task.textIndex, emotion: task.emotion, partial: task.partial}
+		}
+	}()
+	return p.process(task)
+}
+
+// complete 记录合成结果并通知发送协程
+func (p *ttsPipeline) complete(seq uint64, result audioTask) {
+	select {
+	case <-p.h.stopChan:
+		// 连接已关闭，发送协程不再消费结果
+		p.h.deleteAudioFileIfNeeded(result.filepath, "连接关闭时")
+		p.buffer.skip(seq)
+		return
+	default:
+	}
+
+	if !p.buffer.put(seq, result) {
+		p.h.deleteAudioFileIfNeeded(result.filepath, "重复的合成结果")
+		return
+	}
+	p.wake()
+}
+
+// wake 通知发送协程有新的结果
+func (p *ttsPipeline) wake() {
+	select {
+	case p.notify <- struct{}{}:
+	default:
+	}
+}
+
+// flushCoroutine 按编号顺序将合成结果送入音频发送队列
+// 轮次过期或服务端语音停止的结果同样按序送出，由sendAudioMessage统一丢弃
+func (p *ttsPipeline) flushCoroutine() {
+	for {
+		select {
+		case <-p.h.stopChan:
+			p.drain()
+			return
+		case <-p.notify:
+		}
+
+		for {
+			result, ok := p.buffer.pop()
+			if !ok {
+				break
+			}
+			select {
+			case p.h.audioMessagesQueue <- result:
+			case <-p.h.stopChan:
+				p.h.deleteAudioFileIfNeeded(result.filepath, "连接关闭时")
+				p.drain()
+				return
+			}
+		}
+	}
+}
+
+// drain 连接关闭时清理缓冲区中尚未发送的音频文件
+func (p *ttsPipeline) drain() {
+	for _, result := range p.buffer.drain() {
+		p.h.deleteAudioFileIfNeeded(result.filepath, "连接关闭时")
+	}
+}
+
+// reorderBuffer 合成结果的重排缓冲区，按编号乱序放入，按编号顺序取出
+type reorderBuffer struct {
+	mu      sync.Mutex
+	nextOut uint64               // 下一个待取出的编号
+	ready   map[uint64]audioTask // 已完成但尚未轮到取出的结果
+	skipped map[uint64]struct{}  // 没有结果的编号，取出时直接跳过
+}
+
+// newReorderBuffer 创建重排缓冲区
+func newReorderBuffer() *reorderBuffer {
+	return &reorderBuffer{
+		ready:   make(map[uint64]audioTask),
+		skipped: make(map[uint64]struct{}),
+	}
+}
+
+// put 放入指定编号的结果，已取出过的编号直接忽略
+func (b *reorderBuffer) put(seq uint64, value audioTask) bool {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if seq < b.nextOut {
+		return false
+	}
+	b.ready[seq] = value
+	return true
+}
+
+// skip 标记编号没有结果，后续编号不再等待它
+func (b *reorderBuffer) skip(seq uint64) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if seq >= b.nextOut {
+		b.skipped[seq] = struct{}{}
+	}
+}
+
+// pop 取出下一个可发送的结果，跳过没有结果的编号
+func (b *reorderBuffer) pop() (audioTask, bool) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	for {
+		if _, ok := b.skipped[b.nextOut]; ok {
+			delete(b.skipped, b.nextOut)
+			b.nextOut++
+			continue
+		}
+		value, ok := b.ready[b.nextOut]
+		if ok {
+			delete(b.ready, b.nextOut)
+			b.nextOut++
+		}
+		return value, ok
+	}
+}
+
+// drain 取出缓冲区中所有尚未发送的结果
+func (b *reorderBuffer) drain() []audioTask {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	values := make([]audioTask, 0, len(b.ready))
+	for seq, value := range b.ready {
+		values = append(values, value)
+		delete(b.ready, seq)
+	}
+	return values
+}
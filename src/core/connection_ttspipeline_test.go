@@ -0,0 +1,196 @@
+package core
+
+import (
+	"context"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestReorderBuffer(t *testing.T) {
+	b := newReorderBuffer()
+	b.put(2, audioTask{text: "c"})
+	b.put(1, audioTask{text: "b"})
+	if _, ok := b.pop(); ok {
+		t.Fatal("pop() 编号0未完成时不应取出结果")
+	}
+
+	b.put(0, audioTask{text: "a"})
+	b.skip(3)
+	b.put(4, audioTask{text: "e"})
+	var got string
+	for {
+		result, ok := b.pop()
+		if !ok {
+			break
+		}
+		got += result.text
+	}
+	if got != "abce" {
+		t.Errorf("pop() 顺序 = %q, 期望 %q", got, "abce")
+	}
+
+	// 已取出的编号不再接收结果
+	if b.put(1, audioTask{text: "重复"}) {
+		t.Error("put() 已取出的编号应返回 false")
+	}
+	b.put(6, audioTask{text: "g"})
+	if drained := b.drain(); len(drained) != 1 || drained[0].text != "g" {
+		t.Errorf("drain() = %+v", drained)
+	}
+}
+
+// blockingPipeline 创建合成任务按文本等待放行的流水线
+func blockingPipeline(t *testing.T, h *ConnectionHandler, concurrency int) (*ttsPipeline, map[string]chan struct{}) {
+	t.Helper()
+	release := map[string]chan struct{}{}
+	for _, text := range []string{"a", "b", "c", "d", "e"} {
+		release[text] = make(chan struct{})
+	}
+	p := newTTSPipeline(h, concurrency)
+	p.process = func(task ttsTask) audioTask {
+		<-release[task.text]
+		if task.text == "c" {
+			panic("合成失败")
+		}
+		return audioTask{filepath: task.text + ".mp3", text: task.text, round: task.round}
+	}
+	go p.flushCoroutine()
+	return p, release
+}
+
+// receiveAudio 从音频发送队列读取指定数量的结果
+func receiveAudio(t *testing.T, h *ConnectionHandler, n int) string {
+	t.Helper()
+	var got string
+	for i := 0; i < n; i++ {
+		select {
+		case result := <-h.audioMessagesQueue:
+			got += result.text
+		case <-time.After(2 * time.Second):
+			t.Fatalf("等待第%d个结果超时, 已收到 %q", i+1, got)
+		}
+	}
+	return got
+}
+
+func TestTTSPipelineOutOfOrder(t *testing.T) {
+	h := newTestHandler(t)
+	p, release := blockingPipeline(t, h, 5)
+	for _, text := range []string{"a", "b", "c", "d", "e"} {
+		p.submit(ttsTask{text: text, round: 1})
+	}
+
+	// 倒序完成，其中 c 合成异常
+	for _, text := range []string{"e", "d", "c", "b"} {
+		close(release[text])
+	}
+	select {
+	case result := <-h.audioMessagesQueue:
+		t.Fatalf("第一个任务未完成时不应发送结果: %+v", result)
+	case <-time.After(50 * time.Millisecond):
+	}
+	close(release["a"])
+
+	if got := receiveAudio(t, h, 5); got != "abcde" {
+		t.Errorf("发送顺序 = %q, 期望 %q", got, "abcde")
+	}
+}
+
+func TestTTSPipelineCancelMidFlush(t *testing.T) {
+	h := newTestHandler(t)
+	h.audioMessagesQueue = make(chan audioTask, 1)
+	p, release := blockingPipeline(t, h, 2)
+
+	// 旧轮次的 a、b 完成后，b 因音频队列已满阻塞在发送协程中
+	p.submit(ttsTask{text: "a", round: 1})
+	p.submit(ttsTask{text: "b", round: 1})
+	close(release["a"])
+	close(release["b"])
+	time.Sleep(50 * time.Millisecond)
+
+	// 打断时清空音频队列，新轮次的结果仍按顺序发送，不会被阻塞
+	h.cleanTTSAndAudioQueue(false)
+	p.submit(ttsTask{text: "d", round: 2})
+	p.submit(ttsTask{text: "e", round: 2})
+	close(release["e"])
+	close(release["d"])
+
+	// 阻塞中的 b 可能在清空队列时一并丢弃，也可能在清空后送出，但必须排在新轮次之前
+	var got string
+	for !strings.HasSuffix(got, "e") {
+		select {
+		case result := <-h.audioMessagesQueue:
+			got += result.text
+		case <-time.After(2 * time.Second):
+			t.Fatalf("等待结果超时, 已收到 %q", got)
+		}
+	}
+	if got != "bde" && got != "de" {
+		t.Errorf("打断后发送 = %q, 期望 %q 或 %q", got, "bde", "de")
+	}
+}
+
+// inflightTTS 记录同时进行的合成数，concurrent 表示是否声明支持并发合成
+type inflightTTS struct {
+	fakeTTS
+	concurrent bool
+	mu         sync.Mutex
+	inflight   int
+	max        int
+}
+
+func (f *inflightTTS) SupportsConcurrency() bool { return f.concurrent }
+
+func (f *inflightTTS) ToTTS(text string) (string, error) {
+	f.mu.Lock()
+	f.inflight++
+	if f.inflight > f.max {
+		f.max = f.inflight
+	}
+	f.mu.Unlock()
+	time.Sleep(20 * time.Millisecond)
+	f.mu.Lock()
+	f.inflight--
+	f.mu.Unlock()
+	return f.fakeTTS.ToTTS(text)
+}
+
+func TestTTSConcurrencyFollowsProvider(t *testing.T) {
+	h := newTestHandler(t)
+	h.config.TTSConcurrency = 3
+
+	provider := &inflightTTS{fakeTTS: fakeTTS{dir: t.TempDir()}}
+	h.providers.tts = provider
+	if got := h.ttsConcurrency(); got != 1 {
+		t.Errorf("不支持并发的提供者 ttsConcurrency() = %d, 期望 1", got)
+	}
+
+	// 轮次取消后仍在后台进行的合成也不能与新的合成同时进行
+	ctx, cancel := context.WithCancel(context.Background())
+	go func() {
+		time.Sleep(5 * time.Millisecond)
+		cancel()
+	}()
+	if _, err := h.synthesizeInRound(ctx, "被取消", ""); err == nil {
+		t.Error("synthesizeInRound() 轮次取消后应返回错误")
+	}
+	var wg sync.WaitGroup
+	for i := 0; i < 3; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			h.synthesize("你好", "")
+		}()
+	}
+	wg.Wait()
+	if provider.max != 1 {
+		t.Errorf("不支持并发的提供者同时合成 %d 个, 期望 1", provider.max)
+	}
+
+	h.providers.tts = &inflightTTS{fakeTTS: fakeTTS{dir: t.TempDir()}, concurrent: true}
+	if got := h.ttsConcurrency(); got != 3 {
+		t.Errorf("支持并发的提供者 ttsConcurrency() = %d, 期望 3", got)
+	}
+}
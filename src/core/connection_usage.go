@@ -0,0 +1,149 @@
+package core
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+	"unicode/utf8"
+
+	"angrymiao-ai-server/src/core/providers"
+	"angrymiao-ai-server/src/core/types"
+	"angrymiao-ai-server/src/core/usage"
+	"angrymiao-ai-server/src/services"
+)
+
+// 超出配额且未配置拒绝话术时的回复
+const defaultUsageRefuseText = "抱歉，今天的对话额度已经用完了，明天再来找我聊天吧"
+
+// usageEnabled 是否统计用量
+func (h *ConnectionHandler) usageEnabled() bool {
+	return h.config.Usage.Enabled && h.usageService != nil
+}
+
+// usageProviderName 获取提供者在配置中的名称
+func (h *ConnectionHandler) usageProviderName(module string) string {
+	switch module {
+	case "LLM":
+		if cfg := h.llmConfig(); cfg != nil && cfg.Name != "" {
+			return cfg.Name
+		}
+		return h.config.PrimaryLLM()
+	case "TTS":
+		if getter, ok := h.providers.tts.(configGetter); ok && getter.Config().Name != "" {
+			return getter.Config().Name
+		}
+	}
+	return h.config.SelectedModule[module]
+}
+
+// recordLLMUsage 记录一次LLM请求的token数，提供者未返回用量时按消息和回复估算
+func (h *ConnectionHandler) recordLLMUsage(provider string, messages []providers.Message, completion string, reported *types.Usage) {
+	if !h.usageEnabled() {
+		return
+	}
+	if reported != nil && reported.PromptTokens+reported.CompletionTokens > 0 {
+		h.usageMeter.AddLLM(provider, reported.PromptTokens, reported.CompletionTokens, false)
+		return
+	}
+
+	promptTokens := 0
+	for _, msg := range messages {
+		promptTokens += usage.EstimateTokens(msg.Content)
+		for _, call := range msg.ToolCalls {
+			promptTokens += usage.EstimateTokens(call.Function.Name + call.Function.Arguments)
+		}
+	}
+	h.usageMeter.AddLLM(provider, promptTokens, usage.EstimateTokens(completion), true)
+}
+
+// recordTTSUsage 记录一次TTS合成的字符数
+func (h *ConnectionHandler) recordTTSUsage(text string) {
+	if !h.usageEnabled() {
+		return
+	}
+	h.usageMeter.AddTTS(h.usageProviderName("TTS"), utf8.RuneCountInString(text))
+}
+
+// recordASRAudio 记录送入ASR的PCM音频时长
+func (h *ConnectionHandler) recordASRAudio(size int) {
+	if !h.usageEnabled() {
+		return
+	}
+	sampleRate, channels := h.clientAudioSampleRate, h.clientAudioChannels
+	if sampleRate <= 0 {
+		sampleRate = 16000
+	}
+	if channels <= 0 {
+		channels = 1
+	}
+	h.usageMeter.AddASR(h.usageProviderName("ASR"), usage.PCMSeconds(size, sampleRate, channels))
+}
+
+// recordASRResult 记录一次ASR识别结果
+func (h *ConnectionHandler) recordASRResult() {
+	if !h.usageEnabled() {
+		return
+	}
+	h.usageMeter.AddASRRequest(h.usageProviderName("ASR"))
+}
+
+// flushUsage 将累计的用量写入数据库，连接关闭时同步写入，其余时候异步写入
+func (h *ConnectionHandler) flushUsage(wait bool) {
+	if !h.usageEnabled() {
+		return
+	}
+	entries := h.usageMeter.Drain()
+	if len(entries) == 0 {
+		return
+	}
+
+	save := func() {
+		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		if err := h.usageService.Record(ctx, h.userID, h.deviceID, entries); err != nil {
+			h.logger.Error("保存用量失败: %v", err)
+		}
+	}
+	if wait {
+		save()
+		return
+	}
+	go save()
+}
+
+// usageQuotaExceeded 检查用户或设备是否超出对话用量配额，查询失败时不限制
+func (h *ConnectionHandler) usageQuotaExceeded() bool {
+	if !h.usageEnabled() {
+		return false
+	}
+	err := h.usageService.CheckQuota(h.connContext(), h.userID, h.deviceID)
+	if err == nil {
+		return false
+	}
+	if !errors.Is(err, services.ErrUsageQuotaExceeded) {
+		h.logger.Error("检查用量配额失败: %v", err)
+		return false
+	}
+	h.LogInfo(fmt.Sprintf("用户 %s 设备 %s %v", h.userID, h.deviceID, err))
+	return true
+}
+
+// refuseForQuota 超出配额时礼貌拒绝，不调用LLM
+func (h *ConnectionHandler) refuseForQuota(round int) {
+	text := h.config.Usage.RefuseText
+	if text == "" {
+		text = defaultUsageRefuseText
+	}
+	h.tts_last_text_index = 1 // 重置文本索引
+	h.SpeakAndPlay(text, 1, round)
+}
+
+// toolCallText 拼接工具调用的名称和参数，用于估算token数
+func toolCallText(calls []types.ToolCall) string {
+	text := ""
+	for _, call := range calls {
+		text += call.Function.Name + call.Function.Arguments
+	}
+	return text
+}
@@ -0,0 +1,120 @@
+package dictation
+
+import (
+	"strings"
+	"unicode"
+	"unicode/utf8"
+
+	"angrymiao-ai-server/src/core/utils"
+)
+
+// Command 听写中的语音指令
+type Command string
+
+const (
+	CommandNone         Command = ""
+	CommandNewParagraph Command = "new_paragraph"
+	CommandDeleteLast   Command = "delete_last"
+	CommandFinish       Command = "finish"
+)
+
+// 语音指令的说法，去除标点后整句相同时才视为指令，避免误删正文
+var commandPhrases = map[string]Command{
+	"新段落":   CommandNewParagraph,
+	"另起一段":  CommandNewParagraph,
+	"换段":    CommandNewParagraph,
+	"换行":    CommandNewParagraph,
+	"删除上一句": CommandDeleteLast,
+	"删掉上一句": CommandDeleteLast,
+	"撤销上一句": CommandDeleteLast,
+	"结束听写":  CommandFinish,
+	"停止听写":  CommandFinish,
+}
+
+// ParseCommand 判断识别结果是否为语音指令
+func ParseCommand(text string) Command {
+	key := strings.Join(strings.Fields(utils.RemoveAllPunctuation(text)), "")
+	return commandPhrases[key]
+}
+
+// Document 听写文档，按段落累积识别出的句子
+type Document struct {
+	paragraphs [][]string
+}
+
+// Apply 处理一句识别结果，是语音指令时执行指令，否则追加到当前段落
+func (d *Document) Apply(text string) Command {
+	text = strings.TrimSpace(text)
+	if text == "" {
+		return CommandNone
+	}
+	switch command := ParseCommand(text); command {
+	case CommandNewParagraph:
+		// 连续的新段落指令不产生空段落
+		if n := len(d.paragraphs); n > 0 && len(d.paragraphs[n-1]) > 0 {
+			d.paragraphs = append(d.paragraphs, nil)
+		}
+		return command
+	case CommandDeleteLast:
+		d.deleteLast()
+		return command
+	case CommandFinish:
+		return command
+	}
+
+	if len(d.paragraphs) == 0 {
+		d.paragraphs = append(d.paragraphs, nil)
+	}
+	last := len(d.paragraphs) - 1
+	d.paragraphs[last] = append(d.paragraphs[last], text)
+	return CommandNone
+}
+
+// deleteLast 删除最后一句，当前段落为空时删除到上一段的最后一句
+func (d *Document) deleteLast() {
+	for n := len(d.paragraphs); n > 0; n = len(d.paragraphs) {
+		paragraph := d.paragraphs[n-1]
+		if len(paragraph) == 0 {
+			d.paragraphs = d.paragraphs[:n-1]
+			continue
+		}
+		d.paragraphs[n-1] = paragraph[:len(paragraph)-1]
+		return
+	}
+}
+
+// Empty 文档是否没有内容
+func (d *Document) Empty() bool {
+	for _, paragraph := range d.paragraphs {
+		if len(paragraph) > 0 {
+			return false
+		}
+	}
+	return true
+}
+
+// Text 返回文档全文，段落之间空一行
+func (d *Document) Text() string {
+	parts := make([]string, 0, len(d.paragraphs))
+	for _, paragraph := range d.paragraphs {
+		if len(paragraph) == 0 {
+			continue
+		}
+		var b strings.Builder
+		for i, sentence := range paragraph {
+			if i > 0 && needSpace(paragraph[i-1], sentence) {
+				b.WriteByte(' ')
+			}
+			b.WriteString(sentence)
+		}
+		parts = append(parts, b.String())
+	}
+	return strings.Join(parts, "\n\n")
+}
+
+// needSpace 英文等以空格分词的句子之间需要空格，中文句子直接相连
+func needSpace(prev, next string) bool {
+	last, _ := utf8.DecodeLastRuneInString(prev)
+	first, _ := utf8.DecodeRuneInString(next)
+	return last < utf8.RuneSelf && first < utf8.RuneSelf && !unicode.IsSpace(last) && !unicode.IsSpace(first)
+}
@@ -0,0 +1,47 @@
+package dictation
+
+import "testing"
+
+func TestDocument(t *testing.T) {
+	var d Document
+	inputs := []struct {
+		text    string
+		command Command
+	}{
+		{"今天天气很好。", CommandNone},
+		{"我们去公园吧。", CommandNone},
+		{"新段落。", CommandNewParagraph},
+		{"新段落", CommandNewParagraph},
+		{"写错了一句", CommandNone},
+		{"删除上一句！", CommandDeleteLast},
+		{"Hello world.", CommandNone},
+		{"How are you?", CommandNone},
+		{"请删除上一句中的错字", CommandNone},
+		{"删除上一句", CommandDeleteLast},
+		{"结束听写", CommandFinish},
+	}
+	for _, in := range inputs {
+		if got := d.Apply(in.text); got != in.command {
+			t.Errorf("Apply(%q) = %q, 期望 %q", in.text, got, in.command)
+		}
+	}
+
+	want := "今天天气很好。我们去公园吧。\n\nHello world. How are you?"
+	if got := d.Text(); got != want {
+		t.Errorf("Text() = %q, 期望 %q", got, want)
+	}
+}
+
+func TestDocumentDeleteAcrossParagraph(t *testing.T) {
+	var d Document
+	d.Apply("第一句。")
+	d.Apply("新段落")
+	d.Apply("删除上一句")
+	if !d.Empty() {
+		t.Errorf("Text() = %q, 期望为空", d.Text())
+	}
+	d.Apply("删除上一句")
+	if d.Apply("第二句。"); d.Text() != "第二句。" {
+		t.Errorf("Text() = %q", d.Text())
+	}
+}
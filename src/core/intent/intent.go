@@ -0,0 +1,256 @@
+package intent
+
+import (
+	"fmt"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+
+	"angrymiao-ai-server/src/configs"
+	"angrymiao-ai-server/src/core/utils"
+)
+
+// DefaultThreshold 默认置信度阈值
+const DefaultThreshold = 0.8
+
+// 默认去除的客套词和语气词
+var defaultFillers = []string{"请", "麻烦", "帮我", "给我", "一下", "吧", "呀", "啊", "哦", "呢", "嘛", "好吗"}
+
+// 句式中的槽位，如 {song} 或 {volume:number}
+var slotRegex = regexp.MustCompile(`\{(\w+)(?::(\w+))?\}`)
+
+// 槽位类型对应的正则表达式
+const (
+	slotTypeText   = "text"
+	slotTypeNumber = "number"
+)
+
+var slotPatterns = map[string]string{
+	slotTypeText:   `.+`,
+	slotTypeNumber: `[0-9]+|[零一二两三四五六七八九十百]+`,
+}
+
+// Match 意图识别结果
+type Match struct {
+	Intent     string
+	Tool       string
+	Arguments  map[string]interface{}
+	Reply      string
+	Confidence float64 // 句式覆盖整句的比例
+}
+
+// Engine 本地意图识别引擎，按句式匹配用户文本并提取槽位
+type Engine struct {
+	threshold float64
+	fillers   []string
+	synonyms  []synonym
+	rules     []*rule
+}
+
+type synonym struct {
+	variant   string
+	canonical string
+}
+
+type rule struct {
+	config   configs.IntentRuleConfig
+	patterns []*regexp.Regexp
+	slots    map[string]string // 槽位名 -> 类型
+}
+
+// NewEngine 根据配置创建意图识别引擎
+func NewEngine(config configs.IntentConfig) (*Engine, error) {
+	e := &Engine{threshold: config.Threshold, fillers: config.Fillers}
+	if e.threshold <= 0 {
+		e.threshold = DefaultThreshold
+	}
+	if len(e.fillers) == 0 {
+		e.fillers = defaultFillers
+	}
+	for canonical, variants := range config.Synonyms {
+		for _, variant := range variants {
+			if variant = strings.ToLower(strings.TrimSpace(variant)); variant != "" && variant != canonical {
+				e.synonyms = append(e.synonyms, synonym{variant: variant, canonical: strings.ToLower(canonical)})
+			}
+		}
+	}
+	// 先替换较长的说法，避免被其中较短的说法拆开
+	sort.SliceStable(e.synonyms, func(i, j int) bool {
+		return utf8.RuneCountInString(e.synonyms[i].variant) > utf8.RuneCountInString(e.synonyms[j].variant)
+	})
+
+	for _, rc := range config.Intents {
+		if rc.Tool == "" {
+			return nil, fmt.Errorf("意图 %s 未配置工具", rc.Name)
+		}
+		r := &rule{config: rc, slots: make(map[string]string)}
+		for _, pattern := range rc.Patterns {
+			re, err := e.compile(pattern, r.slots)
+			if err != nil {
+				return nil, fmt.Errorf("意图 %s 的句式 %s 无效: %v", rc.Name, pattern, err)
+			}
+			r.patterns = append(r.patterns, re)
+		}
+		e.rules = append(e.rules, r)
+	}
+	return e, nil
+}
+
+// compile 将句式转换为正则表达式，文字部分与用户文本做相同的归一化
+func (e *Engine) compile(pattern string, slots map[string]string) (*regexp.Regexp, error) {
+	var expr strings.Builder
+	last := 0
+	for _, loc := range slotRegex.FindAllStringSubmatchIndex(pattern, -1) {
+		expr.WriteString(regexp.QuoteMeta(e.normalize(pattern[last:loc[0]])))
+		name, slotType := pattern[loc[2]:loc[3]], slotTypeText
+		if loc[4] >= 0 {
+			slotType = pattern[loc[4]:loc[5]]
+		}
+		slotPattern, ok := slotPatterns[slotType]
+		if !ok {
+			return nil, fmt.Errorf("未知的槽位类型 %s", slotType)
+		}
+		slots[name] = slotType
+		fmt.Fprintf(&expr, "(?P<%s>%s)", name, slotPattern)
+		last = loc[1]
+	}
+	expr.WriteString(regexp.QuoteMeta(e.normalize(pattern[last:])))
+	if expr.Len() == 0 {
+		return nil, fmt.Errorf("句式为空")
+	}
+	re, err := regexp.Compile(expr.String())
+	if err != nil {
+		return nil, err
+	}
+	re.Longest()
+	return re, nil
+}
+
+// normalize 归一化文本：去除标点和空白、统一小写、替换同义词、去除客套词
+func (e *Engine) normalize(text string) string {
+	text = strings.ToLower(utils.RemoveAllPunctuation(text))
+	text = strings.Join(strings.Fields(text), "")
+	for _, s := range e.synonyms {
+		text = strings.ReplaceAll(text, s.variant, s.canonical)
+	}
+	for _, filler := range e.fillers {
+		text = strings.ReplaceAll(text, filler, "")
+	}
+	return text
+}
+
+// Match 识别用户文本的意图，返回置信度最高且不低于阈值的结果
+func (e *Engine) Match(text string) (*Match, bool) {
+	normalized := e.normalize(text)
+	total := utf8.RuneCountInString(normalized)
+	if total == 0 {
+		return nil, false
+	}
+
+	var best *Match
+	for _, r := range e.rules {
+		for _, re := range r.patterns {
+			m := re.FindStringSubmatch(normalized)
+			if m == nil {
+				continue
+			}
+			confidence := float64(utf8.RuneCountInString(m[0])) / float64(total)
+			if confidence < e.threshold || (best != nil && confidence <= best.Confidence) {
+				continue
+			}
+			values := make(map[string]string)
+			for i, name := range re.SubexpNames() {
+				if name != "" {
+					values[name] = m[i]
+				}
+			}
+			args, err := r.arguments(values)
+			if err != nil {
+				continue
+			}
+			best = &Match{
+				Intent:     r.config.Name,
+				Tool:       r.config.Tool,
+				Arguments:  args,
+				Reply:      fill(r.config.Reply, values),
+				Confidence: confidence,
+			}
+		}
+	}
+	return best, best != nil
+}
+
+// arguments 用槽位的值生成工具参数，参数值恰好是数字槽位时转换为数字
+func (r *rule) arguments(values map[string]string) (map[string]interface{}, error) {
+	args := make(map[string]interface{}, len(r.config.Arguments))
+	for key, value := range r.config.Arguments {
+		s, ok := value.(string)
+		if !ok {
+			args[key] = value
+			continue
+		}
+		if m := slotRegex.FindStringSubmatch(s); m != nil && m[0] == s && r.slots[m[1]] == slotTypeNumber {
+			n, ok := ParseNumber(values[m[1]])
+			if !ok {
+				return nil, fmt.Errorf("无法解析数字 %s", values[m[1]])
+			}
+			args[key] = n
+			continue
+		}
+		args[key] = fill(s, values)
+	}
+	return args, nil
+}
+
+// fill 将文本中的 {slot} 替换为槽位的值
+func fill(text string, values map[string]string) string {
+	return slotRegex.ReplaceAllStringFunc(text, func(s string) string {
+		name := slotRegex.FindStringSubmatch(s)[1]
+		if value, ok := values[name]; ok {
+			return value
+		}
+		return s
+	})
+}
+
+// 中文数字
+var chineseDigits = map[rune]int{
+	'零': 0, '一': 1, '二': 2, '两': 2, '三': 3, '四': 4,
+	'五': 5, '六': 6, '七': 7, '八': 8, '九': 9,
+}
+
+// ParseNumber 解析阿拉伯数字或一千以内的中文数字，如 "85"、"八十五"、"一百"
+func ParseNumber(s string) (int, bool) {
+	if n, err := strconv.Atoi(s); err == nil {
+		return n, true
+	}
+	if s == "" {
+		return 0, false
+	}
+	total, current := 0, 0
+	for _, r := range s {
+		switch r {
+		case '十':
+			if current == 0 {
+				current = 1
+			}
+			total += current * 10
+			current = 0
+		case '百':
+			if current == 0 {
+				current = 1
+			}
+			total += current * 100
+			current = 0
+		default:
+			digit, ok := chineseDigits[r]
+			if !ok {
+				return 0, false
+			}
+			current = current*10 + digit
+		}
+	}
+	return total + current, true
+}
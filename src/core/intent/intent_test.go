@@ -0,0 +1,105 @@
+package intent
+
+import (
+	"testing"
+
+	"angrymiao-ai-server/src/configs"
+)
+
+func newTestEngine(t *testing.T) *Engine {
+	engine, err := NewEngine(configs.IntentConfig{
+		Synonyms: map[string][]string{
+			"大声点":  {"声音大点", "大点声", "音量大一点"},
+			"音量调到": {"音量调成", "声音调到"},
+		},
+		Intents: []configs.IntentRuleConfig{
+			{
+				Name:      "volume_up",
+				Tool:      "self.audio_speaker.set_volume",
+				Patterns:  []string{"大声点"},
+				Arguments: map[string]interface{}{"volume": 80},
+				Reply:     "好的",
+			},
+			{
+				Name:      "set_volume",
+				Tool:      "self.audio_speaker.set_volume",
+				Patterns:  []string{"音量调到{volume:number}", "音量调到百分之{volume:number}"},
+				Arguments: map[string]interface{}{"volume": "{volume}"},
+				Reply:     "音量已调到{volume}",
+			},
+			{
+				Name:      "play_music",
+				Tool:      "play_music",
+				Patterns:  []string{"播放{song}", "我想听{song}"},
+				Arguments: map[string]interface{}{"song_name": "{song}"},
+			},
+		},
+	})
+	if err != nil {
+		t.Fatalf("NewEngine() 错误: %v", err)
+	}
+	return engine
+}
+
+func TestMatch(t *testing.T) {
+	engine := newTestEngine(t)
+
+	tests := []struct {
+		name   string
+		text   string
+		intent string
+		args   map[string]interface{}
+	}{
+		{name: "同义词", text: "声音大点！", intent: "volume_up", args: map[string]interface{}{"volume": 80}},
+		{name: "客套词", text: "请帮我大声点吧", intent: "volume_up", args: map[string]interface{}{"volume": 80}},
+		{name: "阿拉伯数字", text: "音量调成60", intent: "set_volume", args: map[string]interface{}{"volume": 60}},
+		{name: "中文数字", text: "声音调到百分之八十五", intent: "set_volume", args: map[string]interface{}{"volume": 85}},
+		{name: "文本槽位", text: "播放两只老虎。", intent: "play_music", args: map[string]interface{}{"song_name": "两只老虎"}},
+		{name: "覆盖率不足", text: "你知道昨天谁播放了什么", intent: ""},
+		{name: "无匹配", text: "今天天气怎么样", intent: ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			match, ok := engine.Match(tt.text)
+			if tt.intent == "" {
+				if ok {
+					t.Errorf("Match(%q) = %+v, 期望不匹配", tt.text, match)
+				}
+				return
+			}
+			if !ok || match.Intent != tt.intent {
+				t.Fatalf("Match(%q) = %+v, 期望意图 %s", tt.text, match, tt.intent)
+			}
+			for key, want := range tt.args {
+				if got := match.Arguments[key]; got != want {
+					t.Errorf("Match(%q) 参数 %s = %v(%T), 期望 %v(%T)", tt.text, key, got, got, want, want)
+				}
+			}
+		})
+	}
+
+	if match, _ := engine.Match("音量调到30"); match.Reply != "音量已调到30" {
+		t.Errorf("Match() 回复 = %q", match.Reply)
+	}
+}
+
+func TestNewEngineInvalid(t *testing.T) {
+	if _, err := NewEngine(configs.IntentConfig{Intents: []configs.IntentRuleConfig{{Name: "x", Tool: "t", Patterns: []string{"{a:date}"}}}}); err == nil {
+		t.Error("NewEngine() 未知槽位类型应返回错误")
+	}
+	if _, err := NewEngine(configs.IntentConfig{Intents: []configs.IntentRuleConfig{{Name: "x", Patterns: []string{"暂停"}}}}); err == nil {
+		t.Error("NewEngine() 未配置工具应返回错误")
+	}
+}
+
+func TestParseNumber(t *testing.T) {
+	for input, want := range map[string]int{"42": 42, "十": 10, "十五": 15, "五十": 50, "八十五": 85, "一百": 100, "一百零五": 105, "两百": 200} {
+		if got, ok := ParseNumber(input); !ok || got != want {
+			t.Errorf("ParseNumber(%q) = %d, %v, 期望 %d", input, got, ok, want)
+		}
+	}
+	if _, ok := ParseNumber("很多"); ok {
+		t.Error("ParseNumber(\"很多\") 应返回false")
+	}
+}
@@ -0,0 +1,249 @@
+package knowledge
+
+import (
+	"strings"
+	"unicode/utf8"
+
+	"angrymiao-ai-server/src/models"
+)
+
+const (
+	DefaultChunkSize    = 500
+	DefaultChunkOverlap = 50
+)
+
+// 超长段落的切分位置
+const sentenceEnds = "。！？；.!?;"
+
+// section 同一标题下的段落
+type section struct {
+	heading    string // 标题路径，如 "产品介绍 > 参数"
+	paragraphs []string
+}
+
+// Split 将文档切分为用于向量检索的文本块，size 和 overlap 按字符计
+// Markdown 按标题划分，文本块不跨越标题，每个文本块以所在的标题路径开头，保证检索到的内容有上下文
+// 超长段落按句子切分，同一标题下相邻的文本块重叠 overlap 个字符
+func Split(text string, size, overlap int) []string {
+	if size <= 0 {
+		size = DefaultChunkSize
+	}
+	if overlap < 0 || overlap >= size/2 {
+		overlap = 0
+	}
+
+	var chunks []string
+	for _, sec := range sections(text) {
+		chunks = append(chunks, packSection(sec, size, overlap)...)
+	}
+	return chunks
+}
+
+// sections 按Markdown标题划分段落，代码块中的 # 不视为标题
+func sections(text string) []section {
+	text = strings.ReplaceAll(text, "\r\n", "\n")
+
+	var (
+		result   []section
+		headings []string // 各级标题，下标为级别减一
+		current  section
+		para     []string
+		inFence  bool
+	)
+	flushPara := func() {
+		if p := strings.TrimSpace(strings.Join(para, "\n")); p != "" {
+			current.paragraphs = append(current.paragraphs, p)
+		}
+		para = nil
+	}
+	flushSection := func() {
+		flushPara()
+		if len(current.paragraphs) > 0 {
+			result = append(result, current)
+		}
+	}
+
+	for _, line := range strings.Split(text, "\n") {
+		trimmed := strings.TrimSpace(line)
+		if strings.HasPrefix(trimmed, "```") {
+			inFence = !inFence
+			para = append(para, line)
+			continue
+		}
+		if inFence {
+			para = append(para, line)
+			continue
+		}
+
+		if level, title := heading(trimmed); level > 0 {
+			flushSection()
+			if level > len(headings) {
+				headings = append(headings, make([]string, level-len(headings))...)
+			}
+			headings = append(headings[:level-1], title)
+			current = section{heading: joinHeadings(headings)}
+			continue
+		}
+		if trimmed == "" {
+			flushPara()
+			continue
+		}
+		para = append(para, trimmed)
+	}
+	flushSection()
+	return result
+}
+
+// heading 解析Markdown标题，返回级别和标题文本，不是标题时级别为0
+func heading(line string) (int, string) {
+	level := 0
+	for level < len(line) && line[level] == '#' {
+		level++
+	}
+	if level == 0 || level > 6 || level == len(line) || line[level] != ' ' {
+		return 0, ""
+	}
+	return level, strings.TrimSpace(strings.TrimRight(line[level:], "#"))
+}
+
+// joinHeadings 拼接非空的各级标题
+func joinHeadings(headings []string) string {
+	parts := make([]string, 0, len(headings))
+	for _, h := range headings {
+		if h != "" {
+			parts = append(parts, h)
+		}
+	}
+	return strings.Join(parts, " > ")
+}
+
+// packSection 将同一标题下的段落合并为不超过 size 个字符的文本块
+func packSection(sec section, size, overlap int) []string {
+	prefix := ""
+	if sec.heading != "" {
+		prefix = sec.heading + "\n"
+	}
+	budget := size - utf8.RuneCountInString(prefix)
+	if budget < size/2 {
+		budget = size / 2
+	}
+
+	// 超长段落切分时为重叠内容留出空间
+	pieceLimit := budget - overlap - 1
+	if pieceLimit < budget/2 {
+		pieceLimit = budget
+	}
+	var pieces []string
+	for _, p := range sec.paragraphs {
+		if utf8.RuneCountInString(p) > budget {
+			pieces = append(pieces, splitLong(p, pieceLimit)...)
+		} else {
+			pieces = append(pieces, p)
+		}
+	}
+
+	var chunks []string
+	current := ""
+	for _, piece := range pieces {
+		if current != "" && utf8.RuneCountInString(current)+1+utf8.RuneCountInString(piece) > budget {
+			chunks = append(chunks, prefix+current)
+			current = tail(current, overlap)
+			if utf8.RuneCountInString(current)+1+utf8.RuneCountInString(piece) > budget {
+				current = ""
+			}
+		}
+		if current == "" {
+			current = piece
+		} else {
+			current += "\n" + piece
+		}
+	}
+	if current != "" {
+		chunks = append(chunks, prefix+current)
+	}
+	return chunks
+}
+
+// splitLong 将超过 limit 个字符的段落按句子切分，单个句子仍超长时按字符截断
+func splitLong(paragraph string, limit int) []string {
+	if utf8.RuneCountInString(paragraph) <= limit {
+		return []string{paragraph}
+	}
+
+	var (
+		pieces   []string
+		current  []rune
+		sentence []rune
+	)
+	flush := func() {
+		if s := strings.TrimSpace(string(current)); s != "" {
+			pieces = append(pieces, s)
+		}
+		current = current[:0]
+	}
+	addSentence := func() {
+		for len(sentence) > limit {
+			flush()
+			pieces = append(pieces, string(sentence[:limit]))
+			sentence = sentence[limit:]
+		}
+		if len(current)+len(sentence) > limit {
+			flush()
+		}
+		current = append(current, sentence...)
+		sentence = sentence[:0]
+	}
+
+	for _, r := range paragraph {
+		sentence = append(sentence, r)
+		if r == '\n' || strings.ContainsRune(sentenceEnds, r) {
+			addSentence()
+		}
+	}
+	addSentence()
+	flush()
+	return pieces
+}
+
+// tail 返回文本末尾的 n 个字符
+func tail(text string, n int) string {
+	if n <= 0 {
+		return ""
+	}
+	runes := []rune(text)
+	if len(runes) <= n {
+		return text
+	}
+	return string(runes[len(runes)-n:])
+}
+
+// Prepare 按文档格式整理文本，PDF提取的文本按行折断，合并为段落后再切分
+func Prepare(format, text string) string {
+	text = strings.ReplaceAll(text, "\r\n", "\n")
+	if format != models.KnowledgeFormatPDF {
+		return text
+	}
+
+	text = strings.ReplaceAll(text, "\f", "\n\n")
+	paragraphs := strings.Split(text, "\n\n")
+	for i, p := range paragraphs {
+		var b strings.Builder
+		for _, line := range strings.Split(p, "\n") {
+			line = strings.TrimSpace(line)
+			if line == "" {
+				continue
+			}
+			// 英文单词之间补空格，中文直接相连
+			if b.Len() > 0 {
+				last, _ := utf8.DecodeLastRuneInString(b.String())
+				first, _ := utf8.DecodeRuneInString(line)
+				if last < utf8.RuneSelf && first < utf8.RuneSelf {
+					b.WriteByte(' ')
+				}
+			}
+			b.WriteString(line)
+		}
+		paragraphs[i] = b.String()
+	}
+	return strings.Join(paragraphs, "\n\n")
+}
@@ -0,0 +1,174 @@
+package knowledge
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+
+	"angrymiao-ai-server/src/configs"
+
+	"github.com/sashabaranov/go-openai"
+)
+
+const (
+	defaultBatchSize     = 10
+	defaultEmbedTimeout  = 30 * time.Second
+	defaultOllamaBaseURL = "http://localhost:11434"
+)
+
+// Embedder 文本向量化接口
+type Embedder interface {
+	// Embed 为每段文本生成向量，返回顺序与输入一致
+	Embed(ctx context.Context, texts []string) ([][]float32, error)
+	// Model 向量模型名称，更换模型后已有的向量不再可比
+	Model() string
+}
+
+// NewEmbedder 根据配置创建向量化客户端
+func NewEmbedder(cfg configs.KnowledgeEmbeddingConfig) (Embedder, error) {
+	if cfg.Model == "" {
+		return nil, fmt.Errorf("未配置向量模型")
+	}
+	if cfg.BatchSize <= 0 {
+		cfg.BatchSize = defaultBatchSize
+	}
+	timeout := defaultEmbedTimeout
+	if cfg.Timeout > 0 {
+		timeout = time.Duration(cfg.Timeout) * time.Second
+	}
+	httpClient := &http.Client{Timeout: timeout}
+
+	switch strings.ToLower(cfg.Type) {
+	case "", "openai":
+		clientConfig := openai.DefaultConfig(cfg.APIKey)
+		if cfg.BaseURL != "" {
+			clientConfig.BaseURL = cfg.BaseURL
+		}
+		clientConfig.HTTPClient = httpClient
+		return &openaiEmbedder{
+			client: openai.NewClientWithConfig(clientConfig),
+			config: cfg,
+		}, nil
+	case "ollama":
+		if cfg.BaseURL == "" {
+			cfg.BaseURL = defaultOllamaBaseURL
+		}
+		cfg.BaseURL = strings.TrimSuffix(strings.TrimSuffix(cfg.BaseURL, "/"), "/v1")
+		return &ollamaEmbedder{
+			client: httpClient,
+			config: cfg,
+		}, nil
+	default:
+		return nil, fmt.Errorf("不支持的向量模型类型: %s", cfg.Type)
+	}
+}
+
+// embedInBatches 按批次调用向量化接口
+func embedInBatches(ctx context.Context, texts []string, batchSize int, embed func(context.Context, []string) ([][]float32, error)) ([][]float32, error) {
+	vectors := make([][]float32, 0, len(texts))
+	for start := 0; start < len(texts); start += batchSize {
+		end := min(start+batchSize, len(texts))
+		batch, err := embed(ctx, texts[start:end])
+		if err != nil {
+			return nil, err
+		}
+		if len(batch) != end-start {
+			return nil, fmt.Errorf("向量数量不匹配: 期望 %d，实际 %d", end-start, len(batch))
+		}
+		vectors = append(vectors, batch...)
+	}
+	return vectors, nil
+}
+
+// openaiEmbedder 兼容OpenAI /embeddings 接口的向量化客户端
+type openaiEmbedder struct {
+	client *openai.Client
+	config configs.KnowledgeEmbeddingConfig
+}
+
+func (e *openaiEmbedder) Model() string {
+	return e.config.Model
+}
+
+func (e *openaiEmbedder) Embed(ctx context.Context, texts []string) ([][]float32, error) {
+	return embedInBatches(ctx, texts, e.config.BatchSize, func(ctx context.Context, batch []string) ([][]float32, error) {
+		resp, err := e.client.CreateEmbeddings(ctx, openai.EmbeddingRequestStrings{
+			Input:      batch,
+			Model:      openai.EmbeddingModel(e.config.Model),
+			Dimensions: e.config.Dimensions,
+		})
+		if err != nil {
+			return nil, fmt.Errorf("生成向量失败: %v", err)
+		}
+		vectors := make([][]float32, len(batch))
+		for _, item := range resp.Data {
+			if item.Index < 0 || item.Index >= len(vectors) {
+				return nil, fmt.Errorf("向量序号越界: %d", item.Index)
+			}
+			vectors[item.Index] = item.Embedding
+		}
+		return vectors, nil
+	})
+}
+
+// ollamaEmbedder Ollama /api/embed 接口的向量化客户端
+type ollamaEmbedder struct {
+	client *http.Client
+	config configs.KnowledgeEmbeddingConfig
+}
+
+type ollamaEmbedRequest struct {
+	Model      string   `json:"model"`
+	Input      []string `json:"input"`
+	Dimensions int      `json:"dimensions,omitempty"`
+}
+
+type ollamaEmbedResponse struct {
+	Embeddings [][]float32 `json:"embeddings"`
+}
+
+func (e *ollamaEmbedder) Model() string {
+	return e.config.Model
+}
+
+func (e *ollamaEmbedder) Embed(ctx context.Context, texts []string) ([][]float32, error) {
+	return embedInBatches(ctx, texts, e.config.BatchSize, func(ctx context.Context, batch []string) ([][]float32, error) {
+		body, err := json.Marshal(ollamaEmbedRequest{
+			Model:      e.config.Model,
+			Input:      batch,
+			Dimensions: e.config.Dimensions,
+		})
+		if err != nil {
+			return nil, err
+		}
+		req, err := http.NewRequestWithContext(ctx, http.MethodPost, e.config.BaseURL+"/api/embed", bytes.NewReader(body))
+		if err != nil {
+			return nil, err
+		}
+		req.Header.Set("Content-Type", "application/json")
+
+		resp, err := e.client.Do(req)
+		if err != nil {
+			return nil, fmt.Errorf("生成向量失败: %v", err)
+		}
+		defer resp.Body.Close()
+		data, err := io.ReadAll(resp.Body)
+		if err != nil {
+			return nil, err
+		}
+
+		if resp.StatusCode != http.StatusOK {
+			return nil, fmt.Errorf("生成向量失败: HTTP %d %s", resp.StatusCode, strings.TrimSpace(string(data)))
+		}
+		var result ollamaEmbedResponse
+		if err := json.Unmarshal(data, &result); err != nil {
+			return nil, fmt.Errorf("解析向量响应失败: %v", err)
+		}
+		return result.Embeddings, nil
+	})
+}
@@ -0,0 +1,88 @@
+package knowledge
+
+import (
+	"math"
+	"strings"
+	"testing"
+	"unicode/utf8"
+)
+
+func TestSplitMarkdown(t *testing.T) {
+	doc := "# 怒喵键盘\n\n简介段落。\n\n## 参数\n\n重量 800 克。\n续航 30 天。\n\n```\n# 不是标题\n```\n\n## 保修\n\n整机保修一年。"
+	chunks := Split(doc, 100, 0)
+	want := []string{
+		"怒喵键盘\n简介段落。",
+		"怒喵键盘 > 参数\n重量 800 克。\n续航 30 天。\n```\n# 不是标题\n```",
+		"怒喵键盘 > 保修\n整机保修一年。",
+	}
+	if len(chunks) != len(want) {
+		t.Fatalf("Split() = %q, 期望 %d 块", chunks, len(want))
+	}
+	for i := range want {
+		if chunks[i] != want[i] {
+			t.Errorf("Split()[%d] = %q, 期望 %q", i, chunks[i], want[i])
+		}
+	}
+}
+
+func TestSplitLongParagraph(t *testing.T) {
+	paragraph := strings.Repeat("这是一句很长的产品说明。", 20)
+	chunks := Split(paragraph, 50, 10)
+	if len(chunks) < 5 {
+		t.Fatalf("Split() 块数 = %d, 期望超长段落被切分", len(chunks))
+	}
+	for i, chunk := range chunks {
+		if n := utf8.RuneCountInString(chunk); n > 50 {
+			t.Errorf("Split()[%d] 长度 = %d, 超过 50", i, n)
+		}
+	}
+	// 相邻块重叠
+	if !strings.HasPrefix(chunks[1], tail(chunks[0], 10)) {
+		t.Errorf("Split() 相邻块未重叠: %q %q", chunks[0], chunks[1])
+	}
+
+	hard := Split(strings.Repeat("无标点", 40), 50, 0)
+	for i, chunk := range hard {
+		if n := utf8.RuneCountInString(chunk); n > 50 {
+			t.Errorf("Split() 无标点[%d] 长度 = %d, 超过 50", i, n)
+		}
+	}
+
+	if got := Split(" \n\n ", 50, 0); len(got) != 0 {
+		t.Errorf("Split() 空文档 = %q", got)
+	}
+}
+
+func TestPreparePDF(t *testing.T) {
+	text := "怒喵键盘采用\n铝合金外壳。\nThe keyboard\nsupports Bluetooth.\f第二页"
+	want := "怒喵键盘采用铝合金外壳。The keyboard supports Bluetooth.\n\n第二页"
+	if got := Prepare("pdf", text); got != want {
+		t.Errorf("Prepare() = %q, 期望 %q", got, want)
+	}
+	if got := Prepare("markdown", "a\r\nb"); got != "a\nb" {
+		t.Errorf("Prepare() markdown = %q", got)
+	}
+}
+
+func TestVector(t *testing.T) {
+	v := []float32{0.5, -1.25, 3}
+	decoded := DecodeVector(EncodeVector(v))
+	if len(decoded) != len(v) {
+		t.Fatalf("DecodeVector() = %v", decoded)
+	}
+	for i := range v {
+		if decoded[i] != v[i] {
+			t.Errorf("DecodeVector()[%d] = %v, 期望 %v", i, decoded[i], v[i])
+		}
+	}
+
+	if got := Cosine([]float32{1, 0}, []float32{2, 0}); math.Abs(got-1) > 1e-9 {
+		t.Errorf("Cosine() 同向 = %v", got)
+	}
+	if got := Cosine([]float32{1, 0}, []float32{0, 1}); math.Abs(got) > 1e-9 {
+		t.Errorf("Cosine() 正交 = %v", got)
+	}
+	if got := Cosine([]float32{1, 0}, []float32{1, 0, 0}); got != 0 {
+		t.Errorf("Cosine() 维度不同 = %v", got)
+	}
+}
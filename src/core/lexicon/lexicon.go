@@ -0,0 +1,174 @@
+// Package lexicon 提供发音词典替换，在TTS合成前将品牌名、产品名等替换为音标或别名
+package lexicon
+
+import (
+	"html"
+	"regexp"
+	"sort"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// Entry 发音词条
+type Entry struct {
+	Word     string // 原词
+	Alias    string // 替换读法，不支持SSML时直接替换原词
+	Phoneme  string // 音标，支持SSML时使用<phoneme>标签
+	Alphabet string // 音标字母表，如 py、ipa
+}
+
+// Segment 文本片段，Entry非空表示该片段命中词条
+type Segment struct {
+	Text  string
+	Entry *Entry
+}
+
+// Lexicon 编译后的发音词典
+type Lexicon struct {
+	re      *regexp.Regexp
+	entries map[string]*Entry // key为小写原词
+}
+
+// New 编译发音词典，原词按长度降序匹配，英文不区分大小写
+func New(entries []Entry) *Lexicon {
+	lex := &Lexicon{entries: make(map[string]*Entry)}
+
+	words := make([]string, 0, len(entries))
+	for i := range entries {
+		e := entries[i]
+		if e.Word == "" || (e.Alias == "" && e.Phoneme == "") {
+			continue
+		}
+		key := strings.ToLower(e.Word)
+		if _, exists := lex.entries[key]; !exists {
+			words = append(words, regexp.QuoteMeta(e.Word))
+		}
+		lex.entries[key] = &e
+	}
+	if len(words) == 0 {
+		return lex
+	}
+
+	sort.Slice(words, func(i, j int) bool {
+		return len(words[i]) > len(words[j])
+	})
+	lex.re = regexp.MustCompile(`(?i)` + strings.Join(words, "|"))
+	return lex
+}
+
+// Empty 是否没有任何词条
+func (l *Lexicon) Empty() bool {
+	return l == nil || l.re == nil
+}
+
+// Split 将文本拆分为普通片段和命中词条的片段
+func (l *Lexicon) Split(text string) []Segment {
+	if l.Empty() {
+		return []Segment{{Text: text}}
+	}
+
+	var segments []Segment
+	last := 0
+	for _, loc := range l.re.FindAllStringIndex(text, -1) {
+		start, end := loc[0], loc[1]
+		// 英文词条需要完整单词匹配，避免替换单词的一部分
+		if !wordBoundary(text, start, end) {
+			continue
+		}
+		if start > last {
+			segments = append(segments, Segment{Text: text[last:start]})
+		}
+		segments = append(segments, Segment{
+			Text:  text[start:end],
+			Entry: l.entries[strings.ToLower(text[start:end])],
+		})
+		last = end
+	}
+	if last < len(text) {
+		segments = append(segments, Segment{Text: text[last:]})
+	}
+	return segments
+}
+
+// Render 渲染为TTS输入文本
+// ssml为true时输出SSML，词条使用<phoneme>或<sub>标签；否则使用别名替换
+// normalize 用于处理普通片段和别名，可为nil
+func Render(segments []Segment, ssml bool, normalize func(string) string) string {
+	if normalize == nil {
+		normalize = func(s string) string { return s }
+	}
+
+	hit := false
+	for _, seg := range segments {
+		if seg.Entry != nil {
+			hit = true
+			break
+		}
+	}
+	// 未命中词条时无需切换为SSML
+	ssml = ssml && hit
+
+	var sb strings.Builder
+	if ssml {
+		sb.WriteString("<speak>")
+	}
+	for _, seg := range segments {
+		switch {
+		case seg.Entry == nil:
+			if ssml {
+				sb.WriteString(html.EscapeString(normalize(seg.Text)))
+			} else {
+				sb.WriteString(normalize(seg.Text))
+			}
+		case ssml && seg.Entry.Phoneme != "":
+			sb.WriteString(`<phoneme`)
+			if seg.Entry.Alphabet != "" {
+				sb.WriteString(` alphabet="` + html.EscapeString(seg.Entry.Alphabet) + `"`)
+			}
+			sb.WriteString(` ph="` + html.EscapeString(seg.Entry.Phoneme) + `">`)
+			sb.WriteString(html.EscapeString(seg.Text))
+			sb.WriteString(`</phoneme>`)
+		case ssml:
+			sb.WriteString(`<sub alias="` + html.EscapeString(normalize(seg.Entry.Alias)) + `">`)
+			sb.WriteString(html.EscapeString(seg.Text))
+			sb.WriteString(`</sub>`)
+		case seg.Entry.Alias != "":
+			sb.WriteString(normalize(seg.Entry.Alias))
+		default:
+			// 仅配置了音标且不支持SSML时保留原词
+			sb.WriteString(seg.Text)
+		}
+	}
+	if ssml {
+		sb.WriteString("</speak>")
+	}
+	return sb.String()
+}
+
+// Apply 对文本应用发音词典
+func (l *Lexicon) Apply(text string, ssml bool, normalize func(string) string) string {
+	return Render(l.Split(text), ssml, normalize)
+}
+
+func wordBoundary(text string, start, end int) bool {
+	first, _ := utf8.DecodeRuneInString(text[start:end])
+	lastRune, _ := utf8.DecodeLastRuneInString(text[start:end])
+	if start > 0 && isASCIIWord(first) {
+		prev, _ := utf8.DecodeLastRuneInString(text[:start])
+		if isASCIIWord(prev) {
+			return false
+		}
+	}
+	if end < len(text) && isASCIIWord(lastRune) {
+		next, _ := utf8.DecodeRuneInString(text[end:])
+		if isASCIIWord(next) {
+			return false
+		}
+	}
+	return true
+}
+
+func isASCIIWord(r rune) bool {
+	return r < utf8.RuneSelf && (unicode.IsLetter(r) || unicode.IsDigit(r))
+}
@@ -0,0 +1,64 @@
+package lexicon
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestApplyAlias(t *testing.T) {
+	lex := New([]Entry{
+		{Word: "AngryMiao", Alias: "怒喵"},
+		{Word: "AM", Alias: "诶姆"},
+	})
+
+	tests := []struct {
+		name     string
+		input    string
+		expected string
+	}{
+		{"别名替换", "欢迎使用AngryMiao键盘", "欢迎使用怒喵键盘"},
+		{"不区分大小写", "angrymiao", "怒喵"},
+		{"完整单词匹配", "AMX和AM", "AMX和诶姆"},
+		{"未命中", "你好", "你好"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := lex.Apply(tt.input, false, nil); got != tt.expected {
+				t.Errorf("Apply(%q) = %q, 期望 %q", tt.input, got, tt.expected)
+			}
+		})
+	}
+}
+
+func TestApplySSML(t *testing.T) {
+	lex := New([]Entry{
+		{Word: "重庆", Phoneme: "chong2 qing4", Alphabet: "py"},
+		{Word: "AngryMiao", Alias: "怒喵"},
+	})
+
+	got := lex.Apply("重庆的AngryMiao & 朋友", true, strings.ToUpper)
+	expected := `<speak><phoneme alphabet="py" ph="chong2 qing4">重庆</phoneme>的<sub alias="怒喵">AngryMiao</sub> &amp; 朋友</speak>`
+	if got != expected {
+		t.Errorf("Apply() = %q, 期望 %q", got, expected)
+	}
+
+	// 未命中词条时不输出SSML
+	if got := lex.Apply("hello", true, nil); got != "hello" {
+		t.Errorf("未命中时应原样返回, got %q", got)
+	}
+
+	// 不支持SSML且没有别名时保留原词
+	if got := lex.Apply("重庆", false, nil); got != "重庆" {
+		t.Errorf("仅音标词条应保留原词, got %q", got)
+	}
+}
+
+func TestEmptyLexicon(t *testing.T) {
+	lex := New(nil)
+	if !lex.Empty() {
+		t.Fatal("期望空词典")
+	}
+	if got := lex.Apply("text", true, nil); got != "text" {
+		t.Errorf("空词典应原样返回, got %q", got)
+	}
+}
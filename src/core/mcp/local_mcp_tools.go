@@ -63,21 +63,19 @@ func (c *LocalClient) AddToolTime() error {
 	return nil
 }
 
+// AddToolChangeRole 注册角色切换工具
+// 数据库中的角色在连接建立后由连接处理器更新到工具描述中，这里只列出配置的角色
 func (c *LocalClient) AddToolChangeRole() error {
-	roles := c.cfg.Roles
 	prompts := map[string]string{}
-	roleNames := ""
-	if roles == nil {
-		c.logger.Warn(
-			"AddToolChangeRole: roles settings is nil or empty, Skipping tool registration",
-		)
-		return nil
-	} else {
-		for _, role := range roles {
-			items := strings.Split(role, "@")
-			prompts[items[0]] = items[1]
-			roleNames += items[0] + ", "
+	roleNames := make([]string, 0, len(c.cfg.Roles))
+	for _, role := range c.cfg.Roles {
+		name, text, ok := strings.Cut(role, "@")
+		if !ok {
+			c.logger.Warn("AddToolChangeRole: invalid role setting: %s", role)
+			continue
 		}
+		prompts[name] = text
+		roleNames = append(roleNames, name)
 	}
 
 	InputSchema := ToolInputSchema{
@@ -92,10 +90,10 @@ func (c *LocalClient) AddToolChangeRole() error {
 	}
 
 	c.AddTool("change_role",
-		"当用户想切换角色/模型性格/助手名字时调用,可选的角色有：["+roleNames+"]",
+		ChangeRoleDescription(roleNames),
 		InputSchema,
 		func(ctx context.Context, args map[string]any) (interface{}, error) {
-			role := args["role"].(string)
+			role, _ := args["role"].(string)
 			res := types.ActionResponse{
 				Action: types.ActionTypeCallHandler, // 动作类型
 				Result: types.ActionResponseCall{
@@ -112,6 +110,11 @@ func (c *LocalClient) AddToolChangeRole() error {
 	return nil
 }
 
+// ChangeRoleDescription 生成角色切换工具的描述
+func ChangeRoleDescription(roleNames []string) string {
+	return "当用户想切换角色/模型性格/助手名字时调用,可选的角色有：[" + strings.Join(roleNames, ", ") + "]"
+}
+
 func (c *LocalClient) AddToolChangeVoice() error {
 	voices := []configs.VoiceInfo{}
 	if ttsType, ok := c.cfg.SelectedModule["TTS"]; ok && ttsType != "" {
@@ -0,0 +1,148 @@
+package meeting
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"angrymiao-ai-server/src/core/textnorm"
+)
+
+// DefaultChunkRunes 每次请求LLM的会议记录最大字符数
+const DefaultChunkRunes = 6000
+
+// DefaultChunkPrompt 分段提炼要点的默认提示词
+const DefaultChunkPrompt = `下面是一场会议记录的一部分，每行开头是相对会议开始的时间。
+请提炼这部分的讨论要点、已做出的决定和待办事项（包括负责人和截止时间，如有提及），使用简洁的中文条目，不要编造记录中没有的内容。`
+
+// DefaultSummaryPrompt 生成会议纪要的默认提示词
+const DefaultSummaryPrompt = `你是会议助理，请根据会议记录或各部分的要点生成会议纪要，包括：会议主题、主要讨论内容、结论和决定、待办事项（包括负责人和截止时间，如有提及）。
+纪要会被朗读给与会者，请使用简洁的中文短句，每条单独一行，不要使用表格和Markdown符号，不要编造记录中没有的内容。`
+
+// Utterance 会议中的一句话
+type Utterance struct {
+	At     time.Time     `json:"at"`
+	Offset time.Duration `json:"offset"` // 相对会议开始的时间
+	Text   string        `json:"text"`
+}
+
+// Transcript 会议记录
+type Transcript struct {
+	StartedAt  time.Time
+	Utterances []Utterance
+}
+
+// NewTranscript 创建会议记录
+func NewTranscript(startedAt time.Time) *Transcript {
+	return &Transcript{StartedAt: startedAt}
+}
+
+// Add 记录一句话
+func (t *Transcript) Add(at time.Time, text string) Utterance {
+	u := Utterance{At: at, Offset: at.Sub(t.StartedAt), Text: strings.TrimSpace(text)}
+	t.Utterances = append(t.Utterances, u)
+	return u
+}
+
+// Lines 返回带时间戳的记录行，如 "[00:12:05] 下周一上线"
+func (t *Transcript) Lines() []string {
+	lines := make([]string, 0, len(t.Utterances))
+	for _, u := range t.Utterances {
+		lines = append(lines, fmt.Sprintf("[%s] %s", FormatOffset(u.Offset), u.Text))
+	}
+	return lines
+}
+
+// Text 返回完整的会议记录文本
+func (t *Transcript) Text() string {
+	return strings.Join(t.Lines(), "\n")
+}
+
+// FormatOffset 将相对时间格式化为 时:分:秒
+func FormatOffset(d time.Duration) string {
+	if d < 0 {
+		d = 0
+	}
+	seconds := int(d / time.Second)
+	return fmt.Sprintf("%02d:%02d:%02d", seconds/3600, seconds/60%60, seconds%60)
+}
+
+// Chunk 将文本行按字符数分组，单行超过上限时按断句拆开
+func Chunk(lines []string, maxRunes int) []string {
+	if maxRunes <= 0 {
+		maxRunes = DefaultChunkRunes
+	}
+	var chunks []string
+	var current strings.Builder
+	currentRunes := 0
+	flush := func() {
+		if currentRunes > 0 {
+			chunks = append(chunks, current.String())
+			current.Reset()
+			currentRunes = 0
+		}
+	}
+	for _, line := range lines {
+		for _, part := range textnorm.SplitByRunes(line, maxRunes) {
+			n := utf8.RuneCountInString(part)
+			if currentRunes > 0 && currentRunes+1+n > maxRunes {
+				flush()
+			}
+			if currentRunes > 0 {
+				current.WriteByte('\n')
+				currentRunes++
+			}
+			current.WriteString(part)
+			currentRunes += n
+		}
+	}
+	flush()
+	return chunks
+}
+
+// CompleteFunc 用系统提示词和用户内容请求一次LLM
+type CompleteFunc func(ctx context.Context, system, user string) (string, error)
+
+// Summarize 分段总结会议记录：记录超过上限时先逐段提炼要点，要点仍超过上限时继续合并提炼，最后生成纪要
+func Summarize(ctx context.Context, lines []string, maxRunes int, chunkPrompt, summaryPrompt string, complete CompleteFunc) (string, error) {
+	if chunkPrompt == "" {
+		chunkPrompt = DefaultChunkPrompt
+	}
+	if summaryPrompt == "" {
+		summaryPrompt = DefaultSummaryPrompt
+	}
+
+	chunks := Chunk(lines, maxRunes)
+	if len(chunks) == 0 {
+		return "", fmt.Errorf("会议记录为空")
+	}
+	for len(chunks) > 1 {
+		notes := make([]string, 0, len(chunks))
+		for i, chunk := range chunks {
+			note, err := complete(ctx, chunkPrompt, fmt.Sprintf("第%d/%d部分：\n%s", i+1, len(chunks), chunk))
+			if err != nil {
+				return "", fmt.Errorf("提炼第%d部分要点失败: %v", i+1, err)
+			}
+			notes = append(notes, strings.TrimSpace(note))
+		}
+		next := Chunk(notes, maxRunes)
+		// 要点无法再压缩时直接合并，避免无限循环
+		if len(next) >= len(chunks) {
+			chunks = []string{strings.Join(notes, "\n")}
+			break
+		}
+		chunks = next
+	}
+
+	summary, err := complete(ctx, summaryPrompt, chunks[0])
+	if err != nil {
+		return "", err
+	}
+	summary = strings.TrimSpace(summary)
+	if summary == "" {
+		return "", fmt.Errorf("会议纪要为空")
+	}
+	return summary, nil
+}
@@ -0,0 +1,70 @@
+package meeting
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+	"unicode/utf8"
+)
+
+func TestTranscript(t *testing.T) {
+	start := time.Date(2026, 1, 1, 9, 0, 0, 0, time.Local)
+	tr := NewTranscript(start)
+	tr.Add(start.Add(5*time.Second), " 开始开会 ")
+	tr.Add(start.Add(time.Hour+2*time.Minute+3*time.Second), "散会")
+
+	want := "[00:00:05] 开始开会\n[01:02:03] 散会"
+	if got := tr.Text(); got != want {
+		t.Errorf("Text() = %q, 期望 %q", got, want)
+	}
+}
+
+func TestChunk(t *testing.T) {
+	lines := []string{"一二三四五", "六七八九十", "甲乙丙", strings.Repeat("长", 25)}
+	chunks := Chunk(lines, 12)
+	for _, chunk := range chunks {
+		if n := utf8.RuneCountInString(chunk); n > 12 {
+			t.Errorf("Chunk() 分段 %q 长度 %d 超过上限", chunk, n)
+		}
+	}
+	if chunks[0] != "一二三四五\n六七八九十" || chunks[1] != "甲乙丙" {
+		t.Errorf("Chunk() = %q", chunks)
+	}
+	if got := strings.Join(chunks[2:], ""); got != strings.Repeat("长", 25) {
+		t.Errorf("Chunk() 拆分长行 = %q", chunks[2:])
+	}
+}
+
+func TestSummarize(t *testing.T) {
+	lines := make([]string, 20)
+	for i := range lines {
+		lines[i] = strings.Repeat("话", 9)
+	}
+
+	var chunkCalls, summaryCalls int
+	complete := func(_ context.Context, system, user string) (string, error) {
+		if system == DefaultSummaryPrompt {
+			summaryCalls++
+			return " 纪要 ", nil
+		}
+		chunkCalls++
+		return strings.Repeat("点", 12), nil
+	}
+
+	summary, err := Summarize(context.Background(), lines, 30, "", "", complete)
+	if err != nil {
+		t.Fatalf("Summarize() 错误: %v", err)
+	}
+	if summary != "纪要" || summaryCalls != 1 {
+		t.Errorf("Summarize() = %q, 纪要请求 %d 次", summary, summaryCalls)
+	}
+	// 20行每段3行共7段，要点每段2条，依次合并为4段、2段、1段后生成纪要
+	if chunkCalls != 13 {
+		t.Errorf("Summarize() 提炼请求 %d 次, 期望 13", chunkCalls)
+	}
+
+	if _, err := Summarize(context.Background(), nil, 30, "", "", complete); err == nil {
+		t.Error("Summarize() 空记录应返回错误")
+	}
+}
@@ -0,0 +1,105 @@
+package memory
+
+import (
+	"sort"
+	"strings"
+	"unicode"
+
+	"angrymiao-ai-server/src/models"
+)
+
+// 记忆被视为与查询相关的最低相似度
+const minRelevance = 0.1
+
+// Rank 按与查询的相关度排序记忆，返回最多limit条
+// 个人信息类记忆始终保留，其余记忆只返回与查询相关的
+func Rank(query string, memories []*models.UserMemory, limit int) []*models.UserMemory {
+	if limit <= 0 || len(memories) == 0 {
+		return nil
+	}
+
+	queryTokens := tokenize(query)
+	type scored struct {
+		memory *models.UserMemory
+		score  float64
+	}
+	var candidates []scored
+	for _, m := range memories {
+		relevance := similarity(queryTokens, tokenize(m.Content))
+		if m.Category != models.MemoryCategoryProfile && relevance < minRelevance {
+			continue
+		}
+		score := relevance + 0.05*float64(m.Importance)
+		if m.Category == models.MemoryCategoryProfile {
+			score += 1
+		}
+		candidates = append(candidates, scored{m, score})
+	}
+
+	sort.SliceStable(candidates, func(i, j int) bool {
+		return candidates[i].score > candidates[j].score
+	})
+	if len(candidates) > limit {
+		candidates = candidates[:limit]
+	}
+
+	result := make([]*models.UserMemory, len(candidates))
+	for i, c := range candidates {
+		result[i] = c.memory
+	}
+	return result
+}
+
+// similarity 查询词元被记忆覆盖的比例
+func similarity(query, content map[string]bool) float64 {
+	if len(query) == 0 {
+		return 0
+	}
+	hits := 0
+	for token := range query {
+		if content[token] {
+			hits++
+		}
+	}
+	return float64(hits) / float64(len(query))
+}
+
+// tokenize 切分词元：中文按相邻两字切分，其他文字按单词切分
+func tokenize(text string) map[string]bool {
+	tokens := make(map[string]bool)
+	var word []rune
+	var han []rune
+
+	flushWord := func() {
+		if len(word) > 1 {
+			tokens[string(word)] = true
+		}
+		word = word[:0]
+	}
+	flushHan := func() {
+		if len(han) == 1 {
+			tokens[string(han)] = true
+		}
+		for i := 0; i+1 < len(han); i++ {
+			tokens[string(han[i:i+2])] = true
+		}
+		han = han[:0]
+	}
+
+	for _, r := range strings.ToLower(text) {
+		switch {
+		case unicode.Is(unicode.Han, r):
+			flushWord()
+			han = append(han, r)
+		case unicode.IsLetter(r) || unicode.IsDigit(r):
+			flushHan()
+			word = append(word, r)
+		default:
+			flushWord()
+			flushHan()
+		}
+	}
+	flushWord()
+	flushHan()
+	return tokens
+}
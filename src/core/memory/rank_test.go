@@ -0,0 +1,52 @@
+package memory
+
+import (
+	"testing"
+
+	"angrymiao-ai-server/src/models"
+)
+
+func TestRank(t *testing.T) {
+	memories := []*models.UserMemory{
+		{ID: 1, Category: models.MemoryCategoryPreference, Content: "用户喜欢周杰伦的歌", Importance: 3},
+		{ID: 2, Category: models.MemoryCategoryFact, Content: "用户下周要去上海出差", Importance: 2},
+		{ID: 3, Category: models.MemoryCategoryProfile, Content: "用户叫小明", Importance: 5},
+		{ID: 4, Category: models.MemoryCategoryPreference, Content: "用户不吃辣", Importance: 2},
+	}
+
+	got := Rank("放一首周杰伦的歌", memories, 5)
+	if len(got) != 2 || got[0].ID != 3 || got[1].ID != 1 {
+		t.Errorf("Rank() = %v, 期望个人信息和周杰伦相关记忆", ids(got))
+	}
+
+	got = Rank("上海明天天气怎么样", memories, 1)
+	if len(got) != 1 || got[0].ID != 3 {
+		t.Errorf("Rank() limit=1 = %v", ids(got))
+	}
+
+	if got := Rank("你好", nil, 5); len(got) != 0 {
+		t.Errorf("Rank() 空记忆 = %v", ids(got))
+	}
+}
+
+func TestParseMemories(t *testing.T) {
+	output := "```json\n[{\"category\":\"preference\",\"content\":\"用户喜欢猫\",\"importance\":3},{\"category\":\"other\",\"content\":\"用户养了一只猫\"},{\"content\":\"\"}]\n```"
+	memories, err := parseMemories(output)
+	if err != nil {
+		t.Fatalf("parseMemories() 错误: %v", err)
+	}
+	if len(memories) != 2 || memories[0].Category != models.MemoryCategoryPreference || memories[1].Category != models.MemoryCategoryFact {
+		t.Errorf("parseMemories() = %+v", memories)
+	}
+	if _, err := parseMemories("没有可提取的信息"); err == nil {
+		t.Error("parseMemories() 应返回错误")
+	}
+}
+
+func ids(memories []*models.UserMemory) []uint {
+	result := make([]uint, len(memories))
+	for i, m := range memories {
+		result[i] = m.ID
+	}
+	return result
+}
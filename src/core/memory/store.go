@@ -0,0 +1,212 @@
+package memory
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"time"
+
+	"angrymiao-ai-server/src/configs"
+	"angrymiao-ai-server/src/core/chat"
+	"angrymiao-ai-server/src/core/types"
+	"angrymiao-ai-server/src/core/utils"
+	"angrymiao-ai-server/src/models"
+	"angrymiao-ai-server/src/services"
+)
+
+const (
+	defaultTopK         = 5
+	defaultMinUserTurns = 2
+	queryTimeout        = 3 * time.Second
+	extractTimeout      = 30 * time.Second
+)
+
+// 记忆提取提示词，%s 为已有记忆
+const extractPrompt = `你是用户记忆整理助手。请从下面的对话中提取值得长期记住的关于用户的信息，用于以后的对话。
+只提取用户本人明确表达的、长期有效的信息：
+- profile：个人信息，如称呼、年龄、职业、家庭成员、所在城市
+- preference：偏好和习惯，如喜欢或不喜欢的事物、说话风格要求
+- fact：其他重要事实，如计划中的重要事项
+不要提取临时性的请求、闲聊内容和助手说的话，不要重复已有记忆。
+已有记忆：
+%s
+以JSON数组输出，每项包含 category、content（第三人称简短陈述，如"用户喜欢周杰伦的歌"）、importance（1-5），没有可提取的信息时输出 []。只输出JSON。`
+
+// Store 基于数据库的用户长期记忆，实现 chat.MemoryInterface
+type Store struct {
+	userID  string
+	service services.MemoryService
+	llm     types.LLMProvider
+	config  configs.MemoryConfig
+	logger  *utils.Logger
+}
+
+// NewStore 创建用户长期记忆
+func NewStore(
+	userID string,
+	service services.MemoryService,
+	llm types.LLMProvider,
+	config configs.MemoryConfig,
+	logger *utils.Logger,
+) *Store {
+	if config.TopK <= 0 {
+		config.TopK = defaultTopK
+	}
+	if config.MinUserTurns <= 0 {
+		config.MinUserTurns = defaultMinUserTurns
+	}
+	return &Store{
+		userID:  userID,
+		service: service,
+		llm:     llm,
+		config:  config,
+		logger:  logger,
+	}
+}
+
+// WithLLM 返回使用指定LLM提取记忆的副本，连接关闭后在后台提取时使用
+func (s *Store) WithLLM(llm types.LLMProvider) *Store {
+	clone := *s
+	clone.llm = llm
+	return &clone
+}
+
+// QueryMemory 检索与查询相关的记忆，返回可直接加入提示词的文本
+func (s *Store) QueryMemory(query string) (string, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), queryTimeout)
+	defer cancel()
+
+	memories, err := s.service.ListMemories(ctx, s.userID)
+	if err != nil {
+		return "", err
+	}
+	relevant := Rank(query, memories, s.config.TopK)
+	if len(relevant) == 0 {
+		return "", nil
+	}
+
+	var b strings.Builder
+	b.WriteString("以下是关于用户的长期记忆，回答时自然地参考，不要逐条复述：\n")
+	for _, m := range relevant {
+		b.WriteString("- ")
+		b.WriteString(m.Content)
+		b.WriteString("\n")
+	}
+	return b.String(), nil
+}
+
+// SaveMemory 用LLM从对话中提取用户的个人信息和偏好并保存
+func (s *Store) SaveMemory(dialogue []chat.Message) error {
+	transcript, userTurns := buildTranscript(dialogue)
+	if userTurns < s.config.MinUserTurns {
+		s.logger.Debug("用户 %s 本次会话发言 %d 轮，跳过记忆提取", s.userID, userTurns)
+		return nil
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), extractTimeout)
+	defer cancel()
+
+	existing, err := s.service.ListMemories(ctx, s.userID)
+	if err != nil {
+		return fmt.Errorf("获取已有记忆失败: %v", err)
+	}
+	known := "无"
+	if len(existing) > 0 {
+		lines := make([]string, len(existing))
+		for i, m := range existing {
+			lines[i] = "- " + m.Content
+		}
+		known = strings.Join(lines, "\n")
+	}
+
+	responses, err := s.llm.Response(ctx, "", []types.Message{
+		{Role: "system", Content: fmt.Sprintf(extractPrompt, known)},
+		{Role: "user", Content: transcript},
+	})
+	if err != nil {
+		return fmt.Errorf("提取记忆失败: %v", err)
+	}
+	var output strings.Builder
+	for content := range responses {
+		output.WriteString(content)
+	}
+
+	memories, err := parseMemories(output.String())
+	if err != nil {
+		return fmt.Errorf("解析记忆失败: %v", err)
+	}
+	added, err := s.service.AddMemories(ctx, s.userID, memories)
+	if err != nil {
+		return fmt.Errorf("保存记忆失败: %v", err)
+	}
+	s.logger.Info("用户 %s 提取记忆 %d 条，新增 %d 条", s.userID, len(memories), added)
+	return nil
+}
+
+// ClearMemory 清空用户的全部记忆
+func (s *Store) ClearMemory() error {
+	ctx, cancel := context.WithTimeout(context.Background(), queryTimeout)
+	defer cancel()
+	return s.service.ClearMemories(ctx, s.userID)
+}
+
+// buildTranscript 生成用于提取记忆的对话文本，返回用户发言轮数
+// 系统提示词和工具消息不参与提取，之前的对话摘要会保留
+func buildTranscript(dialogue []chat.Message) (string, int) {
+	var b strings.Builder
+	userTurns := 0
+	for _, msg := range dialogue {
+		switch {
+		case msg.Role == "user":
+			userTurns++
+			b.WriteString("用户：")
+		case msg.Role == "assistant" && msg.Content != "":
+			b.WriteString("助手：")
+		case msg.Role == "system" && strings.HasPrefix(msg.Content, chat.SummaryPrefix):
+			b.WriteString("之前的对话摘要：")
+		default:
+			continue
+		}
+		b.WriteString(strings.TrimPrefix(msg.Content, chat.SummaryPrefix))
+		b.WriteString("\n")
+	}
+	return b.String(), userTurns
+}
+
+// parseMemories 解析LLM输出的记忆JSON数组，兼容代码块包裹和前后多余文字
+func parseMemories(output string) ([]*models.UserMemory, error) {
+	start := strings.Index(output, "[")
+	end := strings.LastIndex(output, "]")
+	if start < 0 || end < start {
+		return nil, fmt.Errorf("未找到JSON数组: %s", output)
+	}
+
+	var items []struct {
+		Category   string `json:"category"`
+		Content    string `json:"content"`
+		Importance int    `json:"importance"`
+	}
+	if err := json.Unmarshal([]byte(output[start:end+1]), &items); err != nil {
+		return nil, err
+	}
+
+	memories := make([]*models.UserMemory, 0, len(items))
+	for _, item := range items {
+		if strings.TrimSpace(item.Content) == "" {
+			continue
+		}
+		category := item.Category
+		switch category {
+		case models.MemoryCategoryProfile, models.MemoryCategoryPreference, models.MemoryCategoryFact:
+		default:
+			category = models.MemoryCategoryFact
+		}
+		memories = append(memories, &models.UserMemory{
+			Category:   category,
+			Content:    item.Content,
+			Importance: item.Importance,
+		})
+	}
+	return memories, nil
+}
@@ -0,0 +1,73 @@
+package moderation
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"angrymiao-ai-server/src/core/types"
+)
+
+// 默认分类提示词
+const defaultClassifierPrompt = `你是儿童智能设备的内容安全审核员。判断用户给出的文本是否适合儿童，以下内容视为不适合：
+- violence：暴力、血腥、自残、危险行为的教唆
+- sexual：色情、性暗示
+- hate：歧视、辱骂、欺凌
+- illegal：违法犯罪、毒品、赌博
+- privacy：诱导儿童透露住址、学校、电话等隐私
+只输出JSON，如 {"flagged":false,"category":""} 或 {"flagged":true,"category":"violence"}。`
+
+// LLMChecker 使用LLM判断文本是否适合儿童
+type LLMChecker struct {
+	llm    types.LLMProvider
+	prompt string
+}
+
+// NewLLMChecker 创建LLM分类器，prompt 为空时使用默认提示词
+func NewLLMChecker(llm types.LLMProvider, prompt string) *LLMChecker {
+	if prompt == "" {
+		prompt = defaultClassifierPrompt
+	}
+	return &LLMChecker{llm: llm, prompt: prompt}
+}
+
+func (c *LLMChecker) Name() string {
+	return CheckerLLM
+}
+
+// Check 请求LLM分类
+func (c *LLMChecker) Check(ctx context.Context, text string) (*Result, error) {
+	responses, err := c.llm.Response(ctx, "", []types.Message{
+		{Role: "system", Content: c.prompt},
+		{Role: "user", Content: text},
+	})
+	if err != nil {
+		return nil, err
+	}
+	var output strings.Builder
+	for content := range responses {
+		output.WriteString(content)
+	}
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	return parseClassification(output.String())
+}
+
+// parseClassification 解析分类结果，兼容代码块包裹和前后多余文字
+func parseClassification(output string) (*Result, error) {
+	start := strings.Index(output, "{")
+	end := strings.LastIndex(output, "}")
+	if start < 0 || end < start {
+		return nil, fmt.Errorf("未找到JSON对象: %s", output)
+	}
+	var parsed struct {
+		Flagged  bool   `json:"flagged"`
+		Category string `json:"category"`
+	}
+	if err := json.Unmarshal([]byte(output[start:end+1]), &parsed); err != nil {
+		return nil, err
+	}
+	return &Result{Flagged: parsed.Flagged, Checker: CheckerLLM, Category: parsed.Category}, nil
+}
@@ -0,0 +1,92 @@
+package moderation
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"sort"
+	"strings"
+
+	"angrymiao-ai-server/src/configs"
+)
+
+// EndpointChecker 调用兼容OpenAI /moderations 接口的审核服务
+type EndpointChecker struct {
+	config configs.ModerationEndpointConfig
+	client *http.Client
+}
+
+// NewEndpointChecker 创建审核服务检查器，超时由审核器的上下文控制
+func NewEndpointChecker(config configs.ModerationEndpointConfig) *EndpointChecker {
+	config.BaseURL = strings.TrimSuffix(config.BaseURL, "/")
+	return &EndpointChecker{config: config, client: &http.Client{}}
+}
+
+func (c *EndpointChecker) Name() string {
+	return CheckerEndpoint
+}
+
+type moderationRequest struct {
+	Input string `json:"input"`
+	Model string `json:"model,omitempty"`
+}
+
+type moderationResponse struct {
+	Results []struct {
+		Flagged    bool            `json:"flagged"`
+		Categories map[string]bool `json:"categories"`
+	} `json:"results"`
+}
+
+// Check 调用审核服务检查文本
+func (c *EndpointChecker) Check(ctx context.Context, text string) (*Result, error) {
+	body, err := json.Marshal(moderationRequest{Input: text, Model: c.config.Model})
+	if err != nil {
+		return nil, err
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, c.config.BaseURL+"/moderations", bytes.NewReader(body))
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	if c.config.APIKey != "" {
+		req.Header.Set("Authorization", "Bearer "+c.config.APIKey)
+	}
+
+	resp, err := c.client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	data, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("审核服务返回 HTTP %d: %s", resp.StatusCode, strings.TrimSpace(string(data)))
+	}
+
+	var parsed moderationResponse
+	if err := json.Unmarshal(data, &parsed); err != nil {
+		return nil, fmt.Errorf("解析审核结果失败: %v", err)
+	}
+	result := &Result{Checker: CheckerEndpoint}
+	var categories []string
+	for _, r := range parsed.Results {
+		if !r.Flagged {
+			continue
+		}
+		result.Flagged = true
+		for name, hit := range r.Categories {
+			if hit {
+				categories = append(categories, name)
+			}
+		}
+	}
+	sort.Strings(categories)
+	result.Category = strings.Join(categories, ",")
+	return result, nil
+}
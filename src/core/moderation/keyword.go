@@ -0,0 +1,53 @@
+package moderation
+
+import (
+	"context"
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+// KeywordChecker 敏感词和正则表达式检查器，返回命中的原文片段用于遮盖
+type KeywordChecker struct {
+	patterns []*regexp.Regexp
+}
+
+// NewKeywordChecker 创建敏感词检查器，敏感词不区分大小写
+func NewKeywordChecker(keywords, patterns []string) (*KeywordChecker, error) {
+	c := &KeywordChecker{}
+	for _, keyword := range keywords {
+		keyword = strings.TrimSpace(keyword)
+		if keyword == "" {
+			continue
+		}
+		c.patterns = append(c.patterns, regexp.MustCompile("(?i)"+regexp.QuoteMeta(keyword)))
+	}
+	for _, pattern := range patterns {
+		re, err := regexp.Compile(pattern)
+		if err != nil {
+			return nil, fmt.Errorf("审核正则表达式 %s 无效: %v", pattern, err)
+		}
+		c.patterns = append(c.patterns, re)
+	}
+	return c, nil
+}
+
+func (c *KeywordChecker) Name() string {
+	return CheckerKeyword
+}
+
+// Check 检查文本中的敏感词
+func (c *KeywordChecker) Check(_ context.Context, text string) (*Result, error) {
+	result := &Result{Checker: CheckerKeyword, Category: CheckerKeyword}
+	seen := map[string]bool{}
+	for _, re := range c.patterns {
+		for _, match := range re.FindAllString(text, -1) {
+			if match != "" && !seen[match] {
+				seen[match] = true
+				result.Matches = append(result.Matches, match)
+			}
+		}
+	}
+	result.Flagged = len(result.Matches) > 0
+	return result, nil
+}
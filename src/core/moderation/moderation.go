@@ -0,0 +1,207 @@
+package moderation
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"angrymiao-ai-server/src/configs"
+	"angrymiao-ai-server/src/core/types"
+	"angrymiao-ai-server/src/core/utils"
+)
+
+// Direction 审核阶段
+type Direction string
+
+const (
+	Input  Direction = "input"  // 用户文本
+	Output Direction = "output" // 助手回复
+)
+
+// Action 命中后的处理动作
+type Action string
+
+const (
+	ActionBlock  Action = "block"  // 替换为话术
+	ActionRedact Action = "redact" // 遮盖命中内容
+	ActionEnd    Action = "end"    // 播报话术后结束会话
+)
+
+// 检查器名称
+const (
+	CheckerKeyword  = "keyword"
+	CheckerEndpoint = "endpoint"
+	CheckerLLM      = "llm"
+)
+
+const defaultTimeout = 3 * time.Second
+
+// 默认替换话术
+const (
+	defaultInputReplacement  = "这个问题我们换个话题聊吧"
+	defaultOutputReplacement = "这个我不太方便说，我们聊点别的吧"
+)
+
+// Result 检查结果
+type Result struct {
+	Flagged  bool
+	Checker  string
+	Category string
+	Matches  []string // 命中的文本片段，用于遮盖，为空表示无法定位
+}
+
+// Checker 内容检查器
+type Checker interface {
+	Name() string
+	Check(ctx context.Context, text string) (*Result, error)
+}
+
+// Decision 审核结论
+type Decision struct {
+	Flagged bool
+	Action  Action
+	Text    string  // 处理后的文本：未命中为原文，redact 为遮盖后的文本，block/end 为替换话术
+	Result  *Result // 命中的检查结果
+}
+
+// Moderator 按阶段组合检查器审核文本
+type Moderator struct {
+	config   configs.ModerationConfig
+	checkers map[Direction][]Checker
+	logger   *utils.Logger
+}
+
+// NewModerator 根据配置创建审核器，llm 为空时不启用LLM分类器
+func NewModerator(config configs.ModerationConfig, llm types.LLMProvider, logger *utils.Logger) (*Moderator, error) {
+	available := map[string]Checker{}
+	if len(config.Keywords) > 0 || len(config.Patterns) > 0 {
+		keyword, err := NewKeywordChecker(config.Keywords, config.Patterns)
+		if err != nil {
+			return nil, err
+		}
+		available[CheckerKeyword] = keyword
+	}
+	if config.Endpoint.BaseURL != "" {
+		available[CheckerEndpoint] = NewEndpointChecker(config.Endpoint)
+	}
+	if config.Classifier.Enabled && llm != nil {
+		available[CheckerLLM] = NewLLMChecker(llm, config.Classifier.Prompt)
+	}
+
+	m := &Moderator{
+		config:   config,
+		checkers: map[Direction][]Checker{},
+		logger:   logger,
+	}
+	for dir, stage := range map[Direction]configs.ModerationStageConfig{Input: config.Input, Output: config.Output} {
+		if !stage.Enabled {
+			continue
+		}
+		if err := validateAction(stage.Action); err != nil {
+			return nil, err
+		}
+		m.checkers[dir] = selectCheckers(available, stage.Checkers)
+	}
+	return m, nil
+}
+
+// selectCheckers 按名称选择检查器，未指定时使用全部检查器，关键词检查器始终排在最前
+func selectCheckers(available map[string]Checker, names []string) []Checker {
+	if len(names) == 0 {
+		for name := range available {
+			names = append(names, name)
+		}
+	}
+	order := map[string]int{CheckerKeyword: 0, CheckerEndpoint: 1, CheckerLLM: 2}
+	sort.Slice(names, func(i, j int) bool { return order[names[i]] < order[names[j]] })
+
+	var checkers []Checker
+	seen := map[string]bool{}
+	for _, name := range names {
+		if checker, ok := available[name]; ok && !seen[name] {
+			checkers = append(checkers, checker)
+			seen[name] = true
+		}
+	}
+	return checkers
+}
+
+func validateAction(action string) error {
+	switch Action(action) {
+	case "", ActionBlock, ActionRedact, ActionEnd:
+		return nil
+	}
+	return fmt.Errorf("不支持的审核动作: %s", action)
+}
+
+// Enabled 该阶段是否有可用的检查器
+func (m *Moderator) Enabled(dir Direction) bool {
+	return m != nil && len(m.checkers[dir]) > 0
+}
+
+// Moderate 依次执行检查器，第一个命中的结果决定处理方式
+// 检查器出错时按配置放行或按命中处理
+func (m *Moderator) Moderate(ctx context.Context, dir Direction, text string) Decision {
+	if !m.Enabled(dir) || strings.TrimSpace(text) == "" {
+		return Decision{Text: text}
+	}
+	timeout := defaultTimeout
+	if m.config.Timeout > 0 {
+		timeout = time.Duration(m.config.Timeout) * time.Millisecond
+	}
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	for _, checker := range m.checkers[dir] {
+		result, err := checker.Check(ctx, text)
+		if err != nil {
+			m.logger.Error("内容审核 %s 检查失败: %v", checker.Name(), err)
+			if !m.config.FailClosed {
+				continue
+			}
+			result = &Result{Flagged: true, Checker: checker.Name(), Category: "error"}
+		}
+		if result != nil && result.Flagged {
+			return m.decide(dir, text, result)
+		}
+	}
+	return Decision{Text: text}
+}
+
+// decide 根据阶段配置生成处理结论，无法定位命中内容时遮盖按替换处理
+func (m *Moderator) decide(dir Direction, text string, result *Result) Decision {
+	stage, replacement := m.config.Input, defaultInputReplacement
+	if dir == Output {
+		stage, replacement = m.config.Output, defaultOutputReplacement
+	}
+	if stage.Replacement != "" {
+		replacement = stage.Replacement
+	}
+
+	action := Action(stage.Action)
+	if action == "" {
+		action = ActionBlock
+	}
+	if action == ActionRedact {
+		if len(result.Matches) == 0 {
+			action = ActionBlock
+		} else {
+			return Decision{Flagged: true, Action: action, Text: Redact(text, result.Matches), Result: result}
+		}
+	}
+	return Decision{Flagged: true, Action: action, Text: replacement, Result: result}
+}
+
+// Redact 将命中的片段替换为等长的星号
+func Redact(text string, matches []string) string {
+	for _, match := range matches {
+		if match == "" {
+			continue
+		}
+		text = strings.ReplaceAll(text, match, strings.Repeat("*", utf8.RuneCountInString(match)))
+	}
+	return text
+}
@@ -0,0 +1,93 @@
+package moderation
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"angrymiao-ai-server/src/configs"
+	"angrymiao-ai-server/src/core/utils"
+)
+
+type fakeChecker struct {
+	result *Result
+	err    error
+}
+
+func (c *fakeChecker) Name() string { return "fake" }
+
+func (c *fakeChecker) Check(context.Context, string) (*Result, error) { return c.result, c.err }
+
+func TestKeywordChecker(t *testing.T) {
+	checker, err := NewKeywordChecker([]string{"坏词", "BadWord"}, []string{`\d{11}`})
+	if err != nil {
+		t.Fatalf("NewKeywordChecker() 错误: %v", err)
+	}
+
+	result, _ := checker.Check(context.Background(), "这是坏词，badword，电话13800138000")
+	if !result.Flagged || len(result.Matches) != 3 {
+		t.Fatalf("Check() = %+v, 期望命中3处", result)
+	}
+	if got := Redact("这是坏词，badword，电话13800138000", result.Matches); got != "这是**，*******，电话***********" {
+		t.Errorf("Redact() = %q", got)
+	}
+
+	if result, _ := checker.Check(context.Background(), "今天天气不错"); result.Flagged {
+		t.Errorf("Check() 正常文本 = %+v", result)
+	}
+
+	if _, err := NewKeywordChecker(nil, []string{"("}); err == nil {
+		t.Error("NewKeywordChecker() 无效正则应返回错误")
+	}
+}
+
+func TestModerate(t *testing.T) {
+	config := configs.ModerationConfig{
+		Input:  configs.ModerationStageConfig{Enabled: true, Action: "redact"},
+		Output: configs.ModerationStageConfig{Enabled: true, Action: "end", Replacement: "再见"},
+	}
+	logger, err := utils.NewLogger(&utils.LogCfg{LogLevel: "ERROR", LogDir: t.TempDir(), LogFile: "test.log"})
+	if err != nil {
+		t.Fatalf("NewLogger() 错误: %v", err)
+	}
+	defer logger.Close()
+	flagged := &fakeChecker{result: &Result{Flagged: true, Checker: "fake", Matches: []string{"坏"}}}
+	m := &Moderator{
+		config:   config,
+		checkers: map[Direction][]Checker{Input: {flagged}, Output: {flagged}},
+		logger:   logger,
+	}
+
+	if d := m.Moderate(context.Background(), Input, "坏孩子"); !d.Flagged || d.Action != ActionRedact || d.Text != "*孩子" {
+		t.Errorf("Moderate() 输入遮盖 = %+v", d)
+	}
+	if d := m.Moderate(context.Background(), Output, "坏孩子"); d.Action != ActionEnd || d.Text != "再见" {
+		t.Errorf("Moderate() 输出结束 = %+v", d)
+	}
+
+	// 无法定位命中内容时遮盖按替换处理
+	flagged.result = &Result{Flagged: true, Checker: "fake"}
+	if d := m.Moderate(context.Background(), Input, "坏孩子"); d.Action != ActionBlock || d.Text != defaultInputReplacement {
+		t.Errorf("Moderate() 无法遮盖 = %+v", d)
+	}
+
+	// 检查器出错时默认放行，fail_closed 时按命中处理
+	flagged.result, flagged.err = nil, errors.New("timeout")
+	if d := m.Moderate(context.Background(), Input, "坏孩子"); d.Flagged || d.Text != "坏孩子" {
+		t.Errorf("Moderate() 出错放行 = %+v", d)
+	}
+	m.config.FailClosed = true
+	if d := m.Moderate(context.Background(), Input, "坏孩子"); !d.Flagged || d.Action != ActionBlock {
+		t.Errorf("Moderate() 出错拦截 = %+v", d)
+	}
+}
+
+func TestParseClassification(t *testing.T) {
+	result, err := parseClassification("```json\n{\"flagged\":true,\"category\":\"violence\"}\n```")
+	if err != nil || !result.Flagged || result.Category != "violence" {
+		t.Errorf("parseClassification() = %+v, %v", result, err)
+	}
+	if _, err := parseClassification("无法判断"); err == nil {
+		t.Error("parseClassification() 无JSON应返回错误")
+	}
+}
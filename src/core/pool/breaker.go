@@ -0,0 +1,216 @@
+package pool
+
+import (
+	"sync"
+	"time"
+)
+
+/*
+* 熔断器，按统计窗口内的错误率决定是否放行请求。
+* 关闭状态正常放行并记录结果，错误率超过阈值后进入打开状态，
+* 打开状态在熔断持续时间内拒绝请求，之后进入半开状态放行一个探测请求，
+* 探测成功则恢复关闭状态，失败则重新打开。
+ */
+
+// BreakerState 熔断器状态
+type BreakerState int
+
+const (
+	BreakerClosed   BreakerState = iota // 关闭，正常放行
+	BreakerOpen                         // 打开，拒绝请求
+	BreakerHalfOpen                     // 半开，放行探测请求
+)
+
+// String 状态名称
+func (s BreakerState) String() string {
+	switch s {
+	case BreakerOpen:
+		return "open"
+	case BreakerHalfOpen:
+		return "half_open"
+	default:
+		return "closed"
+	}
+}
+
+// BreakerConfig 熔断器配置
+type BreakerConfig struct {
+	ErrorRate    float64       // 触发熔断的错误率
+	MinRequests  int           // 统计窗口内触发熔断的最少请求数
+	Window       time.Duration // 错误率统计窗口
+	OpenDuration time.Duration // 熔断持续时间
+}
+
+// DefaultBreakerConfig 默认熔断器配置
+func DefaultBreakerConfig() BreakerConfig {
+	return BreakerConfig{
+		ErrorRate:    0.5,
+		MinRequests:  5,
+		Window:       60 * time.Second,
+		OpenDuration: 30 * time.Second,
+	}
+}
+
+// breakerResult 单次请求结果
+type breakerResult struct {
+	at      time.Time
+	success bool
+}
+
+// CircuitBreaker 熔断器
+type CircuitBreaker struct {
+	name     string
+	config   BreakerConfig
+	mutex    sync.Mutex
+	state    BreakerState
+	openedAt time.Time
+	probeAt  time.Time // 半开状态下探测请求的放行时间，零值表示尚未放行
+	results  []breakerResult
+	now      func() time.Time
+}
+
+// NewCircuitBreaker 创建熔断器
+func NewCircuitBreaker(name string, config BreakerConfig) *CircuitBreaker {
+	defaults := DefaultBreakerConfig()
+	if config.ErrorRate <= 0 || config.ErrorRate > 1 {
+		config.ErrorRate = defaults.ErrorRate
+	}
+	if config.MinRequests <= 0 {
+		config.MinRequests = defaults.MinRequests
+	}
+	if config.Window <= 0 {
+		config.Window = defaults.Window
+	}
+	if config.OpenDuration <= 0 {
+		config.OpenDuration = defaults.OpenDuration
+	}
+	return &CircuitBreaker{
+		name:   name,
+		config: config,
+		now:    time.Now,
+	}
+}
+
+// Name 熔断器名称
+func (b *CircuitBreaker) Name() string {
+	return b.name
+}
+
+// Allow 是否放行请求
+func (b *CircuitBreaker) Allow() bool {
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+
+	now := b.now()
+	switch b.state {
+	case BreakerOpen:
+		if now.Sub(b.openedAt) < b.config.OpenDuration {
+			return false
+		}
+		b.state = BreakerHalfOpen
+		b.probeAt = now
+		return true
+	case BreakerHalfOpen:
+		// 探测请求迟迟没有结果时允许再次探测，避免一直停留在半开状态
+		if !b.probeAt.IsZero() && now.Sub(b.probeAt) < b.config.OpenDuration {
+			return false
+		}
+		b.probeAt = now
+		return true
+	default:
+		return true
+	}
+}
+
+// RecordSuccess 记录成功请求
+func (b *CircuitBreaker) RecordSuccess() {
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+
+	if b.state != BreakerClosed {
+		b.state = BreakerClosed
+		b.results = nil
+		b.probeAt = time.Time{}
+	}
+	b.record(true)
+}
+
+// RecordFailure 记录失败请求，半开状态或错误率超过阈值时打开熔断
+func (b *CircuitBreaker) RecordFailure() {
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+
+	now := b.now()
+	if b.state == BreakerHalfOpen {
+		b.open(now)
+		return
+	}
+	b.record(false)
+	if b.state != BreakerClosed {
+		return
+	}
+
+	requests, failures := b.counts()
+	if requests >= b.config.MinRequests && float64(failures)/float64(requests) >= b.config.ErrorRate {
+		b.open(now)
+	}
+}
+
+// State 当前状态
+func (b *CircuitBreaker) State() BreakerState {
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+	if b.state == BreakerOpen && b.now().Sub(b.openedAt) >= b.config.OpenDuration {
+		return BreakerHalfOpen
+	}
+	return b.state
+}
+
+// GetStats 获取熔断器统计信息，state 为 BreakerState 的数值
+func (b *CircuitBreaker) GetStats() map[string]int {
+	state := b.State()
+
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+	b.prune(b.now())
+	requests, failures := b.counts()
+	return map[string]int{
+		"breaker_state":    int(state),
+		"breaker_requests": requests,
+		"breaker_failures": failures,
+	}
+}
+
+// open 打开熔断，调用方需持有锁
+func (b *CircuitBreaker) open(now time.Time) {
+	b.state = BreakerOpen
+	b.openedAt = now
+	b.probeAt = time.Time{}
+}
+
+// record 记录请求结果并清理过期记录，调用方需持有锁
+func (b *CircuitBreaker) record(success bool) {
+	now := b.now()
+	b.results = append(b.results, breakerResult{at: now, success: success})
+	b.prune(now)
+}
+
+// prune 清理统计窗口之外的记录，调用方需持有锁
+func (b *CircuitBreaker) prune(now time.Time) {
+	i := 0
+	for i < len(b.results) && now.Sub(b.results[i].at) > b.config.Window {
+		i++
+	}
+	b.results = b.results[i:]
+}
+
+// counts 统计窗口内的请求数和失败数，调用方需持有锁
+func (b *CircuitBreaker) counts() (requests, failures int) {
+	for _, r := range b.results {
+		requests++
+		if !r.success {
+			failures++
+		}
+	}
+	return requests, failures
+}
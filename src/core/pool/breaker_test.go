@@ -0,0 +1,64 @@
+package pool
+
+import (
+	"testing"
+	"time"
+)
+
+func TestCircuitBreaker(t *testing.T) {
+	now := time.Unix(0, 0)
+	b := NewCircuitBreaker("test", BreakerConfig{
+		ErrorRate:    0.5,
+		MinRequests:  4,
+		Window:       time.Minute,
+		OpenDuration: 10 * time.Second,
+	})
+	b.now = func() time.Time { return now }
+
+	// 请求数不足时不熔断
+	b.RecordFailure()
+	b.RecordFailure()
+	b.RecordSuccess()
+	if b.State() != BreakerClosed {
+		t.Fatalf("状态 = %s, 期望 closed", b.State())
+	}
+
+	// 错误率达到阈值后熔断
+	b.RecordFailure()
+	if b.State() != BreakerOpen || b.Allow() {
+		t.Fatalf("状态 = %s, 期望 open 且拒绝请求", b.State())
+	}
+
+	// 熔断持续时间后只放行一个探测请求
+	now = now.Add(10 * time.Second)
+	if !b.Allow() || b.Allow() {
+		t.Fatalf("半开状态应只放行一个探测请求")
+	}
+
+	// 探测失败重新熔断
+	b.RecordFailure()
+	if b.State() != BreakerOpen {
+		t.Fatalf("状态 = %s, 期望 open", b.State())
+	}
+
+	// 探测成功恢复并清空统计
+	now = now.Add(10 * time.Second)
+	if !b.Allow() {
+		t.Fatalf("应放行探测请求")
+	}
+	b.RecordSuccess()
+	stats := b.GetStats()
+	if b.State() != BreakerClosed || stats["breaker_requests"] != 1 || stats["breaker_failures"] != 0 {
+		t.Fatalf("状态 = %s, 统计 = %v", b.State(), stats)
+	}
+
+	// 统计窗口外的失败不计入错误率
+	b.RecordFailure()
+	b.RecordFailure()
+	now = now.Add(2 * time.Minute)
+	b.RecordFailure()
+	b.RecordSuccess()
+	if b.State() != BreakerClosed {
+		t.Fatalf("状态 = %s, 期望 closed", b.State())
+	}
+}
@@ -159,7 +159,8 @@ func (hc *HealthChecker) CheckAllProviders(ctx context.Context, mode CheckMode)
 	}
 
 	// 检查LLM
-	if llmType, ok := selectedModule["LLM"]; ok && llmType != "" {
+	// 只检查主LLM，备用LLM由熔断器处理不可用的情况
+	if llmType := hc.config.PrimaryLLM(); llmType != "" {
 		if err := hc.checkLLMProvider(ctx, llmType, mode); err != nil {
 			allErrors = append(allErrors, fmt.Errorf("LLM%s检查失败: %v", checkTypeName, err))
 		}
@@ -574,8 +575,8 @@ func (hc *HealthChecker) checkVLLLMProvider(
 
 		// 收集响应内容
 		var response strings.Builder
-		for content := range responseChan {
-			response.WriteString(content)
+		for chunk := range responseChan {
+			response.WriteString(chunk.Content)
 		}
 		responseText := response.String()
 
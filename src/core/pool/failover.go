@@ -0,0 +1,326 @@
+package pool
+
+import (
+	"angrymiao-ai-server/src/core/providers"
+	"angrymiao-ai-server/src/core/providers/llm"
+	"angrymiao-ai-server/src/core/types"
+	"angrymiao-ai-server/src/core/utils"
+	"context"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/sashabaranov/go-openai"
+)
+
+/*
+* LLM故障转移，按配置顺序依次尝试主LLM和备用LLM。
+* 每个LLM有独立的熔断器，熔断打开时直接跳过。
+* 在输出第一个token之前出错或首个token超时，会透明地切换到下一个LLM重试；
+* 一旦开始输出，后续错误直接返回给调用方，不再切换。
+ */
+
+// 默认首个token超时时间
+const defaultFirstTokenTimeout = 10 * time.Second
+
+// llmCandidate 故障转移候选LLM
+type llmCandidate struct {
+	name    string
+	pool    *ResourcePool
+	breaker *CircuitBreaker
+}
+
+// FailoverLLM 支持故障转移的LLM提供者
+// 主LLM由连接独占，备用LLM仅在切换时从对应资源池借用，本轮结束后归还
+type FailoverLLM struct {
+	primary           providers.LLMProvider
+	candidates        []*llmCandidate // 第一个为主LLM
+	firstTokenTimeout time.Duration
+	logger            *utils.Logger
+
+	mutex         sync.Mutex
+	identityFlags map[string]string // 同步到借用的备用LLM
+}
+
+// newFailoverLLM 创建故障转移LLM
+func newFailoverLLM(
+	primary providers.LLMProvider,
+	candidates []*llmCandidate,
+	firstTokenTimeout time.Duration,
+	logger *utils.Logger,
+) *FailoverLLM {
+	if firstTokenTimeout <= 0 {
+		firstTokenTimeout = defaultFirstTokenTimeout
+	}
+	return &FailoverLLM{
+		primary:           primary,
+		candidates:        candidates,
+		firstTokenTimeout: firstTokenTimeout,
+		logger:            logger,
+		identityFlags:     make(map[string]string),
+	}
+}
+
+// Primary 获取主LLM
+func (f *FailoverLLM) Primary() providers.LLMProvider {
+	return f.primary
+}
+
+// Config 获取主LLM配置
+func (f *FailoverLLM) Config() *llm.Config {
+	if getter, ok := f.primary.(interface{ Config() *llm.Config }); ok {
+		return getter.Config()
+	}
+	return nil
+}
+
+// Initialize 初始化提供者
+func (f *FailoverLLM) Initialize() error {
+	return f.primary.Initialize()
+}
+
+// Cleanup 清理资源
+func (f *FailoverLLM) Cleanup() error {
+	return f.primary.Cleanup()
+}
+
+// GetSessionID 获取当前会话ID
+func (f *FailoverLLM) GetSessionID() string {
+	return f.primary.GetSessionID()
+}
+
+// SetIdentityFlag 设置身份标识
+func (f *FailoverLLM) SetIdentityFlag(idType string, flag string) {
+	f.mutex.Lock()
+	f.identityFlags[idType] = flag
+	f.mutex.Unlock()
+	f.primary.SetIdentityFlag(idType, flag)
+}
+
+// Response types.LLMProvider接口实现
+func (f *FailoverLLM) Response(ctx context.Context, sessionID string, messages []types.Message) (<-chan string, error) {
+	responseChan := make(chan string, 10)
+
+	go func() {
+		defer close(responseChan)
+		f.run(ctx, func(ctx context.Context, provider providers.LLMProvider) (<-chan types.Response, error) {
+			ch, err := provider.Response(ctx, sessionID, messages)
+			if err != nil {
+				return nil, err
+			}
+			out := make(chan types.Response)
+			go func() {
+				defer close(out)
+				for content := range ch {
+					out <- types.Response{Content: content}
+				}
+			}()
+			return out, nil
+		}, func(resp types.Response) {
+			if resp.Content != "" {
+				responseChan <- resp.Content
+			}
+		})
+	}()
+
+	return responseChan, nil
+}
+
+// ResponseWithFunctions types.LLMProvider接口实现
+func (f *FailoverLLM) ResponseWithFunctions(ctx context.Context, sessionID string, messages []types.Message, tools []openai.Tool) (<-chan types.Response, error) {
+	responseChan := make(chan types.Response, 10)
+
+	go func() {
+		defer close(responseChan)
+		f.run(ctx, func(ctx context.Context, provider providers.LLMProvider) (<-chan types.Response, error) {
+			return provider.ResponseWithFunctions(ctx, sessionID, messages, tools)
+		}, func(resp types.Response) {
+			responseChan <- resp
+		})
+	}()
+
+	return responseChan, nil
+}
+
+// streamFunc 使用指定提供者发起流式请求
+type streamFunc func(ctx context.Context, provider providers.LLMProvider) (<-chan types.Response, error)
+
+// run 按顺序尝试候选LLM，直到某个LLM开始输出或全部失败
+func (f *FailoverLLM) run(ctx context.Context, start streamFunc, emit func(types.Response)) {
+	var lastErr error
+	attempted := false
+
+	for i, candidate := range f.candidates {
+		if !candidate.breaker.Allow() {
+			f.logger.Warn("LLM %s 处于熔断状态，跳过", candidate.name)
+			continue
+		}
+		attempted = true
+		done, err := f.attempt(ctx, i, start, emit)
+		if done {
+			return
+		}
+		lastErr = err
+	}
+
+	// 所有LLM都处于熔断状态时，仍尝试主LLM
+	if !attempted {
+		f.logger.Warn("所有LLM均处于熔断状态，尝试主LLM %s", f.candidates[0].name)
+		done, err := f.attempt(ctx, 0, start, emit)
+		if done {
+			return
+		}
+		lastErr = err
+	}
+
+	if lastErr == nil {
+		lastErr = fmt.Errorf("没有可用的LLM")
+	}
+	emit(types.Response{
+		Content: fmt.Sprintf("【LLM服务响应异常: %v】", lastErr),
+		Error:   lastErr.Error(),
+	})
+}
+
+// attempt 使用第index个候选LLM生成回复，已开始输出或请求被取消时返回true
+func (f *FailoverLLM) attempt(ctx context.Context, index int, start streamFunc, emit func(types.Response)) (bool, error) {
+	candidate := f.candidates[index]
+	provider, release, err := f.acquire(index)
+	if err != nil {
+		f.logger.Warn("获取备用LLM %s 失败: %v", candidate.name, err)
+		return false, err
+	}
+	defer release()
+
+	streamed, err := f.stream(ctx, candidate, provider, start, emit)
+	if streamed || ctx.Err() != nil {
+		return true, err
+	}
+	f.logger.Warn("LLM %s 未能输出回复，尝试下一个LLM: %v", candidate.name, err)
+	return false, err
+}
+
+// acquire 获取候选LLM实例，主LLM直接使用，备用LLM从资源池借用
+func (f *FailoverLLM) acquire(index int) (providers.LLMProvider, func(), error) {
+	if index == 0 {
+		return f.primary, func() {}, nil
+	}
+
+	pool := f.candidates[index].pool
+	resource, err := pool.Get()
+	if err != nil {
+		return nil, nil, err
+	}
+	provider := resource.(providers.LLMProvider)
+
+	f.mutex.Lock()
+	for idType, flag := range f.identityFlags {
+		provider.SetIdentityFlag(idType, flag)
+	}
+	f.mutex.Unlock()
+
+	release := func() {
+		if err := pool.Reset(provider); err != nil {
+			f.logger.Warn("重置备用LLM资源状态失败: %v", err)
+		}
+		if err := pool.Put(provider); err != nil {
+			f.logger.Error("归还备用LLM失败: %v", err)
+		}
+	}
+	return provider, release, nil
+}
+
+// stream 使用单个候选LLM生成回复，返回是否已开始输出
+// 开始输出前出错或首个token超时返回false，由调用方切换到下一个LLM
+func (f *FailoverLLM) stream(
+	ctx context.Context,
+	candidate *llmCandidate,
+	provider providers.LLMProvider,
+	start streamFunc,
+	emit func(types.Response),
+) (bool, error) {
+	streamCtx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	// 标记实际生成回复的LLM
+	forward := emit
+	emit = func(resp types.Response) {
+		resp.Provider = candidate.name
+		forward(resp)
+	}
+
+	responses, err := start(streamCtx, provider)
+	if err != nil {
+		candidate.breaker.RecordFailure()
+		return false, err
+	}
+
+	timer := time.NewTimer(f.firstTokenTimeout)
+	defer timer.Stop()
+
+	// 等待首个token
+	for {
+		select {
+		case <-ctx.Done():
+			go drainResponses(responses)
+			return false, ctx.Err()
+		case <-timer.C:
+			candidate.breaker.RecordFailure()
+			go drainResponses(responses)
+			return false, fmt.Errorf("首个token超时(%s)", f.firstTokenTimeout)
+		case resp, ok := <-responses:
+			if !ok {
+				candidate.breaker.RecordFailure()
+				return false, fmt.Errorf("未返回任何内容")
+			}
+			if isErrorResponse(resp) {
+				candidate.breaker.RecordFailure()
+				go drainResponses(responses)
+				return false, fmt.Errorf("%s", responseError(resp))
+			}
+			// 思考内容也算开始输出，避免思考较久的模型触发首个token超时
+			if resp.Content == "" && resp.Reasoning == "" && len(resp.ToolCalls) == 0 {
+				continue
+			}
+			emit(resp)
+		}
+		break
+	}
+
+	// 已开始输出，后续内容直接转发
+	failed := false
+	for resp := range responses {
+		if isErrorResponse(resp) {
+			failed = true
+		}
+		emit(resp)
+	}
+
+	switch {
+	case failed:
+		candidate.breaker.RecordFailure()
+	case ctx.Err() == nil:
+		candidate.breaker.RecordSuccess()
+	}
+	return true, nil
+}
+
+// isErrorResponse 是否为错误响应，部分提供者只在内容中返回异常提示
+func isErrorResponse(resp types.Response) bool {
+	return resp.Error != "" || strings.Contains(resp.Content, "服务响应异常")
+}
+
+// responseError 获取错误响应的错误信息
+func responseError(resp types.Response) string {
+	if resp.Error != "" {
+		return resp.Error
+	}
+	return resp.Content
+}
+
+// drainResponses 丢弃剩余响应，使提供者的发送协程能够退出
+func drainResponses(responses <-chan types.Response) {
+	for range responses {
+	}
+}
@@ -0,0 +1,74 @@
+package pool
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+
+	"angrymiao-ai-server/src/core/providers"
+	"angrymiao-ai-server/src/core/types"
+	"angrymiao-ai-server/src/core/utils"
+
+	"github.com/sashabaranov/go-openai"
+)
+
+// fakeLLM 返回固定内容的LLM，fail 为true时请求失败
+type fakeLLM struct {
+	providers.LLMProvider
+	content string
+	fail    bool
+}
+
+func (f *fakeLLM) SetIdentityFlag(idType string, flag string) {}
+
+func (f *fakeLLM) ResponseWithFunctions(ctx context.Context, sessionID string, messages []types.Message, tools []openai.Tool) (<-chan types.Response, error) {
+	if f.fail {
+		return nil, fmt.Errorf("服务不可用")
+	}
+	responses := make(chan types.Response, 1)
+	responses <- types.Response{Content: f.content}
+	close(responses)
+	return responses, nil
+}
+
+// fakeLLMFactory 创建备用LLM的资源工厂
+type fakeLLMFactory struct {
+	content string
+}
+
+func (f *fakeLLMFactory) Create() (interface{}, error) {
+	return &fakeLLM{content: f.content}, nil
+}
+
+func (f *fakeLLMFactory) Destroy(resource interface{}) error { return nil }
+
+func TestFailoverLLMReportsProvider(t *testing.T) {
+	logger, err := utils.NewLogger(&utils.LogCfg{LogLevel: "ERROR", LogDir: t.TempDir(), LogFile: "test.log"})
+	if err != nil {
+		t.Fatalf("NewLogger() 错误: %v", err)
+	}
+	defer logger.Close()
+	backupPool, err := NewResourcePool("llmPool:backup", &fakeLLMFactory{content: "备用回复"}, PoolConfig{MinSize: 1, MaxSize: 1}, logger)
+	if err != nil {
+		t.Fatalf("NewResourcePool() 错误: %v", err)
+	}
+	defer backupPool.Close()
+
+	failover := newFailoverLLM(&fakeLLM{fail: true}, []*llmCandidate{
+		{name: "primary", breaker: NewCircuitBreaker("primary", BreakerConfig{})},
+		{name: "backup", pool: backupPool, breaker: NewCircuitBreaker("backup", BreakerConfig{})},
+	}, time.Second, logger)
+
+	responses, err := failover.ResponseWithFunctions(context.Background(), "", nil, nil)
+	if err != nil {
+		t.Fatalf("ResponseWithFunctions() 错误: %v", err)
+	}
+	var got []types.Response
+	for resp := range responses {
+		got = append(got, resp)
+	}
+	if len(got) != 1 || got[0].Content != "备用回复" || got[0].Provider != "backup" {
+		t.Errorf("故障转移后响应 = %+v, 期望由 backup 生成", got)
+	}
+}
@@ -8,6 +8,7 @@ import (
 	"angrymiao-ai-server/src/core/utils"
 	"context"
 	"fmt"
+	"sync"
 	"time"
 )
 
@@ -19,6 +20,15 @@ type PoolManager struct {
 	vlllmPool *ResourcePool
 	mcpPool   *ResourcePool
 	logger    *utils.Logger
+
+	llmCandidates     []*llmCandidate // 主LLM和备用LLM，第一个为主LLM
+	firstTokenTimeout time.Duration
+
+	// 按名称借用的其他LLM，首次使用时创建按需资源池
+	config       *configs.Config
+	namedConfig  PoolConfig
+	namedLLMs    map[string]*ResourcePool
+	namedLLMsMux sync.Mutex
 }
 
 // ProviderSet 提供者集合
@@ -33,7 +43,9 @@ type ProviderSet struct {
 // NewPoolManager 创建资源池管理器
 func NewPoolManager(config *configs.Config, logger *utils.Logger) (*PoolManager, error) {
 	pm := &PoolManager{
-		logger: logger,
+		logger:    logger,
+		config:    config,
+		namedLLMs: make(map[string]*ResourcePool),
 	}
 
 	// 执行连通性检查
@@ -71,7 +83,7 @@ func NewPoolManager(config *configs.Config, logger *utils.Logger) (*PoolManager,
 	}
 
 	// 初始化LLM池
-	if llmType, ok := selectedModule["LLM"]; ok && llmType != "" {
+	if llmType := config.PrimaryLLM(); llmType != "" {
 		llmFactory := NewLLMFactory(llmType, config, logger)
 		if llmFactory == nil {
 			return nil, fmt.Errorf("创建LLM工厂失败: 找不到配置 %s", llmType)
@@ -83,6 +95,8 @@ func NewPoolManager(config *configs.Config, logger *utils.Logger) (*PoolManager,
 		pm.llmPool = llmPool
 		_, cnt := llmPool.GetStats()
 		logger.Info("LLM资源池初始化成功，类型: %s, 数量：%d", llmType, cnt)
+
+		pm.initLLMFailover(config, poolConfig)
 	}
 
 	// 初始化TTS池
@@ -164,6 +178,10 @@ func (pm *PoolManager) GetProviderSet() (*ProviderSet, error) {
 			return nil, fmt.Errorf("获取LLM提供者失败: %v", err)
 		}
 		set.LLM = llm.(providers.LLMProvider)
+		// 配置了备用LLM时使用故障转移包装
+		if len(pm.llmCandidates) > 1 {
+			set.LLM = newFailoverLLM(set.LLM, pm.llmCandidates, pm.firstTokenTimeout, pm.logger)
+		}
 	}
 
 	if pm.ttsPool != nil {
@@ -201,6 +219,17 @@ func (pm *PoolManager) Close() {
 	if pm.llmPool != nil {
 		pm.llmPool.Close()
 	}
+	for i, candidate := range pm.llmCandidates {
+		// 主LLM资源池已关闭
+		if i > 0 {
+			candidate.pool.Close()
+		}
+	}
+	pm.namedLLMsMux.Lock()
+	for _, pool := range pm.namedLLMs {
+		pool.Close()
+	}
+	pm.namedLLMsMux.Unlock()
 	if pm.ttsPool != nil {
 		pm.ttsPool.Close()
 	}
@@ -235,7 +264,10 @@ func (pm *PoolManager) ReturnProviderSet(set *ProviderSet) error {
 		}
 	}
 
-	// 归还LLM提供者
+	// 归还LLM提供者，故障转移包装只归还主LLM
+	if failover, ok := set.LLM.(*FailoverLLM); ok {
+		set.LLM = failover.Primary()
+	}
 	if set.LLM != nil && pm.llmPool != nil {
 		if err := pm.llmPool.Reset(set.LLM); err != nil {
 			pm.logger.Warn("重置LLM资源状态失败: %v", err)
@@ -308,6 +340,10 @@ func (pm *PoolManager) GetStats() map[string]map[string]int {
 		available, total := pm.llmPool.GetStats()
 		stats["llm"] = map[string]int{"available": available, "total": total}
 	}
+	pm.addLLMBreakerStats(stats, func(pool *ResourcePool) map[string]int {
+		available, total := pool.GetStats()
+		return map[string]int{"available": available, "total": total}
+	})
 
 	if pm.ttsPool != nil {
 		available, total := pm.ttsPool.GetStats()
@@ -365,6 +401,7 @@ func (pm *PoolManager) GetDetailedStats() map[string]map[string]int {
 	if pm.llmPool != nil {
 		stats["llm"] = pm.llmPool.GetDetailedStats()
 	}
+	pm.addLLMBreakerStats(stats, (*ResourcePool).GetDetailedStats)
 
 	if pm.ttsPool != nil {
 		stats["tts"] = pm.ttsPool.GetDetailedStats()
@@ -380,3 +417,117 @@ func (pm *PoolManager) GetDetailedStats() map[string]map[string]int {
 
 	return stats
 }
+
+// initLLMFailover 初始化备用LLM资源池和各LLM的熔断器
+// 备用LLM只在切换时按需创建，不预创建资源
+func (pm *PoolManager) initLLMFailover(config *configs.Config, poolConfig PoolConfig) {
+	failoverCfg := config.LLMFailover
+	pm.firstTokenTimeout = time.Duration(failoverCfg.FirstTokenTimeout) * time.Second
+	breakerConfig := BreakerConfig{
+		ErrorRate:    failoverCfg.ErrorRate,
+		MinRequests:  failoverCfg.MinRequests,
+		Window:       time.Duration(failoverCfg.Window) * time.Second,
+		OpenDuration: time.Duration(failoverCfg.OpenDuration) * time.Second,
+	}
+
+	names := config.SelectedLLMs()
+	pm.llmCandidates = []*llmCandidate{{
+		name:    names[0],
+		pool:    pm.llmPool,
+		breaker: NewCircuitBreaker(names[0], breakerConfig),
+	}}
+
+	fallbackConfig := poolConfig
+	fallbackConfig.MinSize = 0
+	fallbackConfig.RefillSize = 0
+	pm.namedConfig = fallbackConfig
+	for _, name := range names[1:] {
+		factory := NewLLMFactory(name, config, pm.logger)
+		if factory == nil {
+			pm.logger.Warn("创建备用LLM工厂失败: 找不到配置 %s", name)
+			continue
+		}
+		pool, err := NewResourcePool("llmPool:"+name, factory, fallbackConfig, pm.logger)
+		if err != nil {
+			pm.logger.Warn("初始化备用LLM资源池失败: %s, %v", name, err)
+			continue
+		}
+		pm.llmCandidates = append(pm.llmCandidates, &llmCandidate{
+			name:    name,
+			pool:    pool,
+			breaker: NewCircuitBreaker(name, breakerConfig),
+		})
+		pm.logger.Info("备用LLM资源池初始化成功，类型: %s", name)
+	}
+}
+
+// addLLMBreakerStats 添加LLM熔断器状态，主LLM合并到llm中，备用LLM使用llm:名称
+func (pm *PoolManager) addLLMBreakerStats(stats map[string]map[string]int, poolStats func(*ResourcePool) map[string]int) {
+	for i, candidate := range pm.llmCandidates {
+		key := "llm"
+		if i > 0 {
+			key = "llm:" + candidate.name
+			stats[key] = poolStats(candidate.pool)
+		}
+		if stats[key] == nil {
+			stats[key] = make(map[string]int)
+		}
+		for k, v := range candidate.breaker.GetStats() {
+			stats[key][k] = v
+		}
+	}
+}
+
+// AcquireLLM 按配置名称从资源池借用LLM，用完后调用返回的函数归还
+// 主LLM和备用LLM复用已有的资源池，其他LLM首次使用时创建不预创建资源的按需资源池
+func (pm *PoolManager) AcquireLLM(name string) (providers.LLMProvider, func(), error) {
+	pool, err := pm.namedLLMPool(name)
+	if err != nil {
+		return nil, nil, err
+	}
+	resource, err := pool.Get()
+	if err != nil {
+		return nil, nil, fmt.Errorf("获取LLM %s 失败: %v", name, err)
+	}
+	provider, ok := resource.(providers.LLMProvider)
+	if !ok {
+		pool.Put(resource)
+		return nil, nil, fmt.Errorf("资源池 %s 中的资源不是LLM", name)
+	}
+
+	release := func() {
+		if err := pool.Reset(provider); err != nil {
+			pm.logger.Warn("重置LLM %s 资源状态失败: %v", name, err)
+		}
+		if err := pool.Put(provider); err != nil {
+			pm.logger.Error("归还LLM %s 失败: %v", name, err)
+		}
+	}
+	return provider, release, nil
+}
+
+// namedLLMPool 获取指定名称LLM的资源池
+func (pm *PoolManager) namedLLMPool(name string) (*ResourcePool, error) {
+	for _, candidate := range pm.llmCandidates {
+		if candidate.name == name {
+			return candidate.pool, nil
+		}
+	}
+
+	pm.namedLLMsMux.Lock()
+	defer pm.namedLLMsMux.Unlock()
+	if pool, ok := pm.namedLLMs[name]; ok {
+		return pool, nil
+	}
+	factory := NewLLMFactory(name, pm.config, pm.logger)
+	if factory == nil {
+		return nil, fmt.Errorf("找不到LLM配置 %s", name)
+	}
+	pool, err := NewResourcePool("llmPool:"+name, factory, pm.namedConfig, pm.logger)
+	if err != nil {
+		return nil, fmt.Errorf("初始化LLM资源池 %s 失败: %v", name, err)
+	}
+	pm.namedLLMs[name] = pool
+	pm.logger.Info("按需LLM资源池初始化成功，类型: %s", name)
+	return pool, nil
+}
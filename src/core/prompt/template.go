@@ -0,0 +1,170 @@
+// Package prompt 实现系统提示词模板，每轮对话前用运行时变量渲染
+//
+// 模板使用 Go text/template 语法，例如：
+//
+//	现在是{{.Date}} {{.Weekday}} {{.Time}}。
+//	{{if .Nickname}}用户希望你称呼他为{{.Nickname}}。{{end}}
+//	{{if .Tools}}你可以使用这些工具：{{join .Tools "、"}}。{{end}}
+//	用户的爱好是{{default .Fields.hobby "未知"}}。
+//
+// 不包含 {{ 的提示词按原文使用
+package prompt
+
+import (
+	"fmt"
+	"strings"
+	"text/template"
+	"time"
+
+	"angrymiao-ai-server/src/configs"
+	"angrymiao-ai-server/src/models"
+)
+
+var weekdays = [...]string{"星期日", "星期一", "星期二", "星期三", "星期四", "星期五", "星期六"}
+
+// Variables 提示词模板可用的变量
+type Variables struct {
+	Date     string `json:"date"`     // 当前日期，如 2024-05-01
+	Time     string `json:"time"`     // 当前时间，如 14:30
+	Weekday  string `json:"weekday"`  // 星期，如 星期三
+	Timezone string `json:"timezone"` // 时区，如 Asia/Shanghai (UTC+08:00)
+
+	DeviceID string `json:"device_id"` // 设备ID
+	Board    string `json:"board"`     // 设备型号
+	Nickname string `json:"nickname"`  // 用户昵称
+	Voice    string `json:"voice"`     // 当前音色
+	Role     string `json:"role"`      // 当前角色，默认角色为空
+
+	Tools  []string          `json:"tools"`  // 可用工具名称
+	Fields map[string]string `json:"fields"` // 用户自定义字段
+}
+
+// SetProfile 设置用户昵称和自定义字段，profile为nil时清空
+func (v *Variables) SetProfile(profile *models.UserPromptProfile) {
+	if profile == nil {
+		v.Nickname, v.Fields = "", map[string]string{}
+		return
+	}
+	v.Nickname = profile.Nickname
+	v.Fields = profile.FieldMap()
+}
+
+// SetTime 按指定时区设置日期、时间、星期和时区变量，时区无效时使用本地时区
+func (v *Variables) SetTime(now time.Time, timezone string) {
+	if timezone != "" {
+		if loc, err := time.LoadLocation(timezone); err == nil {
+			now = now.In(loc)
+		}
+	}
+	v.Date = now.Format("2006-01-02")
+	v.Time = now.Format("15:04")
+	v.Weekday = weekdays[now.Weekday()]
+	v.Timezone = fmt.Sprintf("%s (UTC%s)", now.Location().String(), now.Format("-07:00"))
+}
+
+// SampleVariables 用于校验和预览模板的示例变量
+func SampleVariables() Variables {
+	v := Variables{
+		DeviceID: "00:00:00:00:00:00",
+		Board:    "demo-board",
+		Nickname: "小明",
+		Voice:    "zh-CN-XiaoxiaoNeural",
+		Role:     "",
+		Tools:    []string{"time", "exit"},
+		Fields:   map[string]string{},
+	}
+	v.SetTime(time.Now(), "")
+	return v
+}
+
+var funcs = template.FuncMap{
+	"join": strings.Join,
+	// default 在值为空时使用默认值，如 {{default .Nickname "朋友"}}
+	"default": func(value, fallback string) string {
+		if strings.TrimSpace(value) == "" {
+			return fallback
+		}
+		return value
+	},
+}
+
+// Template 系统提示词模板
+type Template struct {
+	text string
+	tmpl *template.Template // 不含模板语法时为nil
+}
+
+// Parse 解析并校验提示词模板，使用示例变量试渲染以发现引用了不存在变量等错误
+func Parse(text string) (*Template, error) {
+	t := &Template{text: text}
+	if !strings.Contains(text, "{{") {
+		return t, nil
+	}
+
+	tmpl, err := template.New("prompt").Funcs(funcs).Option("missingkey=zero").Parse(text)
+	if err != nil {
+		return nil, fmt.Errorf("提示词模板语法错误: %v", err)
+	}
+	t.tmpl = tmpl
+	if _, err := t.Render(SampleVariables()); err != nil {
+		return nil, err
+	}
+	return t, nil
+}
+
+// Text 返回模板原文
+func (t *Template) Text() string {
+	return t.text
+}
+
+// Render 用变量渲染提示词
+func (t *Template) Render(vars Variables) (string, error) {
+	if t.tmpl == nil {
+		return t.text, nil
+	}
+	if vars.Fields == nil {
+		vars.Fields = map[string]string{}
+	}
+	var b strings.Builder
+	if err := t.tmpl.Execute(&b, vars); err != nil {
+		return "", fmt.Errorf("提示词模板渲染失败: %v", err)
+	}
+	return b.String(), nil
+}
+
+// RolePrompt 从配置的角色列表中查找角色提示词，角色列表以@分隔角色名称和提示词
+func RolePrompt(cfg *configs.Config, role string) (string, bool) {
+	for _, item := range cfg.Roles {
+		if name, text, ok := strings.Cut(item, "@"); ok && name == role {
+			return text, true
+		}
+	}
+	return "", false
+}
+
+// ValidateConfig 校验配置中的默认提示词和角色提示词模板
+func ValidateConfig(cfg *configs.Config) error {
+	if _, err := Parse(cfg.DefaultPrompt); err != nil {
+		return fmt.Errorf("默认提示词: %v", err)
+	}
+	for _, role := range cfg.Roles {
+		name, text, ok := strings.Cut(role, "@")
+		if !ok {
+			continue
+		}
+		if _, err := Parse(text); err != nil {
+			return fmt.Errorf("角色 %s 的提示词: %v", name, err)
+		}
+	}
+	if cfg.PromptTemplate.Timezone != "" {
+		if _, err := time.LoadLocation(cfg.PromptTemplate.Timezone); err != nil {
+			return fmt.Errorf("提示词模板时区无效: %v", err)
+		}
+	}
+	return nil
+}
+
+// Literal 创建按原文使用的提示词，用于模板无效时回退
+func Literal(text string) *Template {
+	return &Template{text: text}
+}
@@ -0,0 +1,51 @@
+package prompt
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestRender(t *testing.T) {
+	tmpl, err := Parse(`今天是{{.Date}}{{.Weekday}}。{{if .Nickname}}称呼用户为{{.Nickname}}。{{end}}可用工具：{{join .Tools "、"}}。爱好：{{default .Fields.hobby "未知"}}`)
+	if err != nil {
+		t.Fatalf("Parse() 错误: %v", err)
+	}
+
+	vars := Variables{Tools: []string{"time", "exit"}, Fields: map[string]string{"hobby": "钓鱼"}}
+	vars.SetTime(time.Date(2024, 5, 1, 14, 30, 0, 0, time.UTC), "Asia/Shanghai")
+	got, err := tmpl.Render(vars)
+	if err != nil {
+		t.Fatalf("Render() 错误: %v", err)
+	}
+	want := "今天是2024-05-01星期三。可用工具：time、exit。爱好：钓鱼"
+	if got != want {
+		t.Errorf("Render() = %s, 期望 %s", got, want)
+	}
+	if vars.Time != "22:30" || !strings.HasPrefix(vars.Timezone, "Asia/Shanghai") {
+		t.Errorf("SetTime() 时间 = %s, 时区 = %s", vars.Time, vars.Timezone)
+	}
+
+	vars.Nickname = "小明"
+	vars.Fields = nil
+	if got, _ := tmpl.Render(vars); !strings.Contains(got, "称呼用户为小明") || !strings.HasSuffix(got, "爱好：未知") {
+		t.Errorf("Render() = %s", got)
+	}
+}
+
+func TestParse(t *testing.T) {
+	plain := "你是一个助手 {not a template}"
+	tmpl, err := Parse(plain)
+	if err != nil {
+		t.Fatalf("Parse() 普通文本错误: %v", err)
+	}
+	if got, _ := tmpl.Render(Variables{}); got != plain {
+		t.Errorf("普通文本 Render() = %s", got)
+	}
+
+	for _, text := range []string{"{{if .Nickname}}未闭合", "{{.Unknown}}", "{{upper .Role}}"} {
+		if _, err := Parse(text); err == nil {
+			t.Errorf("Parse(%q) 应返回错误", text)
+		}
+	}
+}
@@ -0,0 +1,415 @@
+package anthropic
+
+import (
+	"angrymiao-ai-server/src/core/providers/llm"
+	"angrymiao-ai-server/src/core/types"
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/sashabaranov/go-openai"
+)
+
+const (
+	defaultBaseURL   = "https://api.anthropic.com"
+	defaultVersion   = "2023-06-01"
+	defaultMaxTokens = 1024
+)
+
+// Provider Anthropic Messages API LLM提供者
+type Provider struct {
+	*llm.BaseProvider
+	client    *http.Client
+	baseURL   string
+	version   string
+	maxTokens int
+}
+
+// 注册提供者
+func init() {
+	llm.Register("anthropic", NewProvider)
+	// 兼容用户配置中使用的claude类型名称
+	llm.Register("claude", NewProvider)
+}
+
+// NewProvider 创建Anthropic提供者
+func NewProvider(config *llm.Config) (llm.Provider, error) {
+	base := llm.NewBaseProvider(config)
+	provider := &Provider{
+		BaseProvider: base,
+		maxTokens:    config.MaxTokens,
+	}
+	if provider.maxTokens <= 0 {
+		provider.maxTokens = defaultMaxTokens
+	}
+
+	return provider, nil
+}
+
+// Initialize 初始化提供者
+func (p *Provider) Initialize() error {
+	config := p.Config()
+	if config.APIKey == "" {
+		return fmt.Errorf("缺少Anthropic API key配置")
+	}
+
+	p.baseURL = strings.TrimSuffix(config.BaseURL, "/")
+	if p.baseURL == "" {
+		p.baseURL = defaultBaseURL
+	}
+	// 兼容填写到/v1的地址
+	p.baseURL = strings.TrimSuffix(p.baseURL, "/v1")
+
+	p.version = defaultVersion
+	if version, ok := config.Extra["anthropic_version"].(string); ok && version != "" {
+		p.version = version
+	}
+
+	p.client = &http.Client{Timeout: 5 * time.Minute}
+	return nil
+}
+
+// Cleanup 清理资源
+func (p *Provider) Cleanup() error {
+	return nil
+}
+
+// Response types.LLMProvider接口实现
+func (p *Provider) Response(ctx context.Context, sessionID string, messages []types.Message) (<-chan string, error) {
+	responseChan := make(chan string, 10)
+
+	go func() {
+		defer close(responseChan)
+
+		err := p.stream(ctx, p.buildRequest(messages, nil), func(chunk types.Response) {
+			if chunk.Content != "" {
+				responseChan <- chunk.Content
+			}
+		})
+		if err != nil {
+			responseChan <- fmt.Sprintf("【Anthropic服务响应异常: %v】", err)
+		}
+	}()
+
+	return responseChan, nil
+}
+
+// ResponseWithFunctions types.LLMProvider接口实现
+func (p *Provider) ResponseWithFunctions(ctx context.Context, sessionID string, messages []types.Message, tools []openai.Tool) (<-chan types.Response, error) {
+	responseChan := make(chan types.Response, 10)
+
+	go func() {
+		defer close(responseChan)
+
+		err := p.stream(ctx, p.buildRequest(messages, tools), func(chunk types.Response) {
+			responseChan <- chunk
+		})
+		if err != nil {
+			responseChan <- types.Response{
+				Content: fmt.Sprintf("【Anthropic服务响应异常: %v】", err),
+				Error:   err.Error(),
+			}
+		}
+	}()
+
+	return responseChan, nil
+}
+
+// messagesRequest Messages API请求体
+type messagesRequest struct {
+	Model       string    `json:"model"`
+	System      string    `json:"system,omitempty"`
+	Messages    []message `json:"messages"`
+	Tools       []tool    `json:"tools,omitempty"`
+	MaxTokens   int       `json:"max_tokens"`
+	Temperature *float64  `json:"temperature,omitempty"`
+	TopP        *float64  `json:"top_p,omitempty"`
+	Stream      bool      `json:"stream"`
+}
+
+// message Anthropic消息，角色只有user和assistant
+type message struct {
+	Role    string         `json:"role"`
+	Content []contentBlock `json:"content"`
+}
+
+// contentBlock 消息内容块
+type contentBlock struct {
+	Type      string          `json:"type"`
+	Text      string          `json:"text,omitempty"`
+	ID        string          `json:"id,omitempty"`
+	Name      string          `json:"name,omitempty"`
+	Input     json.RawMessage `json:"input,omitempty"`
+	ToolUseID string          `json:"tool_use_id,omitempty"`
+	Content   string          `json:"content,omitempty"`
+}
+
+// tool Anthropic工具定义
+type tool struct {
+	Name        string      `json:"name"`
+	Description string      `json:"description,omitempty"`
+	InputSchema interface{} `json:"input_schema"`
+}
+
+// buildRequest 构建Messages API请求
+func (p *Provider) buildRequest(messages []types.Message, tools []openai.Tool) *messagesRequest {
+	config := p.Config()
+	system, converted := convertMessages(messages)
+	req := &messagesRequest{
+		Model:     config.ModelName,
+		System:    system,
+		Messages:  converted,
+		Tools:     convertTools(tools),
+		MaxTokens: p.maxTokens,
+		Stream:    true,
+	}
+	if config.Temperature > 0 {
+		temperature := config.Temperature
+		req.Temperature = &temperature
+	}
+	// top_p为1时等同于不设置，部分模型不允许与temperature同时设置
+	if config.TopP > 0 && config.TopP < 1 {
+		topP := config.TopP
+		req.TopP = &topP
+	}
+	return req
+}
+
+// convertMessages 转换消息格式，system消息提取为系统提示词，tool消息转换为tool_result内容块
+// 连续的同角色消息会合并，保证user和assistant交替出现
+func convertMessages(messages []types.Message) (string, []message) {
+	var systemParts []string
+	var result []message
+
+	appendBlocks := func(role string, blocks []contentBlock) {
+		if len(blocks) == 0 {
+			return
+		}
+		if n := len(result); n > 0 && result[n-1].Role == role {
+			result[n-1].Content = append(result[n-1].Content, blocks...)
+			return
+		}
+		result = append(result, message{Role: role, Content: blocks})
+	}
+
+	for _, msg := range messages {
+		switch msg.Role {
+		case "system":
+			if strings.TrimSpace(msg.Content) != "" {
+				systemParts = append(systemParts, msg.Content)
+			}
+		case "tool":
+			appendBlocks("user", []contentBlock{{
+				Type:      "tool_result",
+				ToolUseID: msg.ToolCallID,
+				Content:   msg.Content,
+			}})
+		case "assistant":
+			var blocks []contentBlock
+			if strings.TrimSpace(msg.Content) != "" {
+				blocks = append(blocks, contentBlock{Type: "text", Text: msg.Content})
+			}
+			for _, tc := range msg.ToolCalls {
+				input := json.RawMessage(tc.Function.Arguments)
+				if !json.Valid(input) {
+					input = json.RawMessage("{}")
+				}
+				blocks = append(blocks, contentBlock{
+					Type:  "tool_use",
+					ID:    tc.ID,
+					Name:  tc.Function.Name,
+					Input: input,
+				})
+			}
+			appendBlocks("assistant", blocks)
+		default:
+			if strings.TrimSpace(msg.Content) != "" {
+				appendBlocks("user", []contentBlock{{Type: "text", Text: msg.Content}})
+			}
+		}
+	}
+
+	return strings.Join(systemParts, "\n"), result
+}
+
+// convertTools 将OpenAI工具定义转换为Anthropic工具定义
+func convertTools(tools []openai.Tool) []tool {
+	var result []tool
+	for _, t := range tools {
+		if t.Function == nil || t.Function.Name == "" {
+			continue
+		}
+		schema := t.Function.Parameters
+		if schema == nil {
+			schema = map[string]interface{}{"type": "object", "properties": map[string]interface{}{}}
+		}
+		result = append(result, tool{
+			Name:        t.Function.Name,
+			Description: t.Function.Description,
+			InputSchema: schema,
+		})
+	}
+	return result
+}
+
+// streamEvent 流式事件
+type streamEvent struct {
+	Type         string `json:"type"`
+	Index        int    `json:"index"`
+	ContentBlock struct {
+		Type string `json:"type"`
+		ID   string `json:"id"`
+		Name string `json:"name"`
+	} `json:"content_block"`
+	Delta struct {
+		Type        string `json:"type"`
+		Text        string `json:"text"`
+		PartialJSON string `json:"partial_json"`
+		StopReason  string `json:"stop_reason"`
+	} `json:"delta"`
+	Message struct {
+		Usage streamUsage `json:"usage"`
+	} `json:"message"`
+	Usage *streamUsage `json:"usage"`
+	Error *apiError    `json:"error"`
+}
+
+// streamUsage 用量，message_start携带输入token数，message_delta携带累计输出token数
+type streamUsage struct {
+	InputTokens  int `json:"input_tokens"`
+	OutputTokens int `json:"output_tokens"`
+}
+
+// apiError Anthropic错误信息
+type apiError struct {
+	Type    string `json:"type"`
+	Message string `json:"message"`
+}
+
+// stream 发送请求并解析SSE流，文本增量映射为Content，tool_use块映射为ToolCalls
+func (p *Provider) stream(ctx context.Context, req *messagesRequest, emit func(types.Response)) error {
+	body, err := json.Marshal(req)
+	if err != nil {
+		return fmt.Errorf("序列化请求失败: %v", err)
+	}
+
+	httpReq, err := http.NewRequestWithContext(ctx, http.MethodPost, p.baseURL+"/v1/messages", bytes.NewReader(body))
+	if err != nil {
+		return fmt.Errorf("创建请求失败: %v", err)
+	}
+	httpReq.Header.Set("Content-Type", "application/json")
+	httpReq.Header.Set("Accept", "text/event-stream")
+	httpReq.Header.Set("x-api-key", p.Config().APIKey)
+	httpReq.Header.Set("anthropic-version", p.version)
+
+	resp, err := p.client.Do(httpReq)
+	if err != nil {
+		return fmt.Errorf("请求失败: %v", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		data, _ := io.ReadAll(resp.Body)
+		var errResp struct {
+			Error apiError `json:"error"`
+		}
+		if json.Unmarshal(data, &errResp) == nil && errResp.Error.Message != "" {
+			return fmt.Errorf("状态码 %d, %s: %s", resp.StatusCode, errResp.Error.Type, errResp.Error.Message)
+		}
+		return fmt.Errorf("状态码 %d: %s", resp.StatusCode, strings.TrimSpace(string(data)))
+	}
+
+	// 内容块索引 -> 工具调用序号，以及是否收到过参数
+	toolIndexes := make(map[int]int)
+	toolHasArgs := make(map[int]bool)
+	inputTokens := 0
+
+	scanner := bufio.NewScanner(resp.Body)
+	scanner.Buffer(make([]byte, 64*1024), 1024*1024)
+	for scanner.Scan() {
+		line := scanner.Text()
+		if !strings.HasPrefix(line, "data:") {
+			continue
+		}
+		data := strings.TrimSpace(strings.TrimPrefix(line, "data:"))
+		if data == "" {
+			continue
+		}
+
+		var event streamEvent
+		if err := json.Unmarshal([]byte(data), &event); err != nil {
+			return fmt.Errorf("解析流式事件失败: %v", err)
+		}
+
+		switch event.Type {
+		case "message_start":
+			inputTokens = event.Message.Usage.InputTokens
+		case "content_block_start":
+			if event.ContentBlock.Type == "tool_use" {
+				toolIndex := len(toolIndexes)
+				toolIndexes[event.Index] = toolIndex
+				emit(types.Response{ToolCalls: []types.ToolCall{{
+					ID:       event.ContentBlock.ID,
+					Type:     "function",
+					Function: types.FunctionCall{Name: event.ContentBlock.Name},
+					Index:    toolIndex,
+				}}})
+			}
+		case "content_block_delta":
+			switch event.Delta.Type {
+			case "text_delta":
+				if event.Delta.Text != "" {
+					emit(types.Response{Content: event.Delta.Text})
+				}
+			case "input_json_delta":
+				toolIndex, ok := toolIndexes[event.Index]
+				if !ok || event.Delta.PartialJSON == "" {
+					continue
+				}
+				toolHasArgs[event.Index] = true
+				emit(types.Response{ToolCalls: []types.ToolCall{{
+					Type:     "function",
+					Function: types.FunctionCall{Arguments: event.Delta.PartialJSON},
+					Index:    toolIndex,
+				}}})
+			}
+		case "content_block_stop":
+			// 无参数的工具调用补全为空对象，便于调用方解析
+			if toolIndex, ok := toolIndexes[event.Index]; ok && !toolHasArgs[event.Index] {
+				emit(types.Response{ToolCalls: []types.ToolCall{{
+					Type:     "function",
+					Function: types.FunctionCall{Arguments: "{}"},
+					Index:    toolIndex,
+				}}})
+			}
+		case "message_delta":
+			if event.Delta.StopReason != "" {
+				emit(types.Response{StopReason: event.Delta.StopReason})
+			}
+			if event.Usage != nil {
+				emit(types.Response{Usage: &types.Usage{
+					PromptTokens:     inputTokens,
+					CompletionTokens: event.Usage.OutputTokens,
+				}})
+			}
+		case "error":
+			if event.Error != nil {
+				return fmt.Errorf("%s: %s", event.Error.Type, event.Error.Message)
+			}
+			return fmt.Errorf("未知错误")
+		case "message_stop":
+			return nil
+		}
+	}
+
+	if err := scanner.Err(); err != nil {
+		return fmt.Errorf("读取流式响应失败: %v", err)
+	}
+	return nil
+}
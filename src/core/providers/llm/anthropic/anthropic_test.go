@@ -0,0 +1,229 @@
+package anthropic
+
+import (
+	"angrymiao-ai-server/src/core/providers/llm"
+	"angrymiao-ai-server/src/core/types"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/sashabaranov/go-openai"
+)
+
+// newTestProvider 创建指向本地服务的提供者
+func newTestProvider(t *testing.T, handler http.HandlerFunc) *Provider {
+	t.Helper()
+	server := httptest.NewServer(handler)
+	t.Cleanup(server.Close)
+
+	provider, err := llm.Create("anthropic", &llm.Config{
+		Type:      "anthropic",
+		ModelName: "claude-test",
+		BaseURL:   server.URL,
+		APIKey:    "test-key",
+	})
+	if err != nil {
+		t.Fatalf("创建提供者失败: %v", err)
+	}
+	return provider.(*Provider)
+}
+
+// writeEvents 按SSE格式写入事件
+func writeEvents(w http.ResponseWriter, events ...string) {
+	w.Header().Set("Content-Type", "text/event-stream")
+	for _, event := range events {
+		var typed struct {
+			Type string `json:"type"`
+		}
+		_ = json.Unmarshal([]byte(event), &typed)
+		fmt.Fprintf(w, "event: %s\ndata: %s\n\n", typed.Type, event)
+	}
+}
+
+func TestResponseStreamsText(t *testing.T) {
+	var got messagesRequest
+	provider := newTestProvider(t, func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/v1/messages" {
+			t.Errorf("请求路径 = %s", r.URL.Path)
+		}
+		if r.Header.Get("x-api-key") != "test-key" || r.Header.Get("anthropic-version") != defaultVersion {
+			t.Errorf("请求头错误: %v", r.Header)
+		}
+		if err := json.NewDecoder(r.Body).Decode(&got); err != nil {
+			t.Errorf("解析请求失败: %v", err)
+		}
+		writeEvents(w,
+			`{"type":"message_start","message":{"id":"msg_1","role":"assistant"}}`,
+			`{"type":"content_block_start","index":0,"content_block":{"type":"text","text":""}}`,
+			`{"type":"content_block_delta","index":0,"delta":{"type":"text_delta","text":"你好"}}`,
+			`{"type":"content_block_delta","index":0,"delta":{"type":"text_delta","text":"，世界"}}`,
+			`{"type":"content_block_stop","index":0}`,
+			`{"type":"message_delta","delta":{"stop_reason":"end_turn"}}`,
+			`{"type":"message_stop"}`,
+		)
+	})
+
+	ch, err := provider.Response(context.Background(), "", []types.Message{
+		{Role: "system", Content: "你是助手"},
+		{Role: "user", Content: "你好"},
+	})
+	if err != nil {
+		t.Fatalf("Response() 错误: %v", err)
+	}
+	var text strings.Builder
+	for chunk := range ch {
+		text.WriteString(chunk)
+	}
+
+	if text.String() != "你好，世界" {
+		t.Errorf("回复 = %q", text.String())
+	}
+	if got.System != "你是助手" || len(got.Messages) != 1 || got.Messages[0].Role != "user" {
+		t.Errorf("系统提示词或消息转换错误: %+v", got)
+	}
+	if got.MaxTokens != defaultMaxTokens || !got.Stream {
+		t.Errorf("max_tokens = %d, stream = %v", got.MaxTokens, got.Stream)
+	}
+}
+
+func TestResponseWithFunctionsStreamsToolUse(t *testing.T) {
+	var got map[string]interface{}
+	provider := newTestProvider(t, func(w http.ResponseWriter, r *http.Request) {
+		_ = json.NewDecoder(r.Body).Decode(&got)
+		writeEvents(w,
+			`{"type":"message_start","message":{"id":"msg_2","role":"assistant","usage":{"input_tokens":42,"output_tokens":1}}}`,
+			`{"type":"content_block_start","index":0,"content_block":{"type":"text","text":""}}`,
+			`{"type":"content_block_delta","index":0,"delta":{"type":"text_delta","text":"查一下"}}`,
+			`{"type":"content_block_stop","index":0}`,
+			`{"type":"content_block_start","index":1,"content_block":{"type":"tool_use","id":"toolu_1","name":"get_weather","input":{}}}`,
+			`{"type":"content_block_delta","index":1,"delta":{"type":"input_json_delta","partial_json":"{\"city\":"}}`,
+			`{"type":"content_block_delta","index":1,"delta":{"type":"input_json_delta","partial_json":"\"北京\"}"}}`,
+			`{"type":"content_block_stop","index":1}`,
+			`{"type":"message_delta","delta":{"stop_reason":"tool_use"},"usage":{"output_tokens":17}}`,
+			`{"type":"message_stop"}`,
+		)
+	})
+
+	tools := []openai.Tool{{
+		Type: openai.ToolTypeFunction,
+		Function: &openai.FunctionDefinition{
+			Name:        "get_weather",
+			Description: "查询天气",
+			Parameters: map[string]interface{}{
+				"type":       "object",
+				"properties": map[string]interface{}{"city": map[string]interface{}{"type": "string"}},
+			},
+		},
+	}}
+	messages := []types.Message{
+		{Role: "system", Content: "你是助手"},
+		{Role: "user", Content: "北京天气"},
+		{Role: "assistant", ToolCalls: []types.ToolCall{{
+			ID:       "toolu_0",
+			Type:     "function",
+			Function: types.FunctionCall{Name: "get_weather", Arguments: `{"city":"上海"}`},
+		}}},
+		{Role: "tool", ToolCallID: "toolu_0", Content: "晴"},
+		{Role: "user", Content: "那北京呢"},
+	}
+
+	ch, err := provider.ResponseWithFunctions(context.Background(), "", messages, tools)
+	if err != nil {
+		t.Fatalf("ResponseWithFunctions() 错误: %v", err)
+	}
+
+	var content, id, name, args, stopReason string
+	var usage *types.Usage
+	for chunk := range ch {
+		if chunk.Error != "" {
+			t.Fatalf("响应错误: %s", chunk.Error)
+		}
+		content += chunk.Content
+		for _, tc := range chunk.ToolCalls {
+			if tc.ID != "" {
+				id = tc.ID
+			}
+			if tc.Function.Name != "" {
+				name = tc.Function.Name
+			}
+			args += tc.Function.Arguments
+		}
+		if chunk.StopReason != "" {
+			stopReason = chunk.StopReason
+		}
+		if chunk.Usage != nil {
+			usage = chunk.Usage
+		}
+	}
+
+	if usage == nil || usage.PromptTokens != 42 || usage.CompletionTokens != 17 {
+		t.Errorf("用量 = %+v, 期望 输入42 输出17", usage)
+	}
+	if content != "查一下" || id != "toolu_1" || name != "get_weather" || args != `{"city":"北京"}` || stopReason != "tool_use" {
+		t.Errorf("content=%q id=%q name=%q args=%q stop=%q", content, id, name, args, stopReason)
+	}
+
+	// 工具定义转换为input_schema
+	reqTools := got["tools"].([]interface{})
+	if tool := reqTools[0].(map[string]interface{}); tool["name"] != "get_weather" || tool["input_schema"] == nil {
+		t.Errorf("工具定义转换错误: %v", tool)
+	}
+
+	// tool消息与后续user消息合并为同一条user消息
+	reqMessages := got["messages"].([]interface{})
+	if len(reqMessages) != 3 {
+		t.Fatalf("消息数量 = %d, 期望 3", len(reqMessages))
+	}
+	assistant := reqMessages[1].(map[string]interface{})["content"].([]interface{})[0].(map[string]interface{})
+	if assistant["type"] != "tool_use" || assistant["id"] != "toolu_0" {
+		t.Errorf("assistant工具调用转换错误: %v", assistant)
+	}
+	user := reqMessages[2].(map[string]interface{})["content"].([]interface{})
+	if len(user) != 2 || user[0].(map[string]interface{})["type"] != "tool_result" || user[0].(map[string]interface{})["tool_use_id"] != "toolu_0" {
+		t.Errorf("tool_result转换错误: %v", user)
+	}
+}
+
+func TestResponseWithFunctionsReportsAPIError(t *testing.T) {
+	provider := newTestProvider(t, func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusUnauthorized)
+		_, _ = w.Write([]byte(`{"type":"error","error":{"type":"authentication_error","message":"invalid x-api-key"}}`))
+	})
+
+	ch, err := provider.ResponseWithFunctions(context.Background(), "", []types.Message{{Role: "user", Content: "hi"}}, nil)
+	if err != nil {
+		t.Fatalf("ResponseWithFunctions() 错误: %v", err)
+	}
+	var last types.Response
+	for chunk := range ch {
+		last = chunk
+	}
+	if !strings.Contains(last.Error, "authentication_error") || !strings.Contains(last.Content, "服务响应异常") {
+		t.Errorf("错误响应 = %+v", last)
+	}
+}
+
+func TestToolUseWithoutInputEmitsEmptyObject(t *testing.T) {
+	provider := newTestProvider(t, func(w http.ResponseWriter, r *http.Request) {
+		writeEvents(w,
+			`{"type":"content_block_start","index":0,"content_block":{"type":"tool_use","id":"toolu_2","name":"exit","input":{}}}`,
+			`{"type":"content_block_stop","index":0}`,
+			`{"type":"message_stop"}`,
+		)
+	})
+
+	ch, _ := provider.ResponseWithFunctions(context.Background(), "", []types.Message{{Role: "user", Content: "退出"}}, nil)
+	args := ""
+	for chunk := range ch {
+		for _, tc := range chunk.ToolCalls {
+			args += tc.Function.Arguments
+		}
+	}
+	if args != "{}" {
+		t.Errorf("参数 = %q, 期望 {}", args)
+	}
+}
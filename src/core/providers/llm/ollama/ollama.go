@@ -3,6 +3,7 @@ package ollama
 import (
 	"angrymiao-ai-server/src/core/providers/llm"
 	"angrymiao-ai-server/src/core/types"
+	"angrymiao-ai-server/src/core/utils"
 	"context"
 	"fmt"
 	"strings"
@@ -15,7 +16,7 @@ type Provider struct {
 	*llm.BaseProvider
 	client    *openai.Client
 	modelName string
-	isQwen3   bool
+	noThink   bool // 是否在用户消息中添加/no_think关闭思考
 }
 
 // 注册提供者
@@ -31,8 +32,10 @@ func NewProvider(config *llm.Config) (llm.Provider, error) {
 		modelName:    config.ModelName,
 	}
 
-	// 检查是否是qwen3模型
-	provider.isQwen3 = config.ModelName != "" && strings.HasPrefix(strings.ToLower(config.ModelName), "qwen3")
+	// qwen3模型默认关闭思考，配置 think: true 时保留思考过程用于展示
+	isQwen3 := config.ModelName != "" && strings.HasPrefix(strings.ToLower(config.ModelName), "qwen3")
+	think, _ := config.Extra["think"].(bool)
+	provider.noThink = isQwen3 && !think
 
 	return provider, nil
 }
@@ -76,8 +79,8 @@ func (p *Provider) Response(ctx context.Context, sessionID string, messages []ty
 	go func() {
 		defer close(responseChan)
 
-		// 如果是qwen3模型，在用户最后一条消息中添加/no_think指令
-		if p.isQwen3 {
+		// 如果是qwen3模型且未开启思考，在用户最后一条消息中添加/no_think指令
+		if p.noThink {
 			messages = p.addNoThinkDirective(messages)
 		}
 
@@ -104,9 +107,8 @@ func (p *Provider) Response(ctx context.Context, sessionID string, messages []ty
 		}
 		defer stream.Close()
 
-		isActive := true
-		buffer := ""
-
+		// 纯文本接口只输出回答，丢弃思考内容
+		var think utils.ThinkParser
 		for {
 			response, err := stream.Recv()
 			if err != nil {
@@ -114,22 +116,14 @@ func (p *Provider) Response(ctx context.Context, sessionID string, messages []ty
 			}
 
 			if len(response.Choices) > 0 {
-				content := response.Choices[0].Delta.Content
-				if content != "" {
-					// 将内容添加到缓冲区
-					buffer += content
-
-					// 处理缓冲区中的标签
-					buffer, isActive = p.handleThinkTagsWithBuffer(buffer, isActive)
-
-					// 如果当前处于活动状态且缓冲区有内容，则输出
-					if isActive && buffer != "" {
-						responseChan <- buffer
-						buffer = ""
-					}
+				if content, _ := think.Feed(response.Choices[0].Delta.Content); content != "" {
+					responseChan <- content
 				}
 			}
 		}
+		if content, _ := think.Flush(); content != "" {
+			responseChan <- content
+		}
 	}()
 
 	return responseChan, nil
@@ -142,8 +136,8 @@ func (p *Provider) ResponseWithFunctions(ctx context.Context, sessionID string,
 	go func() {
 		defer close(responseChan)
 
-		// 如果是qwen3模型，在用户最后一条消息中添加/no_think指令
-		if p.isQwen3 {
+		// 如果是qwen3模型且未开启思考，在用户最后一条消息中添加/no_think指令
+		if p.noThink {
 			messages = p.addNoThinkDirective(messages)
 		}
 
@@ -182,10 +176,11 @@ func (p *Provider) ResponseWithFunctions(ctx context.Context, sessionID string,
 		stream, err := p.client.CreateChatCompletionStream(
 			ctx,
 			openai.ChatCompletionRequest{
-				Model:    p.modelName,
-				Messages: chatMessages,
-				Tools:    tools,
-				Stream:   true,
+				Model:         p.modelName,
+				Messages:      chatMessages,
+				Tools:         tools,
+				Stream:        true,
+				StreamOptions: &openai.StreamOptions{IncludeUsage: true},
 			},
 		)
 		if err != nil {
@@ -197,9 +192,7 @@ func (p *Provider) ResponseWithFunctions(ctx context.Context, sessionID string,
 		}
 		defer stream.Close()
 
-		isActive := true
-		buffer := ""
-
+		var think utils.ThinkParser
 		for {
 			response, err := stream.Recv()
 			if err != nil {
@@ -221,6 +214,9 @@ func (p *Provider) ResponseWithFunctions(ctx context.Context, sessionID string,
 								Arguments: tc.Function.Arguments,
 							},
 						}
+						if tc.Index != nil {
+							toolCalls[i].Index = *tc.Index
+						}
 					}
 					responseChan <- types.Response{
 						ToolCalls: toolCalls,
@@ -228,23 +224,26 @@ func (p *Provider) ResponseWithFunctions(ctx context.Context, sessionID string,
 					continue
 				}
 
-				// 处理文本内容
-				if delta.Content != "" {
-					// 将内容添加到缓冲区
-					buffer += delta.Content
-
-					// 处理缓冲区中的标签
-					buffer, isActive = p.handleThinkTagsWithBuffer(buffer, isActive)
-
-					// 如果当前处于活动状态且缓冲区有内容，则输出
-					if isActive && buffer != "" {
-						responseChan <- types.Response{
-							Content: buffer,
-						}
-						buffer = ""
+				// 处理文本内容，思考内容可能在 reasoning 字段，也可能以<think>标签混在正文中
+				content, reasoning := think.Feed(delta.Content)
+				reasoning = delta.ReasoningContent + reasoning
+				if content != "" || reasoning != "" {
+					responseChan <- types.Response{
+						Content:   content,
+						Reasoning: reasoning,
 					}
 				}
 			}
+
+			if response.Usage != nil {
+				responseChan <- types.Response{Usage: &types.Usage{
+					PromptTokens:     response.Usage.PromptTokens,
+					CompletionTokens: response.Usage.CompletionTokens,
+				}}
+			}
+		}
+		if content, reasoning := think.Flush(); content != "" || reasoning != "" {
+			responseChan <- types.Response{Content: content, Reasoning: reasoning}
 		}
 	}()
 
@@ -268,35 +267,3 @@ func (p *Provider) addNoThinkDirective(messages []types.Message) []types.Message
 
 	return messagesCopy
 }
-
-// handleThinkTagsWithBuffer 处理思考标签并返回处理后的缓冲区和活动状态
-func (p *Provider) handleThinkTagsWithBuffer(buffer string, isActive bool) (string, bool) {
-	if buffer == "" {
-		return buffer, isActive
-	}
-
-	// 处理完整的<think></think>标签
-	for strings.Contains(buffer, "<think>") && strings.Contains(buffer, "</think>") {
-		parts := strings.SplitN(buffer, "<think>", 2)
-		pre := parts[0]
-		parts = strings.SplitN(parts[1], "</think>", 2)
-		post := parts[1]
-		buffer = pre + post
-	}
-
-	// 处理只有开始标签的情况
-	if strings.Contains(buffer, "<think>") {
-		parts := strings.SplitN(buffer, "<think>", 2)
-		buffer = parts[0]
-		isActive = false
-	}
-
-	// 处理只有结束标签的情况
-	if strings.Contains(buffer, "</think>") {
-		parts := strings.SplitN(buffer, "</think>", 2)
-		buffer = parts[1]
-		isActive = true
-	}
-
-	return buffer, isActive
-}
@@ -3,6 +3,7 @@ package openai
 import (
 	"angrymiao-ai-server/src/core/providers/llm"
 	"angrymiao-ai-server/src/core/types"
+	"angrymiao-ai-server/src/core/utils"
 	"context"
 	"fmt"
 
@@ -93,7 +94,8 @@ func (p *Provider) Response(ctx context.Context, sessionID string, messages []ty
 		}
 		defer stream.Close()
 
-		isActive := true
+		// 纯文本接口只输出回答，丢弃思考内容
+		var think utils.ThinkParser
 		for {
 			response, err := stream.Recv()
 			if err != nil {
@@ -101,15 +103,14 @@ func (p *Provider) Response(ctx context.Context, sessionID string, messages []ty
 			}
 
 			if len(response.Choices) > 0 {
-				content := response.Choices[0].Delta.Content
-				if content != "" {
-					// 处理思考标签
-					if content, isActive = handleThinkTags(content, isActive); content != "" {
-						responseChan <- content
-					}
+				if content, _ := think.Feed(response.Choices[0].Delta.Content); content != "" {
+					responseChan <- content
 				}
 			}
 		}
+		if content, _ := think.Flush(); content != "" {
+			responseChan <- content
+		}
 	}()
 
 	return responseChan, nil
@@ -158,10 +159,11 @@ func (p *Provider) ResponseWithFunctions(ctx context.Context, sessionID string,
 		stream, err := p.client.CreateChatCompletionStream(
 			ctx,
 			openai.ChatCompletionRequest{
-				Model:    p.Config().ModelName,
-				Messages: chatMessages,
-				Tools:    tools,
-				Stream:   true,
+				Model:         p.Config().ModelName,
+				Messages:      chatMessages,
+				Tools:         tools,
+				Stream:        true,
+				StreamOptions: &openai.StreamOptions{IncludeUsage: true},
 			},
 		)
 		if err != nil {
@@ -173,6 +175,7 @@ func (p *Provider) ResponseWithFunctions(ctx context.Context, sessionID string,
 		}
 		defer stream.Close()
 
+		var think utils.ThinkParser
 		for {
 			response, err := stream.Recv()
 			if err != nil {
@@ -181,8 +184,11 @@ func (p *Provider) ResponseWithFunctions(ctx context.Context, sessionID string,
 
 			if len(response.Choices) > 0 {
 				delta := response.Choices[0].Delta
+				// 思考内容可能在 reasoning_content 字段，也可能以<think>标签混在正文中
+				content, reasoning := think.Feed(delta.Content)
 				chunk := types.Response{
-					Content: delta.Content,
+					Content:   content,
+					Reasoning: delta.ReasoningContent + reasoning,
 				}
 				//fmt.Println("openai delta:", delta)
 
@@ -197,34 +203,28 @@ func (p *Provider) ResponseWithFunctions(ctx context.Context, sessionID string,
 								Arguments: tc.Function.Arguments,
 							},
 						}
+						if tc.Index != nil {
+							toolCalls[i].Index = *tc.Index
+						}
 					}
 					chunk.ToolCalls = toolCalls
 				}
 
 				responseChan <- chunk
 			}
+
+			// 开启include_usage后最后一个分块不含choices，只携带用量
+			if response.Usage != nil {
+				responseChan <- types.Response{Usage: &types.Usage{
+					PromptTokens:     response.Usage.PromptTokens,
+					CompletionTokens: response.Usage.CompletionTokens,
+				}}
+			}
+		}
+		if content, reasoning := think.Flush(); content != "" || reasoning != "" {
+			responseChan <- types.Response{Content: content, Reasoning: reasoning}
 		}
 	}()
 
 	return responseChan, nil
 }
-
-// handleThinkTags 处理思考标签
-func handleThinkTags(content string, isActive bool) (string, bool) {
-	if content == "" {
-		return "", isActive
-	}
-
-	if content == "<think>" {
-		return "", false
-	}
-	if content == "</think>" {
-		return "", true
-	}
-
-	if !isActive {
-		return "", isActive
-	}
-
-	return content, isActive
-}
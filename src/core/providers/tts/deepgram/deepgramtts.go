@@ -7,8 +7,6 @@ import (
 	"fmt"
 	"net/http"
 	"os"
-	"path/filepath"
-	"time"
 
 	"github.com/gorilla/websocket"
 )
@@ -73,7 +71,6 @@ func (p *Provider) ToTTS(text string) (string, error) {
 
 	// ext := getFileExtension(p.Config().Encoding)
 	ext := "mp3"
-	tempFile := filepath.Join(outputDir, fmt.Sprintf("deepgram_tts_%d.%s", time.Now().UnixNano(), ext))
 	// 接收音频数据
 	var lastSeqID int
 	// 接收音频数据
@@ -129,11 +126,7 @@ loop:
 	}
 
 	// 写入音频文件
-	if err := os.WriteFile(tempFile, audioBuffer.Bytes(), 0644); err != nil {
-		return "", fmt.Errorf("写入音频文件失败: %v", err)
-	}
-
-	return tempFile, nil
+	return tts.WriteAudioFile(outputDir, "deepgram_tts", ext, audioBuffer.Bytes())
 }
 
 // getFileExtension 根据编码获取文件扩展名
@@ -154,6 +147,11 @@ func getFileExtension(encoding string) string {
 	}
 }
 
+// SupportsConcurrency Deepgram TTS每次合成建立独立连接，可并发合成
+func (p *Provider) SupportsConcurrency() bool {
+	return true
+}
+
 func init() {
 	tts.Register("deepgram", func(config *tts.Config, deleteFile bool) (tts.Provider, error) {
 		return NewProvider(config, deleteFile)
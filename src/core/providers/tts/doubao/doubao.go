@@ -10,8 +10,6 @@ import (
 	"net/http"
 	"net/url"
 	"os"
-	"path/filepath"
-	"time"
 
 	"angrymiao-ai-server/src/core/providers/tts"
 
@@ -67,6 +65,11 @@ func NewProvider(config *tts.Config, deleteFile bool) (*Provider, error) {
 
 // ToTTS 实现文本到语音的转换
 func (p *Provider) ToTTS(text string) (string, error) {
+	return p.ToTTSWithVoice(text, p.Config().Voice)
+}
+
+// ToTTSWithVoice 使用指定音色合成，不修改配置中的音色
+func (p *Provider) ToTTSWithVoice(text string, voice string) (string, error) {
 	// 创建WebSocket连接
 	header := http.Header{"Authorization": []string{fmt.Sprintf("Bearer;%s", p.Config().Token)}}
 	conn, _, err := websocket.DefaultDialer.Dial(p.baseURL, header)
@@ -75,6 +78,11 @@ func (p *Provider) ToTTS(text string) (string, error) {
 	}
 	defer conn.Close()
 
+	textType := "plain"
+	if tts.IsSSML(text) {
+		textType = "ssml"
+	}
+
 	// 准备请求参数
 	reqParams := map[string]map[string]interface{}{
 		"app": {
@@ -86,7 +94,7 @@ func (p *Provider) ToTTS(text string) (string, error) {
 			"uid": "uid",
 		},
 		"audio": {
-			"voice_type":   p.Config().Voice,
+			"voice_type":   voice,
 			"encoding":     "mp3",
 			"speed_ratio":  1.0,
 			"volume_ratio": 1.0,
@@ -95,7 +103,7 @@ func (p *Provider) ToTTS(text string) (string, error) {
 		"request": {
 			"reqid":     uuid.New().String(),
 			"text":      text,
-			"text_type": "plain",
+			"text_type": textType,
 			"operation": "submit", // 使用流式合成
 		},
 	}
@@ -136,7 +144,6 @@ func (p *Provider) ToTTS(text string) (string, error) {
 		return "", fmt.Errorf("创建输出目录失败: %v", err)
 	}
 
-	tempFile := filepath.Join(outputDir, fmt.Sprintf("doubao_tts_%d.mp3", time.Now().UnixNano()))
 	var audioData []byte
 
 	// 接收音频数据
@@ -158,11 +165,7 @@ func (p *Provider) ToTTS(text string) (string, error) {
 	}
 
 	// 写入音频文件
-	if err := os.WriteFile(tempFile, audioData, 0644); err != nil {
-		return "", fmt.Errorf("写入音频文件失败: %v", err)
-	}
-
-	return tempFile, nil
+	return tts.WriteAudioFile(outputDir, "doubao_tts", "mp3", audioData)
 }
 
 // parseResponse 解析服务器响应
@@ -212,6 +215,16 @@ func (p *Provider) parseResponse(res []byte) (resp synResp, err error) {
 	return resp, nil
 }
 
+// SupportsSSML 豆包TTS支持SSML输入
+func (p *Provider) SupportsSSML() bool {
+	return true
+}
+
+// SupportsConcurrency 豆包TTS每次合成建立独立连接，可并发合成
+func (p *Provider) SupportsConcurrency() bool {
+	return true
+}
+
 func init() {
 	tts.Register("doubao", func(config *tts.Config, deleteFile bool) (tts.Provider, error) {
 		return NewProvider(config, deleteFile)
@@ -4,7 +4,6 @@ import (
 	"angrymiao-ai-server/src/core/providers/tts"
 	"fmt"
 	"os"
-	"path/filepath"
 	"time"
 
 	"github.com/wujunwei928/edge-tts-go/edge_tts"
@@ -26,9 +25,12 @@ func NewProvider(config *tts.Config, deleteFile bool) (*Provider, error) {
 // ToTTS 将文本转换为音频文件，并返回文件路径
 // 使用的edge库是github.com/wujunwei928/edge-tts-go，默认使用24k采样率
 func (p *Provider) ToTTS(text string) (string, error) {
-	// 获取配置的声音，如果未配置则使用默认值
+	return p.ToTTSWithVoice(text, p.BaseProvider.Config().Voice)
+}
+
+// ToTTSWithVoice 使用指定音色合成，音色为空时使用默认值
+func (p *Provider) ToTTSWithVoice(text string, voice string) (string, error) {
 	edgeTTSStartTime := time.Now()
-	voice := p.BaseProvider.Config().Voice
 	if voice == "" {
 		voice = "zh-CN-XiaoxiaoNeural" // 默认声音
 	}
@@ -42,9 +44,6 @@ func (p *Provider) ToTTS(text string) (string, error) {
 	if err := os.MkdirAll(outputDir, 0755); err != nil {
 		return "", fmt.Errorf("创建输出目录失败 '%s': %v", outputDir, err)
 	}
-	// Use a unique filename
-	tempFile := filepath.Join(outputDir, fmt.Sprintf("edge_tts_go_%d.mp3", time.Now().UnixNano()))
-
 	// 配置 edge-tts-go 连接选项
 	connOptions := []edge_tts.CommunicateOption{
 		edge_tts.SetVoice(voice),
@@ -67,19 +66,12 @@ func (p *Provider) ToTTS(text string) (string, error) {
 	//fmt.Println(fmt.Sprintf("edge-tts-go 语音合成完成，耗时: %s", ttsDuration))
 
 	// 将音频数据写入临时文件
-	err = os.WriteFile(tempFile, audioData, 0644)
-	if err != nil {
-		return "", fmt.Errorf("写入音频文件 '%s' 失败: %v", tempFile, err)
-	}
-
-	// 检查文件是否成功创建
-	if _, err := os.Stat(tempFile); os.IsNotExist(err) {
-		return "", fmt.Errorf("edge-tts-go 未能创建音频文件: %s", tempFile)
-	}
-	//fmt.Printf("音频文件已生成: %s\n", tempFile)
+	return tts.WriteAudioFile(outputDir, "edge_tts_go", "mp3", audioData)
+}
 
-	// Return the path to the generated audio file
-	return tempFile, nil
+// SupportsConcurrency Edge TTS每次合成建立独立连接，可并发合成
+func (p *Provider) SupportsConcurrency() bool {
+	return true
 }
 
 func init() {
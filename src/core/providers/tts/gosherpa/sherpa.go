@@ -5,7 +5,6 @@ import (
 	"context"
 	"fmt"
 	"os"
-	"path/filepath"
 	"time"
 
 	"github.com/gorilla/websocket"
@@ -49,9 +48,6 @@ func (p *Provider) ToTTS(text string) (string, error) {
 	if err := os.MkdirAll(outputDir, 0755); err != nil {
 		return "", fmt.Errorf("创建输出目录失败 '%s': %v", outputDir, err)
 	}
-	// Use a unique filename
-	tempFile := filepath.Join(outputDir, fmt.Sprintf("go_sherpa_tts_%d.wav", time.Now().UnixNano()))
-
 	p.conn.WriteMessage(websocket.TextMessage, []byte(text))
 	_, bytes, err := p.conn.ReadMessage()
 
@@ -63,18 +59,7 @@ func (p *Provider) ToTTS(text string) (string, error) {
 	fmt.Println(fmt.Sprintf("go-sherpa-tts 语音合成完成，耗时: %s", ttsDuration))
 
 	// 将音频数据写入临时文件
-	err = os.WriteFile(tempFile, bytes, 0644)
-	if err != nil {
-		return "", fmt.Errorf("写入音频文件 '%s' 失败: %v", tempFile, err)
-	}
-
-	// 检查文件是否成功创建
-	if _, err := os.Stat(tempFile); os.IsNotExist(err) {
-		return "", fmt.Errorf("go-sherpa-tts 未能创建音频文件: %s", tempFile)
-	}
-
-	// Return the path to the generated audio file
-	return tempFile, nil
+	return tts.WriteAudioFile(outputDir, "go_sherpa_tts", "wav", bytes)
 }
 
 func init() {
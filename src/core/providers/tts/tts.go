@@ -7,6 +7,7 @@ import (
 	"fmt"
 	"os"
 	"path/filepath"
+	"strings"
 )
 
 // Config TTS配置结构
@@ -28,6 +29,27 @@ type Provider interface {
 	providers.TTSProvider
 }
 
+// SSMLProvider 支持SSML输入的TTS提供者，ToTTS收到以<speak>开头的文本时按SSML合成
+type SSMLProvider interface {
+	SupportsSSML() bool
+}
+
+// ConcurrentProvider 每次合成使用独立连接、可在多个协程中同时调用ToTTS的TTS提供者
+// 未实现该接口的提供者（如复用同一个WebSocket连接的提供者）在同一连接内依次合成
+type ConcurrentProvider interface {
+	SupportsConcurrency() bool
+}
+
+// VoiceSynthesizer 支持单次合成指定音色的TTS提供者，不修改共享配置中的音色
+type VoiceSynthesizer interface {
+	ToTTSWithVoice(text string, voice string) (string, error)
+}
+
+// IsSSML 判断文本是否为SSML
+func IsSSML(text string) bool {
+	return strings.HasPrefix(strings.TrimSpace(text), "<speak")
+}
+
 // BaseProvider TTS基础实现
 type BaseProvider struct {
 	config     *Config
@@ -112,6 +134,24 @@ func (p *BaseProvider) Cleanup() error {
 	return nil
 }
 
+// WriteAudioFile 将合成的音频写入输出目录下的唯一文件，同一连接并发合成时文件名不会冲突
+func WriteAudioFile(outputDir, prefix, ext string, data []byte) (string, error) {
+	f, err := os.CreateTemp(outputDir, prefix+"_*."+ext)
+	if err != nil {
+		return "", fmt.Errorf("创建音频文件失败: %v", err)
+	}
+	if _, err := f.Write(data); err != nil {
+		f.Close()
+		os.Remove(f.Name())
+		return "", fmt.Errorf("写入音频文件 '%s' 失败: %v", f.Name(), err)
+	}
+	if err := f.Close(); err != nil {
+		os.Remove(f.Name())
+		return "", fmt.Errorf("写入音频文件 '%s' 失败: %v", f.Name(), err)
+	}
+	return f.Name(), nil
+}
+
 // Factory TTS工厂函数类型
 type Factory func(config *Config, deleteFile bool) (Provider, error)
 
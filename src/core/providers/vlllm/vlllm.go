@@ -13,6 +13,7 @@ import (
 	"angrymiao-ai-server/src/configs"
 	"angrymiao-ai-server/src/core/image"
 	"angrymiao-ai-server/src/core/providers"
+	"angrymiao-ai-server/src/core/types"
 	"angrymiao-ai-server/src/core/utils"
 
 	"github.com/sashabaranov/go-openai"
@@ -146,8 +147,8 @@ func (p *Provider) Cleanup() error {
 	return nil
 }
 
-// ResponseWithImage 处理包含图片的请求 - 核心方法
-func (p *Provider) ResponseWithImage(ctx context.Context, sessionID string, messages []providers.Message, imageData image.ImageData, text string) (<-chan string, error) {
+// ResponseWithImage 处理包含图片的请求 - 核心方法，思考内容通过 Reasoning 字段单独输出
+func (p *Provider) ResponseWithImage(ctx context.Context, sessionID string, messages []providers.Message, imageData image.ImageData, text string) (<-chan types.Response, error) {
 	// 处理图片
 	base64Image, err := p.imageProcessor.ProcessImage(ctx, imageData)
 	if err != nil {
@@ -173,8 +174,8 @@ func (p *Provider) ResponseWithImage(ctx context.Context, sessionID string, mess
 }
 
 // responseWithOpenAIVision 使用OpenAI Vision API
-func (p *Provider) responseWithOpenAIVision(ctx context.Context, messages []providers.Message, base64Image string, text string, format string) (<-chan string, error) {
-	responseChan := make(chan string, 10)
+func (p *Provider) responseWithOpenAIVision(ctx context.Context, messages []providers.Message, base64Image string, text string, format string) (<-chan types.Response, error) {
+	responseChan := make(chan types.Response, 10)
 
 	go func() {
 		defer close(responseChan)
@@ -222,7 +223,7 @@ func (p *Provider) responseWithOpenAIVision(ctx context.Context, messages []prov
 			},
 		)
 		if err != nil {
-			responseChan <- fmt.Sprintf("【VLLLM服务响应异常: %v】", err)
+			responseChan <- types.Response{Content: fmt.Sprintf("【VLLLM服务响应异常: %v】", err)}
 			p.logger.Error("OpenAI Vision API调用失败 %v", err)
 			p.logger.Info("OpenAI Vision API调用失败，%s, maxTokens:%dm, Temperature:%f, top:%f", p.config.ModelName, p.config.MaxTokens, float32(p.config.Temperature), float32(p.config.TopP))
 
@@ -232,7 +233,7 @@ func (p *Provider) responseWithOpenAIVision(ctx context.Context, messages []prov
 
 		p.logger.Info("OpenAI Vision API调用成功，开始接收流式回复")
 
-		isActive := true
+		var think utils.ThinkParser
 		for {
 			response, err := stream.Recv()
 			if err != nil {
@@ -240,15 +241,13 @@ func (p *Provider) responseWithOpenAIVision(ctx context.Context, messages []prov
 			}
 
 			if len(response.Choices) > 0 {
-				content := response.Choices[0].Delta.Content
-				if content != "" {
-					// 处理思考标签
-					if content, isActive = p.handleThinkTags(content, isActive); content != "" {
-						responseChan <- content
-					}
-				}
+				delta := response.Choices[0].Delta
+				content, reasoning := think.Feed(delta.Content)
+				p.sendChunk(responseChan, content, delta.ReasoningContent+reasoning)
 			}
 		}
+		content, reasoning := think.Flush()
+		p.sendChunk(responseChan, content, reasoning)
 
 		p.logger.Info("OpenAI Vision API流式回复完成")
 	}()
@@ -257,8 +256,8 @@ func (p *Provider) responseWithOpenAIVision(ctx context.Context, messages []prov
 }
 
 // responseWithOllamaVision 使用Ollama Vision API
-func (p *Provider) responseWithOllamaVision(ctx context.Context, messages []providers.Message, base64Image string, text string, format string) (<-chan string, error) {
-	responseChan := make(chan string, 10)
+func (p *Provider) responseWithOllamaVision(ctx context.Context, messages []providers.Message, base64Image string, text string, format string) (<-chan types.Response, error) {
+	responseChan := make(chan types.Response, 10)
 
 	go func() {
 		defer close(responseChan)
@@ -296,7 +295,7 @@ func (p *Provider) responseWithOllamaVision(ctx context.Context, messages []prov
 		// 序列化请求
 		requestBody, err := json.Marshal(request)
 		if err != nil {
-			responseChan <- fmt.Sprintf("【请求序列化失败: %v】", err)
+			responseChan <- types.Response{Content: fmt.Sprintf("【请求序列化失败: %v】", err)}
 			p.logger.Error("Ollama请求序列化失败", err)
 			return
 		}
@@ -305,7 +304,7 @@ func (p *Provider) responseWithOllamaVision(ctx context.Context, messages []prov
 		url := fmt.Sprintf("%s/api/chat", strings.TrimSuffix(p.config.BaseURL, "/"))
 		req, err := http.NewRequestWithContext(ctx, "POST", url, bytes.NewBuffer(requestBody))
 		if err != nil {
-			responseChan <- fmt.Sprintf("【创建请求失败: %v】", err)
+			responseChan <- types.Response{Content: fmt.Sprintf("【创建请求失败: %v】", err)}
 			p.logger.Error("创建Ollama请求失败", err)
 			return
 		}
@@ -320,14 +319,14 @@ func (p *Provider) responseWithOllamaVision(ctx context.Context, messages []prov
 
 		resp, err := p.httpClient.Do(req)
 		if err != nil {
-			responseChan <- fmt.Sprintf("【Ollama API调用失败: %v】", err)
+			responseChan <- types.Response{Content: fmt.Sprintf("【Ollama API调用失败: %v】", err)}
 			p.logger.Error("Ollama API调用失败", err)
 			return
 		}
 		defer resp.Body.Close()
 
 		if resp.StatusCode != http.StatusOK {
-			responseChan <- fmt.Sprintf("【Ollama API返回错误: %d】", resp.StatusCode)
+			responseChan <- types.Response{Content: fmt.Sprintf("【Ollama API返回错误: %d】", resp.StatusCode)}
 			p.logger.Error("Ollama API返回错误", map[string]interface{}{
 				"status_code": resp.StatusCode,
 				"status":      resp.Status,
@@ -339,7 +338,7 @@ func (p *Provider) responseWithOllamaVision(ctx context.Context, messages []prov
 
 		// 处理流式响应
 		decoder := json.NewDecoder(resp.Body)
-		isActive := true
+		var think utils.ThinkParser
 
 		for {
 			var response OllamaResponse
@@ -350,18 +349,15 @@ func (p *Provider) responseWithOllamaVision(ctx context.Context, messages []prov
 				break
 			}
 
-			content := response.Message.Content
-			if content != "" {
-				// 处理思考标签
-				if content, isActive = p.handleThinkTags(content, isActive); content != "" {
-					responseChan <- content
-				}
-			}
+			content, reasoning := think.Feed(response.Message.Content)
+			p.sendChunk(responseChan, content, reasoning)
 
 			if response.Done {
 				break
 			}
 		}
+		content, reasoning := think.Flush()
+		p.sendChunk(responseChan, content, reasoning)
 
 		p.logger.Info("Ollama Vision API流式回复完成")
 	}()
@@ -380,24 +376,11 @@ func (p *Provider) Response(ctx context.Context, sessionID string, messages []pr
 	return responseChan, nil
 }
 
-// handleThinkTags 处理思考标签
-func (p *Provider) handleThinkTags(content string, isActive bool) (string, bool) {
-	if content == "" {
-		return "", isActive
-	}
-
-	if content == "<think>" {
-		return "", false
+// sendChunk 输出非空的回答和思考内容
+func (p *Provider) sendChunk(responseChan chan<- types.Response, content, reasoning string) {
+	if content != "" || reasoning != "" {
+		responseChan <- types.Response{Content: content, Reasoning: reasoning}
 	}
-	if content == "</think>" {
-		return "", true
-	}
-
-	if !isActive {
-		return "", isActive
-	}
-
-	return content, isActive
 }
 
 // detectMultimodalMessage 检测是否为多模态消息（向后兼容）
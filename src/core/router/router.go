@@ -0,0 +1,172 @@
+package router
+
+import (
+	"context"
+	"fmt"
+	"regexp"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"angrymiao-ai-server/src/configs"
+	"angrymiao-ai-server/src/core/types"
+)
+
+// 路由来源
+const (
+	SourceRule       = "rule"
+	SourceClassifier = "classifier"
+	SourceDefault    = "default"
+)
+
+// 默认分类超时时间
+const defaultTimeout = 3 * time.Second
+
+// 分类提示词，%s 为路由列表
+const classifierPrompt = `你是对话路由器，判断用户的话属于以下哪一类，只输出类别名称，不要输出其他内容：
+%s`
+
+// Decision 一轮对话的路由结果，Route 为空表示使用连接当前的LLM和角色
+type Decision struct {
+	Route   string
+	LLM     string
+	Persona string
+	Source  string
+}
+
+// Router 按规则或分类LLM为每轮对话选择路由
+type Router struct {
+	config  configs.LLMRouterConfig
+	routes  []*route
+	byName  map[string]*route
+	timeout time.Duration
+}
+
+type route struct {
+	config   configs.LLMRouteConfig
+	keywords []string
+	patterns []*regexp.Regexp
+}
+
+// NewRouter 根据配置创建路由器
+func NewRouter(config configs.LLMRouterConfig) (*Router, error) {
+	r := &Router{config: config, byName: make(map[string]*route), timeout: defaultTimeout}
+	if config.Timeout > 0 {
+		r.timeout = time.Duration(config.Timeout) * time.Millisecond
+	}
+	for _, rc := range config.Routes {
+		if rc.Name == "" {
+			return nil, fmt.Errorf("路由名称不能为空")
+		}
+		if _, ok := r.byName[rc.Name]; ok {
+			return nil, fmt.Errorf("路由 %s 重复", rc.Name)
+		}
+		rt := &route{config: rc}
+		for _, keyword := range rc.Keywords {
+			if keyword = strings.ToLower(strings.TrimSpace(keyword)); keyword != "" {
+				rt.keywords = append(rt.keywords, keyword)
+			}
+		}
+		for _, pattern := range rc.Patterns {
+			re, err := regexp.Compile(pattern)
+			if err != nil {
+				return nil, fmt.Errorf("路由 %s 的正则表达式 %s 无效: %v", rc.Name, pattern, err)
+			}
+			rt.patterns = append(rt.patterns, re)
+		}
+		r.routes = append(r.routes, rt)
+		r.byName[rc.Name] = rt
+	}
+	if config.Default != "" {
+		if _, ok := r.byName[config.Default]; !ok {
+			return nil, fmt.Errorf("默认路由 %s 不存在", config.Default)
+		}
+	}
+	return r, nil
+}
+
+// Route 选择路由：先按顺序匹配规则，未命中时请求分类LLM，仍未确定时使用默认路由
+// classifier 为空时跳过分类
+func (r *Router) Route(ctx context.Context, text string, classifier types.LLMProvider) Decision {
+	for _, rt := range r.routes {
+		if rt.match(text) {
+			return rt.decision(SourceRule)
+		}
+	}
+	if classifier != nil {
+		if rt := r.classify(ctx, text, classifier); rt != nil {
+			return rt.decision(SourceClassifier)
+		}
+	}
+	if rt, ok := r.byName[r.config.Default]; ok {
+		return rt.decision(SourceDefault)
+	}
+	return Decision{Source: SourceDefault}
+}
+
+// classify 请求分类LLM，出错、超时或输出无法识别时返回nil
+func (r *Router) classify(ctx context.Context, text string, classifier types.LLMProvider) *route {
+	var list strings.Builder
+	for _, rt := range r.routes {
+		fmt.Fprintf(&list, "- %s：%s\n", rt.config.Name, rt.config.Description)
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, r.timeout)
+	defer cancel()
+	responses, err := classifier.Response(ctx, "", []types.Message{
+		{Role: "system", Content: fmt.Sprintf(classifierPrompt, list.String())},
+		{Role: "user", Content: text},
+	})
+	if err != nil {
+		return nil
+	}
+	var output strings.Builder
+	for content := range responses {
+		output.WriteString(content)
+	}
+	if ctx.Err() != nil {
+		return nil
+	}
+	return r.parseClassification(output.String())
+}
+
+// parseClassification 从分类输出中找出路由名称，名称较长的优先，避免被其中较短的名称误匹配
+func (r *Router) parseClassification(output string) *route {
+	output = strings.ToLower(strings.TrimSpace(output))
+	if rt, ok := r.byName[output]; ok {
+		return rt
+	}
+	var best *route
+	for _, rt := range r.routes {
+		name := strings.ToLower(rt.config.Name)
+		if strings.Contains(output, name) && (best == nil || len(name) > len(best.config.Name)) {
+			best = rt
+		}
+	}
+	return best
+}
+
+// match 判断文本是否命中路由的规则
+func (rt *route) match(text string) bool {
+	lower := strings.ToLower(text)
+	for _, keyword := range rt.keywords {
+		if strings.Contains(lower, keyword) {
+			return true
+		}
+	}
+	for _, re := range rt.patterns {
+		if re.MatchString(text) {
+			return true
+		}
+	}
+	return rt.config.MinLength > 0 && utf8.RuneCountInString(text) >= rt.config.MinLength
+}
+
+func (rt *route) decision(source string) Decision {
+	return Decision{
+		Route:   rt.config.Name,
+		LLM:     rt.config.LLM,
+		Persona: rt.config.Persona,
+		Source:  source,
+	}
+}
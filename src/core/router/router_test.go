@@ -0,0 +1,81 @@
+package router
+
+import (
+	"context"
+	"testing"
+
+	"angrymiao-ai-server/src/configs"
+	"angrymiao-ai-server/src/core/types"
+)
+
+// fakeClassifier 返回固定分类结果的LLM
+type fakeClassifier struct {
+	types.LLMProvider
+	output string
+}
+
+func (c *fakeClassifier) Response(context.Context, string, []types.Message) (<-chan string, error) {
+	ch := make(chan string, 1)
+	ch <- c.output
+	close(ch)
+	return ch, nil
+}
+
+func newTestRouter(t *testing.T) *Router {
+	r, err := NewRouter(configs.LLMRouterConfig{
+		Default: "chat",
+		Routes: []configs.LLMRouteConfig{
+			{Name: "math", LLM: "StrongLLM", Keywords: []string{"等于", "计算"}, Patterns: []string{`\d+\s*[+\-*/×÷]\s*\d+`}},
+			{Name: "story", Persona: "故事大王", Keywords: []string{"讲个故事"}},
+			{Name: "long", LLM: "StrongLLM", MinLength: 20},
+			{Name: "chat", LLM: "FastLLM", Description: "闲聊"},
+			{Name: "chat_plus", LLM: "StrongLLM", Description: "复杂闲聊"},
+		},
+	})
+	if err != nil {
+		t.Fatalf("NewRouter() 错误: %v", err)
+	}
+	return r
+}
+
+func TestRouteRules(t *testing.T) {
+	r := newTestRouter(t)
+	tests := []struct {
+		text   string
+		route  string
+		source string
+	}{
+		{"帮我计算一下", "math", SourceRule},
+		{"12×8是多少", "math", SourceRule},
+		{"给我讲个故事吧", "story", SourceRule},
+		{"为什么天空是蓝色的而晚霞却是红色的呢你知道吗", "long", SourceRule},
+		{"你好呀", "chat", SourceDefault},
+	}
+	for _, tt := range tests {
+		d := r.Route(context.Background(), tt.text, nil)
+		if d.Route != tt.route || d.Source != tt.source {
+			t.Errorf("Route(%q) = %+v, 期望 %s(%s)", tt.text, d, tt.route, tt.source)
+		}
+	}
+}
+
+func TestRouteClassifier(t *testing.T) {
+	r := newTestRouter(t)
+	d := r.Route(context.Background(), "你好呀", &fakeClassifier{output: "类别：chat_plus"})
+	if d.Route != "chat_plus" || d.LLM != "StrongLLM" || d.Source != SourceClassifier {
+		t.Errorf("Route() 分类 = %+v", d)
+	}
+	d = r.Route(context.Background(), "你好呀", &fakeClassifier{output: "不知道"})
+	if d.Route != "chat" || d.Source != SourceDefault {
+		t.Errorf("Route() 无法分类 = %+v", d)
+	}
+}
+
+func TestNewRouterInvalid(t *testing.T) {
+	if _, err := NewRouter(configs.LLMRouterConfig{Default: "x"}); err == nil {
+		t.Error("NewRouter() 默认路由不存在应返回错误")
+	}
+	if _, err := NewRouter(configs.LLMRouterConfig{Routes: []configs.LLMRouteConfig{{Name: "a", Patterns: []string{"("}}}}); err == nil {
+		t.Error("NewRouter() 无效正则应返回错误")
+	}
+}
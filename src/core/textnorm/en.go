@@ -0,0 +1,419 @@
+package textnorm
+
+import (
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+func init() {
+	Register("en", &EnNormalizer{})
+}
+
+// EnNormalizer 英文(en)文本规范化器
+type EnNormalizer struct{}
+
+var (
+	enOnes = []string{
+		"zero", "one", "two", "three", "four", "five", "six", "seven", "eight", "nine",
+		"ten", "eleven", "twelve", "thirteen", "fourteen", "fifteen", "sixteen", "seventeen", "eighteen", "nineteen",
+	}
+	enTens   = []string{"", "", "twenty", "thirty", "forty", "fifty", "sixty", "seventy", "eighty", "ninety"}
+	enScales = []string{"", "thousand", "million", "billion"}
+	enMonths = []string{
+		"", "January", "February", "March", "April", "May", "June",
+		"July", "August", "September", "October", "November", "December",
+	}
+)
+
+// enCurrency 货币名称：单数、复数、辅币单数、辅币复数
+var enCurrency = map[string][4]string{
+	"$": {"dollar", "dollars", "cent", "cents"},
+	"€": {"euro", "euros", "cent", "cents"},
+	"£": {"pound", "pounds", "penny", "pence"},
+	"¥": {"yuan", "yuan", "", ""},
+}
+
+// enUnits 单位名称：单数、复数
+var enUnits = map[string][2]string{
+	"km/h": {"kilometer per hour", "kilometers per hour"},
+	"mph":  {"mile per hour", "miles per hour"},
+	"km":   {"kilometer", "kilometers"},
+	"kg":   {"kilogram", "kilograms"},
+	"mg":   {"milligram", "milligrams"},
+	"ml":   {"milliliter", "milliliters"},
+	"mL":   {"milliliter", "milliliters"},
+	"cm":   {"centimeter", "centimeters"},
+	"mm":   {"millimeter", "millimeters"},
+	"m":    {"meter", "meters"},
+	"g":    {"gram", "grams"},
+	"L":    {"liter", "liters"},
+	"lb":   {"pound", "pounds"},
+	"lbs":  {"pounds", "pounds"},
+	"ft":   {"foot", "feet"},
+	"TB":   {"terabyte", "terabytes"},
+	"GB":   {"gigabyte", "gigabytes"},
+	"MB":   {"megabyte", "megabytes"},
+	"KB":   {"kilobyte", "kilobytes"},
+	"kHz":  {"kilohertz", "kilohertz"},
+	"MHz":  {"megahertz", "megahertz"},
+	"GHz":  {"gigahertz", "gigahertz"},
+	"Hz":   {"hertz", "hertz"},
+	"kW":   {"kilowatt", "kilowatts"},
+	"W":    {"watt", "watts"},
+	"V":    {"volt", "volts"},
+	"mAh":  {"milliamp hour", "milliamp hours"},
+	"min":  {"minute", "minutes"},
+	"ms":   {"millisecond", "milliseconds"},
+	"h":    {"hour", "hours"},
+}
+
+// 读作整词而不逐字母拼读的缩写
+var enKeepAcronyms = map[string]bool{
+	"OK":     true,
+	"NASA":   true,
+	"NATO":   true,
+	"UNESCO": true,
+}
+
+var enRules = []rule{
+	// 日期 2024-03-05
+	{
+		re:         regexp.MustCompile(`(\d{4})-(\d{1,2})-(\d{1,2})`),
+		standalone: true,
+		replace: func(m []string) string {
+			year, _ := strconv.Atoi(m[1])
+			month, _ := strconv.Atoi(m[2])
+			day, _ := strconv.Atoi(m[3])
+			if month < 1 || month > 12 || day < 1 || day > 31 {
+				return m[0]
+			}
+			return enMonths[month] + " " + enOrdinal(day) + ", " + enYear(year)
+		},
+	},
+	// 时间 10:30、9:05
+	{
+		re:         regexp.MustCompile(`(\d{1,2}):(\d{2})(?::\d{2})?`),
+		standalone: true,
+		replace: func(m []string) string {
+			hour, _ := strconv.Atoi(m[1])
+			minute, _ := strconv.Atoi(m[2])
+			if hour > 24 || minute > 59 {
+				return m[0]
+			}
+			switch {
+			case minute == 0:
+				return enInteger(int64(hour)) + " o'clock"
+			case minute < 10:
+				return enInteger(int64(hour)) + " oh " + enInteger(int64(minute))
+			default:
+				return enInteger(int64(hour)) + " " + enInteger(int64(minute))
+			}
+		},
+	},
+	// 电话号码 555-123-4567、13812345678
+	{
+		re:         regexp.MustCompile(`(\d{3})-(\d{3})-(\d{4})|(1[3-9]\d)(\d{4})(\d{4})`),
+		standalone: true,
+		replace: func(m []string) string {
+			parts := []string{}
+			for _, g := range m[1:] {
+				if g != "" {
+					parts = append(parts, enDigits(g))
+				}
+			}
+			return strings.Join(parts, ", ")
+		},
+	},
+	// 货币 $9.99、€5
+	{
+		re: regexp.MustCompile(`([$€£¥])\s?(\d+)(?:\.(\d{1,2}))?`),
+		replace: func(m []string) string {
+			names := enCurrency[m[1]]
+			if names[2] == "" {
+				// 没有辅币名称时小数部分按小数读，如 ¥12.50 -> twelve point five yuan
+				amount := m[2]
+				if minor := strings.TrimRight(m[3], "0"); minor != "" {
+					amount += "." + minor
+				}
+				return enNumber(amount) + " " + enPlural(amount, names[0], names[1])
+			}
+			out := enInteger(parseInt(m[2])) + " " + enPlural(m[2], names[0], names[1])
+			if m[3] != "" {
+				minor := m[3]
+				if len(minor) == 1 {
+					minor += "0"
+				}
+				if cents := parseInt(minor); cents > 0 {
+					out += " and " + enInteger(cents) + " " + enPlural(strconv.FormatInt(cents, 10), names[2], names[3])
+				}
+			}
+			return out
+		},
+	},
+	// 百分数 50%
+	{
+		re: regexp.MustCompile(`(-?)(\d+(?:\.\d+)?)\s?%`),
+		replace: func(m []string) string {
+			out := enNumber(m[2]) + " percent"
+			if m[1] != "" {
+				out = "minus " + out
+			}
+			return out
+		},
+	},
+	// 温度 25°C、77°F
+	{
+		re: regexp.MustCompile(`(-?)(\d+(?:\.\d+)?)\s?(°C|℃|°F|°)`),
+		replace: func(m []string) string {
+			out := enNumber(m[2]) + " " + enPlural(m[2], "degree", "degrees")
+			if m[1] != "" {
+				out = "minus " + out
+			}
+			switch m[3] {
+			case "°C", "℃":
+				out += " Celsius"
+			case "°F":
+				out += " Fahrenheit"
+			}
+			return out
+		},
+	},
+	// 范围 3-5，数字保留给后续规则处理单位
+	{
+		re:        regexp.MustCompile(`(\d+(?:\.\d+)?)\s?[-~]\s?(\d+(?:\.\d+)?)`),
+		leftBound: true,
+		replace: func(m []string) string {
+			return m[1] + " to " + m[2]
+		},
+	},
+	// 分数 1/2、3/4
+	{
+		re:         regexp.MustCompile(`(\d+)/(\d+)`),
+		standalone: true,
+		replace: func(m []string) string {
+			numerator, denominator := parseInt(m[1]), parseInt(m[2])
+			if denominator < 2 || len(m[1]) > 6 || len(m[2]) > 6 {
+				return enDigits(m[1]) + " slash " + enDigits(m[2])
+			}
+			return enInteger(numerator) + " " + enFraction(denominator, numerator != 1)
+		},
+	},
+	// 版本号 1.2.3
+	{
+		re:         regexp.MustCompile(`\d+(?:\.\d+){2,}`),
+		standalone: true,
+		replace: func(m []string) string {
+			parts := strings.Split(m[0], ".")
+			for i, p := range parts {
+				parts[i] = enNumber(p)
+			}
+			return strings.Join(parts, " point ")
+		},
+	},
+	// 序数词 1st、22nd
+	{
+		re:         regexp.MustCompile(`(\d+)(?:st|nd|rd|th)`),
+		standalone: true,
+		replace: func(m []string) string {
+			return enOrdinal(int(parseInt(m[1])))
+		},
+	},
+	// 单位 5km、2kg
+	{
+		re:         regexp.MustCompile(`(\d+(?:\.\d+)?)\s?(km/h|mph|km|kg|mg|ml|mL|cm|mm|mAh|min|ms|m|g|L|lbs|lb|ft|TB|GB|MB|KB|kHz|MHz|GHz|Hz|kW|W|V|h)`),
+		standalone: true,
+		replace: func(m []string) string {
+			names := enUnits[m[2]]
+			return enNumber(m[1]) + " " + enPlural(m[1], names[0], names[1])
+		},
+	},
+	// 型号 RTX4090、GPT-4o、5G，数字逐位读
+	{
+		re:         regexp.MustCompile(`[A-Za-z]+(?:[-_]?\d+(?:\.\d+)?[A-Za-z]*)+|\d+[A-Za-z]+(?:\d+[A-Za-z]*)*`),
+		standalone: true,
+		replace: func(m []string) string {
+			return readModelNumber(m[0], enDigits, "point", " ")
+		},
+	},
+	// 缩写逐字母读 AI、CPU
+	{
+		re:         regexp.MustCompile(`[A-Z]{2,6}`),
+		standalone: true,
+		replace: func(m []string) string {
+			if enKeepAcronyms[m[0]] {
+				return m[0]
+			}
+			return spellLetters(m[0])
+		},
+	},
+	// 其余数字
+	{
+		re:         regexp.MustCompile(`(-?)(\d+(?:\.\d+)?)`),
+		standalone: true,
+		replace: func(m []string) string {
+			out := enNumber(m[2])
+			if m[1] != "" {
+				out = "minus " + out
+			}
+			return out
+		},
+	},
+	// 常见符号
+	{
+		re: regexp.MustCompile(`\s?&\s?`),
+		replace: func(m []string) string {
+			return " and "
+		},
+	},
+}
+
+// Normalize 规范化英文文本
+func (n *EnNormalizer) Normalize(text string) string {
+	text = stripThousandSeparators(text)
+	return applyRules(text, enRules)
+}
+
+// enDigits 逐位读数字
+func enDigits(s string) string {
+	words := make([]string, 0, len(s))
+	for _, r := range s {
+		if r >= '0' && r <= '9' {
+			words = append(words, enOnes[r-'0'])
+		}
+	}
+	return strings.Join(words, " ")
+}
+
+// enNumber 读十进制数
+func enNumber(s string) string {
+	intPart, fracPart, hasFrac := strings.Cut(s, ".")
+	var out string
+	if len(intPart) > 1 && intPart[0] == '0' || len(intPart) > 12 {
+		out = enDigits(intPart)
+	} else {
+		out = enInteger(parseInt(intPart))
+	}
+	if hasFrac && fracPart != "" {
+		out += " point " + enDigits(fracPart)
+	}
+	return out
+}
+
+// enInteger 读整数
+func enInteger(n int64) string {
+	if n < 20 {
+		return enOnes[n]
+	}
+
+	var parts []string
+	for i := 0; n > 0 && i < len(enScales); i++ {
+		chunk := n % 1000
+		n /= 1000
+		if chunk == 0 {
+			continue
+		}
+		words := enUnderThousand(chunk)
+		if enScales[i] != "" {
+			words += " " + enScales[i]
+		}
+		parts = append([]string{words}, parts...)
+	}
+	return strings.Join(parts, " ")
+}
+
+func enUnderThousand(n int64) string {
+	var parts []string
+	if n >= 100 {
+		parts = append(parts, enOnes[n/100]+" hundred")
+		n %= 100
+	}
+	if n >= 20 {
+		word := enTens[n/10]
+		if n%10 != 0 {
+			word += "-" + enOnes[n%10]
+		}
+		parts = append(parts, word)
+	} else if n > 0 {
+		parts = append(parts, enOnes[n])
+	}
+	return strings.Join(parts, " ")
+}
+
+// enOrdinal 读序数词
+func enOrdinal(n int) string {
+	words := enInteger(int64(n))
+	head, last := "", words
+	if i := strings.LastIndexAny(words, " -"); i >= 0 {
+		head, last = words[:i+1], words[i+1:]
+	}
+
+	irregular := map[string]string{
+		"one":    "first",
+		"two":    "second",
+		"three":  "third",
+		"five":   "fifth",
+		"eight":  "eighth",
+		"nine":   "ninth",
+		"twelve": "twelfth",
+	}
+	switch {
+	case irregular[last] != "":
+		last = irregular[last]
+	case strings.HasSuffix(last, "y"):
+		last = strings.TrimSuffix(last, "y") + "ieth"
+	default:
+		last += "th"
+	}
+	return head + last
+}
+
+// enFraction 读分母，如 2 -> half，4 -> quarter，3 -> third，plural为true时使用复数
+func enFraction(denominator int64, plural bool) string {
+	switch denominator {
+	case 2:
+		if plural {
+			return "halves"
+		}
+		return "half"
+	case 4:
+		if plural {
+			return "quarters"
+		}
+		return "quarter"
+	}
+	word := enOrdinal(int(denominator))
+	if plural {
+		word += "s"
+	}
+	return word
+}
+
+// enYear 按年份习惯读，如 1999 -> nineteen ninety-nine，2024 -> twenty twenty-four
+func enYear(year int) string {
+	if year < 1000 || year > 2099 || year >= 2000 && year < 2010 {
+		return enInteger(int64(year))
+	}
+	high, low := year/100, year%100
+	switch {
+	case low == 0:
+		return enInteger(int64(high)) + " hundred"
+	case low < 10:
+		return enInteger(int64(high)) + " oh " + enOnes[low]
+	default:
+		return enInteger(int64(high)) + " " + enInteger(int64(low))
+	}
+}
+
+// enPlural 数值为1时使用单数
+func enPlural(number, singular, plural string) string {
+	if number == "1" {
+		return singular
+	}
+	return plural
+}
+
+func parseInt(s string) int64 {
+	n, _ := strconv.ParseInt(s, 10, 64)
+	return n
+}
@@ -0,0 +1,172 @@
+// Package textnorm 提供TTS前的文本规范化，将数字、日期、单位、缩写等书面形式转换为口语读法
+package textnorm
+
+import (
+	"regexp"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// DefaultLanguage 默认规范化语言
+const DefaultLanguage = "zh-CN"
+
+// Normalizer 文本规范化器接口，每种语言一个实现
+type Normalizer interface {
+	// Normalize 将文本转换为适合朗读的口语形式
+	Normalize(text string) string
+}
+
+var normalizers = make(map[string]Normalizer)
+
+// Register 注册语言规范化器
+func Register(lang string, n Normalizer) {
+	normalizers[strings.ToLower(lang)] = n
+}
+
+// Get 获取语言规范化器，找不到完整语言标签时按主语言匹配，如 zh-TW 回退到 zh
+func Get(lang string) (Normalizer, bool) {
+	lang = strings.ToLower(strings.ReplaceAll(lang, "_", "-"))
+	if n, ok := normalizers[lang]; ok {
+		return n, true
+	}
+	if i := strings.Index(lang, "-"); i > 0 {
+		lang = lang[:i]
+	}
+	n, ok := normalizers[lang]
+	return n, ok
+}
+
+// Normalize 使用指定语言规范化文本，不支持的语言原样返回
+func Normalize(lang, text string) string {
+	n, ok := Get(lang)
+	if !ok {
+		return text
+	}
+	return n.Normalize(text)
+}
+
+// SplitByRunes 按字符数拆分文本，每段不超过 maxRunes 个字符
+// 优先在标点或空白处断开，避免截断UTF-8字符
+func SplitByRunes(text string, maxRunes int) []string {
+	if maxRunes <= 0 || utf8.RuneCountInString(text) <= maxRunes {
+		if strings.TrimSpace(text) == "" {
+			return nil
+		}
+		return []string{text}
+	}
+
+	var result []string
+	runes := []rune(text)
+	for len(runes) > 0 {
+		if len(runes) <= maxRunes {
+			result = appendChunk(result, string(runes))
+			break
+		}
+
+		cut := maxRunes
+		// 在窗口后半段寻找最后一个断句位置，避免切出过短的片段
+		for i := maxRunes - 1; i >= maxRunes/2; i-- {
+			if isBreakRune(runes[i]) {
+				cut = i + 1
+				break
+			}
+		}
+		result = appendChunk(result, string(runes[:cut]))
+		runes = runes[cut:]
+	}
+	return result
+}
+
+func appendChunk(chunks []string, chunk string) []string {
+	if strings.TrimSpace(chunk) == "" {
+		return chunks
+	}
+	return append(chunks, chunk)
+}
+
+func isBreakRune(r rune) bool {
+	return strings.ContainsRune("。！？；，、：.!?;,:…", r) || unicode.IsSpace(r)
+}
+
+// rule 正则替换规则
+type rule struct {
+	re *regexp.Regexp
+	// standalone 为true时，匹配前后紧邻数字或字母则不替换，避免切开更长的数字串
+	standalone bool
+	// leftBound 为true时，仅要求匹配前一个字符不是数字或字母
+	leftBound bool
+	replace   func(m []string) string
+}
+
+// applyRules 依次应用替换规则
+func applyRules(text string, rules []rule) string {
+	for _, r := range rules {
+		text = replaceSubmatch(text, r)
+	}
+	return text
+}
+
+func replaceSubmatch(text string, r rule) string {
+	matches := r.re.FindAllStringSubmatchIndex(text, -1)
+	if len(matches) == 0 {
+		return text
+	}
+
+	var sb strings.Builder
+	last := 0
+	for _, loc := range matches {
+		start, end := loc[0], loc[1]
+		if r.standalone && !isStandalone(text, start, end) {
+			continue
+		}
+		if r.leftBound && !isStandalone(text, start, start) {
+			continue
+		}
+
+		groups := make([]string, len(loc)/2)
+		for i := range groups {
+			if loc[2*i] >= 0 {
+				groups[i] = text[loc[2*i]:loc[2*i+1]]
+			}
+		}
+		sb.WriteString(text[last:start])
+		sb.WriteString(r.replace(groups))
+		last = end
+	}
+	sb.WriteString(text[last:])
+	return sb.String()
+}
+
+func isStandalone(text string, start, end int) bool {
+	if start > 0 {
+		prev, _ := utf8.DecodeLastRuneInString(text[:start])
+		if isASCIIAlnum(prev) {
+			return false
+		}
+	}
+	if end > start && end < len(text) {
+		next, _ := utf8.DecodeRuneInString(text[end:])
+		if isASCIIAlnum(next) {
+			return false
+		}
+	}
+	return true
+}
+
+func isASCIIAlnum(r rune) bool {
+	return r < utf8.RuneSelf && (unicode.IsLetter(r) || unicode.IsDigit(r))
+}
+
+// stripThousandSeparators 移除千分位分隔符，如 1,234,567 -> 1234567
+var reThousands = regexp.MustCompile(`\d{1,3}(?:,\d{3})+`)
+
+func stripThousandSeparators(text string) string {
+	return replaceSubmatch(text, rule{
+		re:         reThousands,
+		standalone: true,
+		replace: func(m []string) string {
+			return strings.ReplaceAll(m[0], ",", "")
+		},
+	})
+}
@@ -0,0 +1,119 @@
+package textnorm
+
+import (
+	"strings"
+	"testing"
+	"unicode/utf8"
+)
+
+func TestZhNormalize(t *testing.T) {
+	tests := []struct {
+		name     string
+		input    string
+		expected string
+	}{
+		{"整数", "共有1234个", "共有一千二百三十四个"},
+		{"十几", "今年15岁", "今年十五岁"},
+		{"中间的零", "10050元", "一万零五十元"},
+		{"千分位", "人口1,400,000,000", "人口十四亿"},
+		{"小数", "圆周率约3.14", "圆周率约三点一四"},
+		{"负数", "气温 -5", "气温 负五"},
+		{"日期", "2024-03-05出发", "二零二四年三月五日出发"},
+		{"年份", "2024年", "二零二四年"},
+		{"时间", "10:30开会", "十点三十分开会"},
+		{"时间补零", "2:05", "两点零五分"},
+		{"手机号", "电话13812345678", "电话幺三八 幺二三四 五六七八"},
+		{"百分数", "增长了50%", "增长了百分之五十"},
+		{"温度", "最低-3℃", "最低零下三度"},
+		{"货币", "售价¥99.9", "售价九十九点九元"},
+		{"范围和单位", "3-5kg", "三到五公斤"},
+		{"两", "2h", "两小时"},
+		{"分数", "1/3的人", "三分之一的人"},
+		{"型号", "RTX4090显卡", "R T X四零九零显卡"},
+		{"缩写", "AI助手", "A I助手"},
+		{"保留缩写", "OK", "OK"},
+		{"无需处理", "你好，世界", "你好，世界"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := Normalize("zh-CN", tt.input); got != tt.expected {
+				t.Errorf("Normalize(%q) = %q, 期望 %q", tt.input, got, tt.expected)
+			}
+		})
+	}
+}
+
+func TestEnNormalize(t *testing.T) {
+	tests := []struct {
+		name     string
+		input    string
+		expected string
+	}{
+		{"integer", "I have 42 apples", "I have forty-two apples"},
+		{"large", "1,234,567", "one million two hundred thirty-four thousand five hundred sixty-seven"},
+		{"decimal", "pi is 3.14", "pi is three point one four"},
+		{"date", "2024-03-05", "March fifth, twenty twenty-four"},
+		{"time", "at 9:05", "at nine oh five"},
+		{"o'clock", "at 10:00", "at ten o'clock"},
+		{"currency", "$9.99", "nine dollars and ninety-nine cents"},
+		{"currency without minor unit", "¥12.50", "twelve point five yuan"},
+		{"currency whole yuan", "¥12.00", "twelve yuan"},
+		{"fraction half", "1/2 cup", "one half cup"},
+		{"fraction plural", "3/4", "three quarters"},
+		{"fraction ordinal", "2/3", "two thirds"},
+		{"version", "version 1.2.3", "version one point two point three"},
+		{"percent", "50%", "fifty percent"},
+		{"ordinal", "the 21st", "the twenty-first"},
+		{"unit", "1 km", "one kilometer"},
+		{"units plural", "5kg", "five kilograms"},
+		{"acronym", "the CPU", "the C P U"},
+		{"model", "RTX4090", "R T X four zero nine zero"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := Normalize("en-US", tt.input); got != tt.expected {
+				t.Errorf("Normalize(%q) = %q, want %q", tt.input, got, tt.expected)
+			}
+		})
+	}
+}
+
+func TestNormalizeUnknownLanguage(t *testing.T) {
+	if got := Normalize("xx", "123"); got != "123" {
+		t.Errorf("不支持的语言应原样返回, got %q", got)
+	}
+}
+
+func TestSplitByRunes(t *testing.T) {
+	text := strings.Repeat("你好世界，", 30) // 150个字符
+	chunks := SplitByRunes(text, 64)
+	if len(chunks) < 3 {
+		t.Fatalf("期望至少3段, got %d", len(chunks))
+	}
+	if strings.Join(chunks, "") != text {
+		t.Errorf("拆分后内容不一致")
+	}
+	for _, c := range chunks {
+		if !utf8.ValidString(c) {
+			t.Errorf("片段包含非法UTF-8: %q", c)
+		}
+		if n := utf8.RuneCountInString(c); n > 64 {
+			t.Errorf("片段超长: %d", n)
+		}
+		if !strings.HasSuffix(c, "，") && c != chunks[len(chunks)-1] {
+			t.Errorf("片段未在标点处断开: %q", c)
+		}
+	}
+
+	// 没有标点时按字符数硬切分
+	chunks = SplitByRunes(strings.Repeat("中", 10), 4)
+	if len(chunks) != 3 || chunks[2] != "中中" {
+		t.Errorf("硬切分结果错误: %v", chunks)
+	}
+
+	if chunks := SplitByRunes("短文本", 64); len(chunks) != 1 {
+		t.Errorf("短文本不应拆分: %v", chunks)
+	}
+}
@@ -0,0 +1,399 @@
+package textnorm
+
+import (
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+func init() {
+	zh := &ZhNormalizer{}
+	Register("zh-CN", zh)
+	Register("zh", zh)
+}
+
+// ZhNormalizer 中文(zh-CN)文本规范化器
+type ZhNormalizer struct{}
+
+var zhDigitNames = []string{"零", "一", "二", "三", "四", "五", "六", "七", "八", "九"}
+
+var zhCurrencyNames = map[string]string{
+	"¥": "元",
+	"￥": "元",
+	"$": "美元",
+	"€": "欧元",
+	"£": "英镑",
+}
+
+var zhUnitNames = map[string]string{
+	"km/h": "公里每小时",
+	"km":   "公里",
+	"kg":   "公斤",
+	"mg":   "毫克",
+	"ml":   "毫升",
+	"mL":   "毫升",
+	"cm":   "厘米",
+	"mm":   "毫米",
+	"m²":   "平方米",
+	"m³":   "立方米",
+	"m":    "米",
+	"g":    "克",
+	"L":    "升",
+	"TB":   "T",
+	"GB":   "G",
+	"MB":   "兆",
+	"KB":   "K",
+	"kHz":  "千赫兹",
+	"MHz":  "兆赫兹",
+	"GHz":  "吉赫兹",
+	"Hz":   "赫兹",
+	"kW":   "千瓦",
+	"W":    "瓦",
+	"V":    "伏",
+	"mAh":  "毫安时",
+	"min":  "分钟",
+	"ms":   "毫秒",
+	"h":    "小时",
+}
+
+// 读作整词而不逐字母拼读的缩写
+var zhKeepAcronyms = map[string]bool{
+	"OK":   true,
+	"NASA": true,
+	"NATO": true,
+}
+
+var zhRules = []rule{
+	// 日期 2024-03-05、2024/3/5、2024.03.05
+	{
+		re:         regexp.MustCompile(`(\d{4})[-/.](\d{1,2})[-/.](\d{1,2})`),
+		standalone: true,
+		replace: func(m []string) string {
+			month, _ := strconv.Atoi(m[2])
+			day, _ := strconv.Atoi(m[3])
+			if month < 1 || month > 12 || day < 1 || day > 31 {
+				return m[0]
+			}
+			return zhDigits(m[1], false) + "年" + zhInteger(strconv.Itoa(month)) + "月" + zhInteger(strconv.Itoa(day)) + "日"
+		},
+	},
+	// 年份逐位读 2024年、2024-2025年
+	{
+		re:         regexp.MustCompile(`(\d{4})(?:\s?[-~～至到]\s?(\d{4}))?年`),
+		standalone: true,
+		replace: func(m []string) string {
+			if m[2] != "" {
+				return zhDigits(m[1], false) + "到" + zhDigits(m[2], false) + "年"
+			}
+			return zhDigits(m[1], false) + "年"
+		},
+	},
+	// 时间 10:30、08:05:30
+	{
+		re:         regexp.MustCompile(`(\d{1,2}):(\d{2})(?::(\d{2}))?`),
+		standalone: true,
+		replace: func(m []string) string {
+			hour, _ := strconv.Atoi(m[1])
+			minute, _ := strconv.Atoi(m[2])
+			second, _ := strconv.Atoi(m[3])
+			if hour > 24 || minute > 59 || second > 59 {
+				return m[0]
+			}
+			out := zhCount(strconv.Itoa(hour)) + "点"
+			if minute > 0 || second > 0 {
+				if minute < 10 {
+					out += "零"
+				}
+				out += zhInteger(strconv.Itoa(minute)) + "分"
+			}
+			if second > 0 {
+				out += zhInteger(strconv.Itoa(second)) + "秒"
+			}
+			return out
+		},
+	},
+	// 手机号 13812345678、+86 138-1234-5678
+	{
+		re:         regexp.MustCompile(`(\+86[- ]?)?(1[3-9]\d)[- ]?(\d{4})[- ]?(\d{4})`),
+		standalone: true,
+		replace: func(m []string) string {
+			out := zhDigits(m[2], true) + " " + zhDigits(m[3], true) + " " + zhDigits(m[4], true)
+			if m[1] != "" {
+				out = "加八六 " + out
+			}
+			return out
+		},
+	},
+	// 固定电话与服务号码 010-12345678、400-123-4567
+	{
+		re:         regexp.MustCompile(`(0\d{2,3})-(\d{7,8})|([48]00)-?(\d{3})-?(\d{4})`),
+		standalone: true,
+		replace: func(m []string) string {
+			parts := []string{}
+			for _, g := range m[1:] {
+				if g != "" {
+					parts = append(parts, zhDigits(g, true))
+				}
+			}
+			return strings.Join(parts, " ")
+		},
+	},
+	// 货币 ¥100、$9.99
+	{
+		re: regexp.MustCompile(`([¥￥$€£])\s?(\d+(?:\.\d+)?)`),
+		replace: func(m []string) string {
+			return zhNumber(m[2]) + zhCurrencyNames[m[1]]
+		},
+	},
+	// 百分数 50%、-3.5%
+	{
+		re: regexp.MustCompile(`(-?)(\d+(?:\.\d+)?)\s?[%％]`),
+		replace: func(m []string) string {
+			out := "百分之" + zhNumber(m[2])
+			if m[1] != "" {
+				out = "负" + out
+			}
+			return out
+		},
+	},
+	// 温度 25℃、-5°C
+	{
+		re: regexp.MustCompile(`(-?)(\d+(?:\.\d+)?)\s?(?:℃|°C|°)`),
+		replace: func(m []string) string {
+			out := zhNumber(m[2]) + "度"
+			if m[1] != "" {
+				out = "零下" + out
+			}
+			return out
+		},
+	},
+	// 范围 3-5、10~20，数字保留给后续规则处理单位
+	{
+		re:        regexp.MustCompile(`(\d+(?:\.\d+)?)\s?[-~～]\s?(\d+(?:\.\d+)?)`),
+		leftBound: true,
+		replace: func(m []string) string {
+			return m[1] + "到" + m[2]
+		},
+	},
+	// 分数 1/3
+	{
+		re:         regexp.MustCompile(`(\d+)/(\d+)`),
+		standalone: true,
+		replace: func(m []string) string {
+			return zhInteger(m[2]) + "分之" + zhInteger(m[1])
+		},
+	},
+	// 单位 5km、2kg、100GB
+	{
+		re:         regexp.MustCompile(`(\d+(?:\.\d+)?)\s?(km/h|km|kg|mg|ml|mL|cm|mm|m²|m³|mAh|min|ms|m|g|L|TB|GB|MB|KB|kHz|MHz|GHz|Hz|kW|W|V|h)`),
+		standalone: true,
+		replace: func(m []string) string {
+			return zhCount(m[1]) + zhUnitNames[m[2]]
+		},
+	},
+	// 版本号 1.2.3
+	{
+		re:         regexp.MustCompile(`\d+(?:\.\d+){2,}`),
+		standalone: true,
+		replace: func(m []string) string {
+			parts := strings.Split(m[0], ".")
+			for i, p := range parts {
+				parts[i] = zhDigits(p, false)
+			}
+			return strings.Join(parts, "点")
+		},
+	},
+	// 型号 RTX4090、GPT-4o、5G，数字逐位读
+	{
+		re:         regexp.MustCompile(`[A-Za-z]+(?:[-_]?\d+(?:\.\d+)?[A-Za-z]*)+|\d+[A-Za-z]+(?:\d+[A-Za-z]*)*`),
+		standalone: true,
+		replace: func(m []string) string {
+			return readModelNumber(m[0], func(d string) string { return zhDigits(d, false) }, "点", "")
+		},
+	},
+	// 英文缩写逐字母读 AI、CPU
+	{
+		re:         regexp.MustCompile(`[A-Z]{2,5}`),
+		standalone: true,
+		replace: func(m []string) string {
+			if zhKeepAcronyms[m[0]] {
+				return m[0]
+			}
+			return spellLetters(m[0])
+		},
+	},
+	// 其余数字 123、-4、3.14
+	{
+		re:         regexp.MustCompile(`(-?)(\d+(?:\.\d+)?)`),
+		standalone: true,
+		replace: func(m []string) string {
+			out := zhNumber(m[2])
+			if m[1] != "" {
+				out = "负" + out
+			}
+			return out
+		},
+	},
+	// 常见符号
+	{
+		re: regexp.MustCompile(`\s?&\s?`),
+		replace: func(m []string) string {
+			return "和"
+		},
+	},
+}
+
+// Normalize 规范化中文文本
+func (n *ZhNormalizer) Normalize(text string) string {
+	text = stripThousandSeparators(text)
+	return applyRules(text, zhRules)
+}
+
+// zhDigits 逐位读数字，phone为true时“1”读作“幺”
+func zhDigits(s string, phone bool) string {
+	var sb strings.Builder
+	for _, r := range s {
+		if r < '0' || r > '9' {
+			continue
+		}
+		if phone && r == '1' {
+			sb.WriteString("幺")
+			continue
+		}
+		sb.WriteString(zhDigitNames[r-'0'])
+	}
+	return sb.String()
+}
+
+// zhNumber 读十进制数，整数部分按数值读，小数部分逐位读
+func zhNumber(s string) string {
+	intPart, fracPart, hasFrac := strings.Cut(s, ".")
+	out := zhInteger(intPart)
+	if hasFrac && fracPart != "" {
+		out += "点" + zhDigits(fracPart, false)
+	}
+	return out
+}
+
+// zhCount 读作数量，单独的“2”读作“两”
+func zhCount(s string) string {
+	if s == "2" {
+		return "两"
+	}
+	return zhNumber(s)
+}
+
+// zhInteger 读整数，以0开头或超过12位的数字串逐位读
+func zhInteger(s string) string {
+	if len(s) > 1 && s[0] == '0' || len(s) > 12 {
+		return zhDigits(s, false)
+	}
+	n, err := strconv.ParseInt(s, 10, 64)
+	if err != nil {
+		return zhDigits(s, false)
+	}
+	if n == 0 {
+		return "零"
+	}
+
+	sectionUnits := []string{"", "万", "亿"}
+	var sections []int64
+	for v := n; v > 0; v /= 10000 {
+		sections = append(sections, v%10000)
+	}
+
+	var sb strings.Builder
+	zeroPending := false
+	for i := len(sections) - 1; i >= 0; i-- {
+		v := sections[i]
+		if v == 0 {
+			zeroPending = sb.Len() > 0
+			continue
+		}
+		if sb.Len() > 0 && (zeroPending || v < 1000) {
+			sb.WriteString("零")
+		}
+		sb.WriteString(zhSection(v))
+		sb.WriteString(sectionUnits[i])
+		zeroPending = false
+	}
+
+	out := sb.String()
+	// 10~19 读作“十X”而不是“一十X”
+	if strings.HasPrefix(out, "一十") {
+		out = strings.TrimPrefix(out, "一")
+	}
+	return out
+}
+
+// zhSection 读0~9999的数值
+func zhSection(v int64) string {
+	units := []string{"千", "百", "十", ""}
+	divisors := []int64{1000, 100, 10, 1}
+
+	var sb strings.Builder
+	started, zero := false, false
+	for i, d := range divisors {
+		digit := v / d % 10
+		if digit == 0 {
+			zero = started
+			continue
+		}
+		if zero {
+			sb.WriteString("零")
+			zero = false
+		}
+		sb.WriteString(zhDigitNames[digit])
+		sb.WriteString(units[i])
+		started = true
+	}
+	return sb.String()
+}
+
+// readModelNumber 读型号，字母保留，数字逐位读
+func readModelNumber(s string, digits func(string) string, point, sep string) string {
+	var parts []string
+	var run strings.Builder
+	runIsDigit := false
+
+	flush := func() {
+		if run.Len() == 0 {
+			return
+		}
+		if runIsDigit {
+			parts = append(parts, digits(run.String()))
+		} else {
+			parts = append(parts, run.String())
+		}
+		run.Reset()
+	}
+
+	for _, r := range s {
+		switch {
+		case r >= '0' && r <= '9':
+			if !runIsDigit {
+				flush()
+				runIsDigit = true
+			}
+			run.WriteRune(r)
+		case r == '.':
+			flush()
+			parts = append(parts, point)
+		case r == '-' || r == '_':
+			flush()
+		default:
+			if runIsDigit {
+				flush()
+				runIsDigit = false
+			}
+			run.WriteRune(r)
+		}
+	}
+	flush()
+	return strings.Join(parts, sep)
+}
+
+// spellLetters 字母之间加空格，使TTS逐字母朗读
+func spellLetters(s string) string {
+	return strings.Join(strings.Split(s, ""), " ")
+}
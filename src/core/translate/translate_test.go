@@ -0,0 +1,36 @@
+package translate
+
+import "testing"
+
+func TestDirection(t *testing.T) {
+	tests := []struct {
+		name       string
+		text       string
+		source     string
+		target     string
+		autoDetect bool
+		from, to   string
+	}{
+		{name: "未开启自动识别", text: "How are you", source: "zh", target: "en", from: "zh", to: "en"},
+		{name: "源语言", text: "你好，今天天气怎么样", source: "zh", target: "en", autoDetect: true, from: "zh", to: "en"},
+		{name: "目标语言反向翻译", text: "I'm fine, thank you.", source: "zh", target: "en", autoDetect: true, from: "en", to: "zh"},
+		{name: "夹杂英文单词", text: "我想买一台iPhone", source: "zh", target: "en", autoDetect: true, from: "zh", to: "en"},
+		{name: "日语假名", text: "今日はいい天気ですね", source: "zh", target: "ja", autoDetect: true, from: "ja", to: "zh"},
+		{name: "韩语", text: "안녕하세요", source: "en", target: "ko", autoDetect: true, from: "ko", to: "en"},
+		{name: "同种文字无法区分", text: "Bonjour", source: "en", target: "fr", autoDetect: true, from: "en", to: "fr"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			from, to := Direction(tt.text, tt.source, tt.target, tt.autoDetect)
+			if from != tt.from || to != tt.to {
+				t.Errorf("Direction(%q) = %s -> %s, 期望 %s -> %s", tt.text, from, to, tt.from, tt.to)
+			}
+		})
+	}
+}
+
+func TestPrompt(t *testing.T) {
+	if got := Prompt("把{source}翻译成{target}", "zh", "xx"); got != "把中文翻译成xx" {
+		t.Errorf("Prompt() = %q", got)
+	}
+}
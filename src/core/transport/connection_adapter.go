@@ -10,7 +10,6 @@ import (
 	"angrymiao-ai-server/src/core"
 	"angrymiao-ai-server/src/core/pool"
 	"angrymiao-ai-server/src/core/utils"
-	"angrymiao-ai-server/src/services"
 	"angrymiao-ai-server/src/task"
 )
 
@@ -36,13 +35,15 @@ func NewConnectionContextAdapter(
 	taskMgr *task.TaskManager,
 	logger *utils.Logger,
 	req *http.Request,
-	userConfigService services.UserAIConfigService,
+	services core.ConnectionServices,
 ) *ConnectionContextAdapter {
 	clientID := conn.GetID()
 	connCtx, connCancel := context.WithCancel(context.Background())
 
 	// 创建ConnectionHandler
 	handler := core.NewConnectionHandler(config, providerSet, logger, req, connCtx)
+	handler.SetServices(services)
+	handler.SetLLMSource(poolManager)
 
 	adapter := &ConnectionContextAdapter{
 		handler:     handler,
@@ -148,11 +149,11 @@ func (a *ConnectionContextAdapter) CreateSafeCallback() func(func(*core.Connecti
 
 // DefaultConnectionHandlerFactory 默认连接处理器工厂
 type DefaultConnectionHandlerFactory struct {
-	config            *configs.Config
-	poolManager       *pool.PoolManager
-	taskMgr           *task.TaskManager
-	logger            *utils.Logger
-	userConfigService services.UserAIConfigService
+	config      *configs.Config
+	poolManager *pool.PoolManager
+	taskMgr     *task.TaskManager
+	logger      *utils.Logger
+	services    core.ConnectionServices
 }
 
 // NewDefaultConnectionHandlerFactory 创建默认连接处理器工厂
@@ -161,14 +162,14 @@ func NewDefaultConnectionHandlerFactory(
 	poolManager *pool.PoolManager,
 	taskMgr *task.TaskManager,
 	logger *utils.Logger,
-	userConfigService services.UserAIConfigService,
+	services core.ConnectionServices,
 ) *DefaultConnectionHandlerFactory {
 	return &DefaultConnectionHandlerFactory{
-		config:            config,
-		poolManager:       poolManager,
-		taskMgr:           taskMgr,
-		logger:            logger,
-		userConfigService: userConfigService,
+		config:      config,
+		poolManager: poolManager,
+		taskMgr:     taskMgr,
+		logger:      logger,
+		services:    services,
 	}
 }
 
@@ -193,7 +194,7 @@ func (f *DefaultConnectionHandlerFactory) CreateHandler(
 		f.taskMgr,
 		f.logger,
 		req,
-		f.userConfigService,
+		f.services,
 	)
 
 	return adapter
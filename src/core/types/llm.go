@@ -106,8 +106,21 @@ type FunctionCallResult struct {
 type Response struct {
 	Content    string     `json:"content,omitempty"`
 	ToolCalls  []ToolCall `json:"tool_calls,omitempty"`
+	Reasoning  string     `json:"reasoning,omitempty"` // 思考过程增量，只用于展示，不送入TTS
 	StopReason string     `json:"stop_reason,omitempty"`
 	Error      string     `json:"error,omitempty"`
+
+	// 实际生成回复的LLM名称，故障转移切换到备用LLM时用于按实际提供者统计用量
+	Provider string `json:"provider,omitempty"`
+
+	// 提供者返回的用量，通常在流结束前单独发送一次
+	Usage *Usage `json:"usage,omitempty"`
+}
+
+// Usage LLM请求的token用量
+type Usage struct {
+	PromptTokens     int `json:"prompt_tokens"`
+	CompletionTokens int `json:"completion_tokens"`
 }
 
 // Provider 基础提供者接口
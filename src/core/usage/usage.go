@@ -0,0 +1,127 @@
+package usage
+
+import (
+	"sort"
+	"sync"
+	"unicode"
+)
+
+// 用量的提供者类型
+const (
+	TypeLLM = "llm"
+	TypeTTS = "tts"
+	TypeASR = "asr"
+)
+
+// Entry 单个提供者的累计用量
+type Entry struct {
+	ProviderType     string
+	Provider         string // 配置中的提供者名称
+	Requests         int64
+	PromptTokens     int64
+	CompletionTokens int64
+	Estimated        bool // token数是否为估算值
+	TTSChars         int64
+	ASRSeconds       float64
+}
+
+// EstimateTokens 估算文本的token数，提供者未返回用量时使用
+// 中日韩字符按每字一个token计，其余字符按约4个字符一个token计
+func EstimateTokens(text string) int {
+	cjk, other := 0, 0
+	for _, r := range text {
+		switch {
+		case unicode.In(r, unicode.Han, unicode.Hiragana, unicode.Katakana, unicode.Hangul):
+			cjk++
+		case !unicode.IsSpace(r):
+			other++
+		}
+	}
+	return cjk + (other+3)/4
+}
+
+// PCMSeconds 计算16位PCM音频的时长
+func PCMSeconds(size, sampleRate, channels int) float64 {
+	if sampleRate <= 0 || channels <= 0 {
+		return 0
+	}
+	return float64(size) / float64(2*sampleRate*channels)
+}
+
+type entryKey struct {
+	providerType string
+	provider     string
+}
+
+// Meter 连接内的用量计数器，定期取出写入数据库
+type Meter struct {
+	mu      sync.Mutex
+	entries map[entryKey]*Entry
+}
+
+// NewMeter 创建用量计数器
+func NewMeter() *Meter {
+	return &Meter{entries: make(map[entryKey]*Entry)}
+}
+
+func (m *Meter) entry(providerType, provider string) *Entry {
+	key := entryKey{providerType, provider}
+	e, ok := m.entries[key]
+	if !ok {
+		e = &Entry{ProviderType: providerType, Provider: provider}
+		m.entries[key] = e
+	}
+	return e
+}
+
+// AddLLM 记录一次LLM请求的token数
+func (m *Meter) AddLLM(provider string, promptTokens, completionTokens int, estimated bool) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	e := m.entry(TypeLLM, provider)
+	e.Requests++
+	e.PromptTokens += int64(promptTokens)
+	e.CompletionTokens += int64(completionTokens)
+	e.Estimated = e.Estimated || estimated
+}
+
+// AddTTS 记录一次TTS合成的字符数
+func (m *Meter) AddTTS(provider string, chars int) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	e := m.entry(TypeTTS, provider)
+	e.Requests++
+	e.TTSChars += int64(chars)
+}
+
+// AddASR 记录送入ASR的音频时长
+func (m *Meter) AddASR(provider string, seconds float64) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.entry(TypeASR, provider).ASRSeconds += seconds
+}
+
+// AddASRRequest 记录一次ASR识别结果
+func (m *Meter) AddASRRequest(provider string) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.entry(TypeASR, provider).Requests++
+}
+
+// Drain 取出并清空累计的用量
+func (m *Meter) Drain() []Entry {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	entries := make([]Entry, 0, len(m.entries))
+	for _, e := range m.entries {
+		entries = append(entries, *e)
+	}
+	m.entries = make(map[entryKey]*Entry)
+	sort.Slice(entries, func(i, j int) bool {
+		if entries[i].ProviderType != entries[j].ProviderType {
+			return entries[i].ProviderType < entries[j].ProviderType
+		}
+		return entries[i].Provider < entries[j].Provider
+	})
+	return entries
+}
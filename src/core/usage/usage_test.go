@@ -0,0 +1,43 @@
+package usage
+
+import "testing"
+
+func TestEstimateTokens(t *testing.T) {
+	tests := []struct {
+		text string
+		want int
+	}{
+		{"", 0},
+		{"你好世界", 4},
+		{"hello world", 3},
+		{"今天 weather 好", 3 + 2},
+	}
+	for _, tt := range tests {
+		if got := EstimateTokens(tt.text); got != tt.want {
+			t.Errorf("EstimateTokens(%q) = %d, 期望 %d", tt.text, got, tt.want)
+		}
+	}
+}
+
+func TestMeterDrain(t *testing.T) {
+	m := NewMeter()
+	m.AddLLM("QwenLLM", 100, 20, false)
+	m.AddLLM("QwenLLM", 50, 10, true)
+	m.AddTTS("EdgeTTS", 12)
+	m.AddASR("DoubaoASR", PCMSeconds(32000, 16000, 1))
+
+	entries := m.Drain()
+	if len(entries) != 3 {
+		t.Fatalf("条目数 = %d, 期望 3", len(entries))
+	}
+	llm := entries[1]
+	if llm.ProviderType != TypeLLM || llm.Requests != 2 || llm.PromptTokens != 150 || llm.CompletionTokens != 30 || !llm.Estimated {
+		t.Errorf("LLM用量 = %+v", llm)
+	}
+	if asr := entries[0]; asr.ProviderType != TypeASR || asr.ASRSeconds != 1 {
+		t.Errorf("ASR用量 = %+v", asr)
+	}
+	if len(m.Drain()) != 0 {
+		t.Error("Drain 后应清空")
+	}
+}
@@ -1,6 +1,10 @@
 package utils
 
-import "regexp"
+import (
+	"regexp"
+	"sort"
+	"strings"
+)
 
 // EmotionEmoji 定义情绪到表情的映射
 var EmotionEmoji = map[string]string{
@@ -43,3 +47,50 @@ var SimpleEmojiRegex = regexp.MustCompile(`[\x{1F000}-\x{1FFFF}]|` +
 func RemoveAllEmoji(text string) string {
 	return SimpleEmojiRegex.ReplaceAllString(text, "")
 }
+
+// emojiEmotion 表情到情绪的反向映射
+var emojiEmotion = func() map[string]string {
+	m := make(map[string]string, len(EmotionEmoji))
+	for emotion, emoji := range EmotionEmoji {
+		m[emoji] = emotion
+	}
+	return m
+}()
+
+// emotionTagRegex 句首的情绪标签，如 [happy] 或 【happy】
+var emotionTagRegex = regexp.MustCompile(`^\s*(?:\[([a-z]+)\]|【([a-z]+)】)\s*`)
+
+// ExtractEmotion 提取一句回复的情绪，返回情绪和去掉句首情绪标签后的文本
+// 优先识别句首的情绪标签，其次识别句中第一个已知表情，表情本身由TTS前的过滤统一去除
+func ExtractEmotion(text string) (string, string) {
+	if m := emotionTagRegex.FindStringSubmatch(text); m != nil {
+		emotion := m[1] + m[2]
+		if _, ok := EmotionEmoji[emotion]; ok {
+			return emotion, text[len(m[0]):]
+		}
+	}
+	for _, emoji := range SimpleEmojiRegex.FindAllString(text, -1) {
+		if emotion, ok := emojiEmotion[emoji]; ok {
+			return emotion, text
+		}
+	}
+	return "", text
+}
+
+// EmotionPromptList 可选的表情和情绪列表，用于提示词
+func EmotionPromptList() string {
+	emotions := make([]string, 0, len(EmotionEmoji))
+	for emotion := range EmotionEmoji {
+		emotions = append(emotions, emotion)
+	}
+	sort.Strings(emotions)
+	var b strings.Builder
+	for i, emotion := range emotions {
+		if i > 0 {
+			b.WriteString(" ")
+		}
+		b.WriteString(EmotionEmoji[emotion])
+		b.WriteString(emotion)
+	}
+	return b.String()
+}
@@ -0,0 +1,29 @@
+package utils
+
+import "testing"
+
+func TestExtractEmotion(t *testing.T) {
+	tests := []struct {
+		name    string
+		input   string
+		emotion string
+		text    string
+	}{
+		{name: "句首表情", input: "😊今天天气真好！", emotion: "happy", text: "😊今天天气真好！"},
+		{name: "句中表情", input: "哇，好厉害😮", emotion: "surprised", text: "哇，好厉害😮"},
+		{name: "句首标签", input: "[sad] 我有点难过。", emotion: "sad", text: "我有点难过。"},
+		{name: "中文括号标签", input: "【cool】没问题。", emotion: "cool", text: "没问题。"},
+		{name: "未知标签", input: "[unknown]你好", emotion: "", text: "[unknown]你好"},
+		{name: "未知表情", input: "送你一朵花🌹", emotion: "", text: "送你一朵花🌹"},
+		{name: "无情绪", input: "你好。", emotion: "", text: "你好。"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			emotion, text := ExtractEmotion(tt.input)
+			if emotion != tt.emotion || text != tt.text {
+				t.Errorf("ExtractEmotion(%q) = (%q, %q), 期望 (%q, %q)", tt.input, emotion, text, tt.emotion, tt.text)
+			}
+		})
+	}
+}
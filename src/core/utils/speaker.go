@@ -0,0 +1,68 @@
+package utils
+
+import (
+	"regexp"
+	"strings"
+)
+
+// 说话人标签，如 [旁白]、【小明】：，只有配置的说话人名称才作为标签处理
+var reSpeakerTag = regexp.MustCompile(`[\[【]([^\[\]【】\n]{1,16})[\]】]\s*[:：]?\s*`)
+
+// SpeakerSegment 带说话人的文本片段，Speaker为空表示未标注
+type SpeakerSegment struct {
+	Speaker string
+	Text    string
+}
+
+// SpeakerParser 流式解析说话人标签
+// 标签之后的文本都归属该说话人，直到出现下一个标签，因此跨分段调用时保留当前说话人
+// 不是已知说话人的括号内容（如【注意】）保留为普通文本
+type SpeakerParser struct {
+	current string
+	names   map[string]bool // 已知说话人名称，不区分大小写
+}
+
+// NewSpeakerParser 创建说话人标签解析器，names 为可识别的说话人名称
+func NewSpeakerParser(names []string) *SpeakerParser {
+	p := &SpeakerParser{names: make(map[string]bool, len(names))}
+	for _, name := range names {
+		if name = strings.TrimSpace(name); name != "" {
+			p.names[strings.ToLower(name)] = true
+		}
+	}
+	return p
+}
+
+// Current 当前说话人
+func (p *SpeakerParser) Current() string {
+	return p.current
+}
+
+// Parse 解析文本中的说话人标签，返回去掉标签后的片段，空白片段会被丢弃
+func (p *SpeakerParser) Parse(text string) []SpeakerSegment {
+	var segments []SpeakerSegment
+	appendText := func(s string) {
+		if strings.TrimSpace(s) == "" {
+			return
+		}
+		segments = append(segments, SpeakerSegment{Speaker: p.current, Text: strings.TrimSpace(s)})
+	}
+
+	last := 0
+	for _, loc := range reSpeakerTag.FindAllStringSubmatchIndex(text, -1) {
+		speaker := strings.TrimSpace(text[loc[2]:loc[3]])
+		if !p.names[strings.ToLower(speaker)] {
+			continue
+		}
+		appendText(text[last:loc[0]])
+		p.current = speaker
+		last = loc[1]
+	}
+	appendText(text[last:])
+	return segments
+}
+
+// Reset 清除当前说话人
+func (p *SpeakerParser) Reset() {
+	p.current = ""
+}
@@ -0,0 +1,45 @@
+package utils
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestSpeakerParser(t *testing.T) {
+	p := NewSpeakerParser([]string{"旁白", "小明", "小红"})
+
+	got := p.Parse("【旁白】从前有座山。[小明]：你好！")
+	expected := []SpeakerSegment{
+		{Speaker: "旁白", Text: "从前有座山。"},
+		{Speaker: "小明", Text: "你好！"},
+	}
+	if !reflect.DeepEqual(got, expected) {
+		t.Errorf("Parse() = %v, 期望 %v", got, expected)
+	}
+
+	// 跨分段保持说话人
+	got = p.Parse("今天天气不错。")
+	expected = []SpeakerSegment{{Speaker: "小明", Text: "今天天气不错。"}}
+	if !reflect.DeepEqual(got, expected) {
+		t.Errorf("Parse() = %v, 期望 %v", got, expected)
+	}
+
+	// 只有标签时不产生片段，但切换说话人
+	if got := p.Parse("【小红】"); len(got) != 0 || p.Current() != "小红" {
+		t.Errorf("Parse() = %v, 当前说话人 %s", got, p.Current())
+	}
+
+	// 不是已知说话人的括号内容保留为文本
+	got = p.Parse("【注意】不要迟到。[1]见附录")
+	expected = []SpeakerSegment{{Speaker: "小红", Text: "【注意】不要迟到。[1]见附录"}}
+	if !reflect.DeepEqual(got, expected) {
+		t.Errorf("Parse() = %v, 期望 %v", got, expected)
+	}
+
+	p.Reset()
+	got = p.Parse("没有标签的文本")
+	expected = []SpeakerSegment{{Speaker: "", Text: "没有标签的文本"}}
+	if !reflect.DeepEqual(got, expected) {
+		t.Errorf("Parse() = %v, 期望 %v", got, expected)
+	}
+}
@@ -0,0 +1,68 @@
+package utils
+
+import "strings"
+
+const (
+	thinkOpenTag  = "<think>"
+	thinkCloseTag = "</think>"
+)
+
+// ThinkParser 从流式回复中拆分<think>思考内容和正式回答，标签可跨分块
+type ThinkParser struct {
+	thinking bool
+	pending  string // 可能是标签开头的未决内容
+}
+
+// Feed 处理一个分块，返回其中的回答和思考内容
+func (p *ThinkParser) Feed(chunk string) (answer, reasoning string) {
+	text := p.pending + chunk
+	p.pending = ""
+
+	var answerBuf, reasoningBuf strings.Builder
+	emit := func(s string) {
+		if p.thinking {
+			reasoningBuf.WriteString(s)
+		} else {
+			answerBuf.WriteString(s)
+		}
+	}
+
+	for text != "" {
+		tag := thinkOpenTag
+		if p.thinking {
+			tag = thinkCloseTag
+		}
+		if idx := strings.Index(text, tag); idx >= 0 {
+			emit(text[:idx])
+			text = text[idx+len(tag):]
+			p.thinking = !p.thinking
+			continue
+		}
+		// 结尾可能是被拆开的标签，留到下一个分块
+		keep := partialSuffix(text, tag)
+		emit(text[:len(text)-keep])
+		p.pending = text[len(text)-keep:]
+		break
+	}
+	return answerBuf.String(), reasoningBuf.String()
+}
+
+// Flush 流结束时返回未决内容
+func (p *ThinkParser) Flush() (answer, reasoning string) {
+	text := p.pending
+	p.pending = ""
+	if p.thinking {
+		return "", text
+	}
+	return text, ""
+}
+
+// partialSuffix 返回 text 结尾与 tag 前缀重合的最大长度
+func partialSuffix(text, tag string) int {
+	for n := len(tag) - 1; n > 0; n-- {
+		if strings.HasSuffix(text, tag[:n]) {
+			return n
+		}
+	}
+	return 0
+}
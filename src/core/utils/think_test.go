@@ -0,0 +1,53 @@
+package utils
+
+import "testing"
+
+func TestThinkParser(t *testing.T) {
+	tests := []struct {
+		name      string
+		chunks    []string
+		answer    string
+		reasoning string
+	}{
+		{
+			name:   "无思考标签",
+			chunks: []string{"你好", "，世界"},
+			answer: "你好，世界",
+		},
+		{
+			name:      "标签独立分块",
+			chunks:    []string{"<think>", "先想想", "</think>", "答案"},
+			answer:    "答案",
+			reasoning: "先想想",
+		},
+		{
+			name:      "标签跨分块",
+			chunks:    []string{"<thi", "nk>推理</th", "ink>\n\n结果<", "是1"},
+			answer:    "\n\n结果<是1",
+			reasoning: "推理",
+		},
+		{
+			name:      "未闭合的思考",
+			chunks:    []string{"<think>还在想</"},
+			reasoning: "还在想</",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var p ThinkParser
+			var answer, reasoning string
+			for _, chunk := range tt.chunks {
+				a, r := p.Feed(chunk)
+				answer += a
+				reasoning += r
+			}
+			a, r := p.Flush()
+			answer += a
+			reasoning += r
+			if answer != tt.answer || reasoning != tt.reasoning {
+				t.Errorf("ThinkParser = (%q, %q), 期望 (%q, %q)", answer, reasoning, tt.answer, tt.reasoning)
+			}
+		})
+	}
+}
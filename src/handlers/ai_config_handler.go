@@ -9,7 +9,6 @@ import (
 	"strings"
 
 	"angrymiao-ai-server/src/configs"
-	"angrymiao-ai-server/src/core/auth/am_token"
 	"angrymiao-ai-server/src/core/providers/llm"
 	"angrymiao-ai-server/src/core/types"
 	"angrymiao-ai-server/src/core/utils"
@@ -190,7 +189,7 @@ func (h *AIConfigHandler) generateParametersWithLLM(configName, description stri
 	}
 
 	// 获取选定的LLM类型
-	selectedLLM := cfg.SelectedModule["LLM"]
+	selectedLLM := cfg.PrimaryLLM()
 	if selectedLLM == "" {
 		return nil, fmt.Errorf("未配置选定的LLM")
 	}
@@ -610,68 +609,20 @@ func (h *AIConfigHandler) SetConfigPriority(c *gin.Context) {
 
 // authMiddleware JWT认证中间件
 func (h *AIConfigHandler) authMiddleware() gin.HandlerFunc {
-	return func(c *gin.Context) {
-		// 验证认证
-		authHeader := c.GetHeader("Authorization")
-		if !strings.HasPrefix(authHeader, "Bearer ") {
-			h.respondError(c, http.StatusUnauthorized, "无效的认证token或token已过期", nil)
-			c.Abort()
-			return
-		}
-
-		token := authHeader[7:] // 移除"Bearer "前缀
-
-		// 使用Casbin进行JWT token验证
-		claims, err := am_token.ParseToken(token)
-		if err != nil {
-			h.respondError(c, http.StatusUnauthorized, "token验证失败: "+err.Error(), err)
-			c.Abort()
-			return
-		}
-
-		// 将用户ID存储到上下文中
-		c.Set("user_id", uint(claims.UserID))
-		c.Set("jwt_claims", claims)
-
-		c.Next()
-	}
+	return jwtAuthMiddleware(h.logger)
 }
 
 // getUserID 从上下文获取用户ID
 func (h *AIConfigHandler) getUserID(c *gin.Context) string {
-	// 从JWT认证中间件设置的上下文中获取用户ID
-	if userID, exists := c.Get("user_id"); exists {
-		if uid, ok := userID.(uint); ok {
-			return strconv.FormatUint(uint64(uid), 10)
-		}
-	}
-
-	// 如果没有找到用户ID，返回空字符串（这种情况不应该发生，因为有认证中间件）
-	h.logger.Error("无法从上下文中获取用户ID")
-	return ""
+	return getUserIDFromContext(c, h.logger)
 }
 
 // respondSuccess 返回成功响应
 func (h *AIConfigHandler) respondSuccess(c *gin.Context, data interface{}) {
-	c.JSON(http.StatusOK, gin.H{
-		"code":    200,
-		"message": "操作成功",
-		"data":    data,
-	})
+	respondSuccess(c, data)
 }
 
 // respondError 返回错误响应
 func (h *AIConfigHandler) respondError(c *gin.Context, statusCode int, message string, err error) {
-	h.logger.Error("%s: %v", message, err)
-
-	response := gin.H{
-		"code":    statusCode,
-		"message": message,
-	}
-
-	if err != nil {
-		response["error"] = err.Error()
-	}
-
-	c.JSON(statusCode, response)
+	respondError(c, h.logger, statusCode, message, err)
 }
@@ -0,0 +1,102 @@
+package handlers
+
+import (
+	"net/http"
+	"strconv"
+	"strings"
+
+	"angrymiao-ai-server/src/core/auth/am_token"
+	"angrymiao-ai-server/src/core/utils"
+
+	"github.com/gin-gonic/gin"
+)
+
+// jwtAuthMiddleware JWT认证中间件，认证通过后将用户ID和claims写入上下文
+func jwtAuthMiddleware(logger *utils.Logger) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		// 验证认证
+		authHeader := c.GetHeader("Authorization")
+		if !strings.HasPrefix(authHeader, "Bearer ") {
+			respondError(c, logger, http.StatusUnauthorized, "无效的认证token或token已过期", nil)
+			c.Abort()
+			return
+		}
+
+		token := authHeader[7:] // 移除"Bearer "前缀
+
+		claims, err := am_token.ParseToken(token)
+		if err != nil {
+			respondError(c, logger, http.StatusUnauthorized, "token验证失败: "+err.Error(), err)
+			c.Abort()
+			return
+		}
+
+		// 将用户ID存储到上下文中
+		c.Set("user_id", uint(claims.UserID))
+		c.Set("jwt_claims", claims)
+
+		c.Next()
+	}
+}
+
+// adminRole 管理员角色名称，对应JWT中的role字段
+const adminRole = "admin"
+
+// adminMiddleware 管理员权限中间件，需在jwtAuthMiddleware之后使用
+func adminMiddleware(logger *utils.Logger) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if !isAdmin(c) {
+			respondError(c, logger, http.StatusForbidden, "需要管理员权限", nil)
+			c.Abort()
+			return
+		}
+
+		c.Next()
+	}
+}
+
+// isAdmin 判断当前用户是否为管理员，需在jwtAuthMiddleware之后使用
+func isAdmin(c *gin.Context) bool {
+	claims, _ := c.Get("jwt_claims")
+	jwtClaims, ok := claims.(*am_token.JWTClaims)
+	return ok && jwtClaims.Role == adminRole
+}
+
+// getUserIDFromContext 从上下文获取用户ID
+func getUserIDFromContext(c *gin.Context, logger *utils.Logger) string {
+	// 从JWT认证中间件设置的上下文中获取用户ID
+	if userID, exists := c.Get("user_id"); exists {
+		if uid, ok := userID.(uint); ok {
+			return strconv.FormatUint(uint64(uid), 10)
+		}
+	}
+
+	// 如果没有找到用户ID，返回空字符串（这种情况不应该发生，因为有认证中间件）
+	logger.Error("无法从上下文中获取用户ID")
+	return ""
+}
+
+// respondSuccess 返回成功响应
+func respondSuccess(c *gin.Context, data interface{}) {
+	c.JSON(http.StatusOK, gin.H{
+		"code":    200,
+		"message": "操作成功",
+		"data":    data,
+	})
+}
+
+// respondError 返回错误响应
+func respondError(c *gin.Context, logger *utils.Logger, statusCode int, message string, err error) {
+	logger.Error("%s: %v", message, err)
+
+	response := gin.H{
+		"code":    statusCode,
+		"message": message,
+	}
+
+	if err != nil {
+		response["error"] = err.Error()
+	}
+
+	c.JSON(statusCode, response)
+}
@@ -0,0 +1,165 @@
+package handlers
+
+import (
+	"fmt"
+	"net/http"
+	"strconv"
+
+	"angrymiao-ai-server/src/core/utils"
+	"angrymiao-ai-server/src/services"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+// ConversationHandler 对话历史处理器
+type ConversationHandler struct {
+	conversationService services.ConversationService
+	logger              *utils.Logger
+}
+
+// NewConversationHandler 创建对话历史处理器
+func NewConversationHandler(db *gorm.DB, logger *utils.Logger) *ConversationHandler {
+	return &ConversationHandler{
+		conversationService: services.NewConversationService(db, logger),
+		logger:              logger,
+	}
+}
+
+// RegisterRoutes 注册路由
+func (h *ConversationHandler) RegisterRoutes(apiGroup *gin.RouterGroup) {
+	conversationGroup := apiGroup.Group("/conversations")
+	conversationGroup.Use(jwtAuthMiddleware(h.logger))
+	{
+		conversationGroup.GET("", h.ListSessions)
+		conversationGroup.GET("/:id", h.GetTranscript)
+		conversationGroup.GET("/:id/export", h.ExportTranscript)
+		conversationGroup.DELETE("/:id", h.DeleteSession)
+	}
+}
+
+// ListSessions 获取对话会话列表
+// @Summary 获取对话会话列表
+// @Description 分页获取当前用户的对话会话，按最近活跃时间倒序
+// @Tags 对话历史
+// @Produce json
+// @Security BearerAuth
+// @Param page query int false "页码，默认1"
+// @Param page_size query int false "每页数量，默认20，最大100"
+// @Success 200 {object} map[string]interface{} "成功"
+// @Failure 401 {object} map[string]interface{} "未授权"
+// @Router /api/conversations [get]
+func (h *ConversationHandler) ListSessions(c *gin.Context) {
+	userID := getUserIDFromContext(c, h.logger)
+	page, _ := strconv.Atoi(c.DefaultQuery("page", "1"))
+	pageSize, _ := strconv.Atoi(c.DefaultQuery("page_size", "20"))
+
+	sessions, total, err := h.conversationService.ListSessions(c.Request.Context(), userID, page, pageSize)
+	if err != nil {
+		respondError(c, h.logger, http.StatusInternalServerError, "获取对话会话失败", err)
+		return
+	}
+
+	respondSuccess(c, gin.H{
+		"sessions": sessions,
+		"total":    total,
+		"page":     page,
+	})
+}
+
+// GetTranscript 获取对话记录
+// @Summary 获取对话记录
+// @Description 获取会话信息及全部消息，包含工具调用和耗时
+// @Tags 对话历史
+// @Produce json
+// @Security BearerAuth
+// @Param id path string true "会话ID"
+// @Success 200 {object} map[string]interface{} "成功"
+// @Failure 404 {object} map[string]interface{} "会话不存在"
+// @Router /api/conversations/{id} [get]
+func (h *ConversationHandler) GetTranscript(c *gin.Context) {
+	userID := getUserIDFromContext(c, h.logger)
+	conversationID := c.Param("id")
+
+	session, err := h.conversationService.GetSession(c.Request.Context(), userID, conversationID)
+	if err != nil {
+		respondError(c, h.logger, http.StatusNotFound, "会话不存在", err)
+		return
+	}
+	messages, err := h.conversationService.GetMessages(c.Request.Context(), userID, conversationID)
+	if err != nil {
+		respondError(c, h.logger, http.StatusInternalServerError, "获取对话记录失败", err)
+		return
+	}
+
+	respondSuccess(c, gin.H{
+		"session":  session,
+		"messages": messages,
+	})
+}
+
+// ExportTranscript 导出对话记录
+// @Summary 导出对话记录
+// @Description 以JSON或Markdown文件导出对话记录
+// @Tags 对话历史
+// @Produce json
+// @Produce text/markdown
+// @Security BearerAuth
+// @Param id path string true "会话ID"
+// @Param format query string false "导出格式：json（默认）或 markdown"
+// @Success 200 {file} file "对话记录文件"
+// @Failure 400 {object} map[string]interface{} "不支持的导出格式"
+// @Failure 404 {object} map[string]interface{} "会话不存在"
+// @Router /api/conversations/{id}/export [get]
+func (h *ConversationHandler) ExportTranscript(c *gin.Context) {
+	userID := getUserIDFromContext(c, h.logger)
+	conversationID := c.Param("id")
+	format := c.DefaultQuery("format", "json")
+	if format != "json" && format != "markdown" && format != "md" {
+		respondError(c, h.logger, http.StatusBadRequest, "不支持的导出格式: "+format, nil)
+		return
+	}
+
+	session, err := h.conversationService.GetSession(c.Request.Context(), userID, conversationID)
+	if err != nil {
+		respondError(c, h.logger, http.StatusNotFound, "会话不存在", err)
+		return
+	}
+	messages, err := h.conversationService.GetMessages(c.Request.Context(), userID, conversationID)
+	if err != nil {
+		respondError(c, h.logger, http.StatusInternalServerError, "获取对话记录失败", err)
+		return
+	}
+
+	if format == "json" {
+		c.Header("Content-Disposition", fmt.Sprintf("attachment; filename=conversation-%s.json", conversationID))
+		c.JSON(http.StatusOK, gin.H{
+			"session":  session,
+			"messages": messages,
+		})
+		return
+	}
+
+	c.Header("Content-Disposition", fmt.Sprintf("attachment; filename=conversation-%s.md", conversationID))
+	c.Data(http.StatusOK, "text/markdown; charset=utf-8", []byte(services.RenderConversationMarkdown(session, messages)))
+}
+
+// DeleteSession 删除对话会话
+// @Summary 删除对话会话
+// @Tags 对话历史
+// @Produce json
+// @Security BearerAuth
+// @Param id path string true "会话ID"
+// @Success 200 {object} map[string]interface{} "成功"
+// @Failure 404 {object} map[string]interface{} "会话不存在"
+// @Router /api/conversations/{id} [delete]
+func (h *ConversationHandler) DeleteSession(c *gin.Context) {
+	userID := getUserIDFromContext(c, h.logger)
+
+	if err := h.conversationService.DeleteSession(c.Request.Context(), userID, c.Param("id")); err != nil {
+		respondError(c, h.logger, http.StatusNotFound, "删除会话失败", err)
+		return
+	}
+
+	respondSuccess(c, gin.H{"message": "会话删除成功"})
+}
@@ -0,0 +1,134 @@
+package handlers
+
+import (
+	"fmt"
+	"net/http"
+	"strconv"
+
+	"angrymiao-ai-server/src/core/utils"
+	"angrymiao-ai-server/src/services"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+// DictationHandler 听写文档处理器
+type DictationHandler struct {
+	dictationService services.DictationService
+	logger           *utils.Logger
+}
+
+// NewDictationHandler 创建听写文档处理器
+func NewDictationHandler(db *gorm.DB, logger *utils.Logger) *DictationHandler {
+	return &DictationHandler{
+		dictationService: services.NewDictationService(db, logger),
+		logger:           logger,
+	}
+}
+
+// RegisterRoutes 注册路由
+func (h *DictationHandler) RegisterRoutes(apiGroup *gin.RouterGroup) {
+	dictationGroup := apiGroup.Group("/dictations")
+	dictationGroup.Use(jwtAuthMiddleware(h.logger))
+	{
+		dictationGroup.GET("", h.ListDocuments)
+		dictationGroup.GET("/:id", h.GetDocument)
+		dictationGroup.GET("/:id/export", h.ExportDocument)
+		dictationGroup.DELETE("/:id", h.DeleteDocument)
+	}
+}
+
+// ListDocuments 获取听写文档列表
+// @Summary 获取听写文档列表
+// @Description 分页获取当前用户的听写文档，按创建时间倒序
+// @Tags 听写
+// @Produce json
+// @Security BearerAuth
+// @Param page query int false "页码，默认1"
+// @Param page_size query int false "每页数量，默认20，最大100"
+// @Success 200 {object} map[string]interface{} "成功"
+// @Failure 401 {object} map[string]interface{} "未授权"
+// @Router /api/dictations [get]
+func (h *DictationHandler) ListDocuments(c *gin.Context) {
+	userID := getUserIDFromContext(c, h.logger)
+	page, _ := strconv.Atoi(c.DefaultQuery("page", "1"))
+	pageSize, _ := strconv.Atoi(c.DefaultQuery("page_size", "20"))
+
+	docs, total, err := h.dictationService.ListDocuments(c.Request.Context(), userID, page, pageSize)
+	if err != nil {
+		respondError(c, h.logger, http.StatusInternalServerError, "获取听写文档失败", err)
+		return
+	}
+
+	respondSuccess(c, gin.H{
+		"documents": docs,
+		"total":     total,
+		"page":      page,
+	})
+}
+
+// GetDocument 获取听写文档
+// @Summary 获取听写文档
+// @Description 获取听写文档的最终文本和识别原文
+// @Tags 听写
+// @Produce json
+// @Security BearerAuth
+// @Param id path string true "文档ID"
+// @Success 200 {object} map[string]interface{} "成功"
+// @Failure 404 {object} map[string]interface{} "文档不存在"
+// @Router /api/dictations/{id} [get]
+func (h *DictationHandler) GetDocument(c *gin.Context) {
+	userID := getUserIDFromContext(c, h.logger)
+
+	doc, err := h.dictationService.GetDocument(c.Request.Context(), userID, c.Param("id"))
+	if err != nil {
+		respondError(c, h.logger, http.StatusNotFound, "文档不存在", err)
+		return
+	}
+
+	respondSuccess(c, doc)
+}
+
+// ExportDocument 导出听写文档
+// @Summary 导出听写文档
+// @Description 以纯文本文件导出听写文档的最终文本
+// @Tags 听写
+// @Produce text/plain
+// @Security BearerAuth
+// @Param id path string true "文档ID"
+// @Success 200 {file} file "听写文本文件"
+// @Failure 404 {object} map[string]interface{} "文档不存在"
+// @Router /api/dictations/{id}/export [get]
+func (h *DictationHandler) ExportDocument(c *gin.Context) {
+	userID := getUserIDFromContext(c, h.logger)
+	documentID := c.Param("id")
+
+	doc, err := h.dictationService.GetDocument(c.Request.Context(), userID, documentID)
+	if err != nil {
+		respondError(c, h.logger, http.StatusNotFound, "文档不存在", err)
+		return
+	}
+
+	c.Header("Content-Disposition", fmt.Sprintf("attachment; filename=dictation-%s.txt", documentID))
+	c.Data(http.StatusOK, "text/plain; charset=utf-8", []byte(doc.Content))
+}
+
+// DeleteDocument 删除听写文档
+// @Summary 删除听写文档
+// @Tags 听写
+// @Produce json
+// @Security BearerAuth
+// @Param id path string true "文档ID"
+// @Success 200 {object} map[string]interface{} "成功"
+// @Failure 404 {object} map[string]interface{} "文档不存在"
+// @Router /api/dictations/{id} [delete]
+func (h *DictationHandler) DeleteDocument(c *gin.Context) {
+	userID := getUserIDFromContext(c, h.logger)
+
+	if err := h.dictationService.DeleteDocument(c.Request.Context(), userID, c.Param("id")); err != nil {
+		respondError(c, h.logger, http.StatusNotFound, "删除文档失败", err)
+		return
+	}
+
+	respondSuccess(c, gin.H{"message": "文档删除成功"})
+}
@@ -0,0 +1,374 @@
+package handlers
+
+import (
+	"errors"
+	"io"
+	"net/http"
+	"strconv"
+
+	"angrymiao-ai-server/src/configs"
+	"angrymiao-ai-server/src/core/utils"
+	"angrymiao-ai-server/src/models"
+	"angrymiao-ai-server/src/services"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+// 未配置单个文档最大字符数时上传文件的最大字节数
+const defaultKnowledgeUploadBytes = 4 * 200000
+
+// KnowledgeHandler 知识库管理处理器
+// 管理员可管理所有知识库并设置适用的用户，普通用户只能管理自己的知识库
+type KnowledgeHandler struct {
+	knowledgeService services.KnowledgeService
+	config           *configs.Config
+	logger           *utils.Logger
+}
+
+// NewKnowledgeHandler 创建知识库管理处理器
+func NewKnowledgeHandler(db *gorm.DB, config *configs.Config, logger *utils.Logger) *KnowledgeHandler {
+	return &KnowledgeHandler{
+		knowledgeService: services.NewKnowledgeService(db, config, logger),
+		config:           config,
+		logger:           logger,
+	}
+}
+
+// RegisterRoutes 注册路由
+func (h *KnowledgeHandler) RegisterRoutes(apiGroup *gin.RouterGroup) {
+	knowledgeGroup := apiGroup.Group("/knowledge")
+	knowledgeGroup.Use(jwtAuthMiddleware(h.logger))
+	{
+		knowledgeGroup.GET("", h.ListBases)
+		knowledgeGroup.POST("", h.CreateBase)
+		knowledgeGroup.POST("/search", h.Search)
+		knowledgeGroup.PUT("/:id", h.UpdateBase)
+		knowledgeGroup.DELETE("/:id", h.DeleteBase)
+		knowledgeGroup.GET("/:id/documents", h.ListDocuments)
+		knowledgeGroup.POST("/:id/documents", h.AddDocument)
+		knowledgeGroup.DELETE("/:id/documents/:doc_id", h.DeleteDocument)
+	}
+}
+
+// ListBases 获取知识库列表
+// @Summary 获取知识库列表
+// @Description 普通用户返回自己的知识库和共享知识库，管理员返回全部知识库
+// @Tags 知识库
+// @Produce json
+// @Security BearerAuth
+// @Success 200 {object} map[string]interface{} "成功"
+// @Router /api/knowledge [get]
+func (h *KnowledgeHandler) ListBases(c *gin.Context) {
+	userID := ""
+	if !isAdmin(c) {
+		userID = getUserIDFromContext(c, h.logger)
+		if userID == "" {
+			respondError(c, h.logger, http.StatusUnauthorized, "未登录", nil)
+			return
+		}
+	}
+
+	bases, err := h.knowledgeService.ListBases(c.Request.Context(), userID)
+	if err != nil {
+		respondError(c, h.logger, http.StatusInternalServerError, "获取知识库失败", err)
+		return
+	}
+	respondSuccess(c, bases)
+}
+
+// CreateBase 创建知识库
+// @Summary 创建知识库
+// @Description persona 限定知识库适用的角色；普通用户创建的知识库只对自己生效，管理员可通过 user_id 指定用户，为空时对所有用户生效
+// @Tags 知识库
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param request body models.KnowledgeBaseRequest true "知识库"
+// @Success 200 {object} map[string]interface{} "成功"
+// @Failure 400 {object} map[string]interface{} "请求参数错误"
+// @Router /api/knowledge [post]
+func (h *KnowledgeHandler) CreateBase(c *gin.Context) {
+	var req models.KnowledgeBaseRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		respondError(c, h.logger, http.StatusBadRequest, "请求参数错误", err)
+		return
+	}
+
+	base := &models.KnowledgeBase{}
+	if !h.applyBaseRequest(c, base, &req) {
+		return
+	}
+	if err := h.knowledgeService.CreateBase(c.Request.Context(), base); err != nil {
+		respondError(c, h.logger, http.StatusBadRequest, "创建知识库失败: "+err.Error(), err)
+		return
+	}
+
+	respondSuccess(c, base)
+}
+
+// UpdateBase 更新知识库
+// @Summary 更新知识库
+// @Tags 知识库
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param id path int true "知识库ID"
+// @Param request body models.KnowledgeBaseRequest true "知识库"
+// @Success 200 {object} map[string]interface{} "成功"
+// @Failure 400 {object} map[string]interface{} "请求参数错误"
+// @Failure 404 {object} map[string]interface{} "知识库不存在"
+// @Router /api/knowledge/{id} [put]
+func (h *KnowledgeHandler) UpdateBase(c *gin.Context) {
+	var req models.KnowledgeBaseRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		respondError(c, h.logger, http.StatusBadRequest, "请求参数错误", err)
+		return
+	}
+
+	base, ok := h.loadBase(c, true)
+	if !ok {
+		return
+	}
+	if !h.applyBaseRequest(c, base, &req) {
+		return
+	}
+	if err := h.knowledgeService.UpdateBase(c.Request.Context(), base); err != nil {
+		respondError(c, h.logger, http.StatusBadRequest, "更新知识库失败: "+err.Error(), err)
+		return
+	}
+
+	respondSuccess(c, base)
+}
+
+// DeleteBase 删除知识库
+// @Summary 删除知识库
+// @Description 同时删除知识库中的全部文档
+// @Tags 知识库
+// @Produce json
+// @Security BearerAuth
+// @Param id path int true "知识库ID"
+// @Success 200 {object} map[string]interface{} "成功"
+// @Failure 404 {object} map[string]interface{} "知识库不存在"
+// @Router /api/knowledge/{id} [delete]
+func (h *KnowledgeHandler) DeleteBase(c *gin.Context) {
+	base, ok := h.loadBase(c, true)
+	if !ok {
+		return
+	}
+
+	if err := h.knowledgeService.DeleteBase(c.Request.Context(), base.ID); err != nil {
+		respondError(c, h.logger, http.StatusNotFound, "删除知识库失败", err)
+		return
+	}
+
+	respondSuccess(c, gin.H{"message": "知识库删除成功"})
+}
+
+// ListDocuments 获取知识库中的文档
+// @Summary 获取知识库中的文档
+// @Tags 知识库
+// @Produce json
+// @Security BearerAuth
+// @Param id path int true "知识库ID"
+// @Success 200 {object} map[string]interface{} "成功"
+// @Failure 404 {object} map[string]interface{} "知识库不存在"
+// @Router /api/knowledge/{id}/documents [get]
+func (h *KnowledgeHandler) ListDocuments(c *gin.Context) {
+	base, ok := h.loadBase(c, false)
+	if !ok {
+		return
+	}
+
+	documents, err := h.knowledgeService.ListDocuments(c.Request.Context(), base.ID)
+	if err != nil {
+		respondError(c, h.logger, http.StatusInternalServerError, "获取文档失败", err)
+		return
+	}
+	respondSuccess(c, documents)
+}
+
+// AddDocument 上传文档
+// @Summary 上传文档
+// @Description 支持 Markdown、文本和从PDF提取的文本，可提交JSON，也可通过 multipart 表单上传 file 字段，文档切分并生成向量后保存
+// @Tags 知识库
+// @Accept json,mpfd
+// @Produce json
+// @Security BearerAuth
+// @Param id path int true "知识库ID"
+// @Param request body models.KnowledgeDocumentRequest false "文档"
+// @Param file formData file false "文档文件"
+// @Param title formData string false "文档标题，默认使用文件名"
+// @Param format formData string false "文档格式：markdown/text/pdf"
+// @Success 200 {object} map[string]interface{} "成功"
+// @Failure 400 {object} map[string]interface{} "请求参数错误"
+// @Failure 503 {object} map[string]interface{} "知识库未启用"
+// @Router /api/knowledge/{id}/documents [post]
+func (h *KnowledgeHandler) AddDocument(c *gin.Context) {
+	base, ok := h.loadBase(c, true)
+	if !ok {
+		return
+	}
+
+	var req models.KnowledgeDocumentRequest
+	if c.ContentType() == "multipart/form-data" {
+		file, header, err := c.Request.FormFile("file")
+		if err != nil {
+			respondError(c, h.logger, http.StatusBadRequest, "请上传文档文件", err)
+			return
+		}
+		defer file.Close()
+		limit := h.uploadLimit()
+		data, err := io.ReadAll(io.LimitReader(file, limit+1))
+		if err != nil {
+			respondError(c, h.logger, http.StatusBadRequest, "读取文档失败", err)
+			return
+		}
+		if int64(len(data)) > limit {
+			respondError(c, h.logger, http.StatusBadRequest, "文档过大", nil)
+			return
+		}
+		req.Title = c.PostForm("title")
+		if req.Title == "" {
+			req.Title = header.Filename
+		}
+		req.Format = c.PostForm("format")
+		req.Content = string(data)
+	} else if err := c.ShouldBindJSON(&req); err != nil {
+		respondError(c, h.logger, http.StatusBadRequest, "请求参数错误", err)
+		return
+	}
+
+	document, err := h.knowledgeService.AddDocument(c.Request.Context(), base.ID, &req)
+	if err != nil {
+		if errors.Is(err, services.ErrKnowledgeDisabled) {
+			respondError(c, h.logger, http.StatusServiceUnavailable, err.Error(), err)
+			return
+		}
+		respondError(c, h.logger, http.StatusBadRequest, "上传文档失败: "+err.Error(), err)
+		return
+	}
+	respondSuccess(c, document)
+}
+
+// DeleteDocument 删除文档
+// @Summary 删除文档
+// @Tags 知识库
+// @Produce json
+// @Security BearerAuth
+// @Param id path int true "知识库ID"
+// @Param doc_id path int true "文档ID"
+// @Success 200 {object} map[string]interface{} "成功"
+// @Failure 404 {object} map[string]interface{} "文档不存在"
+// @Router /api/knowledge/{id}/documents/{doc_id} [delete]
+func (h *KnowledgeHandler) DeleteDocument(c *gin.Context) {
+	base, ok := h.loadBase(c, true)
+	if !ok {
+		return
+	}
+	documentID, err := strconv.ParseUint(c.Param("doc_id"), 10, 32)
+	if err != nil {
+		respondError(c, h.logger, http.StatusBadRequest, "无效的文档ID", err)
+		return
+	}
+
+	if err := h.knowledgeService.DeleteDocument(c.Request.Context(), base.ID, uint(documentID)); err != nil {
+		respondError(c, h.logger, http.StatusNotFound, "删除文档失败", err)
+		return
+	}
+
+	respondSuccess(c, gin.H{"message": "文档删除成功"})
+}
+
+// Search 检索知识库
+// @Summary 检索知识库
+// @Description 按当前用户和指定角色的范围检索，用于验证上传的文档能否被检索到
+// @Tags 知识库
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param request body models.KnowledgeSearchRequest true "检索内容"
+// @Success 200 {object} map[string]interface{} "成功"
+// @Failure 400 {object} map[string]interface{} "请求参数错误"
+// @Failure 503 {object} map[string]interface{} "知识库未启用"
+// @Router /api/knowledge/search [post]
+func (h *KnowledgeHandler) Search(c *gin.Context) {
+	var req models.KnowledgeSearchRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		respondError(c, h.logger, http.StatusBadRequest, "请求参数错误", err)
+		return
+	}
+	userID := getUserIDFromContext(c, h.logger)
+	if userID == "" {
+		respondError(c, h.logger, http.StatusUnauthorized, "未登录", nil)
+		return
+	}
+
+	scope := services.KnowledgeScope{UserID: userID, Persona: req.Persona}
+	results, err := h.knowledgeService.Search(c.Request.Context(), scope, req.Query, req.TopK)
+	if err != nil {
+		if errors.Is(err, services.ErrKnowledgeDisabled) {
+			respondError(c, h.logger, http.StatusServiceUnavailable, err.Error(), err)
+			return
+		}
+		respondError(c, h.logger, http.StatusInternalServerError, "检索知识库失败", err)
+		return
+	}
+	respondSuccess(c, results)
+}
+
+// uploadLimit 上传文件的最大字节数，按单个文档最大字符数的UTF-8最大长度计算
+func (h *KnowledgeHandler) uploadLimit() int64 {
+	if h.config.Knowledge.MaxDocumentSize > 0 {
+		return 4 * int64(h.config.Knowledge.MaxDocumentSize)
+	}
+	return defaultKnowledgeUploadBytes
+}
+
+// loadBase 加载路径中的知识库并检查权限
+// 共享知识库所有用户可查看，只有管理员可修改；用户的知识库只有本人和管理员可查看和修改
+func (h *KnowledgeHandler) loadBase(c *gin.Context, manage bool) (*models.KnowledgeBase, bool) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		respondError(c, h.logger, http.StatusBadRequest, "无效的知识库ID", err)
+		return nil, false
+	}
+
+	base, err := h.knowledgeService.GetBase(c.Request.Context(), uint(id))
+	if err != nil {
+		respondError(c, h.logger, http.StatusNotFound, "知识库不存在", err)
+		return nil, false
+	}
+	if isAdmin(c) {
+		return base, true
+	}
+
+	userID := getUserIDFromContext(c, h.logger)
+	if base.UserID != "" && base.UserID != userID {
+		respondError(c, h.logger, http.StatusNotFound, "知识库不存在", nil)
+		return nil, false
+	}
+	if manage && base.UserID == "" {
+		respondError(c, h.logger, http.StatusForbidden, "需要管理员权限", nil)
+		return nil, false
+	}
+	return base, true
+}
+
+// applyBaseRequest 将请求写入知识库，普通用户的知识库始终属于自己
+func (h *KnowledgeHandler) applyBaseRequest(c *gin.Context, base *models.KnowledgeBase, req *models.KnowledgeBaseRequest) bool {
+	base.Name = req.Name
+	base.Description = req.Description
+	base.Persona = req.Persona
+	if isAdmin(c) {
+		base.UserID = req.UserID
+		return true
+	}
+
+	base.UserID = getUserIDFromContext(c, h.logger)
+	if base.UserID == "" {
+		respondError(c, h.logger, http.StatusUnauthorized, "未登录", nil)
+		return false
+	}
+	return true
+}
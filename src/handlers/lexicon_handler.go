@@ -0,0 +1,190 @@
+package handlers
+
+import (
+	"net/http"
+	"strconv"
+
+	"angrymiao-ai-server/src/core/utils"
+	"angrymiao-ai-server/src/models"
+	"angrymiao-ai-server/src/services"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+// LexiconHandler 发音词典处理器
+type LexiconHandler struct {
+	lexiconService services.LexiconService
+	logger         *utils.Logger
+}
+
+// NewLexiconHandler 创建发音词典处理器
+func NewLexiconHandler(db *gorm.DB, logger *utils.Logger) *LexiconHandler {
+	return &LexiconHandler{
+		lexiconService: services.NewLexiconService(db, logger),
+		logger:         logger,
+	}
+}
+
+// RegisterRoutes 注册路由
+func (h *LexiconHandler) RegisterRoutes(apiGroup *gin.RouterGroup) {
+	lexiconGroup := apiGroup.Group("/admin/lexicon")
+	// 发音词典为部署级配置，仅管理员可修改
+	lexiconGroup.Use(jwtAuthMiddleware(h.logger), adminMiddleware(h.logger))
+	{
+		lexiconGroup.GET("", h.ListEntries)
+		lexiconGroup.POST("", h.CreateEntry)
+		lexiconGroup.GET("/:id", h.GetEntry)
+		lexiconGroup.PUT("/:id", h.UpdateEntry)
+		lexiconGroup.DELETE("/:id", h.DeleteEntry)
+	}
+}
+
+// ListEntries 获取发音词条列表
+// @Summary 获取发音词条列表
+// @Description 获取发音词典词条，role为空返回全局词条，role为*返回全部词条
+// @Tags 发音词典
+// @Produce json
+// @Param role query string false "角色名称"
+// @Success 200 {object} map[string]interface{} "成功"
+// @Failure 403 {object} map[string]interface{} "需要管理员权限"
+// @Router /api/admin/lexicon [get]
+func (h *LexiconHandler) ListEntries(c *gin.Context) {
+	entries, err := h.lexiconService.ListEntries(c.Request.Context(), c.Query("role"))
+	if err != nil {
+		respondError(c, h.logger, http.StatusInternalServerError, "获取词条失败", err)
+		return
+	}
+
+	respondSuccess(c, gin.H{
+		"entries": entries,
+		"total":   len(entries),
+	})
+}
+
+// CreateEntry 创建发音词条
+// @Summary 创建发音词条
+// @Description 创建全局或角色专属的发音词条
+// @Tags 发音词典
+// @Accept json
+// @Produce json
+// @Param request body models.LexiconEntryRequest true "词条"
+// @Success 200 {object} map[string]interface{} "成功"
+// @Failure 400 {object} map[string]interface{} "请求参数错误"
+// @Failure 403 {object} map[string]interface{} "需要管理员权限"
+// @Router /api/admin/lexicon [post]
+func (h *LexiconHandler) CreateEntry(c *gin.Context) {
+	var req models.LexiconEntryRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		respondError(c, h.logger, http.StatusBadRequest, "请求参数错误", err)
+		return
+	}
+
+	entry := &models.LexiconEntry{IsActive: true}
+	applyLexiconRequest(entry, &req)
+	if err := h.lexiconService.CreateEntry(c.Request.Context(), entry); err != nil {
+		respondError(c, h.logger, http.StatusBadRequest, "创建词条失败", err)
+		return
+	}
+
+	respondSuccess(c, entry)
+}
+
+// GetEntry 获取发音词条详情
+// @Summary 获取发音词条详情
+// @Tags 发音词典
+// @Produce json
+// @Param id path int true "词条ID"
+// @Success 200 {object} map[string]interface{} "成功"
+// @Failure 404 {object} map[string]interface{} "词条不存在"
+// @Router /api/admin/lexicon/{id} [get]
+func (h *LexiconHandler) GetEntry(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		respondError(c, h.logger, http.StatusBadRequest, "无效的词条ID", err)
+		return
+	}
+
+	entry, err := h.lexiconService.GetEntryByID(c.Request.Context(), uint(id))
+	if err != nil {
+		respondError(c, h.logger, http.StatusNotFound, "词条不存在", err)
+		return
+	}
+
+	respondSuccess(c, entry)
+}
+
+// UpdateEntry 更新发音词条
+// @Summary 更新发音词条
+// @Tags 发音词典
+// @Accept json
+// @Produce json
+// @Param id path int true "词条ID"
+// @Param request body models.LexiconEntryRequest true "词条"
+// @Success 200 {object} map[string]interface{} "成功"
+// @Failure 400 {object} map[string]interface{} "请求参数错误"
+// @Failure 404 {object} map[string]interface{} "词条不存在"
+// @Router /api/admin/lexicon/{id} [put]
+func (h *LexiconHandler) UpdateEntry(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		respondError(c, h.logger, http.StatusBadRequest, "无效的词条ID", err)
+		return
+	}
+
+	var req models.LexiconEntryRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		respondError(c, h.logger, http.StatusBadRequest, "请求参数错误", err)
+		return
+	}
+
+	entry, err := h.lexiconService.GetEntryByID(c.Request.Context(), uint(id))
+	if err != nil {
+		respondError(c, h.logger, http.StatusNotFound, "词条不存在", err)
+		return
+	}
+
+	applyLexiconRequest(entry, &req)
+	if err := h.lexiconService.UpdateEntry(c.Request.Context(), entry); err != nil {
+		respondError(c, h.logger, http.StatusBadRequest, "更新词条失败", err)
+		return
+	}
+
+	respondSuccess(c, entry)
+}
+
+// DeleteEntry 删除发音词条
+// @Summary 删除发音词条
+// @Tags 发音词典
+// @Produce json
+// @Param id path int true "词条ID"
+// @Success 200 {object} map[string]interface{} "成功"
+// @Failure 404 {object} map[string]interface{} "词条不存在"
+// @Router /api/admin/lexicon/{id} [delete]
+func (h *LexiconHandler) DeleteEntry(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		respondError(c, h.logger, http.StatusBadRequest, "无效的词条ID", err)
+		return
+	}
+
+	if err := h.lexiconService.DeleteEntry(c.Request.Context(), uint(id)); err != nil {
+		respondError(c, h.logger, http.StatusNotFound, "删除词条失败", err)
+		return
+	}
+
+	respondSuccess(c, gin.H{"message": "词条删除成功"})
+}
+
+// applyLexiconRequest 将请求内容写入词条
+func applyLexiconRequest(entry *models.LexiconEntry, req *models.LexiconEntryRequest) {
+	entry.Word = req.Word
+	entry.Role = req.Role
+	entry.Alias = req.Alias
+	entry.Phoneme = req.Phoneme
+	entry.Alphabet = req.Alphabet
+	entry.Description = req.Description
+	if req.IsActive != nil {
+		entry.IsActive = *req.IsActive
+	}
+}
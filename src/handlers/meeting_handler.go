@@ -0,0 +1,134 @@
+package handlers
+
+import (
+	"fmt"
+	"net/http"
+	"strconv"
+
+	"angrymiao-ai-server/src/core/utils"
+	"angrymiao-ai-server/src/services"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+// MeetingHandler 会议记录处理器
+type MeetingHandler struct {
+	meetingService services.MeetingService
+	logger         *utils.Logger
+}
+
+// NewMeetingHandler 创建会议记录处理器
+func NewMeetingHandler(db *gorm.DB, logger *utils.Logger) *MeetingHandler {
+	return &MeetingHandler{
+		meetingService: services.NewMeetingService(db, logger),
+		logger:         logger,
+	}
+}
+
+// RegisterRoutes 注册路由
+func (h *MeetingHandler) RegisterRoutes(apiGroup *gin.RouterGroup) {
+	meetingGroup := apiGroup.Group("/meetings")
+	meetingGroup.Use(jwtAuthMiddleware(h.logger))
+	{
+		meetingGroup.GET("", h.ListMeetings)
+		meetingGroup.GET("/:id", h.GetMeeting)
+		meetingGroup.GET("/:id/export", h.ExportMeeting)
+		meetingGroup.DELETE("/:id", h.DeleteMeeting)
+	}
+}
+
+// ListMeetings 获取会议记录列表
+// @Summary 获取会议记录列表
+// @Description 分页获取当前用户的会议记录，按开始时间倒序，不包含完整记录
+// @Tags 会议记录
+// @Produce json
+// @Security BearerAuth
+// @Param page query int false "页码，默认1"
+// @Param page_size query int false "每页数量，默认20，最大100"
+// @Success 200 {object} map[string]interface{} "成功"
+// @Failure 401 {object} map[string]interface{} "未授权"
+// @Router /api/meetings [get]
+func (h *MeetingHandler) ListMeetings(c *gin.Context) {
+	userID := getUserIDFromContext(c, h.logger)
+	page, _ := strconv.Atoi(c.DefaultQuery("page", "1"))
+	pageSize, _ := strconv.Atoi(c.DefaultQuery("page_size", "20"))
+
+	records, total, err := h.meetingService.ListMeetings(c.Request.Context(), userID, page, pageSize)
+	if err != nil {
+		respondError(c, h.logger, http.StatusInternalServerError, "获取会议记录失败", err)
+		return
+	}
+
+	respondSuccess(c, gin.H{
+		"meetings": records,
+		"total":    total,
+		"page":     page,
+	})
+}
+
+// GetMeeting 获取会议记录
+// @Summary 获取会议记录
+// @Description 获取会议纪要和带时间戳的完整记录
+// @Tags 会议记录
+// @Produce json
+// @Security BearerAuth
+// @Param id path string true "会议ID"
+// @Success 200 {object} map[string]interface{} "成功"
+// @Failure 404 {object} map[string]interface{} "会议记录不存在"
+// @Router /api/meetings/{id} [get]
+func (h *MeetingHandler) GetMeeting(c *gin.Context) {
+	userID := getUserIDFromContext(c, h.logger)
+
+	record, err := h.meetingService.GetMeeting(c.Request.Context(), userID, c.Param("id"))
+	if err != nil {
+		respondError(c, h.logger, http.StatusNotFound, "会议记录不存在", err)
+		return
+	}
+
+	respondSuccess(c, record)
+}
+
+// ExportMeeting 导出会议记录
+// @Summary 导出会议记录
+// @Description 以Markdown文件导出会议纪要和完整记录
+// @Tags 会议记录
+// @Produce text/markdown
+// @Security BearerAuth
+// @Param id path string true "会议ID"
+// @Success 200 {file} file "会议记录文件"
+// @Failure 404 {object} map[string]interface{} "会议记录不存在"
+// @Router /api/meetings/{id}/export [get]
+func (h *MeetingHandler) ExportMeeting(c *gin.Context) {
+	userID := getUserIDFromContext(c, h.logger)
+	meetingID := c.Param("id")
+
+	record, err := h.meetingService.GetMeeting(c.Request.Context(), userID, meetingID)
+	if err != nil {
+		respondError(c, h.logger, http.StatusNotFound, "会议记录不存在", err)
+		return
+	}
+
+	c.Header("Content-Disposition", fmt.Sprintf("attachment; filename=meeting-%s.md", meetingID))
+	c.Data(http.StatusOK, "text/markdown; charset=utf-8", []byte(services.RenderMeetingMarkdown(record)))
+}
+
+// DeleteMeeting 删除会议记录
+// @Summary 删除会议记录
+// @Tags 会议记录
+// @Produce json
+// @Security BearerAuth
+// @Param id path string true "会议ID"
+// @Success 200 {object} map[string]interface{} "成功"
+// @Failure 404 {object} map[string]interface{} "会议记录不存在"
+// @Router /api/meetings/{id} [delete]
+func (h *MeetingHandler) DeleteMeeting(c *gin.Context) {
+	userID := getUserIDFromContext(c, h.logger)
+
+	if err := h.meetingService.DeleteMeeting(c.Request.Context(), userID, c.Param("id")); err != nil {
+		respondError(c, h.logger, http.StatusNotFound, "删除会议记录失败", err)
+		return
+	}
+
+	respondSuccess(c, gin.H{"message": "会议记录删除成功"})
+}
@@ -0,0 +1,105 @@
+package handlers
+
+import (
+	"net/http"
+	"strconv"
+
+	"angrymiao-ai-server/src/core/utils"
+	"angrymiao-ai-server/src/services"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+// MemoryHandler 用户长期记忆处理器
+type MemoryHandler struct {
+	memoryService services.MemoryService
+	logger        *utils.Logger
+}
+
+// NewMemoryHandler 创建用户长期记忆处理器
+func NewMemoryHandler(db *gorm.DB, logger *utils.Logger) *MemoryHandler {
+	return &MemoryHandler{
+		memoryService: services.NewMemoryService(db, logger),
+		logger:        logger,
+	}
+}
+
+// RegisterRoutes 注册路由
+func (h *MemoryHandler) RegisterRoutes(apiGroup *gin.RouterGroup) {
+	memoryGroup := apiGroup.Group("/memories")
+	memoryGroup.Use(jwtAuthMiddleware(h.logger))
+	{
+		memoryGroup.GET("", h.ListMemories)
+		memoryGroup.DELETE("", h.ClearMemories)
+		memoryGroup.DELETE("/:id", h.DeleteMemory)
+	}
+}
+
+// ListMemories 获取当前用户的长期记忆
+// @Summary 获取用户记忆列表
+// @Description 获取系统从对话中提取的当前用户的长期记忆
+// @Tags 用户记忆
+// @Produce json
+// @Security BearerAuth
+// @Success 200 {object} map[string]interface{} "成功"
+// @Failure 401 {object} map[string]interface{} "未授权"
+// @Router /api/memories [get]
+func (h *MemoryHandler) ListMemories(c *gin.Context) {
+	userID := getUserIDFromContext(c, h.logger)
+
+	memories, err := h.memoryService.ListMemories(c.Request.Context(), userID)
+	if err != nil {
+		respondError(c, h.logger, http.StatusInternalServerError, "获取记忆失败", err)
+		return
+	}
+
+	respondSuccess(c, gin.H{
+		"memories": memories,
+		"total":    len(memories),
+	})
+}
+
+// DeleteMemory 删除当前用户的一条记忆
+// @Summary 删除用户记忆
+// @Tags 用户记忆
+// @Produce json
+// @Security BearerAuth
+// @Param id path int true "记忆ID"
+// @Success 200 {object} map[string]interface{} "成功"
+// @Failure 404 {object} map[string]interface{} "记忆不存在"
+// @Router /api/memories/{id} [delete]
+func (h *MemoryHandler) DeleteMemory(c *gin.Context) {
+	userID := getUserIDFromContext(c, h.logger)
+
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		respondError(c, h.logger, http.StatusBadRequest, "无效的记忆ID", err)
+		return
+	}
+
+	if err := h.memoryService.DeleteMemory(c.Request.Context(), userID, uint(id)); err != nil {
+		respondError(c, h.logger, http.StatusNotFound, "删除记忆失败", err)
+		return
+	}
+
+	respondSuccess(c, gin.H{"message": "记忆删除成功"})
+}
+
+// ClearMemories 清空当前用户的全部记忆
+// @Summary 清空用户记忆
+// @Tags 用户记忆
+// @Produce json
+// @Security BearerAuth
+// @Success 200 {object} map[string]interface{} "成功"
+// @Router /api/memories [delete]
+func (h *MemoryHandler) ClearMemories(c *gin.Context) {
+	userID := getUserIDFromContext(c, h.logger)
+
+	if err := h.memoryService.ClearMemories(c.Request.Context(), userID); err != nil {
+		respondError(c, h.logger, http.StatusInternalServerError, "清空记忆失败", err)
+		return
+	}
+
+	respondSuccess(c, gin.H{"message": "记忆已清空"})
+}
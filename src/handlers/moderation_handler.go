@@ -0,0 +1,67 @@
+package handlers
+
+import (
+	"net/http"
+
+	"angrymiao-ai-server/src/core/utils"
+	"angrymiao-ai-server/src/models"
+	"angrymiao-ai-server/src/services"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+// ModerationHandler 内容审核审计处理器
+type ModerationHandler struct {
+	moderationService services.ModerationService
+	logger            *utils.Logger
+}
+
+// NewModerationHandler 创建内容审核审计处理器
+func NewModerationHandler(db *gorm.DB, logger *utils.Logger) *ModerationHandler {
+	return &ModerationHandler{
+		moderationService: services.NewModerationService(db, logger),
+		logger:            logger,
+	}
+}
+
+// RegisterRoutes 注册路由
+func (h *ModerationHandler) RegisterRoutes(apiGroup *gin.RouterGroup) {
+	adminGroup := apiGroup.Group("/admin/moderation")
+	adminGroup.Use(jwtAuthMiddleware(h.logger), adminMiddleware(h.logger))
+	{
+		adminGroup.GET("/events", h.ListEvents)
+	}
+}
+
+// ListEvents 查询审核事件
+// @Summary 查询审核事件
+// @Description 分页查询命中内容审核的用户输入和助手回复，按时间倒序
+// @Tags 内容审核
+// @Produce json
+// @Param user_id query string false "用户ID"
+// @Param device_id query string false "设备ID"
+// @Param direction query string false "审核阶段：input/output"
+// @Param page query int false "页码，默认1"
+// @Param page_size query int false "每页数量，默认20，最大100"
+// @Success 200 {object} map[string]interface{} "成功"
+// @Failure 403 {object} map[string]interface{} "需要管理员权限"
+// @Router /api/admin/moderation/events [get]
+func (h *ModerationHandler) ListEvents(c *gin.Context) {
+	var query models.ModerationEventQuery
+	if err := c.ShouldBindQuery(&query); err != nil {
+		respondError(c, h.logger, http.StatusBadRequest, "请求参数错误", err)
+		return
+	}
+
+	events, total, err := h.moderationService.ListEvents(c.Request.Context(), &query)
+	if err != nil {
+		respondError(c, h.logger, http.StatusInternalServerError, "查询审核事件失败", err)
+		return
+	}
+
+	respondSuccess(c, gin.H{
+		"events": events,
+		"total":  total,
+	})
+}
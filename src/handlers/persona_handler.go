@@ -0,0 +1,210 @@
+package handlers
+
+import (
+	"net/http"
+	"strconv"
+
+	"angrymiao-ai-server/src/configs"
+	"angrymiao-ai-server/src/core/utils"
+	"angrymiao-ai-server/src/models"
+	"angrymiao-ai-server/src/services"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+// PersonaHandler 角色管理处理器
+type PersonaHandler struct {
+	personaService services.PersonaService
+	logger         *utils.Logger
+}
+
+// NewPersonaHandler 创建角色管理处理器
+func NewPersonaHandler(db *gorm.DB, config *configs.Config, logger *utils.Logger) *PersonaHandler {
+	return &PersonaHandler{
+		personaService: services.NewPersonaService(db, config, logger),
+		logger:         logger,
+	}
+}
+
+// RegisterRoutes 注册路由
+func (h *PersonaHandler) RegisterRoutes(apiGroup *gin.RouterGroup) {
+	// 用户可查看启用的角色
+	apiGroup.GET("/personas", jwtAuthMiddleware(h.logger), h.ListActivePersonas)
+
+	personaGroup := apiGroup.Group("/admin/personas")
+	// 角色为部署级配置，仅管理员可修改
+	personaGroup.Use(jwtAuthMiddleware(h.logger), adminMiddleware(h.logger))
+	{
+		personaGroup.GET("", h.ListPersonas)
+		personaGroup.POST("", h.CreatePersona)
+		personaGroup.GET("/:id", h.GetPersona)
+		personaGroup.PUT("/:id", h.UpdatePersona)
+		personaGroup.DELETE("/:id", h.DeletePersona)
+	}
+}
+
+// ListActivePersonas 获取可用角色列表
+// @Summary 获取可用角色列表
+// @Description 获取所有启用的角色，可在对话中说出角色名称切换
+// @Tags 角色管理
+// @Produce json
+// @Security BearerAuth
+// @Success 200 {object} map[string]interface{} "成功"
+// @Router /api/personas [get]
+func (h *PersonaHandler) ListActivePersonas(c *gin.Context) {
+	personas, err := h.personaService.ListActivePersonas(c.Request.Context())
+	if err != nil {
+		respondError(c, h.logger, http.StatusInternalServerError, "获取角色失败", err)
+		return
+	}
+
+	items := make([]gin.H, 0, len(personas))
+	for _, p := range personas {
+		items = append(items, gin.H{
+			"name":        p.Name,
+			"description": p.Description,
+			"greeting":    p.Greeting,
+			"is_default":  p.IsDefault,
+		})
+	}
+	respondSuccess(c, gin.H{
+		"personas": items,
+		"total":    len(items),
+	})
+}
+
+// ListPersonas 获取角色列表
+// @Summary 获取角色列表
+// @Description 获取全部角色，包括已停用的角色
+// @Tags 角色管理
+// @Produce json
+// @Success 200 {object} map[string]interface{} "成功"
+// @Failure 403 {object} map[string]interface{} "需要管理员权限"
+// @Router /api/admin/personas [get]
+func (h *PersonaHandler) ListPersonas(c *gin.Context) {
+	personas, err := h.personaService.ListPersonas(c.Request.Context())
+	if err != nil {
+		respondError(c, h.logger, http.StatusInternalServerError, "获取角色失败", err)
+		return
+	}
+
+	respondSuccess(c, gin.H{
+		"personas": personas,
+		"total":    len(personas),
+	})
+}
+
+// CreatePersona 创建角色
+// @Summary 创建角色
+// @Description 创建角色，voices 的键为配置中的TTS名称，allowed_tools 为空时不限制工具，llm 为配置中的LLM名称
+// @Tags 角色管理
+// @Accept json
+// @Produce json
+// @Param request body models.PersonaRequest true "角色"
+// @Success 200 {object} map[string]interface{} "成功"
+// @Failure 400 {object} map[string]interface{} "请求参数错误"
+// @Failure 403 {object} map[string]interface{} "需要管理员权限"
+// @Router /api/admin/personas [post]
+func (h *PersonaHandler) CreatePersona(c *gin.Context) {
+	var req models.PersonaRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		respondError(c, h.logger, http.StatusBadRequest, "请求参数错误", err)
+		return
+	}
+
+	persona := &models.Persona{IsActive: true}
+	services.ApplyPersonaRequest(persona, &req)
+	if err := h.personaService.CreatePersona(c.Request.Context(), persona); err != nil {
+		respondError(c, h.logger, http.StatusBadRequest, "创建角色失败: "+err.Error(), err)
+		return
+	}
+
+	respondSuccess(c, persona)
+}
+
+// GetPersona 获取角色详情
+// @Summary 获取角色详情
+// @Tags 角色管理
+// @Produce json
+// @Param id path int true "角色ID"
+// @Success 200 {object} map[string]interface{} "成功"
+// @Failure 404 {object} map[string]interface{} "角色不存在"
+// @Router /api/admin/personas/{id} [get]
+func (h *PersonaHandler) GetPersona(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		respondError(c, h.logger, http.StatusBadRequest, "无效的角色ID", err)
+		return
+	}
+
+	persona, err := h.personaService.GetPersonaByID(c.Request.Context(), uint(id))
+	if err != nil {
+		respondError(c, h.logger, http.StatusNotFound, "角色不存在", err)
+		return
+	}
+
+	respondSuccess(c, persona)
+}
+
+// UpdatePersona 更新角色
+// @Summary 更新角色
+// @Tags 角色管理
+// @Accept json
+// @Produce json
+// @Param id path int true "角色ID"
+// @Param request body models.PersonaRequest true "角色"
+// @Success 200 {object} map[string]interface{} "成功"
+// @Failure 400 {object} map[string]interface{} "请求参数错误"
+// @Failure 404 {object} map[string]interface{} "角色不存在"
+// @Router /api/admin/personas/{id} [put]
+func (h *PersonaHandler) UpdatePersona(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		respondError(c, h.logger, http.StatusBadRequest, "无效的角色ID", err)
+		return
+	}
+
+	var req models.PersonaRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		respondError(c, h.logger, http.StatusBadRequest, "请求参数错误", err)
+		return
+	}
+
+	persona, err := h.personaService.GetPersonaByID(c.Request.Context(), uint(id))
+	if err != nil {
+		respondError(c, h.logger, http.StatusNotFound, "角色不存在", err)
+		return
+	}
+
+	services.ApplyPersonaRequest(persona, &req)
+	if err := h.personaService.UpdatePersona(c.Request.Context(), persona); err != nil {
+		respondError(c, h.logger, http.StatusBadRequest, "更新角色失败: "+err.Error(), err)
+		return
+	}
+
+	respondSuccess(c, persona)
+}
+
+// DeletePersona 删除角色
+// @Summary 删除角色
+// @Tags 角色管理
+// @Produce json
+// @Param id path int true "角色ID"
+// @Success 200 {object} map[string]interface{} "成功"
+// @Failure 404 {object} map[string]interface{} "角色不存在"
+// @Router /api/admin/personas/{id} [delete]
+func (h *PersonaHandler) DeletePersona(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		respondError(c, h.logger, http.StatusBadRequest, "无效的角色ID", err)
+		return
+	}
+
+	if err := h.personaService.DeletePersona(c.Request.Context(), uint(id)); err != nil {
+		respondError(c, h.logger, http.StatusNotFound, "删除角色失败", err)
+		return
+	}
+
+	respondSuccess(c, gin.H{"message": "角色删除成功"})
+}
@@ -0,0 +1,184 @@
+package handlers
+
+import (
+	"fmt"
+	"net/http"
+	"time"
+
+	"angrymiao-ai-server/src/configs"
+	"angrymiao-ai-server/src/core/prompt"
+	"angrymiao-ai-server/src/core/utils"
+	"angrymiao-ai-server/src/models"
+	"angrymiao-ai-server/src/services"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+// PromptHandler 提示词模板处理器
+type PromptHandler struct {
+	promptService  services.PromptService
+	personaService services.PersonaService
+	config         *configs.Config
+	logger         *utils.Logger
+}
+
+// NewPromptHandler 创建提示词模板处理器
+func NewPromptHandler(db *gorm.DB, config *configs.Config, logger *utils.Logger) *PromptHandler {
+	return &PromptHandler{
+		promptService:  services.NewPromptService(db, logger),
+		personaService: services.NewPersonaService(db, config, logger),
+		config:         config,
+		logger:         logger,
+	}
+}
+
+// RegisterRoutes 注册路由
+func (h *PromptHandler) RegisterRoutes(apiGroup *gin.RouterGroup) {
+	promptGroup := apiGroup.Group("/prompt")
+	promptGroup.Use(jwtAuthMiddleware(h.logger))
+	{
+		promptGroup.GET("/variables", h.GetVariables)
+		promptGroup.PUT("/variables", h.UpdateVariables)
+		promptGroup.POST("/preview", h.Preview)
+	}
+}
+
+// GetVariables 获取当前用户的提示词变量
+// @Summary 获取提示词变量
+// @Description 获取当前用户的昵称和自定义字段，在提示词模板中以 {{.Nickname}} 和 {{.Fields.字段名}} 引用
+// @Tags 提示词模板
+// @Produce json
+// @Security BearerAuth
+// @Success 200 {object} map[string]interface{} "成功"
+// @Failure 401 {object} map[string]interface{} "未授权"
+// @Router /api/prompt/variables [get]
+func (h *PromptHandler) GetVariables(c *gin.Context) {
+	userID := getUserIDFromContext(c, h.logger)
+
+	profile, err := h.promptService.GetProfile(c.Request.Context(), userID)
+	if err != nil {
+		respondError(c, h.logger, http.StatusInternalServerError, "获取提示词变量失败", err)
+		return
+	}
+
+	var vars prompt.Variables
+	vars.SetProfile(profile)
+	respondSuccess(c, gin.H{
+		"nickname": vars.Nickname,
+		"fields":   vars.Fields,
+	})
+}
+
+// UpdateVariables 更新当前用户的提示词变量
+// @Summary 更新提示词变量
+// @Description 设置当前用户的昵称和自定义字段，字段名只能包含字母、数字和下划线
+// @Tags 提示词模板
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param request body models.UpdatePromptProfileRequest true "提示词变量"
+// @Success 200 {object} map[string]interface{} "成功"
+// @Failure 400 {object} map[string]interface{} "参数错误"
+// @Router /api/prompt/variables [put]
+func (h *PromptHandler) UpdateVariables(c *gin.Context) {
+	userID := getUserIDFromContext(c, h.logger)
+
+	var req models.UpdatePromptProfileRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		respondError(c, h.logger, http.StatusBadRequest, "请求参数错误", err)
+		return
+	}
+
+	profile, err := h.promptService.SaveProfile(c.Request.Context(), userID, &req)
+	if err != nil {
+		respondError(c, h.logger, http.StatusBadRequest, "保存提示词变量失败: "+err.Error(), err)
+		return
+	}
+
+	respondSuccess(c, gin.H{
+		"nickname": profile.Nickname,
+		"fields":   profile.FieldMap(),
+	})
+}
+
+// Preview 预览提示词
+// @Summary 预览提示词
+// @Description 校验提示词模板，并用当前时间和用户的提示词变量渲染，工具列表使用配置的本地工具
+// @Tags 提示词模板
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param request body models.PreviewPromptRequest true "预览参数"
+// @Success 200 {object} map[string]interface{} "成功"
+// @Failure 400 {object} map[string]interface{} "模板错误"
+// @Router /api/prompt/preview [post]
+func (h *PromptHandler) Preview(c *gin.Context) {
+	userID := getUserIDFromContext(c, h.logger)
+
+	var req models.PreviewPromptRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		respondError(c, h.logger, http.StatusBadRequest, "请求参数错误", err)
+		return
+	}
+
+	text := req.Template
+	if text == "" {
+		text = h.config.DefaultPrompt
+		if req.Role != "" {
+			rolePrompt, err := h.rolePrompt(c, req.Role)
+			if err != nil {
+				respondError(c, h.logger, http.StatusBadRequest, err.Error(), err)
+				return
+			}
+			text = rolePrompt
+		}
+	}
+
+	tmpl, err := prompt.Parse(text)
+	if err != nil {
+		respondError(c, h.logger, http.StatusBadRequest, err.Error(), err)
+		return
+	}
+
+	profile, err := h.promptService.GetProfile(c.Request.Context(), userID)
+	if err != nil {
+		respondError(c, h.logger, http.StatusInternalServerError, "获取提示词变量失败", err)
+		return
+	}
+	vars := prompt.Variables{
+		DeviceID: req.DeviceID,
+		Board:    req.Board,
+		Voice:    req.Voice,
+		Role:     req.Role,
+		Tools:    h.config.LocalMCPFun,
+	}
+	vars.SetTime(time.Now(), h.config.PromptTemplate.Timezone)
+	vars.SetProfile(profile)
+
+	rendered, err := tmpl.Render(vars)
+	if err != nil {
+		respondError(c, h.logger, http.StatusBadRequest, err.Error(), err)
+		return
+	}
+
+	respondSuccess(c, gin.H{
+		"prompt":    rendered,
+		"variables": vars,
+	})
+}
+
+// rolePrompt 查找角色的提示词，数据库中没有时回退到配置的角色列表
+func (h *PromptHandler) rolePrompt(c *gin.Context, role string) (string, error) {
+	persona, err := h.personaService.GetActivePersona(c.Request.Context(), role)
+	if err != nil {
+		return "", err
+	}
+	if persona != nil {
+		return persona.Prompt, nil
+	}
+	if text, ok := prompt.RolePrompt(h.config, role); ok {
+		return text, nil
+	}
+	return "", fmt.Errorf("角色不存在: %s", role)
+}
@@ -0,0 +1,186 @@
+package handlers
+
+import (
+	"net/http"
+
+	"angrymiao-ai-server/src/configs"
+	"angrymiao-ai-server/src/core/utils"
+	"angrymiao-ai-server/src/models"
+	"angrymiao-ai-server/src/services"
+	"angrymiao-ai-server/src/task"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+// UsageHandler 用量统计处理器
+type UsageHandler struct {
+	usageService services.UsageService
+	logger       *utils.Logger
+}
+
+// NewUsageHandler 创建用量统计处理器
+func NewUsageHandler(db *gorm.DB, config *configs.Config, logger *utils.Logger) *UsageHandler {
+	return &UsageHandler{
+		usageService: services.NewUsageService(db, config, logger),
+		logger:       logger,
+	}
+}
+
+// RegisterRoutes 注册路由
+func (h *UsageHandler) RegisterRoutes(apiGroup *gin.RouterGroup) {
+	usageGroup := apiGroup.Group("/usage")
+	usageGroup.Use(jwtAuthMiddleware(h.logger))
+	{
+		usageGroup.GET("", h.GetMyUsage)
+		usageGroup.GET("/quota", h.GetMyQuota)
+	}
+
+	adminGroup := apiGroup.Group("/admin/usage")
+	adminGroup.Use(jwtAuthMiddleware(h.logger), adminMiddleware(h.logger))
+	{
+		adminGroup.GET("", h.QueryUsage)
+		adminGroup.GET("/quota/:user_id", h.GetUserQuota)
+		adminGroup.PUT("/levels/:user_id", h.SetUserLevel)
+	}
+}
+
+// GetMyUsage 获取当前用户的用量
+// @Summary 获取当前用户的用量
+// @Description 按设备、提供者和日期返回当前用户的用量记录、合计和估算费用
+// @Tags 用量统计
+// @Produce json
+// @Security BearerAuth
+// @Param device_id query string false "设备ID"
+// @Param provider_type query string false "提供者类型：llm/tts/asr"
+// @Param provider query string false "提供者名称"
+// @Param from query string false "开始日期，如 2024-01-01"
+// @Param to query string false "结束日期，包含当天"
+// @Success 200 {object} map[string]interface{} "成功"
+// @Failure 400 {object} map[string]interface{} "请求参数错误"
+// @Router /api/usage [get]
+func (h *UsageHandler) GetMyUsage(c *gin.Context) {
+	var query models.UsageQuery
+	if err := c.ShouldBindQuery(&query); err != nil {
+		respondError(c, h.logger, http.StatusBadRequest, "请求参数错误", err)
+		return
+	}
+	query.UserID = getUserIDFromContext(c, h.logger)
+	if query.UserID == "" {
+		respondError(c, h.logger, http.StatusUnauthorized, "未登录", nil)
+		return
+	}
+
+	h.respondUsage(c, &query)
+}
+
+// GetMyQuota 获取当前用户的配额
+// @Summary 获取当前用户的配额
+// @Description 返回当前用户的级别、配额以及当日和当月的用量
+// @Tags 用量统计
+// @Produce json
+// @Security BearerAuth
+// @Success 200 {object} map[string]interface{} "成功"
+// @Router /api/usage/quota [get]
+func (h *UsageHandler) GetMyQuota(c *gin.Context) {
+	userID := getUserIDFromContext(c, h.logger)
+	if userID == "" {
+		respondError(c, h.logger, http.StatusUnauthorized, "未登录", nil)
+		return
+	}
+
+	status, err := h.usageService.GetStatus(c.Request.Context(), userID, "")
+	if err != nil {
+		respondError(c, h.logger, http.StatusInternalServerError, "获取配额失败", err)
+		return
+	}
+	respondSuccess(c, status)
+}
+
+// QueryUsage 查询用量
+// @Summary 查询用量
+// @Description 按用户、设备、提供者和日期查询用量记录、合计和估算费用
+// @Tags 用量统计
+// @Produce json
+// @Param user_id query string false "用户ID"
+// @Param device_id query string false "设备ID"
+// @Param provider_type query string false "提供者类型：llm/tts/asr"
+// @Param provider query string false "提供者名称"
+// @Param from query string false "开始日期，如 2024-01-01"
+// @Param to query string false "结束日期，包含当天"
+// @Success 200 {object} map[string]interface{} "成功"
+// @Failure 403 {object} map[string]interface{} "需要管理员权限"
+// @Router /api/admin/usage [get]
+func (h *UsageHandler) QueryUsage(c *gin.Context) {
+	var query models.UsageQuery
+	if err := c.ShouldBindQuery(&query); err != nil {
+		respondError(c, h.logger, http.StatusBadRequest, "请求参数错误", err)
+		return
+	}
+
+	h.respondUsage(c, &query)
+}
+
+// GetUserQuota 获取用户的配额
+// @Summary 获取用户的配额
+// @Tags 用量统计
+// @Produce json
+// @Param user_id path string true "用户ID"
+// @Success 200 {object} map[string]interface{} "成功"
+// @Failure 403 {object} map[string]interface{} "需要管理员权限"
+// @Router /api/admin/usage/quota/{user_id} [get]
+func (h *UsageHandler) GetUserQuota(c *gin.Context) {
+	status, err := h.usageService.GetStatus(c.Request.Context(), c.Param("user_id"), "")
+	if err != nil {
+		respondError(c, h.logger, http.StatusInternalServerError, "获取配额失败", err)
+		return
+	}
+	respondSuccess(c, status)
+}
+
+// SetUserLevel 设置用户级别
+// @Summary 设置用户级别
+// @Description 用户级别决定对话用量配额，可选 basic/premium/business
+// @Tags 用量统计
+// @Accept json
+// @Produce json
+// @Param user_id path string true "用户ID"
+// @Param request body models.SetUsageLevelRequest true "用户级别"
+// @Success 200 {object} map[string]interface{} "成功"
+// @Failure 400 {object} map[string]interface{} "请求参数错误"
+// @Failure 403 {object} map[string]interface{} "需要管理员权限"
+// @Router /api/admin/usage/levels/{user_id} [put]
+func (h *UsageHandler) SetUserLevel(c *gin.Context) {
+	var req models.SetUsageLevelRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		respondError(c, h.logger, http.StatusBadRequest, "请求参数错误", err)
+		return
+	}
+
+	userID := c.Param("user_id")
+	if err := h.usageService.SetLevel(c.Request.Context(), userID, task.UserLevel(req.Level)); err != nil {
+		respondError(c, h.logger, http.StatusBadRequest, "设置用户级别失败: "+err.Error(), err)
+		return
+	}
+
+	status, err := h.usageService.GetStatus(c.Request.Context(), userID, "")
+	if err != nil {
+		respondError(c, h.logger, http.StatusInternalServerError, "获取配额失败", err)
+		return
+	}
+	respondSuccess(c, status)
+}
+
+// respondUsage 查询并返回用量记录和合计
+func (h *UsageHandler) respondUsage(c *gin.Context, query *models.UsageQuery) {
+	records, total, err := h.usageService.Query(c.Request.Context(), query)
+	if err != nil {
+		respondError(c, h.logger, http.StatusBadRequest, "查询用量失败: "+err.Error(), err)
+		return
+	}
+
+	respondSuccess(c, gin.H{
+		"records": records,
+		"total":   total,
+	})
+}
@@ -0,0 +1,170 @@
+package handlers
+
+import (
+	"net/http"
+
+	"angrymiao-ai-server/src/configs"
+	"angrymiao-ai-server/src/core/utils"
+	"angrymiao-ai-server/src/models"
+	"angrymiao-ai-server/src/services"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+// VoiceHandler 音色处理器
+type VoiceHandler struct {
+	voiceService services.VoiceService
+	logger       *utils.Logger
+}
+
+// NewVoiceHandler 创建音色处理器
+func NewVoiceHandler(db *gorm.DB, config *configs.Config, logger *utils.Logger) *VoiceHandler {
+	return &VoiceHandler{
+		voiceService: services.NewVoiceService(db, config, logger),
+		logger:       logger,
+	}
+}
+
+// RegisterRoutes 注册路由
+func (h *VoiceHandler) RegisterRoutes(apiGroup *gin.RouterGroup) {
+	voiceGroup := apiGroup.Group("/voices")
+	{
+		// 音色目录无需登录
+		voiceGroup.GET("", h.ListProviders)
+		voiceGroup.GET("/:provider", h.ListVoices)
+		// 试听未命中缓存时会调用TTS合成，需要登录
+		voiceGroup.GET("/:provider/:voice/preview", jwtAuthMiddleware(h.logger), h.GetPreview)
+	}
+
+	userGroup := apiGroup.Group("/voices/default")
+	userGroup.Use(jwtAuthMiddleware(h.logger))
+	{
+		userGroup.GET("", h.GetDefaultVoice)
+		userGroup.PUT("", h.SetDefaultVoice)
+	}
+}
+
+// ListProviders 获取所有TTS提供者及音色
+// @Summary 获取音色目录
+// @Description 获取所有已配置TTS提供者及其支持的音色
+// @Tags 音色管理
+// @Produce json
+// @Success 200 {object} map[string]interface{} "成功"
+// @Router /api/voices [get]
+func (h *VoiceHandler) ListProviders(c *gin.Context) {
+	providers := h.voiceService.ListProviders(c.Request.Context())
+	respondSuccess(c, gin.H{
+		"providers": providers,
+		"total":     len(providers),
+	})
+}
+
+// ListVoices 获取指定TTS提供者的音色列表
+// @Summary 获取TTS提供者音色列表
+// @Description 获取指定TTS提供者支持的音色
+// @Tags 音色管理
+// @Produce json
+// @Param provider path string true "TTS提供者名称"
+// @Success 200 {object} map[string]interface{} "成功"
+// @Failure 404 {object} map[string]interface{} "TTS提供者不存在"
+// @Router /api/voices/{provider} [get]
+func (h *VoiceHandler) ListVoices(c *gin.Context) {
+	provider := c.Param("provider")
+	voices, err := h.voiceService.ListVoices(c.Request.Context(), provider)
+	if err != nil {
+		respondError(c, h.logger, http.StatusNotFound, "获取音色列表失败", err)
+		return
+	}
+
+	respondSuccess(c, gin.H{
+		"provider": provider,
+		"voices":   voices,
+		"total":    len(voices),
+	})
+}
+
+// GetPreview 获取音色试听音频
+// @Summary 音色试听
+// @Description 返回音色试听音频，首次请求时合成并缓存
+// @Tags 音色管理
+// @Produce octet-stream
+// @Security BearerAuth
+// @Param provider path string true "TTS提供者名称"
+// @Param voice path string true "音色名称"
+// @Success 200 {file} file "音频文件"
+// @Failure 401 {object} map[string]interface{} "未认证"
+// @Failure 404 {object} map[string]interface{} "音色不存在"
+// @Failure 500 {object} map[string]interface{} "服务器内部错误"
+// @Router /api/voices/{provider}/{voice}/preview [get]
+func (h *VoiceHandler) GetPreview(c *gin.Context) {
+	provider := c.Param("provider")
+	voice := c.Param("voice")
+
+	if _, err := h.voiceService.ResolveVoice(provider, voice); err != nil {
+		respondError(c, h.logger, http.StatusNotFound, "音色不存在", err)
+		return
+	}
+
+	audioPath, err := h.voiceService.GetPreview(c.Request.Context(), provider, voice)
+	if err != nil {
+		respondError(c, h.logger, http.StatusInternalServerError, "生成试听音频失败", err)
+		return
+	}
+
+	c.Header("Cache-Control", "private, max-age=86400")
+	c.File(audioPath)
+}
+
+// GetDefaultVoice 获取用户默认音色
+// @Summary 获取用户默认音色
+// @Description 获取当前用户在指定TTS提供者下的默认音色，未指定时使用当前选中的TTS
+// @Tags 音色管理
+// @Produce json
+// @Param provider query string false "TTS提供者名称"
+// @Success 200 {object} map[string]interface{} "成功"
+// @Failure 401 {object} map[string]interface{} "未认证"
+// @Failure 500 {object} map[string]interface{} "服务器内部错误"
+// @Router /api/voices/default [get]
+func (h *VoiceHandler) GetDefaultVoice(c *gin.Context) {
+	userID := getUserIDFromContext(c, h.logger)
+	pref, err := h.voiceService.GetUserVoice(c.Request.Context(), userID, c.Query("provider"))
+	if err != nil {
+		respondError(c, h.logger, http.StatusInternalServerError, "获取默认音色失败", err)
+		return
+	}
+
+	respondSuccess(c, gin.H{
+		"voice": pref,
+	})
+}
+
+// SetDefaultVoice 设置用户默认音色
+// @Summary 设置用户默认音色
+// @Description 设置当前用户的默认音色，下次连接时生效
+// @Tags 音色管理
+// @Accept json
+// @Produce json
+// @Param request body models.SetDefaultVoiceRequest true "默认音色"
+// @Success 200 {object} map[string]interface{} "成功"
+// @Failure 400 {object} map[string]interface{} "请求参数错误"
+// @Failure 401 {object} map[string]interface{} "未认证"
+// @Router /api/voices/default [put]
+func (h *VoiceHandler) SetDefaultVoice(c *gin.Context) {
+	var req models.SetDefaultVoiceRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		respondError(c, h.logger, http.StatusBadRequest, "请求参数错误", err)
+		return
+	}
+
+	userID := getUserIDFromContext(c, h.logger)
+	pref, err := h.voiceService.SetUserVoice(c.Request.Context(), userID, req.TTSProvider, req.Voice)
+	if err != nil {
+		respondError(c, h.logger, http.StatusBadRequest, "设置默认音色失败", err)
+		return
+	}
+
+	respondSuccess(c, gin.H{
+		"voice": pref,
+	})
+}
@@ -28,10 +28,12 @@ import (
 	cfg "angrymiao-ai-server/src/configs/server"
 
 	// 项目内部包 - 核心功能
+	"angrymiao-ai-server/src/core"
 	"angrymiao-ai-server/src/core/auth"
 	"angrymiao-ai-server/src/core/auth/am_token"
 	"angrymiao-ai-server/src/core/auth/store"
 	"angrymiao-ai-server/src/core/pool"
+	"angrymiao-ai-server/src/core/prompt"
 	"angrymiao-ai-server/src/core/transport"
 	"angrymiao-ai-server/src/core/transport/websocket"
 	"angrymiao-ai-server/src/core/utils"
@@ -51,6 +53,7 @@ import (
 	_ "angrymiao-ai-server/src/core/providers/asr/deepgram"
 	_ "angrymiao-ai-server/src/core/providers/asr/doubao"
 	_ "angrymiao-ai-server/src/core/providers/asr/gosherpa"
+	_ "angrymiao-ai-server/src/core/providers/llm/anthropic"
 	_ "angrymiao-ai-server/src/core/providers/llm/coze"
 	_ "angrymiao-ai-server/src/core/providers/llm/ollama"
 	_ "angrymiao-ai-server/src/core/providers/llm/openai"
@@ -173,6 +176,16 @@ func (app *Application) validateConfiguration() error {
 		app.logger.Warn("所有传输层都未启用，这可能导致功能受限")
 	}
 
+	// 验证提示词模板
+	if err := prompt.ValidateConfig(app.config); err != nil {
+		return fmt.Errorf("提示词模板配置无效: %w", err)
+	}
+
+	// 验证音色试听文本
+	if err := services.ValidateVoiceConfig(app.config); err != nil {
+		return fmt.Errorf("音色配置无效: %w", err)
+	}
+
 	app.logger.Info("配置验证通过")
 	return nil
 }
@@ -263,13 +276,32 @@ func (app *Application) startTransportServer() error {
 	transportManager := transport.NewTransportManager(app.config, app.logger)
 	app.serverManager.transportManager = transportManager
 
+	// 角色表为空时从配置的角色列表导入
+	personaService := services.NewPersonaService(app.db, app.config, app.logger)
+	if err := personaService.SeedFromConfig(context.Background()); err != nil {
+		app.logger.Error("导入配置角色失败: %v", err)
+	}
+
 	// 创建连接处理器工厂
 	handlerFactory := transport.NewDefaultConnectionHandlerFactory(
 		app.config,
 		poolManager,
 		taskMgr,
 		app.logger,
-		userConfigService,
+		core.ConnectionServices{
+			UserConfig:   userConfigService,
+			Voice:        services.NewVoiceService(app.db, app.config, app.logger),
+			Lexicon:      services.NewLexiconService(app.db, app.logger),
+			Memory:       services.NewMemoryService(app.db, app.logger),
+			Conversation: services.NewConversationService(app.db, app.logger),
+			Prompt:       services.NewPromptService(app.db, app.logger),
+			Persona:      personaService,
+			Usage:        services.NewUsageService(app.db, app.config, app.logger),
+			Knowledge:    services.NewKnowledgeService(app.db, app.config, app.logger),
+			Moderation:   services.NewModerationService(app.db, app.logger),
+			Dictation:    services.NewDictationService(app.db, app.logger),
+			Meeting:      services.NewMeetingService(app.db, app.logger),
+		},
 	)
 
 	// 根据配置启用不同的传输层
@@ -422,6 +454,61 @@ func (app *Application) registerRoutes(router *gin.Engine) error {
 	aiConfigHandler.RegisterRoutes(apiGroup)
 	app.logger.Info("AI配置管理服务已注册，访问地址: /api/ai-configs")
 
+	// 启动音色服务
+	voiceHandler := handlers.NewVoiceHandler(app.db, app.config, app.logger)
+	voiceHandler.RegisterRoutes(apiGroup)
+	app.logger.Info("音色服务已注册，访问地址: /api/voices")
+
+	// 启动发音词典管理服务
+	lexiconHandler := handlers.NewLexiconHandler(app.db, app.logger)
+	lexiconHandler.RegisterRoutes(apiGroup)
+	app.logger.Info("发音词典管理服务已注册，访问地址: /api/admin/lexicon")
+
+	// 启动用户长期记忆服务
+	memoryHandler := handlers.NewMemoryHandler(app.db, app.logger)
+	memoryHandler.RegisterRoutes(apiGroup)
+	app.logger.Info("用户记忆服务已注册，访问地址: /api/memories")
+
+	// 启动对话历史服务
+	conversationHandler := handlers.NewConversationHandler(app.db, app.logger)
+	conversationHandler.RegisterRoutes(apiGroup)
+	app.logger.Info("对话历史服务已注册，访问地址: /api/conversations")
+
+	// 启动提示词模板服务
+	promptHandler := handlers.NewPromptHandler(app.db, app.config, app.logger)
+	promptHandler.RegisterRoutes(apiGroup)
+	app.logger.Info("提示词模板服务已注册，访问地址: /api/prompt")
+
+	// 启动角色管理服务
+	personaHandler := handlers.NewPersonaHandler(app.db, app.config, app.logger)
+	personaHandler.RegisterRoutes(apiGroup)
+	app.logger.Info("角色管理服务已注册，访问地址: /api/personas, /api/admin/personas")
+
+	// 启动用量统计服务
+	usageHandler := handlers.NewUsageHandler(app.db, app.config, app.logger)
+	usageHandler.RegisterRoutes(apiGroup)
+	app.logger.Info("用量统计服务已注册，访问地址: /api/usage, /api/admin/usage")
+
+	// 启动知识库服务
+	knowledgeHandler := handlers.NewKnowledgeHandler(app.db, app.config, app.logger)
+	knowledgeHandler.RegisterRoutes(apiGroup)
+	app.logger.Info("知识库服务已注册，访问地址: /api/knowledge")
+
+	// 启动内容审核审计服务
+	moderationHandler := handlers.NewModerationHandler(app.db, app.logger)
+	moderationHandler.RegisterRoutes(apiGroup)
+	app.logger.Info("内容审核审计服务已注册，访问地址: /api/admin/moderation/events")
+
+	// 启动听写文档服务
+	dictationHandler := handlers.NewDictationHandler(app.db, app.logger)
+	dictationHandler.RegisterRoutes(apiGroup)
+	app.logger.Info("听写文档服务已注册，访问地址: /api/dictations")
+
+	// 启动会议记录服务
+	meetingHandler := handlers.NewMeetingHandler(app.db, app.logger)
+	meetingHandler.RegisterRoutes(apiGroup)
+	app.logger.Info("会议记录服务已注册，访问地址: /api/meetings")
+
 	// 注册Swagger文档路由
 	router.GET("/swagger/*any", ginSwagger.WrapHandler(swaggerFiles.Handler))
 
@@ -0,0 +1,49 @@
+package models
+
+import "time"
+
+// ConversationSession 对话会话表，每个连接对应一个会话
+// SessionID 为客户端会话ID，同一设备的多次连接可能相同，因此以 ConversationID 区分
+type ConversationSession struct {
+	ID             uint       `json:"id" gorm:"primaryKey"`
+	ConversationID string     `json:"conversation_id" gorm:"type:varchar(64);not null;uniqueIndex"`
+	SessionID      string     `json:"session_id" gorm:"type:varchar(128);index"`
+	UserID         string     `json:"user_id" gorm:"type:varchar(64);index"`
+	DeviceID       string     `json:"device_id" gorm:"type:varchar(128);index"`
+	ClientID       string     `json:"client_id" gorm:"type:varchar(128)"`
+	Title          string     `json:"title" gorm:"type:varchar(128)"` // 取第一句用户发言
+	RoundCount     int        `json:"round_count" gorm:"not null;default:0"`
+	MessageCount   int        `json:"message_count" gorm:"not null;default:0"`
+	StartedAt      time.Time  `json:"started_at"`
+	LastActiveAt   time.Time  `json:"last_active_at" gorm:"index"`
+	EndedAt        *time.Time `json:"ended_at,omitempty"`
+	CreatedAt      time.Time  `json:"created_at"`
+	UpdatedAt      time.Time  `json:"updated_at"`
+}
+
+// TableName 指定ConversationSession表名
+func (ConversationSession) TableName() string {
+	return "conversation_sessions"
+}
+
+// ConversationMessage 对话消息表
+type ConversationMessage struct {
+	ID             uint      `json:"id" gorm:"primaryKey"`
+	ConversationID string    `json:"conversation_id" gorm:"type:varchar(64);not null;index"`
+	Round          int       `json:"round" gorm:"not null"`
+	Role           string    `json:"role" gorm:"type:varchar(16);not null"` // user、assistant、tool
+	Content        string    `json:"content" gorm:"type:text"`
+	ToolCalls      string    `json:"tool_calls,omitempty" gorm:"type:text"` // assistant发起的工具调用，JSON格式
+	ToolCallID     string    `json:"tool_call_id,omitempty" gorm:"type:varchar(128)"`
+	ToolName       string    `json:"tool_name,omitempty" gorm:"type:varchar(128)"` // tool消息对应的工具名称
+	LatencyMs      int64     `json:"latency_ms,omitempty"`                         // 本轮开始到第一句回复的耗时
+	DurationMs     int64     `json:"duration_ms,omitempty"`                        // 本轮开始到回复生成完成的耗时
+	Route          string    `json:"route,omitempty" gorm:"type:varchar(64)"`      // 本轮的LLM路由，记录在user消息上
+	LLM            string    `json:"llm,omitempty" gorm:"type:varchar(64)"`        // 本轮使用的LLM
+	CreatedAt      time.Time `json:"created_at"`
+}
+
+// TableName 指定ConversationMessage表名
+func (ConversationMessage) TableName() string {
+	return "conversation_messages"
+}
@@ -0,0 +1,24 @@
+package models
+
+import "time"
+
+// DictationDocument 听写文档表，每次听写结束时保存
+type DictationDocument struct {
+	ID         uint      `json:"id" gorm:"primaryKey"`
+	DocumentID string    `json:"document_id" gorm:"type:varchar(64);not null;uniqueIndex"`
+	UserID     string    `json:"user_id" gorm:"type:varchar(64);index"`
+	DeviceID   string    `json:"device_id" gorm:"type:varchar(128);index"`
+	SessionID  string    `json:"session_id" gorm:"type:varchar(128)"`
+	Title      string    `json:"title" gorm:"type:varchar(128)"` // 取第一句
+	Content    string    `json:"content" gorm:"type:text"`       // 最终文本，整理后的文本或原文
+	RawContent string    `json:"raw_content" gorm:"type:text"`   // 识别原文
+	Polished   bool      `json:"polished" gorm:"not null;default:false"`
+	StartedAt  time.Time `json:"started_at"`
+	CreatedAt  time.Time `json:"created_at" gorm:"index"`
+	UpdatedAt  time.Time `json:"updated_at"`
+}
+
+// TableName 指定DictationDocument表名
+func (DictationDocument) TableName() string {
+	return "dictation_documents"
+}
@@ -0,0 +1,91 @@
+package models
+
+import "time"
+
+// 知识库文档格式
+const (
+	KnowledgeFormatMarkdown = "markdown"
+	KnowledgeFormatText     = "text"
+	KnowledgeFormatPDF      = "pdf" // 从PDF提取的文本
+)
+
+// KnowledgeBase 知识库表
+// Persona 不为空时只对该角色生效，UserID 不为空时只对该用户生效，都为空时对所有对话生效
+type KnowledgeBase struct {
+	ID             uint      `json:"id" gorm:"primaryKey"`
+	Name           string    `json:"name" gorm:"type:varchar(64);not null"`
+	Description    string    `json:"description" gorm:"type:varchar(256)"` // 用于 search_knowledge 工具的说明
+	Persona        string    `json:"persona" gorm:"type:varchar(64);not null;default:'';index"`
+	UserID         string    `json:"user_id" gorm:"type:varchar(64);not null;default:'';index"`
+	EmbeddingModel string    `json:"embedding_model" gorm:"type:varchar(128)"` // 生成向量使用的模型，更换模型后需重新上传文档
+	CreatedAt      time.Time `json:"created_at"`
+	UpdatedAt      time.Time `json:"updated_at"`
+}
+
+// TableName 指定KnowledgeBase表名
+func (KnowledgeBase) TableName() string {
+	return "knowledge_bases"
+}
+
+// KnowledgeDocument 知识库文档表
+type KnowledgeDocument struct {
+	ID              uint      `json:"id" gorm:"primaryKey"`
+	KnowledgeBaseID uint      `json:"knowledge_base_id" gorm:"not null;index"`
+	Title           string    `json:"title" gorm:"type:varchar(256);not null"`
+	Format          string    `json:"format" gorm:"type:varchar(16);not null"` // markdown/text/pdf
+	Size            int       `json:"size"`                                    // 字符数
+	ChunkCount      int       `json:"chunk_count"`
+	CreatedAt       time.Time `json:"created_at"`
+	UpdatedAt       time.Time `json:"updated_at"`
+}
+
+// TableName 指定KnowledgeDocument表名
+func (KnowledgeDocument) TableName() string {
+	return "knowledge_documents"
+}
+
+// KnowledgeChunk 知识库文本块表，向量以小端序float32二进制保存
+type KnowledgeChunk struct {
+	ID              uint      `json:"id" gorm:"primaryKey"`
+	KnowledgeBaseID uint      `json:"knowledge_base_id" gorm:"not null;index"`
+	DocumentID      uint      `json:"document_id" gorm:"not null;index"`
+	Seq             int       `json:"seq"` // 在文档中的序号
+	Content         string    `json:"content" gorm:"type:text;not null"`
+	Vector          []byte    `json:"-" gorm:"not null"`
+	CreatedAt       time.Time `json:"created_at"`
+}
+
+// TableName 指定KnowledgeChunk表名
+func (KnowledgeChunk) TableName() string {
+	return "knowledge_chunks"
+}
+
+// KnowledgeBaseRequest 创建/更新知识库请求结构
+type KnowledgeBaseRequest struct {
+	Name        string `json:"name" binding:"required"`
+	Description string `json:"description,omitempty"`
+	Persona     string `json:"persona,omitempty"` // 限定角色，为空对所有角色生效
+	UserID      string `json:"user_id,omitempty"` // 限定用户，仅管理员可设置，普通用户创建的知识库只对自己生效
+}
+
+// KnowledgeDocumentRequest 上传文档请求结构
+type KnowledgeDocumentRequest struct {
+	Title   string `json:"title" binding:"required"`
+	Format  string `json:"format,omitempty"` // markdown/text/pdf，为空时按标题扩展名判断
+	Content string `json:"content" binding:"required"`
+}
+
+// KnowledgeSearchRequest 检索知识库请求结构
+type KnowledgeSearchRequest struct {
+	Query   string `json:"query" binding:"required"`
+	Persona string `json:"persona,omitempty"` // 按角色范围检索，为空时只检索不限角色的知识库
+	TopK    int    `json:"top_k,omitempty"`
+}
+
+// KnowledgeSearchResult 检索结果
+type KnowledgeSearchResult struct {
+	KnowledgeBase string  `json:"knowledge_base"`
+	Document      string  `json:"document"`
+	Content       string  `json:"content"`
+	Score         float64 `json:"score"`
+}
@@ -0,0 +1,34 @@
+package models
+
+import "time"
+
+// LexiconEntry 发音词典词条表
+// Role 为空表示全局词条，非空表示仅在该角色下生效并覆盖同名全局词条
+type LexiconEntry struct {
+	ID          uint      `json:"id" gorm:"primaryKey"`
+	Word        string    `json:"word" gorm:"type:varchar(128);not null;uniqueIndex:uniq_lexicon_word_role"`
+	Role        string    `json:"role" gorm:"type:varchar(64);not null;default:'';uniqueIndex:uniq_lexicon_word_role"`
+	Alias       string    `json:"alias" gorm:"type:varchar(256)"`   // 替换读法
+	Phoneme     string    `json:"phoneme" gorm:"type:varchar(256)"` // 音标
+	Alphabet    string    `json:"alphabet" gorm:"type:varchar(32)"` // 音标字母表，如 py、ipa
+	Description string    `json:"description" gorm:"type:text"`
+	IsActive    bool      `json:"is_active" gorm:"default:true"`
+	CreatedAt   time.Time `json:"created_at"`
+	UpdatedAt   time.Time `json:"updated_at"`
+}
+
+// TableName 指定LexiconEntry表名
+func (LexiconEntry) TableName() string {
+	return "lexicon_entries"
+}
+
+// LexiconEntryRequest 创建/更新发音词条请求结构
+type LexiconEntryRequest struct {
+	Word        string `json:"word" binding:"required"`
+	Role        string `json:"role,omitempty"`
+	Alias       string `json:"alias,omitempty"`
+	Phoneme     string `json:"phoneme,omitempty"`
+	Alphabet    string `json:"alphabet,omitempty"`
+	Description string `json:"description,omitempty"`
+	IsActive    *bool  `json:"is_active,omitempty"`
+}
@@ -0,0 +1,26 @@
+package models
+
+import "time"
+
+// MeetingRecord 会议记录表，会议结束时保存完整记录和纪要
+type MeetingRecord struct {
+	ID             uint      `json:"id" gorm:"primaryKey"`
+	MeetingID      string    `json:"meeting_id" gorm:"type:varchar(64);not null;uniqueIndex"`
+	UserID         string    `json:"user_id" gorm:"type:varchar(64);index"`
+	DeviceID       string    `json:"device_id" gorm:"type:varchar(128);index"`
+	SessionID      string    `json:"session_id" gorm:"type:varchar(128)"`
+	Title          string    `json:"title" gorm:"type:varchar(128)"`
+	Transcript     string    `json:"transcript,omitempty" gorm:"type:text"` // 带时间戳的完整记录，每行一句
+	Summary        string    `json:"summary" gorm:"type:text"`              // 会议纪要和待办事项，生成失败时为空
+	UtteranceCount int       `json:"utterance_count" gorm:"not null;default:0"`
+	DurationSec    int       `json:"duration_sec" gorm:"not null;default:0"`
+	StartedAt      time.Time `json:"started_at"`
+	EndedAt        time.Time `json:"ended_at"`
+	CreatedAt      time.Time `json:"created_at" gorm:"index"`
+	UpdatedAt      time.Time `json:"updated_at"`
+}
+
+// TableName 指定MeetingRecord表名
+func (MeetingRecord) TableName() string {
+	return "meeting_records"
+}
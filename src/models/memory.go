@@ -0,0 +1,27 @@
+package models
+
+import "time"
+
+// 记忆类别
+const (
+	MemoryCategoryProfile    = "profile"    // 个人信息，如称呼、年龄、职业
+	MemoryCategoryPreference = "preference" // 偏好，如喜欢的音乐、饮食习惯
+	MemoryCategoryFact       = "fact"       // 其他值得长期记住的事实
+)
+
+// UserMemory 用户长期记忆表
+// 会话结束时由LLM从对话中提取，后续对话按相关度检索后加入提示词
+type UserMemory struct {
+	ID         uint      `json:"id" gorm:"primaryKey"`
+	UserID     string    `json:"user_id" gorm:"type:varchar(64);not null;index"`
+	Category   string    `json:"category" gorm:"type:varchar(32);not null;default:'fact'"`
+	Content    string    `json:"content" gorm:"type:varchar(512);not null"`
+	Importance int       `json:"importance" gorm:"not null;default:1"` // 重要程度 1-5
+	CreatedAt  time.Time `json:"created_at"`
+	UpdatedAt  time.Time `json:"updated_at"`
+}
+
+// TableName 指定UserMemory表名
+func (UserMemory) TableName() string {
+	return "user_memories"
+}
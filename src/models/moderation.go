@@ -0,0 +1,32 @@
+package models
+
+import "time"
+
+// ModerationEvent 内容审核事件表，记录命中审核的用户输入和助手回复
+type ModerationEvent struct {
+	ID        uint      `json:"id" gorm:"primaryKey"`
+	UserID    string    `json:"user_id" gorm:"type:varchar(64);not null;default:'';index"`
+	DeviceID  string    `json:"device_id" gorm:"type:varchar(64);not null;default:'';index"`
+	SessionID string    `json:"session_id" gorm:"type:varchar(64)"`
+	Direction string    `json:"direction" gorm:"type:varchar(8);not null"` // input/output
+	Checker   string    `json:"checker" gorm:"type:varchar(32);not null"`  // keyword/endpoint/llm
+	Category  string    `json:"category" gorm:"type:varchar(128)"`
+	Action    string    `json:"action" gorm:"type:varchar(16);not null"` // block/redact/end
+	Content   string    `json:"content" gorm:"type:text"`                // 原文
+	Result    string    `json:"result" gorm:"type:text"`                 // 处理后的文本
+	CreatedAt time.Time `json:"created_at" gorm:"index"`
+}
+
+// TableName 指定ModerationEvent表名
+func (ModerationEvent) TableName() string {
+	return "moderation_events"
+}
+
+// ModerationEventQuery 审核事件查询条件
+type ModerationEventQuery struct {
+	UserID    string `form:"user_id"`
+	DeviceID  string `form:"device_id"`
+	Direction string `form:"direction"`
+	Page      int    `form:"page"`
+	PageSize  int    `form:"page_size"`
+}
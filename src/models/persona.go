@@ -0,0 +1,73 @@
+package models
+
+import (
+	"encoding/json"
+	"time"
+)
+
+// Persona 角色表
+// 角色的提示词支持模板变量，音色按TTS提供者分别配置，未配置的提供者沿用当前音色
+type Persona struct {
+	ID           uint      `json:"id" gorm:"primaryKey"`
+	Name         string    `json:"name" gorm:"type:varchar(64);not null;uniqueIndex"`
+	Description  string    `json:"description" gorm:"type:varchar(256)"` // 简短描述，用于角色切换工具的说明
+	Prompt       string    `json:"prompt" gorm:"type:text;not null"`     // 提示词模板
+	Voices       string    `json:"-" gorm:"type:text"`                   // TTS提供者名称到音色，JSON对象
+	Greeting     string    `json:"greeting" gorm:"type:varchar(512)"`    // 切换到该角色时的问候语
+	AllowedTools string    `json:"-" gorm:"type:text"`                   // 允许使用的工具，JSON数组，为空不限制
+	LLM          string    `json:"llm" gorm:"type:varchar(64)"`          // 覆盖使用的LLM配置名称，为空使用默认LLM
+	IsDefault    bool      `json:"is_default" gorm:"default:false"`      // 是否为新连接的默认角色
+	IsActive     bool      `json:"is_active" gorm:"default:true"`
+	CreatedAt    time.Time `json:"created_at"`
+	UpdatedAt    time.Time `json:"updated_at"`
+}
+
+// TableName 指定Persona表名
+func (Persona) TableName() string {
+	return "personas"
+}
+
+// VoiceMap 解析各TTS提供者的音色
+func (p *Persona) VoiceMap() map[string]string {
+	voices := map[string]string{}
+	if p.Voices != "" {
+		_ = json.Unmarshal([]byte(p.Voices), &voices)
+	}
+	return voices
+}
+
+// ToolList 解析允许使用的工具
+func (p *Persona) ToolList() []string {
+	var tools []string
+	if p.AllowedTools != "" {
+		_ = json.Unmarshal([]byte(p.AllowedTools), &tools)
+	}
+	return tools
+}
+
+// MarshalJSON 输出时展开音色和工具列表
+func (p Persona) MarshalJSON() ([]byte, error) {
+	type alias Persona
+	return json.Marshal(struct {
+		alias
+		Voices       map[string]string `json:"voices"`
+		AllowedTools []string          `json:"allowed_tools"`
+	}{
+		alias:        alias(p),
+		Voices:       p.VoiceMap(),
+		AllowedTools: p.ToolList(),
+	})
+}
+
+// PersonaRequest 创建/更新角色请求结构
+type PersonaRequest struct {
+	Name         string            `json:"name" binding:"required"`
+	Description  string            `json:"description,omitempty"`
+	Prompt       string            `json:"prompt" binding:"required"`
+	Voices       map[string]string `json:"voices,omitempty"` // 如 {"EdgeTTS": "zh-CN-XiaoyiNeural"}
+	Greeting     string            `json:"greeting,omitempty"`
+	AllowedTools []string          `json:"allowed_tools,omitempty"` // 为空不限制
+	LLM          string            `json:"llm,omitempty"`
+	IsDefault    *bool             `json:"is_default,omitempty"`
+	IsActive     *bool             `json:"is_active,omitempty"`
+}
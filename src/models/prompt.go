@@ -0,0 +1,46 @@
+package models
+
+import (
+	"encoding/json"
+	"time"
+)
+
+// UserPromptProfile 用户的提示词模板变量
+// 昵称和自定义字段在每轮对话前渲染到系统提示词中
+type UserPromptProfile struct {
+	ID        uint      `json:"id" gorm:"primaryKey"`
+	UserID    string    `json:"user_id" gorm:"type:varchar(64);not null;uniqueIndex"`
+	Nickname  string    `json:"nickname" gorm:"type:varchar(64)"`
+	Fields    string    `json:"-" gorm:"type:text"` // 自定义字段，JSON对象
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+// TableName 指定UserPromptProfile表名
+func (UserPromptProfile) TableName() string {
+	return "user_prompt_profiles"
+}
+
+// FieldMap 解析自定义字段
+func (p *UserPromptProfile) FieldMap() map[string]string {
+	fields := map[string]string{}
+	if p.Fields != "" {
+		_ = json.Unmarshal([]byte(p.Fields), &fields)
+	}
+	return fields
+}
+
+// UpdatePromptProfileRequest 更新提示词变量请求结构
+type UpdatePromptProfileRequest struct {
+	Nickname string            `json:"nickname"`
+	Fields   map[string]string `json:"fields"` // 字段名只能包含字母、数字和下划线，模板中以 {{.Fields.字段名}} 引用
+}
+
+// PreviewPromptRequest 预览提示词请求结构
+type PreviewPromptRequest struct {
+	Template string `json:"template"` // 提示词模板，为空时使用 role 对应的提示词
+	Role     string `json:"role"`     // 角色名称，为空时使用默认提示词
+	DeviceID string `json:"device_id"`
+	Board    string `json:"board"`
+	Voice    string `json:"voice"`
+}
@@ -0,0 +1,79 @@
+package models
+
+import "time"
+
+// UsageRecord 用量记录表，按用户、设备、提供者和日期汇总
+// 未登录的设备 UserID 为空，只按设备统计
+type UsageRecord struct {
+	ID               uint      `json:"id" gorm:"primaryKey"`
+	UserID           string    `json:"user_id" gorm:"type:varchar(64);not null;default:'';uniqueIndex:uniq_usage_record;index"`
+	DeviceID         string    `json:"device_id" gorm:"type:varchar(64);not null;default:'';uniqueIndex:uniq_usage_record"`
+	ProviderType     string    `json:"provider_type" gorm:"type:varchar(16);not null;uniqueIndex:uniq_usage_record"` // llm/tts/asr
+	Provider         string    `json:"provider" gorm:"type:varchar(64);not null;uniqueIndex:uniq_usage_record"`      // 配置中的提供者名称
+	Date             string    `json:"date" gorm:"type:varchar(10);not null;uniqueIndex:uniq_usage_record;index"`    // 日期，格式 2006-01-02
+	Requests         int64     `json:"requests" gorm:"not null;default:0"`
+	PromptTokens     int64     `json:"prompt_tokens" gorm:"not null;default:0"`
+	CompletionTokens int64     `json:"completion_tokens" gorm:"not null;default:0"`
+	EstimatedTokens  int64     `json:"estimated_tokens" gorm:"not null;default:0"` // 提供者未返回用量时估算的token数
+	TTSChars         int64     `json:"tts_chars" gorm:"not null;default:0"`
+	ASRSeconds       float64   `json:"asr_seconds" gorm:"not null;default:0"`
+	Cost             float64   `json:"cost" gorm:"-"` // 按配置单价估算的费用，查询时计算
+	CreatedAt        time.Time `json:"created_at"`
+	UpdatedAt        time.Time `json:"updated_at"`
+}
+
+// TableName 指定UsageRecord表名
+func (UsageRecord) TableName() string {
+	return "usage_records"
+}
+
+// UserUsageLevel 用户级别表，决定对话用量配额
+type UserUsageLevel struct {
+	ID        uint      `json:"id" gorm:"primaryKey"`
+	UserID    string    `json:"user_id" gorm:"type:varchar(64);not null;uniqueIndex"`
+	Level     string    `json:"level" gorm:"type:varchar(16);not null"`
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+// TableName 指定UserUsageLevel表名
+func (UserUsageLevel) TableName() string {
+	return "user_usage_levels"
+}
+
+// UsageQuery 用量查询条件
+type UsageQuery struct {
+	UserID       string `form:"user_id"`
+	DeviceID     string `form:"device_id"`
+	ProviderType string `form:"provider_type"`
+	Provider     string `form:"provider"`
+	From         string `form:"from"` // 开始日期，格式 2006-01-02
+	To           string `form:"to"`   // 结束日期，包含当天
+}
+
+// UsageTotal 用量合计
+type UsageTotal struct {
+	Requests         int64   `json:"requests"`
+	PromptTokens     int64   `json:"prompt_tokens"`
+	CompletionTokens int64   `json:"completion_tokens"`
+	EstimatedTokens  int64   `json:"estimated_tokens"`
+	TTSChars         int64   `json:"tts_chars"`
+	ASRSeconds       float64 `json:"asr_seconds"`
+	Cost             float64 `json:"cost"`
+}
+
+// Add 累加一条用量记录
+func (t *UsageTotal) Add(r *UsageRecord) {
+	t.Requests += r.Requests
+	t.PromptTokens += r.PromptTokens
+	t.CompletionTokens += r.CompletionTokens
+	t.EstimatedTokens += r.EstimatedTokens
+	t.TTSChars += r.TTSChars
+	t.ASRSeconds += r.ASRSeconds
+	t.Cost += r.Cost
+}
+
+// SetUsageLevelRequest 设置用户级别请求结构
+type SetUsageLevelRequest struct {
+	Level string `json:"level" binding:"required"` // basic/premium/business
+}
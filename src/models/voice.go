@@ -0,0 +1,25 @@
+package models
+
+import "time"
+
+// UserVoicePreference 用户默认音色表
+// 音色名称与TTS提供者强相关，因此按 用户+TTS提供者 保存一条记录
+type UserVoicePreference struct {
+	ID          uint      `json:"id" gorm:"primaryKey"`
+	UserID      string    `json:"user_id" gorm:"type:varchar(64);not null;uniqueIndex:uniq_user_tts_voice"`
+	TTSProvider string    `json:"tts_provider" gorm:"type:varchar(64);not null;uniqueIndex:uniq_user_tts_voice"` // 配置中的TTS名称，如 EdgeTTS
+	Voice       string    `json:"voice" gorm:"type:varchar(128);not null"`                                       // 音色名称
+	CreatedAt   time.Time `json:"created_at"`
+	UpdatedAt   time.Time `json:"updated_at"`
+}
+
+// TableName 指定UserVoicePreference表名
+func (UserVoicePreference) TableName() string {
+	return "user_voice_preferences"
+}
+
+// SetDefaultVoiceRequest 设置默认音色请求结构
+type SetDefaultVoiceRequest struct {
+	TTSProvider string `json:"tts_provider,omitempty"`   // 为空时使用当前选中的TTS
+	Voice       string `json:"voice" binding:"required"` // 音色名称或显示名称
+}
@@ -0,0 +1,254 @@
+package services
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"time"
+
+	"angrymiao-ai-server/src/core/types"
+	"angrymiao-ai-server/src/core/utils"
+	"angrymiao-ai-server/src/models"
+
+	"gorm.io/gorm"
+)
+
+// 会话标题的最大字符数
+const conversationTitleMaxRunes = 40
+
+// ConversationService 对话历史服务接口
+type ConversationService interface {
+	// 记录对话
+	StartSession(ctx context.Context, session *models.ConversationSession) error
+	SaveRound(ctx context.Context, conversationID string, messages []*models.ConversationMessage) error
+	UpdateReply(ctx context.Context, conversationID string, round int, content string) error
+	EndSession(ctx context.Context, conversationID string) error
+
+	// 查询和管理，只能访问用户自己的会话
+	ListSessions(ctx context.Context, userID string, page, pageSize int) ([]*models.ConversationSession, int64, error)
+	GetSession(ctx context.Context, userID, conversationID string) (*models.ConversationSession, error)
+	GetMessages(ctx context.Context, userID, conversationID string) ([]*models.ConversationMessage, error)
+	DeleteSession(ctx context.Context, userID, conversationID string) error
+}
+
+// DefaultConversationService 默认对话历史服务实现
+type DefaultConversationService struct {
+	db     *gorm.DB
+	logger *utils.Logger
+}
+
+// NewConversationService 创建对话历史服务实例
+func NewConversationService(db *gorm.DB, logger *utils.Logger) ConversationService {
+	return &DefaultConversationService{
+		db:     db,
+		logger: logger,
+	}
+}
+
+// StartSession 创建会话记录
+func (s *DefaultConversationService) StartSession(ctx context.Context, session *models.ConversationSession) error {
+	if session.ConversationID == "" {
+		return fmt.Errorf("会话ID不能为空")
+	}
+	now := time.Now()
+	if session.StartedAt.IsZero() {
+		session.StartedAt = now
+	}
+	session.LastActiveAt = session.StartedAt
+	return s.db.WithContext(ctx).Create(session).Error
+}
+
+// SaveRound 保存一轮对话的消息，并更新会话的统计信息
+func (s *DefaultConversationService) SaveRound(ctx context.Context, conversationID string, messages []*models.ConversationMessage) error {
+	if len(messages) == 0 {
+		return nil
+	}
+
+	return s.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		var session models.ConversationSession
+		if err := tx.Where("conversation_id = ?", conversationID).First(&session).Error; err != nil {
+			return fmt.Errorf("会话不存在: %v", err)
+		}
+
+		for _, m := range messages {
+			m.ConversationID = conversationID
+		}
+		if err := tx.Create(&messages).Error; err != nil {
+			return err
+		}
+
+		updates := map[string]interface{}{
+			"round_count":    gorm.Expr("round_count + 1"),
+			"message_count":  gorm.Expr("message_count + ?", len(messages)),
+			"last_active_at": time.Now(),
+		}
+		if session.Title == "" {
+			for _, m := range messages {
+				if m.Role == "user" && strings.TrimSpace(m.Content) != "" {
+					updates["title"] = truncateTitle(m.Content)
+					break
+				}
+			}
+		}
+		return tx.Model(&session).Updates(updates).Error
+	})
+}
+
+// UpdateReply 更新指定轮次最后一条助手文本回复的内容，回复被打断时调用
+func (s *DefaultConversationService) UpdateReply(ctx context.Context, conversationID string, round int, content string) error {
+	var reply models.ConversationMessage
+	err := s.db.WithContext(ctx).
+		Where("conversation_id = ? AND round = ? AND role = ? AND content <> '' AND (tool_calls IS NULL OR tool_calls = '')", conversationID, round, "assistant").
+		Order("id DESC").
+		First(&reply).Error
+	if err != nil {
+		if err == gorm.ErrRecordNotFound {
+			return nil
+		}
+		return err
+	}
+	return s.db.WithContext(ctx).Model(&reply).Update("content", content).Error
+}
+
+// EndSession 标记会话结束
+func (s *DefaultConversationService) EndSession(ctx context.Context, conversationID string) error {
+	now := time.Now()
+	return s.db.WithContext(ctx).
+		Model(&models.ConversationSession{}).
+		Where("conversation_id = ?", conversationID).
+		Update("ended_at", &now).Error
+}
+
+// ListSessions 分页获取用户的会话，按最近活跃时间倒序，不包含没有消息的会话
+func (s *DefaultConversationService) ListSessions(ctx context.Context, userID string, page, pageSize int) ([]*models.ConversationSession, int64, error) {
+	if page <= 0 {
+		page = 1
+	}
+	if pageSize <= 0 || pageSize > 100 {
+		pageSize = 20
+	}
+
+	query := s.db.WithContext(ctx).
+		Model(&models.ConversationSession{}).
+		Where("user_id = ? AND message_count > 0", userID)
+
+	var total int64
+	if err := query.Count(&total).Error; err != nil {
+		return nil, 0, err
+	}
+
+	var sessions []*models.ConversationSession
+	err := query.Order("last_active_at DESC").
+		Offset((page - 1) * pageSize).
+		Limit(pageSize).
+		Find(&sessions).Error
+	if err != nil {
+		return nil, 0, err
+	}
+	return sessions, total, nil
+}
+
+// GetSession 获取用户的会话
+func (s *DefaultConversationService) GetSession(ctx context.Context, userID, conversationID string) (*models.ConversationSession, error) {
+	var session models.ConversationSession
+	err := s.db.WithContext(ctx).
+		Where("conversation_id = ? AND user_id = ?", conversationID, userID).
+		First(&session).Error
+	if err != nil {
+		if err == gorm.ErrRecordNotFound {
+			return nil, fmt.Errorf("会话不存在")
+		}
+		return nil, err
+	}
+	return &session, nil
+}
+
+// GetMessages 获取会话的全部消息，按轮次和写入顺序排列
+func (s *DefaultConversationService) GetMessages(ctx context.Context, userID, conversationID string) ([]*models.ConversationMessage, error) {
+	if _, err := s.GetSession(ctx, userID, conversationID); err != nil {
+		return nil, err
+	}
+
+	var messages []*models.ConversationMessage
+	err := s.db.WithContext(ctx).
+		Where("conversation_id = ?", conversationID).
+		Order("round ASC, id ASC").
+		Find(&messages).Error
+	if err != nil {
+		return nil, err
+	}
+	return messages, nil
+}
+
+// DeleteSession 删除用户的会话及其消息
+func (s *DefaultConversationService) DeleteSession(ctx context.Context, userID, conversationID string) error {
+	if _, err := s.GetSession(ctx, userID, conversationID); err != nil {
+		return err
+	}
+
+	return s.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("conversation_id = ?", conversationID).Delete(&models.ConversationMessage{}).Error; err != nil {
+			return err
+		}
+		return tx.Where("conversation_id = ?", conversationID).Delete(&models.ConversationSession{}).Error
+	})
+}
+
+// RenderConversationMarkdown 将会话导出为Markdown文本
+func RenderConversationMarkdown(session *models.ConversationSession, messages []*models.ConversationMessage) string {
+	var b strings.Builder
+
+	title := session.Title
+	if title == "" {
+		title = "对话记录"
+	}
+	fmt.Fprintf(&b, "# %s\n\n", title)
+	fmt.Fprintf(&b, "- 会话ID: %s\n", session.ConversationID)
+	if session.DeviceID != "" {
+		fmt.Fprintf(&b, "- 设备: %s\n", session.DeviceID)
+	}
+	fmt.Fprintf(&b, "- 开始时间: %s\n", session.StartedAt.Format("2006-01-02 15:04:05"))
+	if session.EndedAt != nil {
+		fmt.Fprintf(&b, "- 结束时间: %s\n", session.EndedAt.Format("2006-01-02 15:04:05"))
+	}
+	fmt.Fprintf(&b, "- 轮次: %d\n", session.RoundCount)
+
+	round := 0
+	for _, m := range messages {
+		if m.Round != round {
+			round = m.Round
+			fmt.Fprintf(&b, "\n## 第%d轮\n\n", round)
+		}
+		switch m.Role {
+		case "user":
+			fmt.Fprintf(&b, "**用户** (%s)：%s\n\n", m.CreatedAt.Format("15:04:05"), m.Content)
+		case "assistant":
+			var toolCalls []types.ToolCall
+			if m.ToolCalls != "" && json.Unmarshal([]byte(m.ToolCalls), &toolCalls) == nil {
+				for _, tc := range toolCalls {
+					fmt.Fprintf(&b, "> 调用工具 `%s`：`%s`\n\n", tc.Function.Name, tc.Function.Arguments)
+				}
+			}
+			if m.Content != "" {
+				fmt.Fprintf(&b, "**助手**：%s", m.Content)
+				if m.LatencyMs > 0 || m.DurationMs > 0 {
+					fmt.Fprintf(&b, " _(首句 %dms，总计 %dms)_", m.LatencyMs, m.DurationMs)
+				}
+				b.WriteString("\n\n")
+			}
+		case "tool":
+			fmt.Fprintf(&b, "> 工具 `%s` 返回：%s\n\n", m.ToolName, strings.ReplaceAll(m.Content, "\n", " "))
+		}
+	}
+	return b.String()
+}
+
+// truncateTitle 截取会话标题
+func truncateTitle(text string) string {
+	runes := []rune(strings.TrimSpace(text))
+	if len(runes) <= conversationTitleMaxRunes {
+		return string(runes)
+	}
+	return string(runes[:conversationTitleMaxRunes]) + "..."
+}
@@ -0,0 +1,61 @@
+package services
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"angrymiao-ai-server/src/models"
+)
+
+func TestRenderConversationMarkdown(t *testing.T) {
+	start := time.Date(2026, 1, 1, 9, 0, 0, 0, time.Local)
+	end := start.Add(5 * time.Minute)
+	session := &models.ConversationSession{
+		ConversationID: "conv-1",
+		DeviceID:       "device-1",
+		RoundCount:     2,
+		StartedAt:      start,
+		EndedAt:        &end,
+	}
+	messages := []*models.ConversationMessage{
+		{Round: 1, Role: "user", Content: "北京天气", CreatedAt: start},
+		{Round: 1, Role: "assistant", ToolCalls: `[{"id":"call_1","type":"function","function":{"name":"get_weather","arguments":"{\"city\":\"北京\"}"}}]`},
+		{Round: 1, Role: "tool", ToolName: "get_weather", Content: "晴\n25度"},
+		{Round: 1, Role: "assistant", Content: "北京今天晴。", LatencyMs: 800, DurationMs: 2000},
+		{Round: 2, Role: "user", Content: "谢谢", CreatedAt: start.Add(time.Minute)},
+		{Round: 2, Role: "assistant", Content: "不客气"},
+	}
+
+	got := RenderConversationMarkdown(session, messages)
+	want := []string{
+		"# 对话记录\n",
+		"- 会话ID: conv-1\n",
+		"- 设备: device-1\n",
+		"- 开始时间: 2026-01-01 09:00:00\n",
+		"- 结束时间: 2026-01-01 09:05:00\n",
+		"## 第1轮\n",
+		"**用户** (09:00:00)：北京天气\n",
+		"> 调用工具 `get_weather`：`{\"city\":\"北京\"}`\n",
+		"> 工具 `get_weather` 返回：晴 25度\n",
+		"**助手**：北京今天晴。 _(首句 800ms，总计 2000ms)_\n",
+		"## 第2轮\n",
+		"**助手**：不客气\n",
+	}
+	last := -1
+	for _, part := range want {
+		i := strings.Index(got, part)
+		if i < 0 {
+			t.Fatalf("RenderConversationMarkdown() 缺少 %q:\n%s", part, got)
+		}
+		if i < last {
+			t.Errorf("RenderConversationMarkdown() %q 顺序错误", part)
+		}
+		last = i
+	}
+
+	session.Title = "北京天气"
+	if got := RenderConversationMarkdown(session, nil); !strings.HasPrefix(got, "# 北京天气\n") {
+		t.Errorf("RenderConversationMarkdown() 标题 = %q", got)
+	}
+}
@@ -0,0 +1,105 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"angrymiao-ai-server/src/core/utils"
+	"angrymiao-ai-server/src/models"
+
+	"gorm.io/gorm"
+)
+
+// DictationService 听写文档服务接口
+type DictationService interface {
+	SaveDocument(ctx context.Context, doc *models.DictationDocument) error
+
+	// 查询和管理，只能访问用户自己的文档
+	ListDocuments(ctx context.Context, userID string, page, pageSize int) ([]*models.DictationDocument, int64, error)
+	GetDocument(ctx context.Context, userID, documentID string) (*models.DictationDocument, error)
+	DeleteDocument(ctx context.Context, userID, documentID string) error
+}
+
+// DefaultDictationService 默认听写文档服务实现
+type DefaultDictationService struct {
+	db     *gorm.DB
+	logger *utils.Logger
+}
+
+// NewDictationService 创建听写文档服务实例
+func NewDictationService(db *gorm.DB, logger *utils.Logger) DictationService {
+	return &DefaultDictationService{
+		db:     db,
+		logger: logger,
+	}
+}
+
+// SaveDocument 保存听写文档，未设置标题时取第一句
+func (s *DefaultDictationService) SaveDocument(ctx context.Context, doc *models.DictationDocument) error {
+	if doc.DocumentID == "" {
+		return fmt.Errorf("文档ID不能为空")
+	}
+	if doc.Title == "" {
+		doc.Title = truncateTitle(strings.SplitN(doc.Content, "\n", 2)[0])
+	}
+	return s.db.WithContext(ctx).Create(doc).Error
+}
+
+// ListDocuments 分页获取用户的听写文档，按创建时间倒序
+func (s *DefaultDictationService) ListDocuments(ctx context.Context, userID string, page, pageSize int) ([]*models.DictationDocument, int64, error) {
+	if page <= 0 {
+		page = 1
+	}
+	if pageSize <= 0 || pageSize > 100 {
+		pageSize = 20
+	}
+
+	query := s.db.WithContext(ctx).
+		Model(&models.DictationDocument{}).
+		Where("user_id = ?", userID)
+
+	var total int64
+	if err := query.Count(&total).Error; err != nil {
+		return nil, 0, err
+	}
+
+	var docs []*models.DictationDocument
+	err := query.Order("created_at DESC").
+		Offset((page - 1) * pageSize).
+		Limit(pageSize).
+		Find(&docs).Error
+	if err != nil {
+		return nil, 0, err
+	}
+	return docs, total, nil
+}
+
+// GetDocument 获取用户的听写文档
+func (s *DefaultDictationService) GetDocument(ctx context.Context, userID, documentID string) (*models.DictationDocument, error) {
+	var doc models.DictationDocument
+	err := s.db.WithContext(ctx).
+		Where("document_id = ? AND user_id = ?", documentID, userID).
+		First(&doc).Error
+	if err != nil {
+		if err == gorm.ErrRecordNotFound {
+			return nil, fmt.Errorf("文档不存在")
+		}
+		return nil, err
+	}
+	return &doc, nil
+}
+
+// DeleteDocument 删除用户的听写文档
+func (s *DefaultDictationService) DeleteDocument(ctx context.Context, userID, documentID string) error {
+	result := s.db.WithContext(ctx).
+		Where("document_id = ? AND user_id = ?", documentID, userID).
+		Delete(&models.DictationDocument{})
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return fmt.Errorf("文档不存在")
+	}
+	return nil
+}
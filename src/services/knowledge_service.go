@@ -0,0 +1,420 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"path"
+	"sort"
+	"strings"
+	"unicode/utf8"
+
+	"angrymiao-ai-server/src/configs"
+	"angrymiao-ai-server/src/core/knowledge"
+	"angrymiao-ai-server/src/core/utils"
+	"angrymiao-ai-server/src/models"
+
+	"gorm.io/gorm"
+)
+
+const (
+	defaultKnowledgeTopK        = 3
+	defaultKnowledgeMaxDocument = 200000
+	maxKnowledgeTopK            = 10
+	maxKnowledgeNameRunes       = 64
+)
+
+// ErrKnowledgeDisabled 未启用知识库或向量模型不可用
+var ErrKnowledgeDisabled = errors.New("知识库未启用")
+
+// KnowledgeScope 检索范围，包含不限用户和角色的知识库，以及属于该用户或该角色的知识库
+type KnowledgeScope struct {
+	UserID  string
+	Persona string
+}
+
+// KnowledgeService 知识库服务接口
+type KnowledgeService interface {
+	// ListBases 列出用户可见的知识库，userID为空时列出全部
+	ListBases(ctx context.Context, userID string) ([]*models.KnowledgeBase, error)
+	GetBase(ctx context.Context, id uint) (*models.KnowledgeBase, error)
+	CreateBase(ctx context.Context, base *models.KnowledgeBase) error
+	UpdateBase(ctx context.Context, base *models.KnowledgeBase) error
+	// DeleteBase 删除知识库及其文档和文本块
+	DeleteBase(ctx context.Context, id uint) error
+
+	ListDocuments(ctx context.Context, baseID uint) ([]*models.KnowledgeDocument, error)
+	// AddDocument 切分文档并生成向量后保存
+	AddDocument(ctx context.Context, baseID uint, req *models.KnowledgeDocumentRequest) (*models.KnowledgeDocument, error)
+	DeleteDocument(ctx context.Context, baseID, documentID uint) error
+
+	// Search 检索范围内与查询最相关的文本块，topK不大于0时使用配置值
+	Search(ctx context.Context, scope KnowledgeScope, query string, topK int) ([]*models.KnowledgeSearchResult, error)
+}
+
+// DefaultKnowledgeService 默认知识库服务实现
+type DefaultKnowledgeService struct {
+	db       *gorm.DB
+	config   *configs.Config
+	embedder knowledge.Embedder // 未启用或配置错误时为空
+	logger   *utils.Logger
+}
+
+// NewKnowledgeService 创建知识库服务实例
+func NewKnowledgeService(db *gorm.DB, config *configs.Config, logger *utils.Logger) KnowledgeService {
+	s := &DefaultKnowledgeService{
+		db:     db,
+		config: config,
+		logger: logger,
+	}
+	if config.Knowledge.Enabled {
+		embedder, err := knowledge.NewEmbedder(config.Knowledge.Embedding)
+		if err != nil {
+			logger.Error("创建向量模型客户端失败，知识库不可用: %v", err)
+		} else {
+			s.embedder = embedder
+		}
+	}
+	return s
+}
+
+// ListBases 列出知识库
+func (s *DefaultKnowledgeService) ListBases(ctx context.Context, userID string) ([]*models.KnowledgeBase, error) {
+	db := s.db.WithContext(ctx)
+	if userID != "" {
+		db = db.Where("user_id = ? OR user_id = ?", "", userID)
+	}
+	var bases []*models.KnowledgeBase
+	if err := db.Order("id ASC").Find(&bases).Error; err != nil {
+		return nil, err
+	}
+	return bases, nil
+}
+
+// GetBase 根据ID获取知识库
+func (s *DefaultKnowledgeService) GetBase(ctx context.Context, id uint) (*models.KnowledgeBase, error) {
+	var base models.KnowledgeBase
+	if err := s.db.WithContext(ctx).First(&base, id).Error; err != nil {
+		if err == gorm.ErrRecordNotFound {
+			return nil, fmt.Errorf("知识库不存在")
+		}
+		return nil, err
+	}
+	return &base, nil
+}
+
+// CreateBase 创建知识库
+func (s *DefaultKnowledgeService) CreateBase(ctx context.Context, base *models.KnowledgeBase) error {
+	if err := validateKnowledgeBase(base); err != nil {
+		return err
+	}
+	if err := s.db.WithContext(ctx).Create(base).Error; err != nil {
+		s.logger.Error("创建知识库失败: %v", err)
+		return err
+	}
+
+	s.logger.Info("创建知识库成功: %s (ID: %d)", base.Name, base.ID)
+	return nil
+}
+
+// UpdateBase 更新知识库
+func (s *DefaultKnowledgeService) UpdateBase(ctx context.Context, base *models.KnowledgeBase) error {
+	if err := validateKnowledgeBase(base); err != nil {
+		return err
+	}
+	if err := s.db.WithContext(ctx).Save(base).Error; err != nil {
+		s.logger.Error("更新知识库失败: %v", err)
+		return err
+	}
+
+	s.logger.Info("更新知识库成功: %s (ID: %d)", base.Name, base.ID)
+	return nil
+}
+
+// DeleteBase 删除知识库
+func (s *DefaultKnowledgeService) DeleteBase(ctx context.Context, id uint) error {
+	err := s.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		result := tx.Delete(&models.KnowledgeBase{}, id)
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected == 0 {
+			return fmt.Errorf("知识库不存在")
+		}
+		if err := tx.Where("knowledge_base_id = ?", id).Delete(&models.KnowledgeChunk{}).Error; err != nil {
+			return err
+		}
+		return tx.Where("knowledge_base_id = ?", id).Delete(&models.KnowledgeDocument{}).Error
+	})
+	if err != nil {
+		s.logger.Error("删除知识库失败: %v", err)
+		return err
+	}
+
+	s.logger.Info("删除知识库成功 (ID: %d)", id)
+	return nil
+}
+
+// ListDocuments 列出知识库中的文档
+func (s *DefaultKnowledgeService) ListDocuments(ctx context.Context, baseID uint) ([]*models.KnowledgeDocument, error) {
+	var documents []*models.KnowledgeDocument
+	err := s.db.WithContext(ctx).Where("knowledge_base_id = ?", baseID).Order("id ASC").Find(&documents).Error
+	if err != nil {
+		return nil, err
+	}
+	return documents, nil
+}
+
+// AddDocument 上传文档
+func (s *DefaultKnowledgeService) AddDocument(ctx context.Context, baseID uint, req *models.KnowledgeDocumentRequest) (*models.KnowledgeDocument, error) {
+	if s.embedder == nil {
+		return nil, ErrKnowledgeDisabled
+	}
+	base, err := s.GetBase(ctx, baseID)
+	if err != nil {
+		return nil, err
+	}
+	if base.EmbeddingModel != "" && base.EmbeddingModel != s.embedder.Model() {
+		return nil, fmt.Errorf("知识库使用的向量模型 %s 与当前配置的 %s 不一致，请新建知识库", base.EmbeddingModel, s.embedder.Model())
+	}
+
+	format, err := knowledgeFormat(req.Format, req.Title)
+	if err != nil {
+		return nil, err
+	}
+	if !utf8.ValidString(req.Content) {
+		return nil, fmt.Errorf("文档内容必须是UTF-8编码的文本")
+	}
+	size := utf8.RuneCountInString(req.Content)
+	maxSize := s.config.Knowledge.MaxDocumentSize
+	if maxSize <= 0 {
+		maxSize = defaultKnowledgeMaxDocument
+	}
+	if size > maxSize {
+		return nil, fmt.Errorf("文档超过 %d 个字符", maxSize)
+	}
+
+	chunkSize, overlap := s.config.Knowledge.ChunkSize, s.config.Knowledge.ChunkOverlap
+	if chunkSize <= 0 {
+		chunkSize, overlap = knowledge.DefaultChunkSize, knowledge.DefaultChunkOverlap
+	}
+	texts := knowledge.Split(knowledge.Prepare(format, req.Content), chunkSize, overlap)
+	if len(texts) == 0 {
+		return nil, fmt.Errorf("文档内容为空")
+	}
+	vectors, err := s.embedder.Embed(ctx, texts)
+	if err != nil {
+		s.logger.Error("文档 %s 生成向量失败: %v", req.Title, err)
+		return nil, err
+	}
+
+	document := &models.KnowledgeDocument{
+		KnowledgeBaseID: base.ID,
+		Title:           strings.TrimSpace(req.Title),
+		Format:          format,
+		Size:            size,
+		ChunkCount:      len(texts),
+	}
+	err = s.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := tx.Create(document).Error; err != nil {
+			return err
+		}
+		chunks := make([]*models.KnowledgeChunk, len(texts))
+		for i, text := range texts {
+			chunks[i] = &models.KnowledgeChunk{
+				KnowledgeBaseID: base.ID,
+				DocumentID:      document.ID,
+				Seq:             i,
+				Content:         text,
+				Vector:          knowledge.EncodeVector(vectors[i]),
+			}
+		}
+		if err := tx.CreateInBatches(chunks, 100).Error; err != nil {
+			return err
+		}
+		if base.EmbeddingModel == "" {
+			return tx.Model(base).Update("embedding_model", s.embedder.Model()).Error
+		}
+		return nil
+	})
+	if err != nil {
+		s.logger.Error("保存文档失败: %v", err)
+		return nil, err
+	}
+
+	s.logger.Info("知识库 %s 添加文档: %s，共 %d 个文本块", base.Name, document.Title, len(texts))
+	return document, nil
+}
+
+// DeleteDocument 删除文档及其文本块
+func (s *DefaultKnowledgeService) DeleteDocument(ctx context.Context, baseID, documentID uint) error {
+	err := s.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		result := tx.Where("knowledge_base_id = ?", baseID).Delete(&models.KnowledgeDocument{}, documentID)
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected == 0 {
+			return fmt.Errorf("文档不存在")
+		}
+		return tx.Where("document_id = ?", documentID).Delete(&models.KnowledgeChunk{}).Error
+	})
+	if err != nil {
+		s.logger.Error("删除文档失败: %v", err)
+		return err
+	}
+
+	s.logger.Info("删除文档成功 (ID: %d)", documentID)
+	return nil
+}
+
+// Search 检索知识库
+// 文本块向量全部读入内存计算相似度，适用于产品资料等规模较小的知识库
+func (s *DefaultKnowledgeService) Search(ctx context.Context, scope KnowledgeScope, query string, topK int) ([]*models.KnowledgeSearchResult, error) {
+	if s.embedder == nil {
+		return nil, ErrKnowledgeDisabled
+	}
+	query = strings.TrimSpace(query)
+	if query == "" {
+		return nil, nil
+	}
+	if topK <= 0 {
+		topK = s.config.Knowledge.TopK
+	}
+	if topK <= 0 {
+		topK = defaultKnowledgeTopK
+	}
+	topK = min(topK, maxKnowledgeTopK)
+
+	var bases []*models.KnowledgeBase
+	err := s.db.WithContext(ctx).
+		Where("user_id = ? OR user_id = ?", "", scope.UserID).
+		Where("persona = ? OR persona = ?", "", scope.Persona).
+		Where("embedding_model = ?", s.embedder.Model()).
+		Find(&bases).Error
+	if err != nil {
+		return nil, err
+	}
+	if len(bases) == 0 {
+		return nil, nil
+	}
+	baseNames := make(map[uint]string, len(bases))
+	baseIDs := make([]uint, 0, len(bases))
+	for _, base := range bases {
+		baseNames[base.ID] = base.Name
+		baseIDs = append(baseIDs, base.ID)
+	}
+
+	vectors, err := s.embedder.Embed(ctx, []string{query})
+	if err != nil {
+		return nil, err
+	}
+	queryVector := vectors[0]
+
+	var candidates []*models.KnowledgeChunk
+	err = s.db.WithContext(ctx).Select("id", "vector").
+		Where("knowledge_base_id IN ?", baseIDs).
+		Find(&candidates).Error
+	if err != nil {
+		return nil, err
+	}
+
+	type scored struct {
+		id    uint
+		score float64
+	}
+	ranked := make([]scored, 0, len(candidates))
+	for _, c := range candidates {
+		score := knowledge.Cosine(queryVector, knowledge.DecodeVector(c.Vector))
+		if score >= s.config.Knowledge.MinScore {
+			ranked = append(ranked, scored{id: c.ID, score: score})
+		}
+	}
+	sort.Slice(ranked, func(i, j int) bool { return ranked[i].score > ranked[j].score })
+	if len(ranked) > topK {
+		ranked = ranked[:topK]
+	}
+	if len(ranked) == 0 {
+		return nil, nil
+	}
+
+	ids := make([]uint, len(ranked))
+	for i, r := range ranked {
+		ids[i] = r.id
+	}
+	var chunks []*models.KnowledgeChunk
+	err = s.db.WithContext(ctx).Select("id", "knowledge_base_id", "document_id", "content").
+		Where("id IN ?", ids).
+		Find(&chunks).Error
+	if err != nil {
+		return nil, err
+	}
+	chunkByID := make(map[uint]*models.KnowledgeChunk, len(chunks))
+	documentIDs := make([]uint, 0, len(chunks))
+	for _, c := range chunks {
+		chunkByID[c.ID] = c
+		documentIDs = append(documentIDs, c.DocumentID)
+	}
+	var documents []*models.KnowledgeDocument
+	if err := s.db.WithContext(ctx).Where("id IN ?", documentIDs).Find(&documents).Error; err != nil {
+		return nil, err
+	}
+	titles := make(map[uint]string, len(documents))
+	for _, d := range documents {
+		titles[d.ID] = d.Title
+	}
+
+	results := make([]*models.KnowledgeSearchResult, 0, len(ranked))
+	for _, r := range ranked {
+		c, ok := chunkByID[r.id]
+		if !ok {
+			continue
+		}
+		results = append(results, &models.KnowledgeSearchResult{
+			KnowledgeBase: baseNames[c.KnowledgeBaseID],
+			Document:      titles[c.DocumentID],
+			Content:       c.Content,
+			Score:         r.score,
+		})
+	}
+	return results, nil
+}
+
+// validateKnowledgeBase 校验知识库字段
+func validateKnowledgeBase(base *models.KnowledgeBase) error {
+	base.Name = strings.TrimSpace(base.Name)
+	base.Persona = strings.TrimSpace(base.Persona)
+	if base.Name == "" {
+		return fmt.Errorf("知识库名称不能为空")
+	}
+	if utf8.RuneCountInString(base.Name) > maxKnowledgeNameRunes {
+		return fmt.Errorf("知识库名称不能超过 %d 个字符", maxKnowledgeNameRunes)
+	}
+	if utf8.RuneCountInString(base.Description) > 256 {
+		return fmt.Errorf("知识库描述不能超过 256 个字符")
+	}
+	return nil
+}
+
+// knowledgeFormat 确定文档格式，未指定时按标题扩展名判断
+func knowledgeFormat(format, title string) (string, error) {
+	switch strings.ToLower(strings.TrimSpace(format)) {
+	case models.KnowledgeFormatMarkdown, "md":
+		return models.KnowledgeFormatMarkdown, nil
+	case models.KnowledgeFormatText, "txt":
+		return models.KnowledgeFormatText, nil
+	case models.KnowledgeFormatPDF:
+		return models.KnowledgeFormatPDF, nil
+	case "":
+	default:
+		return "", fmt.Errorf("不支持的文档格式: %s", format)
+	}
+
+	switch strings.ToLower(path.Ext(title)) {
+	case ".md", ".markdown":
+		return models.KnowledgeFormatMarkdown, nil
+	case ".pdf":
+		return models.KnowledgeFormatPDF, nil
+	default:
+		return models.KnowledgeFormatText, nil
+	}
+}
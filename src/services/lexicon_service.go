@@ -0,0 +1,177 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"angrymiao-ai-server/src/core/lexicon"
+	"angrymiao-ai-server/src/core/utils"
+	"angrymiao-ai-server/src/models"
+
+	"gorm.io/gorm"
+)
+
+// LexiconService 发音词典服务接口
+type LexiconService interface {
+	// CRUD操作
+	ListEntries(ctx context.Context, role string) ([]*models.LexiconEntry, error)
+	GetEntryByID(ctx context.Context, id uint) (*models.LexiconEntry, error)
+	CreateEntry(ctx context.Context, entry *models.LexiconEntry) error
+	UpdateEntry(ctx context.Context, entry *models.LexiconEntry) error
+	DeleteEntry(ctx context.Context, id uint) error
+
+	// GetEffectiveEntries 获取角色下生效的词条，角色词条覆盖同名全局词条
+	GetEffectiveEntries(ctx context.Context, role string) ([]lexicon.Entry, error)
+}
+
+// DefaultLexiconService 默认发音词典服务实现
+type DefaultLexiconService struct {
+	db     *gorm.DB
+	logger *utils.Logger
+}
+
+// NewLexiconService 创建发音词典服务实例
+func NewLexiconService(db *gorm.DB, logger *utils.Logger) LexiconService {
+	return &DefaultLexiconService{
+		db:     db,
+		logger: logger,
+	}
+}
+
+// ListEntries 列出词条，role为 "*" 时返回全部，否则只返回该角色的词条（空为全局）
+func (s *DefaultLexiconService) ListEntries(ctx context.Context, role string) ([]*models.LexiconEntry, error) {
+	var entries []*models.LexiconEntry
+	query := s.db.WithContext(ctx).Order("role ASC, word ASC")
+	if role != "*" {
+		query = query.Where("role = ?", role)
+	}
+	if err := query.Find(&entries).Error; err != nil {
+		return nil, err
+	}
+	return entries, nil
+}
+
+// GetEntryByID 根据ID获取词条
+func (s *DefaultLexiconService) GetEntryByID(ctx context.Context, id uint) (*models.LexiconEntry, error) {
+	var entry models.LexiconEntry
+	if err := s.db.WithContext(ctx).First(&entry, id).Error; err != nil {
+		if err == gorm.ErrRecordNotFound {
+			return nil, fmt.Errorf("词条不存在")
+		}
+		return nil, err
+	}
+	return &entry, nil
+}
+
+// CreateEntry 创建词条
+func (s *DefaultLexiconService) CreateEntry(ctx context.Context, entry *models.LexiconEntry) error {
+	if err := s.validateEntry(ctx, entry); err != nil {
+		return err
+	}
+
+	isActive := entry.IsActive
+	if err := s.db.WithContext(ctx).Create(entry).Error; err != nil {
+		s.logger.Error("创建发音词条失败: %v", err)
+		return err
+	}
+	// 字段默认值为true，创建后再写入停用状态
+	if !isActive {
+		if err := s.db.WithContext(ctx).Model(entry).Update("is_active", false).Error; err != nil {
+			return err
+		}
+	}
+
+	s.logger.Info("创建发音词条成功: %s (角色: %s, ID: %d)", entry.Word, entry.Role, entry.ID)
+	return nil
+}
+
+// UpdateEntry 更新词条
+func (s *DefaultLexiconService) UpdateEntry(ctx context.Context, entry *models.LexiconEntry) error {
+	if err := s.validateEntry(ctx, entry); err != nil {
+		return err
+	}
+
+	if err := s.db.WithContext(ctx).Save(entry).Error; err != nil {
+		s.logger.Error("更新发音词条失败: %v", err)
+		return err
+	}
+
+	s.logger.Info("更新发音词条成功: %s (角色: %s, ID: %d)", entry.Word, entry.Role, entry.ID)
+	return nil
+}
+
+// DeleteEntry 删除词条
+func (s *DefaultLexiconService) DeleteEntry(ctx context.Context, id uint) error {
+	result := s.db.WithContext(ctx).Delete(&models.LexiconEntry{}, id)
+	if result.Error != nil {
+		s.logger.Error("删除发音词条失败: %v", result.Error)
+		return result.Error
+	}
+
+	if result.RowsAffected == 0 {
+		return fmt.Errorf("词条不存在")
+	}
+
+	s.logger.Info("删除发音词条成功 (ID: %d)", id)
+	return nil
+}
+
+// GetEffectiveEntries 获取角色下生效的词条
+func (s *DefaultLexiconService) GetEffectiveEntries(ctx context.Context, role string) ([]lexicon.Entry, error) {
+	var rows []*models.LexiconEntry
+	query := s.db.WithContext(ctx).Where("is_active = ?", true)
+	if role != "" {
+		query = query.Where("role = ? OR role = ?", "", role)
+	} else {
+		query = query.Where("role = ?", "")
+	}
+	if err := query.Find(&rows).Error; err != nil {
+		return nil, err
+	}
+
+	// 角色词条覆盖同名全局词条
+	merged := make(map[string]*models.LexiconEntry, len(rows))
+	for _, row := range rows {
+		key := strings.ToLower(row.Word)
+		if existing, ok := merged[key]; ok && existing.Role != "" && row.Role == "" {
+			continue
+		}
+		merged[key] = row
+	}
+
+	entries := make([]lexicon.Entry, 0, len(merged))
+	for _, row := range merged {
+		entries = append(entries, lexicon.Entry{
+			Word:     row.Word,
+			Alias:    row.Alias,
+			Phoneme:  row.Phoneme,
+			Alphabet: row.Alphabet,
+		})
+	}
+	return entries, nil
+}
+
+// validateEntry 校验词条
+func (s *DefaultLexiconService) validateEntry(ctx context.Context, entry *models.LexiconEntry) error {
+	entry.Word = strings.TrimSpace(entry.Word)
+	entry.Role = strings.TrimSpace(entry.Role)
+	if entry.Word == "" {
+		return fmt.Errorf("词条不能为空")
+	}
+	if entry.Alias == "" && entry.Phoneme == "" {
+		return fmt.Errorf("别名和音标至少需要填写一项")
+	}
+
+	var count int64
+	err := s.db.WithContext(ctx).Model(&models.LexiconEntry{}).
+		Where("LOWER(word) = ? AND role = ? AND id <> ?", strings.ToLower(entry.Word), entry.Role, entry.ID).
+		Count(&count).Error
+	if err != nil {
+		return err
+	}
+	if count > 0 {
+		return fmt.Errorf("词条 '%s' 在该角色下已存在", entry.Word)
+	}
+	return nil
+}
@@ -0,0 +1,131 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"angrymiao-ai-server/src/core/utils"
+	"angrymiao-ai-server/src/models"
+
+	"gorm.io/gorm"
+)
+
+// MeetingService 会议记录服务接口
+type MeetingService interface {
+	SaveMeeting(ctx context.Context, record *models.MeetingRecord) error
+
+	// 查询和管理，只能访问用户自己的会议记录
+	ListMeetings(ctx context.Context, userID string, page, pageSize int) ([]*models.MeetingRecord, int64, error)
+	GetMeeting(ctx context.Context, userID, meetingID string) (*models.MeetingRecord, error)
+	DeleteMeeting(ctx context.Context, userID, meetingID string) error
+}
+
+// DefaultMeetingService 默认会议记录服务实现
+type DefaultMeetingService struct {
+	db     *gorm.DB
+	logger *utils.Logger
+}
+
+// NewMeetingService 创建会议记录服务实例
+func NewMeetingService(db *gorm.DB, logger *utils.Logger) MeetingService {
+	return &DefaultMeetingService{
+		db:     db,
+		logger: logger,
+	}
+}
+
+// SaveMeeting 保存会议记录，未设置标题时取纪要的第一行
+func (s *DefaultMeetingService) SaveMeeting(ctx context.Context, record *models.MeetingRecord) error {
+	if record.MeetingID == "" {
+		return fmt.Errorf("会议ID不能为空")
+	}
+	if record.Title == "" && record.Summary != "" {
+		record.Title = truncateTitle(strings.SplitN(record.Summary, "\n", 2)[0])
+	}
+	return s.db.WithContext(ctx).Create(record).Error
+}
+
+// ListMeetings 分页获取用户的会议记录，按开始时间倒序，不包含完整记录
+func (s *DefaultMeetingService) ListMeetings(ctx context.Context, userID string, page, pageSize int) ([]*models.MeetingRecord, int64, error) {
+	if page <= 0 {
+		page = 1
+	}
+	if pageSize <= 0 || pageSize > 100 {
+		pageSize = 20
+	}
+
+	query := s.db.WithContext(ctx).
+		Model(&models.MeetingRecord{}).
+		Where("user_id = ?", userID)
+
+	var total int64
+	if err := query.Count(&total).Error; err != nil {
+		return nil, 0, err
+	}
+
+	var records []*models.MeetingRecord
+	err := query.Omit("transcript").
+		Order("started_at DESC").
+		Offset((page - 1) * pageSize).
+		Limit(pageSize).
+		Find(&records).Error
+	if err != nil {
+		return nil, 0, err
+	}
+	return records, total, nil
+}
+
+// GetMeeting 获取用户的会议记录
+func (s *DefaultMeetingService) GetMeeting(ctx context.Context, userID, meetingID string) (*models.MeetingRecord, error) {
+	var record models.MeetingRecord
+	err := s.db.WithContext(ctx).
+		Where("meeting_id = ? AND user_id = ?", meetingID, userID).
+		First(&record).Error
+	if err != nil {
+		if err == gorm.ErrRecordNotFound {
+			return nil, fmt.Errorf("会议记录不存在")
+		}
+		return nil, err
+	}
+	return &record, nil
+}
+
+// DeleteMeeting 删除用户的会议记录
+func (s *DefaultMeetingService) DeleteMeeting(ctx context.Context, userID, meetingID string) error {
+	result := s.db.WithContext(ctx).
+		Where("meeting_id = ? AND user_id = ?", meetingID, userID).
+		Delete(&models.MeetingRecord{})
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return fmt.Errorf("会议记录不存在")
+	}
+	return nil
+}
+
+// RenderMeetingMarkdown 将会议记录导出为Markdown文本
+func RenderMeetingMarkdown(record *models.MeetingRecord) string {
+	var b strings.Builder
+
+	title := record.Title
+	if title == "" {
+		title = "会议记录"
+	}
+	fmt.Fprintf(&b, "# %s\n\n", title)
+	fmt.Fprintf(&b, "- 开始时间: %s\n", record.StartedAt.Format("2006-01-02 15:04:05"))
+	fmt.Fprintf(&b, "- 结束时间: %s\n", record.EndedAt.Format("2006-01-02 15:04:05"))
+	fmt.Fprintf(&b, "- 时长: %d分钟\n", (record.DurationSec+59)/60)
+
+	if record.Summary != "" {
+		fmt.Fprintf(&b, "\n## 会议纪要\n\n%s\n", record.Summary)
+	}
+	b.WriteString("\n## 完整记录\n\n")
+	for _, line := range strings.Split(record.Transcript, "\n") {
+		if strings.TrimSpace(line) != "" {
+			fmt.Fprintf(&b, "%s\n\n", line)
+		}
+	}
+	return b.String()
+}
@@ -0,0 +1,161 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"angrymiao-ai-server/src/core/utils"
+	"angrymiao-ai-server/src/models"
+
+	"gorm.io/gorm"
+)
+
+// 每个用户最多保存的记忆条数
+const defaultMaxMemories = 200
+
+// MemoryService 用户长期记忆服务接口
+type MemoryService interface {
+	ListMemories(ctx context.Context, userID string) ([]*models.UserMemory, error)
+	DeleteMemory(ctx context.Context, userID string, id uint) error
+	ClearMemories(ctx context.Context, userID string) error
+
+	// AddMemories 保存新提取的记忆，忽略与已有记忆重复的内容，超出上限时淘汰最不重要的旧记忆
+	AddMemories(ctx context.Context, userID string, memories []*models.UserMemory) (int, error)
+}
+
+// DefaultMemoryService 默认用户长期记忆服务实现
+type DefaultMemoryService struct {
+	db          *gorm.DB
+	logger      *utils.Logger
+	maxMemories int
+}
+
+// NewMemoryService 创建用户长期记忆服务实例
+func NewMemoryService(db *gorm.DB, logger *utils.Logger) MemoryService {
+	return &DefaultMemoryService{
+		db:          db,
+		logger:      logger,
+		maxMemories: defaultMaxMemories,
+	}
+}
+
+// ListMemories 获取用户的全部记忆，按更新时间倒序
+func (s *DefaultMemoryService) ListMemories(ctx context.Context, userID string) ([]*models.UserMemory, error) {
+	var memories []*models.UserMemory
+	err := s.db.WithContext(ctx).
+		Where("user_id = ?", userID).
+		Order("updated_at DESC").
+		Find(&memories).Error
+	if err != nil {
+		return nil, err
+	}
+	return memories, nil
+}
+
+// DeleteMemory 删除用户的一条记忆
+func (s *DefaultMemoryService) DeleteMemory(ctx context.Context, userID string, id uint) error {
+	result := s.db.WithContext(ctx).
+		Where("id = ? AND user_id = ?", id, userID).
+		Delete(&models.UserMemory{})
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return fmt.Errorf("记忆不存在")
+	}
+	return nil
+}
+
+// ClearMemories 清空用户的全部记忆
+func (s *DefaultMemoryService) ClearMemories(ctx context.Context, userID string) error {
+	return s.db.WithContext(ctx).
+		Where("user_id = ?", userID).
+		Delete(&models.UserMemory{}).Error
+}
+
+// AddMemories 保存新提取的记忆，返回实际新增的条数
+func (s *DefaultMemoryService) AddMemories(ctx context.Context, userID string, memories []*models.UserMemory) (int, error) {
+	if userID == "" {
+		return 0, fmt.Errorf("用户ID不能为空")
+	}
+
+	existing, err := s.ListMemories(ctx, userID)
+	if err != nil {
+		return 0, err
+	}
+	seen := make(map[string]bool, len(existing))
+	for _, m := range existing {
+		seen[normalizeMemoryContent(m.Content)] = true
+	}
+
+	var added []*models.UserMemory
+	for _, m := range memories {
+		key := normalizeMemoryContent(m.Content)
+		if key == "" || seen[key] {
+			continue
+		}
+		seen[key] = true
+
+		m.ID = 0
+		m.UserID = userID
+		m.Content = strings.TrimSpace(m.Content)
+		if m.Category == "" {
+			m.Category = models.MemoryCategoryFact
+		}
+		if m.Importance < 1 || m.Importance > 5 {
+			m.Importance = 1
+		}
+		added = append(added, m)
+	}
+	if len(added) == 0 {
+		return 0, nil
+	}
+
+	err = s.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := tx.Create(&added).Error; err != nil {
+			return err
+		}
+		return s.evict(tx, userID)
+	})
+	if err != nil {
+		return 0, err
+	}
+	return len(added), nil
+}
+
+// evict 超出上限时淘汰重要程度最低、最早的记忆
+func (s *DefaultMemoryService) evict(tx *gorm.DB, userID string) error {
+	var count int64
+	if err := tx.Model(&models.UserMemory{}).Where("user_id = ?", userID).Count(&count).Error; err != nil {
+		return err
+	}
+	overflow := int(count) - s.maxMemories
+	if overflow <= 0 {
+		return nil
+	}
+
+	var ids []uint
+	err := tx.Model(&models.UserMemory{}).
+		Where("user_id = ?", userID).
+		Order("importance ASC, updated_at ASC").
+		Limit(overflow).
+		Pluck("id", &ids).Error
+	if err != nil {
+		return err
+	}
+	s.logger.Info("用户 %s 记忆超出上限，淘汰 %d 条", userID, len(ids))
+	return tx.Where("id IN ?", ids).Delete(&models.UserMemory{}).Error
+}
+
+// normalizeMemoryContent 用于去重的记忆内容，忽略空白和标点
+func normalizeMemoryContent(content string) string {
+	var b strings.Builder
+	for _, r := range strings.ToLower(content) {
+		if strings.ContainsRune(" \t\r\n，。！？、；：,.!?;:\"'“”‘’", r) {
+			continue
+		}
+		b.WriteRune(r)
+	}
+	return b.String()
+}
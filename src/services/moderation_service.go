@@ -0,0 +1,78 @@
+package services
+
+import (
+	"context"
+
+	"angrymiao-ai-server/src/core/utils"
+	"angrymiao-ai-server/src/models"
+
+	"gorm.io/gorm"
+)
+
+// ModerationService 内容审核审计服务接口
+type ModerationService interface {
+	// RecordEvent 记录一次命中审核的事件
+	RecordEvent(ctx context.Context, event *models.ModerationEvent) error
+	// ListEvents 分页查询审核事件，按时间倒序
+	ListEvents(ctx context.Context, query *models.ModerationEventQuery) ([]*models.ModerationEvent, int64, error)
+}
+
+// DefaultModerationService 默认内容审核审计服务实现
+type DefaultModerationService struct {
+	db     *gorm.DB
+	logger *utils.Logger
+}
+
+// NewModerationService 创建内容审核审计服务实例
+func NewModerationService(db *gorm.DB, logger *utils.Logger) ModerationService {
+	return &DefaultModerationService{
+		db:     db,
+		logger: logger,
+	}
+}
+
+// RecordEvent 记录审核事件
+func (s *DefaultModerationService) RecordEvent(ctx context.Context, event *models.ModerationEvent) error {
+	if err := s.db.WithContext(ctx).Create(event).Error; err != nil {
+		s.logger.Error("保存审核事件失败: %v", err)
+		return err
+	}
+	return nil
+}
+
+// ListEvents 查询审核事件
+func (s *DefaultModerationService) ListEvents(ctx context.Context, query *models.ModerationEventQuery) ([]*models.ModerationEvent, int64, error) {
+	page, pageSize := query.Page, query.PageSize
+	if page <= 0 {
+		page = 1
+	}
+	if pageSize <= 0 || pageSize > 100 {
+		pageSize = 20
+	}
+
+	db := s.db.WithContext(ctx).Model(&models.ModerationEvent{})
+	if query.UserID != "" {
+		db = db.Where("user_id = ?", query.UserID)
+	}
+	if query.DeviceID != "" {
+		db = db.Where("device_id = ?", query.DeviceID)
+	}
+	if query.Direction != "" {
+		db = db.Where("direction = ?", query.Direction)
+	}
+
+	var total int64
+	if err := db.Count(&total).Error; err != nil {
+		return nil, 0, err
+	}
+
+	var events []*models.ModerationEvent
+	err := db.Order("id DESC").
+		Offset((page - 1) * pageSize).
+		Limit(pageSize).
+		Find(&events).Error
+	if err != nil {
+		return nil, 0, err
+	}
+	return events, total, nil
+}
@@ -0,0 +1,345 @@
+package services
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+	"unicode/utf8"
+
+	"angrymiao-ai-server/src/configs"
+	"angrymiao-ai-server/src/core/prompt"
+	"angrymiao-ai-server/src/core/utils"
+	"angrymiao-ai-server/src/models"
+
+	"gorm.io/gorm"
+)
+
+const (
+	maxPersonaNameRunes     = 64
+	maxPersonaGreetingRunes = 512
+)
+
+// 旧版按角色名写死的edge音色，从配置导入角色时写入所有edge类型的TTS
+var legacyEdgeRoleVoices = map[string]string{
+	"陕西女友":  "zh-CN-shaanxi-XiaoniNeural",
+	"英语老师":  "zh-CN-XiaoyiNeural",
+	"好奇小男孩": "zh-CN-YunxiNeural",
+}
+
+// PersonaService 角色服务接口
+type PersonaService interface {
+	// CRUD操作
+	ListPersonas(ctx context.Context) ([]*models.Persona, error)
+	GetPersonaByID(ctx context.Context, id uint) (*models.Persona, error)
+	CreatePersona(ctx context.Context, persona *models.Persona) error
+	UpdatePersona(ctx context.Context, persona *models.Persona) error
+	DeletePersona(ctx context.Context, id uint) error
+
+	// ListActivePersonas 列出启用的角色
+	ListActivePersonas(ctx context.Context) ([]*models.Persona, error)
+	// GetActivePersona 按名称获取启用的角色，不存在时返回nil
+	GetActivePersona(ctx context.Context, name string) (*models.Persona, error)
+	// GetDefaultPersona 获取新连接的默认角色，未设置时返回nil
+	GetDefaultPersona(ctx context.Context) (*models.Persona, error)
+
+	// SeedFromConfig 角色表为空时从配置的角色列表导入
+	SeedFromConfig(ctx context.Context) error
+}
+
+// DefaultPersonaService 默认角色服务实现
+type DefaultPersonaService struct {
+	db     *gorm.DB
+	config *configs.Config
+	logger *utils.Logger
+}
+
+// NewPersonaService 创建角色服务实例
+func NewPersonaService(db *gorm.DB, config *configs.Config, logger *utils.Logger) PersonaService {
+	return &DefaultPersonaService{
+		db:     db,
+		config: config,
+		logger: logger,
+	}
+}
+
+// ListPersonas 列出全部角色
+func (s *DefaultPersonaService) ListPersonas(ctx context.Context) ([]*models.Persona, error) {
+	var personas []*models.Persona
+	if err := s.db.WithContext(ctx).Order("id ASC").Find(&personas).Error; err != nil {
+		return nil, err
+	}
+	return personas, nil
+}
+
+// ListActivePersonas 列出启用的角色
+func (s *DefaultPersonaService) ListActivePersonas(ctx context.Context) ([]*models.Persona, error) {
+	var personas []*models.Persona
+	err := s.db.WithContext(ctx).Where("is_active = ?", true).Order("id ASC").Find(&personas).Error
+	if err != nil {
+		return nil, err
+	}
+	return personas, nil
+}
+
+// GetPersonaByID 根据ID获取角色
+func (s *DefaultPersonaService) GetPersonaByID(ctx context.Context, id uint) (*models.Persona, error) {
+	var persona models.Persona
+	if err := s.db.WithContext(ctx).First(&persona, id).Error; err != nil {
+		if err == gorm.ErrRecordNotFound {
+			return nil, fmt.Errorf("角色不存在")
+		}
+		return nil, err
+	}
+	return &persona, nil
+}
+
+// GetActivePersona 按名称获取启用的角色
+func (s *DefaultPersonaService) GetActivePersona(ctx context.Context, name string) (*models.Persona, error) {
+	return s.findOne(ctx, "name = ? AND is_active = ?", strings.TrimSpace(name), true)
+}
+
+// GetDefaultPersona 获取新连接的默认角色
+func (s *DefaultPersonaService) GetDefaultPersona(ctx context.Context) (*models.Persona, error) {
+	return s.findOne(ctx, "is_default = ? AND is_active = ?", true, true)
+}
+
+// findOne 查询单个角色，不存在时返回nil
+func (s *DefaultPersonaService) findOne(ctx context.Context, query string, args ...interface{}) (*models.Persona, error) {
+	var persona models.Persona
+	if err := s.db.WithContext(ctx).Where(query, args...).First(&persona).Error; err != nil {
+		if err == gorm.ErrRecordNotFound {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return &persona, nil
+}
+
+// CreatePersona 创建角色
+func (s *DefaultPersonaService) CreatePersona(ctx context.Context, persona *models.Persona) error {
+	if err := s.validatePersona(ctx, persona); err != nil {
+		return err
+	}
+
+	isActive := persona.IsActive
+	err := s.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := tx.Create(persona).Error; err != nil {
+			return err
+		}
+		// 字段默认值为true，创建后再写入停用状态
+		if !isActive {
+			if err := tx.Model(persona).Update("is_active", false).Error; err != nil {
+				return err
+			}
+		}
+		return s.clearOtherDefaults(tx, persona)
+	})
+	if err != nil {
+		s.logger.Error("创建角色失败: %v", err)
+		return err
+	}
+
+	s.logger.Info("创建角色成功: %s (ID: %d)", persona.Name, persona.ID)
+	return nil
+}
+
+// UpdatePersona 更新角色
+func (s *DefaultPersonaService) UpdatePersona(ctx context.Context, persona *models.Persona) error {
+	if err := s.validatePersona(ctx, persona); err != nil {
+		return err
+	}
+
+	err := s.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := tx.Save(persona).Error; err != nil {
+			return err
+		}
+		return s.clearOtherDefaults(tx, persona)
+	})
+	if err != nil {
+		s.logger.Error("更新角色失败: %v", err)
+		return err
+	}
+
+	s.logger.Info("更新角色成功: %s (ID: %d)", persona.Name, persona.ID)
+	return nil
+}
+
+// DeletePersona 删除角色
+func (s *DefaultPersonaService) DeletePersona(ctx context.Context, id uint) error {
+	result := s.db.WithContext(ctx).Delete(&models.Persona{}, id)
+	if result.Error != nil {
+		s.logger.Error("删除角色失败: %v", result.Error)
+		return result.Error
+	}
+
+	if result.RowsAffected == 0 {
+		return fmt.Errorf("角色不存在")
+	}
+
+	s.logger.Info("删除角色成功 (ID: %d)", id)
+	return nil
+}
+
+// clearOtherDefaults 只保留一个默认角色
+func (s *DefaultPersonaService) clearOtherDefaults(tx *gorm.DB, persona *models.Persona) error {
+	if !persona.IsDefault {
+		return nil
+	}
+	return tx.Model(&models.Persona{}).
+		Where("id <> ? AND is_default = ?", persona.ID, true).
+		Update("is_default", false).Error
+}
+
+// SeedFromConfig 角色表为空时从配置的角色列表导入，配置格式为 角色名称@提示词
+func (s *DefaultPersonaService) SeedFromConfig(ctx context.Context) error {
+	var count int64
+	if err := s.db.WithContext(ctx).Model(&models.Persona{}).Count(&count).Error; err != nil {
+		return err
+	}
+	if count > 0 || len(s.config.Roles) == 0 {
+		return nil
+	}
+
+	for _, role := range s.config.Roles {
+		name, text, ok := strings.Cut(role, "@")
+		if !ok {
+			s.logger.Warn("忽略格式错误的角色配置: %s", role)
+			continue
+		}
+		persona := &models.Persona{
+			Name:     strings.TrimSpace(name),
+			Prompt:   text,
+			IsActive: true,
+		}
+		if voice, ok := legacyEdgeRoleVoices[persona.Name]; ok {
+			voices := map[string]string{}
+			for ttsName, ttsCfg := range s.config.TTS {
+				if ttsCfg.Type == "edge" {
+					voices[ttsName] = voice
+				}
+			}
+			persona.Voices = marshalPersonaField(voices)
+		}
+		if err := s.CreatePersona(ctx, persona); err != nil {
+			s.logger.Error("导入配置角色 %s 失败: %v", persona.Name, err)
+		}
+	}
+	return nil
+}
+
+// ApplyPersonaRequest 将请求内容写入角色
+func ApplyPersonaRequest(persona *models.Persona, req *models.PersonaRequest) {
+	persona.Name = req.Name
+	persona.Description = req.Description
+	persona.Prompt = req.Prompt
+	persona.Voices = marshalPersonaField(req.Voices)
+	persona.Greeting = req.Greeting
+	persona.AllowedTools = marshalPersonaField(req.AllowedTools)
+	persona.LLM = req.LLM
+	if req.IsDefault != nil {
+		persona.IsDefault = *req.IsDefault
+	}
+	if req.IsActive != nil {
+		persona.IsActive = *req.IsActive
+	}
+}
+
+// marshalPersonaField 序列化角色的JSON字段，空值存为空字符串
+func marshalPersonaField(v interface{}) string {
+	switch val := v.(type) {
+	case map[string]string:
+		if len(val) == 0 {
+			return ""
+		}
+	case []string:
+		if len(val) == 0 {
+			return ""
+		}
+	}
+	data, err := json.Marshal(v)
+	if err != nil {
+		return ""
+	}
+	return string(data)
+}
+
+// validatePersona 校验角色
+func (s *DefaultPersonaService) validatePersona(ctx context.Context, persona *models.Persona) error {
+	persona.Name = strings.TrimSpace(persona.Name)
+	persona.LLM = strings.TrimSpace(persona.LLM)
+	if persona.Name == "" {
+		return fmt.Errorf("角色名称不能为空")
+	}
+	if utf8.RuneCountInString(persona.Name) > maxPersonaNameRunes {
+		return fmt.Errorf("角色名称不能超过%d个字符", maxPersonaNameRunes)
+	}
+	if utf8.RuneCountInString(persona.Greeting) > maxPersonaGreetingRunes {
+		return fmt.Errorf("问候语不能超过%d个字符", maxPersonaGreetingRunes)
+	}
+	if strings.TrimSpace(persona.Prompt) == "" {
+		return fmt.Errorf("角色提示词不能为空")
+	}
+	if _, err := prompt.Parse(persona.Prompt); err != nil {
+		return err
+	}
+	if persona.LLM != "" {
+		if _, ok := s.config.LLM[persona.LLM]; !ok {
+			return fmt.Errorf("LLM配置不存在: %s", persona.LLM)
+		}
+	}
+	if persona.IsDefault && !persona.IsActive {
+		return fmt.Errorf("默认角色不能停用")
+	}
+
+	for provider, voice := range persona.VoiceMap() {
+		ttsCfg, ok := s.config.TTS[provider]
+		if !ok {
+			return fmt.Errorf("TTS提供者不存在: %s", provider)
+		}
+		if voice == "" {
+			return fmt.Errorf("TTS提供者 %s 的音色不能为空", provider)
+		}
+		if len(ttsCfg.SupportedVoices) > 0 && !supportsVoice(ttsCfg.SupportedVoices, voice) {
+			return fmt.Errorf("TTS提供者 %s 不支持音色: %s", provider, voice)
+		}
+	}
+
+	// 工具列表去重排序后保存
+	tools := persona.ToolList()
+	seen := make(map[string]bool, len(tools))
+	cleaned := make([]string, 0, len(tools))
+	for _, tool := range tools {
+		tool = strings.TrimSpace(tool)
+		if tool == "" || seen[tool] {
+			continue
+		}
+		seen[tool] = true
+		cleaned = append(cleaned, tool)
+	}
+	sort.Strings(cleaned)
+	persona.AllowedTools = marshalPersonaField(cleaned)
+
+	var count int64
+	err := s.db.WithContext(ctx).Model(&models.Persona{}).
+		Where("name = ? AND id <> ?", persona.Name, persona.ID).
+		Count(&count).Error
+	if err != nil {
+		return err
+	}
+	if count > 0 {
+		return fmt.Errorf("角色 '%s' 已存在", persona.Name)
+	}
+	return nil
+}
+
+// supportsVoice 判断音色是否在支持列表中
+func supportsVoice(voices []configs.VoiceInfo, voice string) bool {
+	for _, v := range voices {
+		if v.Name == voice {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,96 @@
+package services
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"unicode/utf8"
+
+	"angrymiao-ai-server/src/core/utils"
+	"angrymiao-ai-server/src/models"
+
+	"gorm.io/gorm"
+)
+
+const (
+	maxPromptFields     = 20  // 每个用户最多的自定义字段数
+	maxPromptFieldRunes = 200 // 自定义字段值的最大字符数
+	maxNicknameRunes    = 32
+)
+
+// 自定义字段名需能在模板中以 .Fields.字段名 引用
+var promptFieldNamePattern = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_]{0,31}$`)
+
+// PromptService 用户提示词变量服务接口
+type PromptService interface {
+	// GetProfile 获取用户的提示词变量，未设置时返回nil
+	GetProfile(ctx context.Context, userID string) (*models.UserPromptProfile, error)
+	SaveProfile(ctx context.Context, userID string, req *models.UpdatePromptProfileRequest) (*models.UserPromptProfile, error)
+}
+
+// DefaultPromptService 默认用户提示词变量服务实现
+type DefaultPromptService struct {
+	db     *gorm.DB
+	logger *utils.Logger
+}
+
+// NewPromptService 创建用户提示词变量服务实例
+func NewPromptService(db *gorm.DB, logger *utils.Logger) PromptService {
+	return &DefaultPromptService{
+		db:     db,
+		logger: logger,
+	}
+}
+
+// GetProfile 获取用户的提示词变量
+func (s *DefaultPromptService) GetProfile(ctx context.Context, userID string) (*models.UserPromptProfile, error) {
+	var profile models.UserPromptProfile
+	err := s.db.WithContext(ctx).Where("user_id = ?", userID).First(&profile).Error
+	if err != nil {
+		if err == gorm.ErrRecordNotFound {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return &profile, nil
+}
+
+// SaveProfile 校验并保存用户的提示词变量
+func (s *DefaultPromptService) SaveProfile(ctx context.Context, userID string, req *models.UpdatePromptProfileRequest) (*models.UserPromptProfile, error) {
+	if userID == "" {
+		return nil, fmt.Errorf("用户ID不能为空")
+	}
+	if utf8.RuneCountInString(req.Nickname) > maxNicknameRunes {
+		return nil, fmt.Errorf("昵称不能超过%d个字符", maxNicknameRunes)
+	}
+	if len(req.Fields) > maxPromptFields {
+		return nil, fmt.Errorf("自定义字段不能超过%d个", maxPromptFields)
+	}
+	for name, value := range req.Fields {
+		if !promptFieldNamePattern.MatchString(name) {
+			return nil, fmt.Errorf("字段名 %s 无效，只能包含字母、数字和下划线且不能以数字开头", name)
+		}
+		if utf8.RuneCountInString(value) > maxPromptFieldRunes {
+			return nil, fmt.Errorf("字段 %s 的值不能超过%d个字符", name, maxPromptFieldRunes)
+		}
+	}
+	fields, err := json.Marshal(req.Fields)
+	if err != nil {
+		return nil, err
+	}
+
+	profile, err := s.GetProfile(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	if profile == nil {
+		profile = &models.UserPromptProfile{UserID: userID}
+	}
+	profile.Nickname = req.Nickname
+	profile.Fields = string(fields)
+	if err := s.db.WithContext(ctx).Save(profile).Error; err != nil {
+		return nil, err
+	}
+	return profile, nil
+}
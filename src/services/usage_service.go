@@ -0,0 +1,273 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"angrymiao-ai-server/src/configs"
+	"angrymiao-ai-server/src/core/usage"
+	"angrymiao-ai-server/src/core/utils"
+	"angrymiao-ai-server/src/models"
+	"angrymiao-ai-server/src/task"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// usageDateLayout 用量记录的日期格式
+const usageDateLayout = "2006-01-02"
+
+// ErrUsageQuotaExceeded 对话用量超出配额
+var ErrUsageQuotaExceeded = errors.New("对话用量超出配额")
+
+// UsageStatus 用户或设备当前的用量和配额
+type UsageStatus struct {
+	Level   task.UserLevel         `json:"level"`
+	Quota   task.ConversationQuota `json:"quota"`
+	Daily   task.ConversationUsage `json:"daily"`
+	Monthly task.ConversationUsage `json:"monthly"`
+}
+
+// UsageService 用量统计服务接口
+type UsageService interface {
+	// Record 累加一个连接的用量，userID为空时只按设备统计
+	Record(ctx context.Context, userID, deviceID string, entries []usage.Entry) error
+	// Query 查询用量记录并按配置单价计算费用
+	Query(ctx context.Context, query *models.UsageQuery) ([]*models.UsageRecord, *models.UsageTotal, error)
+
+	// 用户级别和配额
+	GetLevel(ctx context.Context, userID string) (task.UserLevel, error)
+	SetLevel(ctx context.Context, userID string, level task.UserLevel) error
+	GetStatus(ctx context.Context, userID, deviceID string) (*UsageStatus, error)
+	// CheckQuota 检查是否超出配额，超出时返回包装了 ErrUsageQuotaExceeded 的错误
+	CheckQuota(ctx context.Context, userID, deviceID string) error
+}
+
+// DefaultUsageService 默认用量统计服务实现
+type DefaultUsageService struct {
+	db     *gorm.DB
+	config *configs.Config
+	logger *utils.Logger
+}
+
+// NewUsageService 创建用量统计服务实例
+func NewUsageService(db *gorm.DB, config *configs.Config, logger *utils.Logger) UsageService {
+	return &DefaultUsageService{
+		db:     db,
+		config: config,
+		logger: logger,
+	}
+}
+
+// Record 累加用量到当天的汇总记录
+func (s *DefaultUsageService) Record(ctx context.Context, userID, deviceID string, entries []usage.Entry) error {
+	date := time.Now().Format(usageDateLayout)
+	for _, e := range entries {
+		record := &models.UsageRecord{
+			UserID:           userID,
+			DeviceID:         deviceID,
+			ProviderType:     e.ProviderType,
+			Provider:         e.Provider,
+			Date:             date,
+			Requests:         e.Requests,
+			PromptTokens:     e.PromptTokens,
+			CompletionTokens: e.CompletionTokens,
+			TTSChars:         e.TTSChars,
+			ASRSeconds:       e.ASRSeconds,
+		}
+		if e.Estimated {
+			record.EstimatedTokens = e.PromptTokens + e.CompletionTokens
+		}
+		err := s.db.WithContext(ctx).Clauses(clause.OnConflict{
+			Columns: []clause.Column{{Name: "user_id"}, {Name: "device_id"}, {Name: "provider_type"}, {Name: "provider"}, {Name: "date"}},
+			DoUpdates: clause.Assignments(map[string]interface{}{
+				"requests":          gorm.Expr("requests + ?", record.Requests),
+				"prompt_tokens":     gorm.Expr("prompt_tokens + ?", record.PromptTokens),
+				"completion_tokens": gorm.Expr("completion_tokens + ?", record.CompletionTokens),
+				"estimated_tokens":  gorm.Expr("estimated_tokens + ?", record.EstimatedTokens),
+				"tts_chars":         gorm.Expr("tts_chars + ?", record.TTSChars),
+				"asr_seconds":       gorm.Expr("asr_seconds + ?", record.ASRSeconds),
+				"updated_at":        time.Now(),
+			}),
+		}).Create(record).Error
+		if err != nil {
+			s.logger.Error("保存用量记录失败: %v", err)
+			return err
+		}
+	}
+	return nil
+}
+
+// Query 查询用量记录
+func (s *DefaultUsageService) Query(ctx context.Context, query *models.UsageQuery) ([]*models.UsageRecord, *models.UsageTotal, error) {
+	db := s.db.WithContext(ctx).Model(&models.UsageRecord{})
+	if query.UserID != "" {
+		db = db.Where("user_id = ?", query.UserID)
+	}
+	if query.DeviceID != "" {
+		db = db.Where("device_id = ?", query.DeviceID)
+	}
+	if query.ProviderType != "" {
+		db = db.Where("provider_type = ?", query.ProviderType)
+	}
+	if query.Provider != "" {
+		db = db.Where("provider = ?", query.Provider)
+	}
+	for _, d := range []string{query.From, query.To} {
+		if d == "" {
+			continue
+		}
+		if _, err := time.Parse(usageDateLayout, d); err != nil {
+			return nil, nil, fmt.Errorf("日期格式错误，应为 %s: %s", usageDateLayout, d)
+		}
+	}
+	if query.From != "" {
+		db = db.Where("date >= ?", query.From)
+	}
+	if query.To != "" {
+		db = db.Where("date <= ?", query.To)
+	}
+
+	var records []*models.UsageRecord
+	if err := db.Order("date DESC, provider_type ASC, provider ASC").Find(&records).Error; err != nil {
+		return nil, nil, err
+	}
+
+	total := &models.UsageTotal{}
+	for _, r := range records {
+		r.Cost = s.cost(r)
+		total.Add(r)
+	}
+	return records, total, nil
+}
+
+// cost 按配置单价估算费用
+func (s *DefaultUsageService) cost(r *models.UsageRecord) float64 {
+	price, ok := s.config.Usage.Prices[r.Provider]
+	if !ok {
+		return 0
+	}
+	return float64(r.PromptTokens)/1000*price.PromptPer1K +
+		float64(r.CompletionTokens)/1000*price.CompletionPer1K +
+		float64(r.TTSChars)/1000*price.CharsPer1K +
+		r.ASRSeconds/60*price.PerMinute
+}
+
+// GetLevel 获取用户级别，未设置时使用配置的默认级别
+func (s *DefaultUsageService) GetLevel(ctx context.Context, userID string) (task.UserLevel, error) {
+	defaultLevel, ok := task.ParseUserLevel(s.config.Usage.DefaultLevel)
+	if !ok {
+		defaultLevel = task.UserLevelBasic
+	}
+	if userID == "" {
+		return defaultLevel, nil
+	}
+
+	var row models.UserUsageLevel
+	err := s.db.WithContext(ctx).Where("user_id = ?", userID).First(&row).Error
+	if err != nil {
+		if err == gorm.ErrRecordNotFound {
+			return defaultLevel, nil
+		}
+		return "", err
+	}
+	if level, ok := task.ParseUserLevel(row.Level); ok {
+		return level, nil
+	}
+	return defaultLevel, nil
+}
+
+// SetLevel 设置用户级别
+func (s *DefaultUsageService) SetLevel(ctx context.Context, userID string, level task.UserLevel) error {
+	if userID == "" {
+		return fmt.Errorf("用户ID不能为空")
+	}
+	if _, ok := task.ParseUserLevel(string(level)); !ok {
+		return fmt.Errorf("无效的用户级别: %s", level)
+	}
+
+	row := &models.UserUsageLevel{UserID: userID, Level: string(level)}
+	err := s.db.WithContext(ctx).Clauses(clause.OnConflict{
+		Columns:   []clause.Column{{Name: "user_id"}},
+		DoUpdates: clause.AssignmentColumns([]string{"level", "updated_at"}),
+	}).Create(row).Error
+	if err != nil {
+		s.logger.Error("设置用户级别失败: %v", err)
+		return err
+	}
+
+	s.logger.Info("用户 %s 的级别设置为: %s", userID, level)
+	return nil
+}
+
+// quota 获取用户级别的配额，配置优先于内置默认值
+func (s *DefaultUsageService) quota(level task.UserLevel) task.ConversationQuota {
+	rq := task.NewResourceQuota()
+	rq.SetUserLevel(level)
+	if cfg, ok := s.config.Usage.Quotas[string(level)]; ok {
+		rq.SetConversationQuota(task.ConversationQuota{
+			DailyTokens:       cfg.DailyTokens,
+			MonthlyTokens:     cfg.MonthlyTokens,
+			DailyTTSChars:     cfg.DailyTTSChars,
+			MonthlyTTSChars:   cfg.MonthlyTTSChars,
+			DailyASRSeconds:   cfg.DailyASRSeconds,
+			MonthlyASRSeconds: cfg.MonthlyASRSeconds,
+		})
+	}
+	return rq.Conversation
+}
+
+// GetStatus 获取当日和当月的用量及配额，登录用户按用户统计，否则按设备统计
+func (s *DefaultUsageService) GetStatus(ctx context.Context, userID, deviceID string) (*UsageStatus, error) {
+	level, err := s.GetLevel(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	now := time.Now()
+	today := now.Format(usageDateLayout)
+	monthStart := time.Date(now.Year(), now.Month(), 1, 0, 0, 0, 0, now.Location()).Format(usageDateLayout)
+
+	db := s.db.WithContext(ctx).Model(&models.UsageRecord{}).Where("date >= ?", monthStart)
+	if userID != "" {
+		db = db.Where("user_id = ?", userID)
+	} else {
+		db = db.Where("user_id = ? AND device_id = ?", "", deviceID)
+	}
+	var records []*models.UsageRecord
+	if err := db.Find(&records).Error; err != nil {
+		return nil, err
+	}
+
+	status := &UsageStatus{Level: level, Quota: s.quota(level)}
+	for _, r := range records {
+		u := task.ConversationUsage{
+			PromptTokens:     r.PromptTokens,
+			CompletionTokens: r.CompletionTokens,
+			TTSChars:         r.TTSChars,
+			ASRSeconds:       r.ASRSeconds,
+		}
+		status.Monthly.Add(u)
+		if r.Date == today {
+			status.Daily.Add(u)
+		}
+	}
+	return status, nil
+}
+
+// CheckQuota 检查是否超出配额
+func (s *DefaultUsageService) CheckQuota(ctx context.Context, userID, deviceID string) error {
+	if userID == "" && deviceID == "" {
+		return nil
+	}
+	status, err := s.GetStatus(ctx, userID, deviceID)
+	if err != nil {
+		return err
+	}
+	if err := status.Quota.Check(status.Daily, status.Monthly); err != nil {
+		return fmt.Errorf("%w: %v", ErrUsageQuotaExceeded, err)
+	}
+	return nil
+}
@@ -192,7 +192,7 @@ func (s *DefaultUserAIConfigService) ValidateLLMConfig(ctx context.Context, conf
 	}
 
 	// 验证支持的LLM类型
-	supportedTypes := []string{"qwen", "chatglm", "ollama", "coze", "openai", "claude"}
+	supportedTypes := []string{"qwen", "chatglm", "ollama", "coze", "openai", "claude", "anthropic"}
 	isSupported := false
 	for _, t := range supportedTypes {
 		if strings.ToLower(config.LLMType) == t {
@@ -0,0 +1,333 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/url"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+
+	"angrymiao-ai-server/src/configs"
+	"angrymiao-ai-server/src/core/providers/tts"
+	"angrymiao-ai-server/src/core/utils"
+	"angrymiao-ai-server/src/models"
+
+	"golang.org/x/sync/singleflight"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// 默认音色试听文本，%s 替换为音色显示名称
+const defaultVoicePreviewText = "你好，我是%s，很高兴为你服务。"
+
+// ValidateVoiceConfig 校验各TTS配置的试听文本，启动时调用
+func ValidateVoiceConfig(cfg *configs.Config) error {
+	for name, ttsCfg := range cfg.TTS {
+		if ttsCfg.PreviewText == "" {
+			continue
+		}
+		if err := validatePreviewText(ttsCfg.PreviewText); err != nil {
+			return fmt.Errorf("TTS %s: %w", name, err)
+		}
+	}
+	return nil
+}
+
+// validatePreviewText 试听文本需包含且只包含一个 %s 作为音色名称，字面的百分号写作 %%
+func validatePreviewText(text string) error {
+	rest := strings.ReplaceAll(text, "%%", "")
+	if strings.Count(rest, "%s") != 1 || strings.Count(rest, "%") != 1 {
+		return fmt.Errorf("试听文本需包含一个 %%s 作为音色名称: %q", text)
+	}
+	return nil
+}
+
+// VoiceProviderInfo TTS提供者及其音色列表
+type VoiceProviderInfo struct {
+	Name     string              `json:"name"`     // 配置中的TTS名称，如 EdgeTTS
+	Type     string              `json:"type"`     // TTS类型，如 edge
+	Selected bool                `json:"selected"` // 是否为当前选中的TTS
+	Voices   []configs.VoiceInfo `json:"voices"`
+}
+
+// VoiceService 音色服务接口
+type VoiceService interface {
+	// 音色目录
+	ListProviders(ctx context.Context) []*VoiceProviderInfo
+	ListVoices(ctx context.Context, provider string) ([]configs.VoiceInfo, error)
+	ResolveVoice(provider, voice string) (*configs.VoiceInfo, error)
+
+	// 试听音频，返回本地缓存文件路径
+	GetPreview(ctx context.Context, provider, voice string) (string, error)
+
+	// 用户默认音色
+	GetUserVoice(ctx context.Context, userID, provider string) (*models.UserVoicePreference, error)
+	SetUserVoice(ctx context.Context, userID, provider, voice string) (*models.UserVoicePreference, error)
+}
+
+// DefaultVoiceService 默认音色服务实现
+type DefaultVoiceService struct {
+	db       *gorm.DB
+	logger   *utils.Logger
+	config   *configs.Config
+	cacheDir string // 试听音频缓存目录
+
+	// 同一音色并发未命中缓存时只合成一次
+	previewGroup singleflight.Group
+	// synthesize 合成试听音频并返回临时文件路径，测试时可替换
+	synthesize func(provider string, info *configs.VoiceInfo) (string, error)
+}
+
+// NewVoiceService 创建音色服务实例
+func NewVoiceService(db *gorm.DB, config *configs.Config, logger *utils.Logger) VoiceService {
+	s := &DefaultVoiceService{
+		db:       db,
+		logger:   logger,
+		config:   config,
+		cacheDir: "voice_preview",
+	}
+	s.synthesize = s.synthesizePreview
+	return s
+}
+
+// ListProviders 列出所有配置的TTS提供者及其音色
+func (s *DefaultVoiceService) ListProviders(ctx context.Context) []*VoiceProviderInfo {
+	names := make([]string, 0, len(s.config.TTS))
+	for name := range s.config.TTS {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	selected := s.config.SelectedModule["TTS"]
+	result := make([]*VoiceProviderInfo, 0, len(names))
+	for _, name := range names {
+		ttsCfg := s.config.TTS[name]
+		result = append(result, &VoiceProviderInfo{
+			Name:     name,
+			Type:     ttsCfg.Type,
+			Selected: name == selected,
+			Voices:   s.withPreviewURL(name, ttsCfg.SupportedVoices),
+		})
+	}
+	return result
+}
+
+// ListVoices 列出指定TTS提供者支持的音色
+func (s *DefaultVoiceService) ListVoices(ctx context.Context, provider string) ([]configs.VoiceInfo, error) {
+	ttsCfg, ok := s.config.TTS[s.providerName(provider)]
+	if !ok {
+		return nil, fmt.Errorf("TTS提供者不存在: %s", provider)
+	}
+	return s.withPreviewURL(s.providerName(provider), ttsCfg.SupportedVoices), nil
+}
+
+// ResolveVoice 按音色名称或显示名称查找音色
+func (s *DefaultVoiceService) ResolveVoice(provider, voice string) (*configs.VoiceInfo, error) {
+	ttsCfg, ok := s.config.TTS[s.providerName(provider)]
+	if !ok {
+		return nil, fmt.Errorf("TTS提供者不存在: %s", provider)
+	}
+	for i := range ttsCfg.SupportedVoices {
+		v := ttsCfg.SupportedVoices[i]
+		if v.Name == voice || v.DisplayName == voice {
+			return &v, nil
+		}
+	}
+	return nil, fmt.Errorf("音色不存在: %s", voice)
+}
+
+// GetPreview 获取音色试听音频，已缓存时直接返回缓存文件
+// 试听文本按TTS提供者固定，只能合成已配置的音色
+func (s *DefaultVoiceService) GetPreview(ctx context.Context, provider, voice string) (string, error) {
+	provider = s.providerName(provider)
+	info, err := s.ResolveVoice(provider, voice)
+	if err != nil {
+		return "", err
+	}
+
+	// 不同TTS输出的音频格式不同，按文件名前缀查找缓存
+	cacheBase := filepath.Join(s.cacheDir, sanitizePathSegment(provider), sanitizePathSegment(info.Name))
+	if cachePath := findPreviewCache(cacheBase); cachePath != "" {
+		return cachePath, nil
+	}
+
+	result, err, _ := s.previewGroup.Do(cacheBase, func() (interface{}, error) {
+		// 等待期间其他请求可能已生成缓存
+		if cachePath := findPreviewCache(cacheBase); cachePath != "" {
+			return cachePath, nil
+		}
+		return s.generatePreview(provider, info, cacheBase)
+	})
+	if err != nil {
+		return "", err
+	}
+	return result.(string), nil
+}
+
+// generatePreview 合成试听音频并写入缓存，先写临时文件再重命名，避免并发读取到不完整的文件
+func (s *DefaultVoiceService) generatePreview(provider string, info *configs.VoiceInfo, cacheBase string) (string, error) {
+	start := time.Now()
+	audioPath, err := s.synthesize(provider, info)
+	if err != nil {
+		return "", fmt.Errorf("合成试听音频失败: %v", err)
+	}
+	defer os.Remove(audioPath)
+
+	cachePath := cacheBase + filepath.Ext(audioPath)
+	if err := os.MkdirAll(filepath.Dir(cachePath), 0o755); err != nil {
+		return "", fmt.Errorf("创建缓存目录失败: %v", err)
+	}
+	tmpPath := cachePath + ".tmp"
+	if err := copyFile(audioPath, tmpPath); err != nil {
+		os.Remove(tmpPath)
+		return "", err
+	}
+	if err := os.Rename(tmpPath, cachePath); err != nil {
+		os.Remove(tmpPath)
+		return "", fmt.Errorf("写入缓存文件失败: %v", err)
+	}
+
+	s.logger.Info("生成音色试听音频: %s/%s, 耗时: %s", provider, info.Name, time.Since(start))
+	return cachePath, nil
+}
+
+// synthesizePreview 使用配置的试听文本合成音频
+func (s *DefaultVoiceService) synthesizePreview(provider string, info *configs.VoiceInfo) (string, error) {
+	ttsCfg := s.config.TTS[provider]
+	// 使用独立的提供者实例，避免修改资源池中共享的音色配置
+	ttsProvider, err := tts.Create(ttsCfg.Type, &tts.Config{
+		Name:            provider,
+		Type:            ttsCfg.Type,
+		Voice:           info.Name,
+		Format:          ttsCfg.Format,
+		OutputDir:       ttsCfg.OutputDir,
+		AppID:           ttsCfg.AppID,
+		Token:           ttsCfg.Token,
+		Cluster:         ttsCfg.Cluster,
+		SupportedVoices: ttsCfg.SupportedVoices,
+	}, true)
+	if err != nil {
+		return "", err
+	}
+
+	previewText := ttsCfg.PreviewText
+	if err := validatePreviewText(previewText); err != nil {
+		if previewText != "" {
+			s.logger.Warn("%s 的试听文本无效，使用默认文本: %v", provider, err)
+		}
+		previewText = defaultVoicePreviewText
+	}
+	displayName := info.DisplayName
+	if displayName == "" {
+		displayName = info.Name
+	}
+	return ttsProvider.ToTTS(fmt.Sprintf(previewText, displayName))
+}
+
+// findPreviewCache 查找已缓存的试听音频，忽略写入中的临时文件
+func findPreviewCache(cacheBase string) string {
+	matches, _ := filepath.Glob(cacheBase + ".*")
+	for _, match := range matches {
+		if filepath.Ext(match) != ".tmp" {
+			return match
+		}
+	}
+	return ""
+}
+
+// GetUserVoice 获取用户在指定TTS提供者下的默认音色，未设置时返回 nil
+func (s *DefaultVoiceService) GetUserVoice(ctx context.Context, userID, provider string) (*models.UserVoicePreference, error) {
+	var pref models.UserVoicePreference
+	err := s.db.WithContext(ctx).
+		Where("user_id = ? AND tts_provider = ?", userID, s.providerName(provider)).
+		First(&pref).Error
+	if err != nil {
+		if err == gorm.ErrRecordNotFound {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return &pref, nil
+}
+
+// SetUserVoice 设置用户在指定TTS提供者下的默认音色
+func (s *DefaultVoiceService) SetUserVoice(ctx context.Context, userID, provider, voice string) (*models.UserVoicePreference, error) {
+	provider = s.providerName(provider)
+	info, err := s.ResolveVoice(provider, voice)
+	if err != nil {
+		return nil, err
+	}
+
+	pref := &models.UserVoicePreference{
+		UserID:      userID,
+		TTSProvider: provider,
+		Voice:       info.Name,
+	}
+	err = s.db.WithContext(ctx).Clauses(clause.OnConflict{
+		Columns:   []clause.Column{{Name: "user_id"}, {Name: "tts_provider"}},
+		DoUpdates: clause.AssignmentColumns([]string{"voice", "updated_at"}),
+	}).Create(pref).Error
+	if err != nil {
+		s.logger.Error("保存用户默认音色失败: %v", err)
+		return nil, err
+	}
+
+	s.logger.Info("用户 %s 设置默认音色: %s/%s", userID, provider, info.Name)
+	return s.GetUserVoice(ctx, userID, provider)
+}
+
+// withPreviewURL 返回音色列表副本，未配置AudioURL的音色填充为试听接口地址
+func (s *DefaultVoiceService) withPreviewURL(provider string, voices []configs.VoiceInfo) []configs.VoiceInfo {
+	result := make([]configs.VoiceInfo, len(voices))
+	for i, v := range voices {
+		if v.AudioURL == "" {
+			v.AudioURL = fmt.Sprintf("/api/voices/%s/%s/preview", url.PathEscape(provider), url.PathEscape(v.Name))
+		}
+		result[i] = v
+	}
+	return result
+}
+
+// providerName 为空时返回当前选中的TTS名称
+func (s *DefaultVoiceService) providerName(provider string) string {
+	if provider == "" {
+		return s.config.SelectedModule["TTS"]
+	}
+	return provider
+}
+
+var unsafePathChars = regexp.MustCompile(`[^A-Za-z0-9_\-.]+`)
+
+// sanitizePathSegment 清理路径片段，防止目录穿越
+func sanitizePathSegment(name string) string {
+	safe := unsafePathChars.ReplaceAllString(name, "_")
+	if safe == "" || safe == "." || safe == ".." {
+		return "_"
+	}
+	return safe
+}
+
+// copyFile 复制文件
+func copyFile(src, dst string) error {
+	in, err := os.Open(src)
+	if err != nil {
+		return fmt.Errorf("打开源文件失败: %v", err)
+	}
+	defer in.Close()
+
+	out, err := os.Create(dst)
+	if err != nil {
+		return fmt.Errorf("创建目标文件失败: %v", err)
+	}
+	defer out.Close()
+
+	if _, err := io.Copy(out, in); err != nil {
+		return fmt.Errorf("复制文件失败: %v", err)
+	}
+	return nil
+}
@@ -0,0 +1,129 @@
+package services
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"angrymiao-ai-server/src/configs"
+	"angrymiao-ai-server/src/core/utils"
+)
+
+func newTestVoiceService(t *testing.T, synthesize func(provider string, info *configs.VoiceInfo) (string, error)) *DefaultVoiceService {
+	t.Helper()
+	logger, err := utils.NewLogger(&utils.LogCfg{LogLevel: "ERROR", LogDir: t.TempDir(), LogFile: "test.log"})
+	if err != nil {
+		t.Fatalf("NewLogger() 错误: %v", err)
+	}
+	t.Cleanup(func() { logger.Close() })
+
+	config := &configs.Config{
+		TTS: map[string]configs.TTSConfig{
+			"EdgeTTS": {Type: "edge", SupportedVoices: []configs.VoiceInfo{{Name: "zh-CN-XiaoxiaoNeural", DisplayName: "晓晓"}}},
+		},
+		SelectedModule: map[string]string{"TTS": "EdgeTTS"},
+	}
+	s := NewVoiceService(nil, config, logger).(*DefaultVoiceService)
+	s.cacheDir = t.TempDir()
+	s.synthesize = synthesize
+	return s
+}
+
+// fakeSynthesize 写入一个临时音频文件并统计调用次数
+func fakeSynthesize(t *testing.T, calls *int32, delay time.Duration) func(string, *configs.VoiceInfo) (string, error) {
+	dir := t.TempDir()
+	return func(provider string, info *configs.VoiceInfo) (string, error) {
+		n := atomic.AddInt32(calls, 1)
+		time.Sleep(delay)
+		path := filepath.Join(dir, info.Name+string(rune('0'+n))+".mp3")
+		return path, os.WriteFile(path, []byte("audio"), 0o644)
+	}
+}
+
+func TestGetPreviewCache(t *testing.T) {
+	var calls int32
+	s := newTestVoiceService(t, fakeSynthesize(t, &calls, 0))
+
+	first, err := s.GetPreview(context.Background(), "", "晓晓")
+	if err != nil {
+		t.Fatalf("GetPreview() 错误: %v", err)
+	}
+	second, err := s.GetPreview(context.Background(), "EdgeTTS", "zh-CN-XiaoxiaoNeural")
+	if err != nil {
+		t.Fatalf("GetPreview() 错误: %v", err)
+	}
+	if first != second || calls != 1 {
+		t.Errorf("GetPreview() 缓存未命中: %s, %s, 合成 %d 次", first, second, calls)
+	}
+	if data, err := os.ReadFile(first); err != nil || string(data) != "audio" {
+		t.Errorf("GetPreview() 缓存文件内容 = %q, %v", data, err)
+	}
+
+	if _, err := s.GetPreview(context.Background(), "EdgeTTS", "未配置的音色"); err == nil {
+		t.Error("GetPreview() 未配置的音色应返回错误")
+	}
+	if calls != 1 {
+		t.Errorf("GetPreview() 未配置的音色不应合成, 合成 %d 次", calls)
+	}
+}
+
+func TestGetPreviewSingleFlight(t *testing.T) {
+	var calls int32
+	s := newTestVoiceService(t, fakeSynthesize(t, &calls, 50*time.Millisecond))
+
+	var wg sync.WaitGroup
+	paths := make([]string, 8)
+	for i := range paths {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			path, err := s.GetPreview(context.Background(), "EdgeTTS", "晓晓")
+			if err != nil {
+				t.Errorf("GetPreview() 错误: %v", err)
+			}
+			paths[i] = path
+		}(i)
+	}
+	wg.Wait()
+
+	if calls != 1 {
+		t.Errorf("GetPreview() 并发未命中合成 %d 次, 期望 1", calls)
+	}
+	for _, path := range paths {
+		if path != paths[0] {
+			t.Errorf("GetPreview() 并发返回不同路径: %v", paths)
+			break
+		}
+	}
+	if matches, _ := filepath.Glob(filepath.Join(s.cacheDir, "*", "*.tmp")); len(matches) > 0 {
+		t.Errorf("GetPreview() 残留临时文件: %v", matches)
+	}
+}
+
+func TestValidatePreviewText(t *testing.T) {
+	tests := []struct {
+		text  string
+		valid bool
+	}{
+		{"你好，我是%s。", true},
+		{"我是%s，音量100%%", true},
+		{"大家好", false},
+		{"%s和%s", false},
+		{"我是%d号", false},
+		{"我是%s，音量100%", false},
+	}
+	for _, tt := range tests {
+		if err := validatePreviewText(tt.text); (err == nil) != tt.valid {
+			t.Errorf("validatePreviewText(%q) 错误 = %v, 期望有效 %v", tt.text, err, tt.valid)
+		}
+	}
+
+	config := &configs.Config{TTS: map[string]configs.TTSConfig{"EdgeTTS": {PreviewText: "大家好"}}}
+	if err := ValidateVoiceConfig(config); err == nil {
+		t.Error("ValidateVoiceConfig() 缺少音色名称占位符的试听文本应返回错误")
+	}
+}
@@ -73,6 +73,7 @@ func NewResourceQuota() *ResourceQuota {
 		TotalUsedQuota:     0,
 		TotalRunningTasks:  0,
 		UserLevel:          UserLevelBasic,
+		Conversation:       DefaultConversationQuota(UserLevelBasic),
 		LastResetDate: time.Date(
 			now.Year(),
 			now.Month(),
@@ -107,6 +108,7 @@ func (rq *ResourceQuota) SetUserLevel(level UserLevel) {
 		rq.MaxTotalTasks = 2000
 		rq.MaxConcurrentTasks = 50
 	}
+	rq.Conversation = DefaultConversationQuota(level)
 }
 
 func (rq *ResourceQuota) CheckAndResetDailyQuota() {
@@ -159,6 +159,27 @@ type ResourceQuota struct {
 	UserLevel          UserLevel // 新增用户级别字段
 	LastResetDate      time.Time
 	mu                 sync.RWMutex
+
+	// 对话用量配额，随用户级别设置
+	Conversation ConversationQuota
+}
+
+// ConversationQuota 对话用量配额，0表示不限制
+type ConversationQuota struct {
+	DailyTokens       int64   `json:"daily_tokens"`        // 每日LLM token数
+	MonthlyTokens     int64   `json:"monthly_tokens"`      // 每月LLM token数
+	DailyTTSChars     int64   `json:"daily_tts_chars"`     // 每日TTS字符数
+	MonthlyTTSChars   int64   `json:"monthly_tts_chars"`   // 每月TTS字符数
+	DailyASRSeconds   float64 `json:"daily_asr_seconds"`   // 每日ASR音频秒数
+	MonthlyASRSeconds float64 `json:"monthly_asr_seconds"` // 每月ASR音频秒数
+}
+
+// ConversationUsage 对话用量
+type ConversationUsage struct {
+	PromptTokens     int64   `json:"prompt_tokens"`
+	CompletionTokens int64   `json:"completion_tokens"`
+	TTSChars         int64   `json:"tts_chars"`
+	ASRSeconds       float64 `json:"asr_seconds"`
 }
 
 // ClientContext holds client-specific settings and state
@@ -0,0 +1,73 @@
+package task
+
+import "fmt"
+
+// 各用户级别默认的对话用量配额
+var defaultConversationQuotas = map[UserLevel]ConversationQuota{
+	UserLevelBasic: {
+		DailyTokens:     200000,
+		MonthlyTokens:   3000000,
+		DailyTTSChars:   20000,
+		DailyASRSeconds: 3600,
+	},
+	UserLevelPremium: {
+		DailyTokens:     1000000,
+		MonthlyTokens:   20000000,
+		DailyTTSChars:   100000,
+		DailyASRSeconds: 4 * 3600,
+	},
+	UserLevelBusiness: {},
+}
+
+// ParseUserLevel 解析用户级别
+func ParseUserLevel(s string) (UserLevel, bool) {
+	switch level := UserLevel(s); level {
+	case UserLevelBasic, UserLevelPremium, UserLevelBusiness:
+		return level, true
+	}
+	return "", false
+}
+
+// DefaultConversationQuota 获取用户级别默认的对话用量配额
+func DefaultConversationQuota(level UserLevel) ConversationQuota {
+	return defaultConversationQuotas[level]
+}
+
+// SetConversationQuota 设置对话用量配额
+func (rq *ResourceQuota) SetConversationQuota(quota ConversationQuota) {
+	rq.mu.Lock()
+	defer rq.mu.Unlock()
+	rq.Conversation = quota
+}
+
+// Tokens LLM token总数
+func (u ConversationUsage) Tokens() int64 {
+	return u.PromptTokens + u.CompletionTokens
+}
+
+// Add 累加用量
+func (u *ConversationUsage) Add(other ConversationUsage) {
+	u.PromptTokens += other.PromptTokens
+	u.CompletionTokens += other.CompletionTokens
+	u.TTSChars += other.TTSChars
+	u.ASRSeconds += other.ASRSeconds
+}
+
+// Check 检查当日和当月用量是否超出配额，超出时返回说明
+func (q ConversationQuota) Check(daily, monthly ConversationUsage) error {
+	switch {
+	case q.DailyTokens > 0 && daily.Tokens() >= q.DailyTokens:
+		return fmt.Errorf("daily token quota exceeded: %d/%d", daily.Tokens(), q.DailyTokens)
+	case q.MonthlyTokens > 0 && monthly.Tokens() >= q.MonthlyTokens:
+		return fmt.Errorf("monthly token quota exceeded: %d/%d", monthly.Tokens(), q.MonthlyTokens)
+	case q.DailyTTSChars > 0 && daily.TTSChars >= q.DailyTTSChars:
+		return fmt.Errorf("daily tts quota exceeded: %d/%d", daily.TTSChars, q.DailyTTSChars)
+	case q.MonthlyTTSChars > 0 && monthly.TTSChars >= q.MonthlyTTSChars:
+		return fmt.Errorf("monthly tts quota exceeded: %d/%d", monthly.TTSChars, q.MonthlyTTSChars)
+	case q.DailyASRSeconds > 0 && daily.ASRSeconds >= q.DailyASRSeconds:
+		return fmt.Errorf("daily asr quota exceeded: %.0f/%.0f", daily.ASRSeconds, q.DailyASRSeconds)
+	case q.MonthlyASRSeconds > 0 && monthly.ASRSeconds >= q.MonthlyASRSeconds:
+		return fmt.Errorf("monthly asr quota exceeded: %.0f/%.0f", monthly.ASRSeconds, q.MonthlyASRSeconds)
+	}
+	return nil
+}
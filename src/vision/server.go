@@ -340,8 +340,8 @@ func (s *DefaultVisionService) processVisionRequest(req *VisionRequest) (string,
 
 	// 收集所有响应内容
 	var result strings.Builder
-	for content := range responseChan {
-		result.WriteString(content)
+	for response := range responseChan {
+		result.WriteString(response.Content)
 	}
 	s.logger.Info(fmt.Sprintf("VLLLM分析结果: %s", result.String()))
 